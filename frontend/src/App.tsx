@@ -3,6 +3,7 @@ import { useEffect } from 'react';
 import { useAuthStore } from './store/auth';
 import Layout from './components/Layout';
 import Login from './features/auth/Login';
+import ChangePassword from './features/auth/ChangePassword';
 import TestExecution from './features/test-execution/TestExecutionPage';
 import AdminPage from './features/admin/AdminPage';
 import HistoryPage from './features/history/HistoryPage';
@@ -23,6 +24,10 @@ function ProtectedRoute({ children }: { children: React.ReactNode }) {
     return <Navigate to="/login" replace />;
   }
 
+  if (user.must_change_password) {
+    return <Navigate to="/change-password" replace />;
+  }
+
   return <>{children}</>;
 }
 
@@ -39,6 +44,7 @@ function App() {
     <BrowserRouter>
       <Routes>
         <Route path="/login" element={<Login />} />
+        <Route path="/change-password" element={<ChangePassword />} />
         <Route
           path="/"
           element={
@@ -21,6 +21,16 @@ export const authApi = {
     return response.data;
   },
 
+  changePassword: async (currentPassword: string, newPassword: string): Promise<string> => {
+    const response = await apiClient.post<{ token: string }>('/api/v1/auth/change-password', {
+      current_password: currentPassword,
+      new_password: newPassword,
+    });
+    const token = response.data.token;
+    localStorage.setItem('token', token);
+    return token;
+  },
+
   logout: () => {
     localStorage.removeItem('token');
     localStorage.removeItem('user');
@@ -45,10 +45,17 @@ apiClient.interceptors.request.use(
   (error) => Promise.reject(error)
 );
 
-// Response interceptor - handle 401
+// Response interceptor - handle 401 and required password changes
 apiClient.interceptors.response.use(
   (response) => response,
-  (error: AxiosError) => {
+  (error: AxiosError<{ code?: string }>) => {
+    if (
+      error.response?.status === 403 &&
+      error.response.data?.code === 'password_change_required' &&
+      window.location.pathname !== '/change-password'
+    ) {
+      window.location.href = '/change-password';
+    }
     if (error.response?.status === 401) {
       // Clear all auth-related storage to prevent stale state after Zustand rehydration
       localStorage.removeItem('token');
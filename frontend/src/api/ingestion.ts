@@ -1,5 +1,5 @@
 import apiClient from './client';
-import type { APISpecification } from '../types';
+import type { APISpecification, APISpecRevision, SpecDiff } from '../types';
 
 export const ingestionApi = {
   listAPIs: async (): Promise<{ apis: APISpecification[]; count: number }> => {
@@ -7,6 +7,26 @@ export const ingestionApi = {
     return response.data;
   },
 
+  listRevisions: async (apiId: string): Promise<{
+    api_id: string;
+    revisions: APISpecRevision[];
+    count: number;
+  }> => {
+    const response = await apiClient.get(`/api/v1/apis/${apiId}/revisions`);
+    return response.data;
+  },
+
+  getRevision: async (apiId: string, revision: number): Promise<APISpecRevision> => {
+    const response = await apiClient.get(`/api/v1/apis/${apiId}/revisions/${revision}`);
+    return response.data;
+  },
+
+  // from and to default to the revision before the latest and the latest
+  diffRevisions: async (apiId: string, range?: { from?: number; to?: number }): Promise<SpecDiff> => {
+    const response = await apiClient.get(`/api/v1/apis/${apiId}/diff`, { params: range });
+    return response.data;
+  },
+
   ingestFolder: async (folderPath: string): Promise<{
     message: string;
     ingested: number;
@@ -20,6 +40,54 @@ export const ingestionApi = {
     return response.data;
   },
 
+  ingestGit: async (source: {
+    repository: string;
+    ref?: string;
+    glob?: string;
+    full?: boolean;
+  }): Promise<{
+    message: string;
+    commit: string;
+    previous_commit?: string;
+    incremental?: boolean;
+    ingested?: number;
+    updated?: number;
+    skipped?: number;
+    failed?: number;
+    removed?: string[] | null;
+    files?: { path: string; status: string; api_id?: string; error?: string }[];
+    errors?: string[] | null;
+  }> => {
+    const response = await apiClient.post('/api/v1/ingest/git', source);
+    return response.data;
+  },
+
+  ingestURL: async (source: {
+    url: string;
+    headers?: Record<string, string>;
+    refresh_interval_seconds?: number;
+  }): Promise<{
+    message: string;
+    status: 'ingested' | 'updated' | 'unchanged';
+    api_id?: string;
+    source: {
+      id: string;
+      url: string;
+      header_names?: string[];
+      refresh_interval_seconds?: number;
+      api_spec_id?: string;
+      content_hash?: string;
+      last_fetched_at?: string;
+      last_error?: string;
+      next_refresh_at?: string;
+      created_at: string;
+      updated_at: string;
+    };
+  }> => {
+    const response = await apiClient.post('/api/v1/ingest/url', source);
+    return response.data;
+  },
+
   getStatus: async (): Promise<unknown> => {
     const response = await apiClient.get('/api/v1/ingest/status');
     return response.data;
@@ -41,5 +109,60 @@ export const ingestionApi = {
     });
     return response.data;
   },
+
+  uploadGraphQL: async (file: File, api: {
+    name: string;
+    version?: string;
+    description?: string;
+    base_url?: string;
+    path?: string;
+  }): Promise<{
+    message: string;
+    api_id: string;
+    name: string;
+    operations: number;
+  }> => {
+    const formData = new FormData();
+    formData.append('file', file);
+    Object.entries(api).forEach(([key, value]) => {
+      if (value) {
+        formData.append(key, value);
+      }
+    });
+
+    const response = await apiClient.post('/api/v1/ingest/graphql', formData, {
+      headers: {
+        'Content-Type': 'multipart/form-data',
+      },
+    });
+    return response.data;
+  },
+
+  uploadGRPC: async (files: File[], api: {
+    name: string;
+    version?: string;
+    description?: string;
+    base_url?: string;
+  }): Promise<{
+    message: string;
+    api_id: string;
+    name: string;
+    methods: number;
+  }> => {
+    const formData = new FormData();
+    files.forEach((file) => formData.append('file', file));
+    Object.entries(api).forEach(([key, value]) => {
+      if (value) {
+        formData.append(key, value);
+      }
+    });
+
+    const response = await apiClient.post('/api/v1/ingest/grpc', formData, {
+      headers: {
+        'Content-Type': 'multipart/form-data',
+      },
+    });
+    return response.data;
+  },
 };
 
@@ -7,12 +7,13 @@ interface ValidateParams {
 }
 
 export const validationApi = {
-  validate: async (response: ValidateParams, expectedStatus?: number, schema?: unknown): Promise<ValidationResult> => {
+  validate: async (response: ValidateParams, expectedStatus?: number, schema?: unknown, protocol?: string): Promise<ValidationResult> => {
     const result = await apiClient.post<ValidationResult>('/api/v1/validate', {
       response: response.body,
       status_code: response.status_code,
       expected_status: expectedStatus,
       expected_schema: schema,
+      protocol,
     });
     return result.data;
   },
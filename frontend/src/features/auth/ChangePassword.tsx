@@ -0,0 +1,148 @@
+import { useState, FormEvent } from 'react';
+import { Navigate, useNavigate } from 'react-router-dom';
+import { AlertCircle, Loader2 } from 'lucide-react';
+import { useAuthStore } from '../../store/auth';
+import Logo from '../../components/Logo';
+
+export default function ChangePassword() {
+  const [currentPassword, setCurrentPassword] = useState('');
+  const [newPassword, setNewPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+  const [mismatch, setMismatch] = useState(false);
+  const { user, changePassword, isLoading, error, clearError } = useAuthStore();
+  const navigate = useNavigate();
+
+  if (!user) {
+    return <Navigate to="/login" replace />;
+  }
+
+  const handleSubmit = async (e: FormEvent) => {
+    e.preventDefault();
+    if (newPassword !== confirmPassword) {
+      setMismatch(true);
+      return;
+    }
+    setMismatch(false);
+    try {
+      await changePassword(currentPassword, newPassword);
+      navigate('/test');
+    } catch {
+      // Error is handled by the store
+    }
+  };
+
+  const inputClass =
+    'w-full px-4 py-3 bg-surface-light border border-border-default rounded-lg text-text-primary placeholder-text-muted focus:outline-none focus:ring-2 focus:ring-primary/50 focus:border-primary transition-all';
+
+  return (
+    <div className="min-h-screen bg-background flex items-center justify-center p-4 relative overflow-hidden">
+      <div className="absolute inset-0 bg-mesh-gradient pointer-events-none" />
+
+      <div className="w-full max-w-md relative z-10">
+        <div className="text-center mb-10">
+          <div className="mx-auto mb-6 flex justify-center">
+            <Logo size={72} variant="hero" />
+          </div>
+          <h1 className="text-3xl font-bold text-text-primary tracking-tight">
+            TestPilot<span className="text-primary">.AI</span>
+          </h1>
+        </div>
+
+        <div className="bg-surface rounded-xl p-8 border border-border-default shadow-xl shadow-black/20">
+          <h2 className="text-lg font-medium text-text-primary mb-2">Change your password</h2>
+          {user.must_change_password && (
+            <p className="text-sm text-text-muted mb-6">
+              Your password must be changed before you can continue.
+            </p>
+          )}
+
+          {(error || mismatch) && (
+            <div className="mb-5 p-3.5 rounded-lg bg-error/10 border border-error/20 flex items-start gap-3 animate-slideIn">
+              <AlertCircle className="w-5 h-5 text-error flex-shrink-0 mt-0.5" />
+              <div className="flex-1">
+                <span className="text-sm text-error">{mismatch ? 'Passwords do not match' : error}</span>
+              </div>
+              <button
+                onClick={() => {
+                  clearError();
+                  setMismatch(false);
+                }}
+                className="text-error/60 hover:text-error text-lg leading-none"
+              >
+                ×
+              </button>
+            </div>
+          )}
+
+          <form onSubmit={handleSubmit} className="space-y-5">
+            <div>
+              <label htmlFor="current-password" className="block text-sm font-medium text-text-secondary mb-2">
+                Current password
+              </label>
+              <input
+                id="current-password"
+                type="password"
+                value={currentPassword}
+                onChange={(e) => setCurrentPassword(e.target.value)}
+                className={inputClass}
+                required
+                autoComplete="current-password"
+                disabled={isLoading}
+              />
+            </div>
+
+            <div>
+              <label htmlFor="new-password" className="block text-sm font-medium text-text-secondary mb-2">
+                New password
+              </label>
+              <input
+                id="new-password"
+                type="password"
+                value={newPassword}
+                onChange={(e) => setNewPassword(e.target.value)}
+                className={inputClass}
+                required
+                autoComplete="new-password"
+                disabled={isLoading}
+              />
+              <p className="mt-1 text-xs text-text-muted">
+                At least 8 characters with upper and lower case letters and a digit
+              </p>
+            </div>
+
+            <div>
+              <label htmlFor="confirm-password" className="block text-sm font-medium text-text-secondary mb-2">
+                Confirm new password
+              </label>
+              <input
+                id="confirm-password"
+                type="password"
+                value={confirmPassword}
+                onChange={(e) => setConfirmPassword(e.target.value)}
+                className={inputClass}
+                required
+                autoComplete="new-password"
+                disabled={isLoading}
+              />
+            </div>
+
+            <button
+              type="submit"
+              disabled={isLoading || !currentPassword || !newPassword || !confirmPassword}
+              className="w-full py-3 px-4 bg-primary text-background font-semibold rounded-lg hover:bg-primary-light focus:outline-none focus:ring-2 focus:ring-primary focus:ring-offset-2 focus:ring-offset-surface disabled:opacity-50 disabled:cursor-not-allowed transition-all flex items-center justify-center gap-2 mt-6"
+            >
+              {isLoading ? (
+                <>
+                  <Loader2 className="w-5 h-5 animate-spin" />
+                  <span>Saving...</span>
+                </>
+              ) : (
+                <span>Change password</span>
+              )}
+            </button>
+          </form>
+        </div>
+      </div>
+    </div>
+  );
+}
@@ -127,18 +127,28 @@ export default function TestExecutionPage() {
         method: constructedRequest.method,
         url: constructedRequest.url,
         headers: constructedRequest.headers,
+        query_params: constructedRequest.query_params,
+        query_param_styles: constructedRequest.query_param_styles,
         body: constructedRequest.body,
+        body_type: constructedRequest.body_type,
+        grpc: constructedRequest.grpc,
+        api_spec_id: constructedRequest.api_spec_id,
         natural_language_request: naturalLanguageInput,
       });
       setState((prev) => ({ ...prev, response }));
 
       // Step 4: Validate
       setState((prev) => ({ ...prev, step: 'validating' }));
-      // Determine expected status code based on HTTP method
-      const expectedStatusCode = getExpectedStatusCode(constructedRequest.method, response.status_code);
+      // Determine expected status code based on HTTP method; gRPC calls
+      // report status OK as 200
+      const expectedStatusCode = constructedRequest.grpc
+        ? 200
+        : getExpectedStatusCode(constructedRequest.method, response.status_code);
       const validationResult = await validationApi.validate(
         { status_code: response.status_code, body: response.body },
-        expectedStatusCode
+        expectedStatusCode,
+        undefined,
+        constructedRequest.body_type === 'graphql' ? 'graphql' : undefined
       );
       setState((prev) => ({ ...prev, validationResult, step: 'complete' }));
 
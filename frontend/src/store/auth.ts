@@ -12,6 +12,7 @@ interface AuthState {
   login: (username: string, password: string) => Promise<void>;
   logout: () => void;
   checkAuth: () => Promise<void>;
+  changePassword: (currentPassword: string, newPassword: string) => Promise<void>;
   clearError: () => void;
 }
 
@@ -29,7 +30,8 @@ export const useAuthStore = create<AuthState>()(
           const { token, user } = await authApi.login({ username, password });
           set({ user, token, isLoading: false });
         } catch (err) {
-          const message = err instanceof Error ? err.message : 'Login failed';
+          const data = (err as { response?: { data?: { error?: string } } }).response?.data;
+          const message = data?.error || (err instanceof Error ? err.message : 'Login failed');
           set({ error: message, isLoading: false });
           throw err;
         }
@@ -53,6 +55,24 @@ export const useAuthStore = create<AuthState>()(
         }
       },
 
+      changePassword: async (currentPassword: string, newPassword: string) => {
+        set({ isLoading: true, error: null });
+        try {
+          const token = await authApi.changePassword(currentPassword, newPassword);
+          const { user } = get();
+          set({
+            token,
+            user: user ? { ...user, must_change_password: false } : user,
+            isLoading: false,
+          });
+        } catch (err) {
+          const data = (err as { response?: { data?: { error?: string } } }).response?.data;
+          const message = data?.error || (err instanceof Error ? err.message : 'Password change failed');
+          set({ error: message, isLoading: false });
+          throw err;
+        }
+      },
+
       clearError: () => set({ error: null }),
     }),
     {
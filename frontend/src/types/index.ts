@@ -3,6 +3,7 @@ export interface User {
   id: string;
   username: string;
   role: 'admin' | 'user';
+  must_change_password?: boolean;
 }
 
 export interface LoginRequest {
@@ -28,15 +29,54 @@ export interface APISpecification {
     description?: string;
     endpoints?: number;
   };
+  // The file and commit of specs ingested from a git repository
+  git?: {
+    source_id: string;
+    repository: string;
+    path: string;
+    commit_sha: string;
+  };
   created_at: string;
   updated_at: string;
 }
 
+// An immutable snapshot of an API specification, one per ingestion with
+// new content; config is only returned for a single revision
+export interface APISpecRevision {
+  id: string;
+  api_spec_id: string;
+  revision: number;
+  content_hash: string;
+  source_type: string;
+  source_path?: string;
+  config?: Record<string, unknown>;
+  created_at: string;
+}
+
+export interface SpecChange {
+  kind: string;
+  severity: 'breaking' | 'non_breaking';
+  endpoint: string;
+  location?: string;
+  message: string;
+}
+
+export interface SpecDiff {
+  api_id: string;
+  from: number;
+  to: number;
+  breaking: boolean;
+  breaking_count: number;
+  non_breaking_count: number;
+  changes: SpecChange[];
+}
+
 // LLM types
 export interface ParseResult {
   intent: string;
   api_name?: string;
   endpoint?: string;
+  operation?: string;
   method?: string;
   parameters?: Record<string, unknown>;
   missing_required?: string[];
@@ -58,17 +98,86 @@ export interface ConstructedRequest {
   url: string;
   path: string;
   headers: Record<string, string>;
-  query_params?: Record<string, string>;
+  query_params?: Record<string, unknown>;
+  query_param_styles?: Record<string, string>;
   body?: Record<string, unknown>;
+  body_type?: string;
+  grpc?: GRPCCall;
+  api_spec_id?: string;
   confidence: number;
 }
 
+// A unary gRPC call of method (package.Service/Method)
+export interface GRPCCall {
+  method: string;
+  descriptor_set?: string;
+}
+
+export interface GRPCResult {
+  code: number;
+  status: string;
+  message?: string;
+  details?: unknown[];
+  header?: Record<string, string[]>;
+  trailer?: Record<string, string[]>;
+}
+
+// A WebSocket or server-sent events test: scripted messages are sent and
+// received messages collected into the response body
+export interface StreamCondition {
+  path: string;
+  operator?: 'equals' | 'not_equals' | 'exists' | 'in';
+  value?: unknown;
+  event?: string;
+}
+
+export interface StreamAssertion extends StreamCondition {
+  match?: 'any' | 'all' | 'none';
+}
+
+export interface StreamOptions {
+  protocol: 'websocket' | 'sse';
+  subprotocols?: string[];
+  send?: { data: unknown; delay_ms?: number }[];
+  duration_seconds?: number;
+  max_messages?: number;
+  min_messages?: number;
+  until?: StreamCondition[];
+  assertions?: StreamAssertion[];
+}
+
+export interface StreamMessage {
+  direction: 'sent' | 'received';
+  type: string;
+  data: unknown;
+  id?: string;
+  at_ms: number;
+}
+
+export interface StreamResult {
+  protocol: string;
+  sent_count: number;
+  received_count: number;
+  stop_reason: 'duration' | 'condition' | 'max_messages' | 'size_limit' | 'closed';
+  condition_met?: boolean;
+  close_code?: number;
+  close_reason?: string;
+  assertions?: (StreamAssertion & { checked: number; matched: number; passed: boolean })[];
+  duration_ms: number;
+}
+
 // Execution types
 export interface ExecuteRequest {
   method: string;
   url: string;
   headers?: Record<string, string>;
+  query_params?: Record<string, unknown>;
+  query_param_styles?: Record<string, string>;
   body?: unknown;
+  body_type?: string;
+  grpc?: GRPCCall;
+  stream?: StreamOptions;
+  api_spec_id?: string;
   environment_id?: string;
   natural_language_request?: string;
 }
@@ -79,6 +188,8 @@ export interface ExecuteResponse {
   headers: Record<string, string>;
   body: unknown;
   execution_time_ms: number;
+  grpc?: GRPCResult;
+  stream?: StreamResult;
   success: boolean;
 }
 
@@ -104,6 +215,11 @@ export interface ValidationResult {
     is_valid: boolean;
     errors?: string[];
   };
+  graphql_check?: {
+    is_valid: boolean;
+    has_data: boolean;
+    errors?: string[];
+  };
   errors?: string[];
   validated_at: string;
 }
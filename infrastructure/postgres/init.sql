@@ -15,6 +15,11 @@ CREATE TABLE IF NOT EXISTS users (
     username VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     role VARCHAR(50) NOT NULL CHECK (role IN ('admin', 'user')),
+    -- Password lifecycle: forced rotation and lockout after failed logins
+    must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+    password_changed_at TIMESTAMP WITH TIME ZONE,
+    failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -29,7 +34,7 @@ CREATE TABLE IF NOT EXISTS api_specifications (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(255) NOT NULL,
     version VARCHAR(50) NOT NULL,
-    source_type VARCHAR(50) NOT NULL CHECK (source_type IN ('file', 'postman', 'git', 'url')),
+    source_type VARCHAR(50) NOT NULL CHECK (source_type IN ('file', 'postman', 'graphql', 'grpc', 'git', 'url')),
     source_path TEXT,
     content_hash VARCHAR(64) NOT NULL,
     metadata JSONB,
@@ -47,6 +52,75 @@ CREATE INDEX IF NOT EXISTS idx_api_spec_source_type ON api_specifications(source
 -- Index on created_by for user filtering
 CREATE INDEX IF NOT EXISTS idx_api_spec_created_by ON api_specifications(created_by);
 
+-- Compiled protobuf descriptors of gRPC API specs, which the execution
+-- service calls their methods with
+CREATE TABLE IF NOT EXISTS grpc_descriptor_sets (
+    api_spec_id UUID PRIMARY KEY REFERENCES api_specifications(id) ON DELETE CASCADE,
+    descriptor_set BYTEA NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Immutable revisions of API specs, one per ingestion with new content,
+-- so earlier versions can be listed and compared
+CREATE TABLE IF NOT EXISTS api_spec_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    api_spec_id UUID NOT NULL REFERENCES api_specifications(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content_hash VARCHAR(64) NOT NULL,
+    source_type VARCHAR(50) NOT NULL,
+    source_path TEXT,
+    config JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (api_spec_id, revision)
+);
+
+-- Git repositories API specs are ingested from, with the last commit
+-- ingested so re-ingestion reads only the files changed since
+CREATE TABLE IF NOT EXISTS git_sources (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    repository TEXT NOT NULL,
+    ref VARCHAR(255) NOT NULL DEFAULT '', -- empty for the default branch
+    glob TEXT NOT NULL DEFAULT '',
+    last_commit VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (repository, ref, glob)
+);
+
+-- The file and commit each git-ingested API spec was last ingested from
+CREATE TABLE IF NOT EXISTS git_spec_files (
+    api_spec_id UUID PRIMARY KEY REFERENCES api_specifications(id) ON DELETE CASCADE,
+    git_source_id UUID NOT NULL REFERENCES git_sources(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    commit_sha VARCHAR(64) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Spec documents ingested from URLs; those with a refresh interval are
+-- re-fetched when next_refresh_at passes and re-ingested on change
+CREATE TABLE IF NOT EXISTS url_sources (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url TEXT UNIQUE NOT NULL,
+    -- Headers sent with every fetch, e.g. authentication, as JSON
+    -- envelope-encrypted like environment_secrets; NULL without headers
+    headers_ciphertext BYTEA,
+    headers_nonce BYTEA,
+    headers_wrapped_key BYTEA,
+    headers_key_nonce BYTEA,
+    headers_key_id VARCHAR(100),
+    refresh_interval_seconds INTEGER NOT NULL DEFAULT 0, -- 0 never refreshes
+    api_spec_id UUID REFERENCES api_specifications(id) ON DELETE SET NULL,
+    content_hash VARCHAR(64),
+    last_fetched_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    next_refresh_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index on next_refresh_at for finding sources due for a refresh
+CREATE INDEX IF NOT EXISTS idx_url_sources_next_refresh ON url_sources(next_refresh_at) WHERE refresh_interval_seconds > 0;
+
 -- ============================================
 -- ENVIRONMENTS TABLE
 -- ============================================
@@ -62,6 +136,28 @@ CREATE TABLE IF NOT EXISTS environments (
 -- Index on name for faster lookups
 CREATE INDEX IF NOT EXISTS idx_environments_name ON environments(name);
 
+-- ============================================
+-- ENVIRONMENT SECRETS TABLE
+-- ============================================
+-- Credential values from environments.auth_config, envelope-encrypted with
+-- AES-256-GCM. Each value has its own data key, wrapped by a master key
+-- identified by key_id. Plaintext never reaches this table.
+CREATE TABLE IF NOT EXISTS environment_secrets (
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    ciphertext BYTEA NOT NULL,
+    nonce BYTEA NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    key_nonce BYTEA NOT NULL,
+    key_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (environment_id, name)
+);
+
+-- Index on key_id to find secrets still wrapped by a retired master key
+CREATE INDEX IF NOT EXISTS idx_environment_secrets_key_id ON environment_secrets(key_id);
+
 -- ============================================
 -- TEST EXECUTIONS TABLE
 -- ============================================
@@ -76,6 +172,11 @@ CREATE TABLE IF NOT EXISTS test_executions (
     validation_result JSONB,
     status VARCHAR(50) NOT NULL CHECK (status IN ('success', 'failed', 'error')),
     execution_time_ms INTEGER,
+    timing JSONB, -- HTTP phase breakdown: dns_ms, connect_ms, tls_ms, ttfb_ms, transfer_ms, total_ms, ...
+    attempts JSONB, -- per-attempt status, error and delay for executions that retried or polled
+    request_fingerprint VARCHAR(64), -- hash of the guarded request, for duplicate detection
+    idempotency_key VARCHAR(255),
+    replay_of UUID REFERENCES test_executions(id) ON DELETE SET NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -88,6 +189,100 @@ CREATE INDEX IF NOT EXISTS idx_test_exec_created_at ON test_executions(created_a
 -- Full-text search index on natural language requests
 CREATE INDEX IF NOT EXISTS idx_test_exec_nl_request ON test_executions USING gin(to_tsvector('english', natural_language_request));
 
+-- Duplicate detection looks up recent executions by fingerprint
+CREATE INDEX IF NOT EXISTS idx_test_exec_fingerprint ON test_executions(request_fingerprint, created_at DESC) WHERE request_fingerprint IS NOT NULL;
+
+-- ============================================
+-- IDEMPOTENCY SETTINGS TABLE
+-- ============================================
+-- Per-API idempotency keys and duplicate protection, managed by the execution service
+CREATE TABLE IF NOT EXISTS idempotency_settings (
+    api_spec_id UUID PRIMARY KEY REFERENCES api_specifications(id) ON DELETE CASCADE,
+    generate_keys BOOLEAN NOT NULL DEFAULT false,
+    header_name VARCHAR(100) NOT NULL DEFAULT 'Idempotency-Key',
+    methods TEXT[] NOT NULL DEFAULT ARRAY['POST', 'PATCH'],
+    duplicate_window_seconds INTEGER NOT NULL DEFAULT 60,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================
+-- VCR CASSETTES TABLES
+-- ============================================
+-- Recorded request/response pairs replayed by the execution service
+CREATE TABLE IF NOT EXISTS cassettes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) UNIQUE NOT NULL,
+    matching JSONB NOT NULL, -- host, path, query, body and headers compared on replay
+    strict BOOLEAN NOT NULL DEFAULT false,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cassette_interactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    cassette_id UUID NOT NULL REFERENCES cassettes(id) ON DELETE CASCADE,
+    sequence INTEGER NOT NULL,
+    request JSONB NOT NULL,
+    response JSONB NOT NULL,
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (cassette_id, sequence)
+);
+
+-- ============================================
+-- CALLBACK RECEIVERS TABLES
+-- ============================================
+-- Per-test webhook URLs handed out by the execution service
+CREATE TABLE IF NOT EXISTS callback_receivers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    token VARCHAR(64) UNIQUE NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    execution_id UUID REFERENCES test_executions(id) ON DELETE SET NULL,
+    environment_id UUID REFERENCES environments(id) ON DELETE SET NULL,
+    signature JSONB, -- header, algorithm, encoding, prefix and secret field
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_callback_receivers_execution_id ON callback_receivers(execution_id);
+
+CREATE TABLE IF NOT EXISTS received_callbacks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    receiver_id UUID NOT NULL REFERENCES callback_receivers(id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL,
+    query JSONB,
+    headers JSONB NOT NULL,
+    body JSONB,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    signature_valid BOOLEAN, -- NULL when the receiver checks no signature
+    signature_error TEXT,
+    remote_addr VARCHAR(255),
+    received_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_received_callbacks_receiver_id ON received_callbacks(receiver_id, received_at);
+
+-- ============================================
+-- LOAD TEST RUNS TABLE
+-- ============================================
+-- One aggregated record per load test run; its requests are not stored in test_executions
+CREATE TABLE IF NOT EXISTS load_test_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    name VARCHAR(255),
+    config JSONB NOT NULL, -- scenario, mode, rate or concurrency, duration and thresholds
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'completed', 'stopped', 'failed', 'interrupted')),
+    stats JSONB, -- latency summary and histogram, throughput, status codes, per-step stats
+    thresholds JSONB,
+    passed BOOLEAN,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_load_test_runs_user_id ON load_test_runs(user_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_load_test_runs_started_at ON load_test_runs(started_at DESC);
+
 -- ============================================
 -- VALIDATION RULES TABLE
 -- ============================================
@@ -134,6 +329,16 @@ CREATE TABLE IF NOT EXISTS system_config (
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- ============================================
+-- LLM USAGE TABLE (daily per-user quota tracking)
+-- ============================================
+CREATE TABLE IF NOT EXISTS llm_usage_daily (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    usage_date DATE NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, usage_date)
+);
+
 -- ============================================
 -- INGESTION LOGS TABLE
 -- ============================================
@@ -150,6 +355,32 @@ CREATE TABLE IF NOT EXISTS ingestion_logs (
 -- Index on created_at for recent logs
 CREATE INDEX IF NOT EXISTS idx_ingestion_logs_created_at ON ingestion_logs(created_at DESC);
 
+-- ============================================
+-- AUDIT LOG TABLE (append-only)
+-- ============================================
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    source VARCHAR(50) NOT NULL,
+    -- No FK on actor_id: entries must outlive deleted users
+    actor_id UUID,
+    actor_role VARCHAR(50),
+    action VARCHAR(100) NOT NULL,
+    target_type VARCHAR(100),
+    target_id TEXT,
+    before_state JSONB,
+    after_state JSONB,
+    request_id VARCHAR(100),
+    ip_address VARCHAR(64),
+    metadata JSONB
+);
+
+-- Indexes for audit filtering
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log(actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+
 -- ============================================
 -- TRIGGERS FOR UPDATED_AT
 -- ============================================
@@ -182,6 +413,34 @@ CREATE TRIGGER update_learned_patterns_updated_at BEFORE UPDATE ON learned_patte
 CREATE TRIGGER update_system_config_updated_at BEFORE UPDATE ON system_config
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_cassettes_updated_at BEFORE UPDATE ON cassettes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Reject any modification of audit entries
+CREATE OR REPLACE FUNCTION prevent_audit_log_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION prevent_audit_log_modification();
+
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION prevent_audit_log_modification();
+
+-- Reject changes to spec revisions; they are only removed with their spec
+CREATE OR REPLACE FUNCTION prevent_api_spec_revision_modification()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'api_spec_revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER api_spec_revisions_immutable BEFORE UPDATE ON api_spec_revisions
+    FOR EACH ROW EXECUTE FUNCTION prevent_api_spec_revision_modification();
+
 -- ============================================
 -- DEFAULT DATA
 -- ============================================
@@ -191,14 +450,16 @@ INSERT INTO system_config (key, value, description) VALUES
     ('learning_threshold', '5', 'Number of successful tests before learning patterns'),
     ('history_retention_days', '90', 'Number of days to retain test execution history'),
     ('max_request_size_mb', '10', 'Maximum request size in megabytes'),
-    ('default_timeout_seconds', '30', 'Default timeout for API calls in seconds')
+    ('default_timeout_seconds', '30', 'Default timeout for API calls in seconds'),
+    ('rate_limits', '{"llm_per_minute": 10, "execution_per_minute": 30, "default_per_minute": 60, "llm_daily_quota": 500}', 'Gateway rate limits per user and route group'),
+    ('execution_limits', '{"default_timeout_seconds": 30, "max_timeout_seconds": 60, "max_response_bytes": 10485760}', 'Execution timeout and response size limits')
 ON CONFLICT (key) DO NOTHING;
 
 -- Insert default admin user (password: admin123)
--- Note: This should be changed immediately in production
+-- The password must be changed on first login
 -- Password: admin123 (bcrypt hash compatible with Go)
-INSERT INTO users (username, password_hash, role) VALUES
-    ('admin', '$2b$12$edNxZHB7VurltIA6FuA8kuZbC2nf3n8mpsCv0aVf8dsQIPpRAS5Ry', 'admin')
+INSERT INTO users (username, password_hash, role, must_change_password) VALUES
+    ('admin', '$2b$12$edNxZHB7VurltIA6FuA8kuZbC2nf3n8mpsCv0aVf8dsQIPpRAS5Ry', 'admin', TRUE)
 ON CONFLICT (username) DO NOTHING;
 
 -- Insert default QA environment
@@ -257,6 +518,6 @@ DO $$
 BEGIN
     RAISE NOTICE 'TestPilot AI database initialized successfully!';
     RAISE NOTICE 'Default admin user created: username=admin, password=admin123';
-    RAISE NOTICE 'Please change the default admin password immediately!';
+    RAISE NOTICE 'The default admin password must be changed on first login.';
 END $$;
 
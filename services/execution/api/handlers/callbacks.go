@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// callbackReceiverRequest opens a receiver outside of an execution
+type callbackReceiverRequest struct {
+	EnvironmentID *uuid.UUID `json:"environment_id"`
+	entities.CallbackOptions
+}
+
+// CreateCallbackReceiver opens a receiver and returns its URL. Receivers
+// opened by an execution are linked to it; these stand alone.
+func (h *ExecutionHandler) CreateCallbackReceiver(c *gin.Context) {
+	var req callbackReceiverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	receiver, err := h.callbacks.Open(c.Request.Context(), callerID(c), req.EnvironmentID, &req.CallbackOptions)
+	if err != nil {
+		c.JSON(callbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "callback_receiver.create", "callback_receiver", receiver.ID.String())
+	event.After = gin.H{
+		"environment_id": receiver.EnvironmentID,
+		"signature":      receiver.Signature,
+		"expires_at":     receiver.ExpiresAt,
+	}
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusCreated, receiver)
+}
+
+// GetCallbackReceiver returns a receiver with the callbacks it received
+// (its owner or an admin)
+func (h *ExecutionHandler) GetCallbackReceiver(c *gin.Context) {
+	receiver, ok := h.ownedReceiver(c)
+	if !ok {
+		return
+	}
+
+	callbacks, err := h.callbacks.GetReceiver(c.Request.Context(), receiver.ID)
+	if err != nil {
+		c.JSON(callbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, callbacks)
+}
+
+// CloseCallbackReceiver stops a receiver from accepting callbacks; those
+// already received are kept (its owner or an admin)
+func (h *ExecutionHandler) CloseCallbackReceiver(c *gin.Context) {
+	receiver, ok := h.ownedReceiver(c)
+	if !ok {
+		return
+	}
+
+	if err := h.callbacks.Close(c.Request.Context(), receiver.ID); err != nil {
+		c.JSON(callbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "callback_receiver.close", "callback_receiver", receiver.ID.String())
+	event.Metadata = map[string]interface{}{"received_count": receiver.ReceivedCount}
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "callback receiver closed"})
+}
+
+// WaitForCallback blocks until a callback passes the wait's assertions or
+// the timeout elapses. Both outcomes answer 200; "matched" tells them apart.
+func (h *ExecutionHandler) WaitForCallback(c *gin.Context) {
+	receiver, ok := h.ownedReceiver(c)
+	if !ok {
+		return
+	}
+
+	var wait entities.CallbackWait
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&wait); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.callbacks.Wait(c.Request.Context(), receiver.ID, &wait)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		c.JSON(callbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ReceiveCallback stores a callback sent to a receiver's URL. It is served
+// without authentication: the unguessable token in the URL is the credential.
+func (h *ExecutionHandler) ReceiveCallback(c *gin.Context) {
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, entities.MaxCallbackBodyBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read callback body"})
+		return
+	}
+	truncated := len(body) > entities.MaxCallbackBodyBytes
+	if truncated {
+		body = body[:entities.MaxCallbackBodyBytes]
+	}
+
+	callback := &entities.ReceivedCallback{
+		Method:     c.Request.Method,
+		Query:      c.Request.URL.Query(),
+		Headers:    c.Request.Header.Clone(),
+		Truncated:  truncated,
+		RemoteAddr: c.ClientIP(),
+	}
+	if err := h.callbacks.Receive(c.Request.Context(), c.Param("token"), callback, body); err != nil {
+		status := callbackErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			logger.WithRequestID(requestIDStr).Err(err).Msg("Failed to store callback")
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Str("receiver_id", callback.ReceiverID.String()).
+		Str("method", callback.Method).
+		Bool("truncated", truncated).
+		Msg("Callback received")
+
+	c.JSON(http.StatusOK, gin.H{"received": true, "id": callback.ID})
+}
+
+// ownedReceiver loads the receiver in the path, writing an error response
+// unless the caller opened it or is an admin
+func (h *ExecutionHandler) ownedReceiver(c *gin.Context) (*entities.CallbackReceiver, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid callback receiver ID"})
+		return nil, false
+	}
+
+	receiver, err := h.callbacks.FindReceiver(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(callbackErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	userID := callerID(c)
+	if !isAdmin(c) && (userID == nil || receiver.UserID == nil || *receiver.UserID != *userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the receiver's owner or an admin can access it"})
+		return nil, false
+	}
+	return receiver, true
+}
+
+// callbackErrorStatus maps callback use case errors to HTTP statuses
+func callbackErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entities.ErrCallbackReceiverNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entities.ErrCallbackReceiverExpired):
+		return http.StatusGone
+	case errors.Is(err, entities.ErrCallbackReceiverFull):
+		return http.StatusTooManyRequests
+	case errors.Is(err, entities.ErrInvalidCallback):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
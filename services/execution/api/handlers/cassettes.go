@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// cassetteRequest is the editable part of a cassette
+type cassetteRequest struct {
+	Name     string                     `json:"name" binding:"required"`
+	Matching *entities.CassetteMatching `json:"matching"`
+	Strict   bool                       `json:"strict"`
+}
+
+// ListCassettes lists all cassettes without their interactions
+func (h *ExecutionHandler) ListCassettes(c *gin.Context) {
+	cassettes, err := h.cassettes.ListCassettes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cassettes": cassettes,
+		"count":     len(cassettes),
+	})
+}
+
+// GetCassette returns a cassette with its recorded interactions
+func (h *ExecutionHandler) GetCassette(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cassette ID"})
+		return
+	}
+
+	cassette, err := h.cassettes.GetCassette(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cassette)
+}
+
+// CreateCassette creates an empty cassette. Recording into an unknown
+// cassette creates it too, with the default matching.
+func (h *ExecutionHandler) CreateCassette(c *gin.Context) {
+	var req cassetteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cassette := &entities.Cassette{
+		Name:      req.Name,
+		Matching:  req.Matching,
+		Strict:    req.Strict,
+		CreatedBy: callerID(c),
+	}
+	if err := h.cassettes.CreateCassette(c.Request.Context(), cassette); err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "cassette.create", "cassette", cassette.ID.String())
+	event.After = cassetteSnapshot(cassette)
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusCreated, cassette)
+}
+
+// UpdateCassette changes a cassette's name, matching and strict mode
+// (its creator or an admin)
+func (h *ExecutionHandler) UpdateCassette(c *gin.Context) {
+	before, ok := h.ownedCassette(c)
+	if !ok {
+		return
+	}
+
+	var req cassetteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cassette := *before
+	cassette.Name = req.Name
+	cassette.Matching = req.Matching
+	cassette.Strict = req.Strict
+	if err := h.cassettes.UpdateCassette(c.Request.Context(), &cassette); err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "cassette.update", "cassette", cassette.ID.String())
+	event.Before = cassetteSnapshot(before)
+	event.After = cassetteSnapshot(&cassette)
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, cassette)
+}
+
+// DeleteCassette deletes a cassette with its interactions (its creator or an admin)
+func (h *ExecutionHandler) DeleteCassette(c *gin.Context) {
+	before, ok := h.ownedCassette(c)
+	if !ok {
+		return
+	}
+
+	if err := h.cassettes.DeleteCassette(c.Request.Context(), before.ID); err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "cassette.delete", "cassette", before.ID.String())
+	event.Before = cassetteSnapshot(before)
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "cassette deleted successfully"})
+}
+
+// ClearCassette deletes a cassette's interactions so it can be recorded
+// again (its creator or an admin)
+func (h *ExecutionHandler) ClearCassette(c *gin.Context) {
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	before, ok := h.ownedCassette(c)
+	if !ok {
+		return
+	}
+
+	cleared, err := h.cassettes.ClearCassette(c.Request.Context(), before.ID)
+	if err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Str("cassette", before.Name).
+		Int64("cleared", cleared).
+		Msg("Cassette interactions cleared")
+
+	event := audit.FromRequest(c.Request, "cassette.clear", "cassette", before.ID.String())
+	event.Metadata = map[string]interface{}{"cleared": cleared}
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, gin.H{"message": "cassette cleared", "cleared": cleared})
+}
+
+// RewindCassette makes the next replays start from the cassette's first interactions
+func (h *ExecutionHandler) RewindCassette(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cassette ID"})
+		return
+	}
+
+	h.cassettes.Rewind(id)
+	c.JSON(http.StatusOK, gin.H{"message": "cassette rewound"})
+}
+
+// ownedCassette loads the cassette in the path, writing an error response
+// unless the caller created it or is an admin
+func (h *ExecutionHandler) ownedCassette(c *gin.Context) (*entities.Cassette, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cassette ID"})
+		return nil, false
+	}
+
+	cassette, err := h.cassettes.FindCassette(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(cassetteErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	userID := callerID(c)
+	if !isAdmin(c) && (userID == nil || cassette.CreatedBy == nil || *cassette.CreatedBy != *userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the cassette's creator or an admin can change it"})
+		return nil, false
+	}
+	return cassette, true
+}
+
+// callerID returns the user the gateway authenticated, if any
+func callerID(c *gin.Context) *uuid.UUID {
+	userID, err := uuid.Parse(c.GetHeader("X-User-ID"))
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// cassetteErrorStatus maps cassette use case errors to HTTP statuses
+func cassetteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entities.ErrCassetteNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entities.ErrCassetteExists):
+		return http.StatusConflict
+	case errors.Is(err, entities.ErrInvalidCassette):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func cassetteSnapshot(cassette *entities.Cassette) gin.H {
+	return gin.H{
+		"id":       cassette.ID,
+		"name":     cassette.Name,
+		"matching": cassette.Matching,
+		"strict":   cassette.Strict,
+	}
+}
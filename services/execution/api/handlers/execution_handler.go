@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/testpilot-ai/execution/application/usecases"
 	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/audit"
 	"github.com/testpilot-ai/shared/logger"
 )
 
@@ -14,16 +18,40 @@ import (
 type ExecutionHandler struct {
 	executeUseCase *usecases.ExecuteAPICallUseCase
 	envUseCase     *usecases.ManageEnvironmentsUseCase
+	tokenProvider  *usecases.OAuth2TokenProvider
+	limitsUseCase  *usecases.ExecutionLimitsUseCase
+	idempotency    *usecases.IdempotencyGuard
+	egress         *usecases.EgressPolicyUseCase
+	cassettes      *usecases.CassetteUseCase
+	callbacks      *usecases.CallbackUseCase
+	loadTests      *usecases.LoadTestUseCase
+	auditRecorder  *audit.Recorder
 }
 
 // NewExecutionHandler creates a new execution handler
 func NewExecutionHandler(
 	executeUseCase *usecases.ExecuteAPICallUseCase,
 	envUseCase *usecases.ManageEnvironmentsUseCase,
+	tokenProvider *usecases.OAuth2TokenProvider,
+	limitsUseCase *usecases.ExecutionLimitsUseCase,
+	idempotency *usecases.IdempotencyGuard,
+	egress *usecases.EgressPolicyUseCase,
+	cassettes *usecases.CassetteUseCase,
+	callbacks *usecases.CallbackUseCase,
+	loadTests *usecases.LoadTestUseCase,
+	auditRecorder *audit.Recorder,
 ) *ExecutionHandler {
 	return &ExecutionHandler{
 		executeUseCase: executeUseCase,
 		envUseCase:     envUseCase,
+		tokenProvider:  tokenProvider,
+		limitsUseCase:  limitsUseCase,
+		idempotency:    idempotency,
+		egress:         egress,
+		cassettes:      cassettes,
+		callbacks:      callbacks,
+		loadTests:      loadTests,
+		auditRecorder:  auditRecorder,
 	}
 }
 
@@ -57,11 +85,57 @@ func (h *ExecutionHandler) ExecuteAPICall(c *gin.Context) {
 
 	// Execute the API call
 	response, err := h.executeUseCase.Execute(c.Request.Context(), &request)
+	if response != nil {
+		event := audit.FromRequest(c.Request, "execution.run", "test_execution", response.ID.String())
+		event.Metadata = map[string]interface{}{
+			"method":      request.Method,
+			"url":         request.URL,
+			"status_code": response.StatusCode,
+			"success":     response.Success,
+		}
+		if request.EnvironmentID != nil {
+			event.Metadata["environment_id"] = request.EnvironmentID.String()
+		}
+		if request.GRPC != nil {
+			event.Metadata["grpc_method"] = request.GRPC.Method
+		}
+		if request.Stream != nil {
+			event.Metadata["stream_protocol"] = request.Stream.Protocol
+		}
+		h.recordAudit(c, event)
+	}
+	var duplicate *entities.DuplicateExecutionError
+	if errors.As(err, &duplicate) {
+		logger.WithRequestID(requestIDStr).Info().
+			Str("request_id", request.ID.String()).
+			Msg("Rejected duplicate execution")
+		body := gin.H{"error": err.Error()}
+		if duplicate.ExecutionID != uuid.Nil {
+			body["duplicate_of"] = duplicate.ExecutionID
+		}
+		c.JSON(http.StatusConflict, body)
+		return
+	}
 	if err != nil {
 		logger.WithRequestID(requestIDStr).Err(err).
 			Str("request_id", request.ID.String()).
 			Msg("API execution failed")
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, entities.ErrTimeout):
+			status = http.StatusGatewayTimeout
+		case errors.Is(err, entities.ErrEgressBlocked):
+			status = http.StatusForbidden
+		case errors.Is(err, entities.ErrCassetteMiss), errors.Is(err, entities.ErrCassetteNotFound),
+			errors.Is(err, entities.ErrGRPCDescriptorsNotFound):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, entities.ErrInvalidMethod), errors.Is(err, entities.ErrInvalidURL),
+			errors.Is(err, entities.ErrInvalidBody), errors.Is(err, entities.ErrInvalidPolicy),
+			errors.Is(err, entities.ErrInvalidCassette), errors.Is(err, entities.ErrInvalidCallback),
+			errors.Is(err, entities.ErrInvalidStream):
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
 			"error":    err.Error(),
 			"response": response,
 		})
@@ -78,6 +152,219 @@ func (h *ExecutionHandler) ExecuteAPICall(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// ReplayExecution sends a stored execution's request again with the same
+// idempotency key and compares the result with the original
+func (h *ExecutionHandler) ReplayExecution(c *gin.Context) {
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid execution ID"})
+		return
+	}
+
+	var userID *uuid.UUID
+	if parsed, err := uuid.Parse(c.GetHeader("X-User-ID")); err == nil {
+		userID = &parsed
+	}
+
+	result, err := h.executeUseCase.Replay(c.Request.Context(), id, userID, isAdmin(c))
+	if result == nil {
+		if errors.Is(err, entities.ErrExecutionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "execution not found"})
+			return
+		}
+		logger.WithRequestID(requestIDStr).Err(err).
+			Str("execution_id", id.String()).
+			Msg("Failed to replay execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "execution.replay", "test_execution", result.Response.ID.String())
+	event.Metadata = map[string]interface{}{
+		"replay_of":      id.String(),
+		"status_code":    result.Response.StatusCode,
+		"status_matches": result.StatusMatches,
+		"body_matches":   result.BodyMatches,
+	}
+	h.recordAudit(c, event)
+
+	logger.WithRequestID(requestIDStr).Info().
+		Str("execution_id", id.String()).
+		Int("status_code", result.Response.StatusCode).
+		Bool("status_matches", result.StatusMatches).
+		Bool("body_matches", result.BodyMatches).
+		Msg("Execution replayed")
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, entities.ErrTimeout) {
+			status = http.StatusGatewayTimeout
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "replay": result})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetIdempotencySettings returns an API spec's idempotency settings
+func (h *ExecutionHandler) GetIdempotencySettings(c *gin.Context) {
+	apiSpecID, err := uuid.Parse(c.Param("api_spec_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API spec ID"})
+		return
+	}
+
+	settings, err := h.idempotency.Settings(c.Request.Context(), &apiSpecID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateIdempotencySettings replaces an API spec's idempotency settings (admin only)
+func (h *ExecutionHandler) UpdateIdempotencySettings(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	apiSpecID, err := uuid.Parse(c.Param("api_spec_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API spec ID"})
+		return
+	}
+
+	var settings entities.IdempotencySettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	settings.APISpecID = apiSpecID
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	before, _ := h.idempotency.Settings(c.Request.Context(), &apiSpecID)
+	if err := h.idempotency.UpdateSettings(c.Request.Context(), &settings); err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).Msg("Failed to save idempotency settings")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save idempotency settings"})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "idempotency.update", "api_spec", apiSpecID.String())
+	event.Before = before
+	event.After = settings
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetExecutionLimits returns the execution timeout and response size limits
+func (h *ExecutionHandler) GetExecutionLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, h.limitsUseCase.Limits(c.Request.Context()))
+}
+
+// UpdateExecutionLimits replaces the execution limits (admin only)
+func (h *ExecutionHandler) UpdateExecutionLimits(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var limits entities.ExecutionLimits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if err := limits.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	before := h.limitsUseCase.Limits(c.Request.Context())
+	if err := h.limitsUseCase.UpdateLimits(c.Request.Context(), limits); err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).Msg("Failed to save execution limits")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save execution limits"})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Int("default_timeout_seconds", limits.DefaultTimeoutSeconds).
+		Int("max_timeout_seconds", limits.MaxTimeoutSeconds).
+		Int64("max_response_bytes", limits.MaxResponseBytes).
+		Msg("Execution limits updated by admin")
+
+	event := audit.FromRequest(c.Request, "config.update", "system_config", entities.ExecutionLimitsKey)
+	event.Before = before
+	event.After = limits
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// GetEgressPolicy returns the hosts and address ranges API calls may reach
+// (admin only, as it lists internal ranges)
+func (h *ExecutionHandler) GetEgressPolicy(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	c.JSON(http.StatusOK, h.egress.Policy(c.Request.Context()))
+}
+
+// UpdateEgressPolicy replaces the egress policy (admin only)
+func (h *ExecutionHandler) UpdateEgressPolicy(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var policy entities.EgressPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	before := h.egress.Policy(c.Request.Context())
+	policy, err := h.egress.UpdatePolicy(c.Request.Context(), policy)
+	if err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).Msg("Failed to save egress policy")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save egress policy"})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Strs("allowed_hosts", policy.AllowedHosts).
+		Strs("allowed_cidrs", policy.AllowedCIDRs).
+		Int("environments", len(policy.Environments)).
+		Msg("Egress policy updated by admin")
+
+	event := audit.FromRequest(c.Request, "config.update", "system_config", entities.EgressPolicyKey)
+	event.Before = before
+	event.After = policy
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, policy)
+}
+
 // ListEnvironments handles environment listing
 func (h *ExecutionHandler) ListEnvironments(c *gin.Context) {
 	environments, err := h.envUseCase.ListEnvironments(c.Request.Context())
@@ -118,11 +405,20 @@ func (h *ExecutionHandler) CreateEnvironment(c *gin.Context) {
 		return
 	}
 
+	if entities.InsecureSkipVerifyRequested(env.AuthConfig) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can disable TLS verification"})
+		return
+	}
+
 	if err := h.envUseCase.CreateEnvironment(c.Request.Context(), &env); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(environmentErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "environment.create", "environment", env.ID.String())
+	event.After = environmentSnapshot(&env)
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusCreated, env)
 }
 
@@ -141,12 +437,30 @@ func (h *ExecutionHandler) UpdateEnvironment(c *gin.Context) {
 		return
 	}
 
+	before, err := h.envUseCase.GetEnvironmentByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	}
+
+	// Non-admins may keep an existing insecure flag but not turn it on
+	if entities.InsecureSkipVerifyRequested(env.AuthConfig) &&
+		!entities.InsecureSkipVerifyRequested(before.AuthConfig) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can disable TLS verification"})
+		return
+	}
+
 	env.ID = id
 	if err := h.envUseCase.UpdateEnvironment(c.Request.Context(), &env); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(environmentErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "environment.update", "environment", idStr)
+	event.Before = environmentSnapshot(before)
+	event.After = environmentSnapshot(&env)
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusOK, env)
 }
 
@@ -159,14 +473,134 @@ func (h *ExecutionHandler) DeleteEnvironment(c *gin.Context) {
 		return
 	}
 
+	before, err := h.envUseCase.GetEnvironmentByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	}
+
 	if err := h.envUseCase.DeleteEnvironment(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "environment.delete", "environment", idStr)
+	event.Before = environmentSnapshot(before)
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusOK, gin.H{"message": "environment deleted successfully"})
 }
 
+// TestEnvironmentAuth fetches a fresh OAuth2 token for an environment to
+// check its settings. Only token metadata is returned, never the token.
+func (h *ExecutionHandler) TestEnvironmentAuth(c *gin.Context) {
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid environment ID"})
+		return
+	}
+
+	env, err := h.envUseCase.GetEnvironmentByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	}
+	if authType, _ := env.AuthConfig["type"].(string); !strings.EqualFold(authType, usecases.AuthTypeOAuth2) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "environment does not use oauth2 authentication"})
+		return
+	}
+
+	token, err := h.tokenProvider.TestEnvironment(h.egress.Bind(c.Request.Context(), env), id)
+	if err != nil {
+		logger.WithRequestID(requestIDStr).Warn().
+			Err(err).
+			Str("environment_id", idStr).
+			Msg("OAuth2 token test failed")
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, entities.ErrInvalidEnvironment):
+			status = http.StatusBadRequest
+		case errors.Is(err, entities.ErrEgressBlocked):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result := gin.H{
+		"success":     true,
+		"token_type":  token.TokenType,
+		"scope":       token.Scope,
+		"obtained_at": token.ObtainedAt,
+		"refreshable": token.RefreshToken != "",
+	}
+	if !token.ExpiresAt.IsZero() {
+		result["expires_at"] = token.ExpiresAt
+		result["expires_in"] = int64(time.Until(token.ExpiresAt).Seconds())
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RotateSecrets re-wraps all environment secrets with the active master key (admin only)
+func (h *ExecutionHandler) RotateSecrets(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	rotated, err := h.envUseCase.RotateSecrets(c.Request.Context())
+	if err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).
+			Int("rotated", rotated).
+			Msg("Secret rotation failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "rotated": rotated})
+		return
+	}
+
+	status, err := h.envUseCase.SecretKeyStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Int("rotated", rotated).
+		Str("active_key_id", status.ActiveKeyID).
+		Msg("Environment secrets rotated")
+
+	event := audit.FromRequest(c.Request, "secrets.rotate", "environment_secrets", status.ActiveKeyID)
+	event.Metadata = map[string]interface{}{"rotated": rotated}
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rotated": rotated,
+		"status":  status,
+	})
+}
+
+// SecretStatus reports which master keys protect stored secrets (admin only)
+func (h *ExecutionHandler) SecretStatus(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	status, err := h.envUseCase.SecretKeyStatus(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
 // HealthCheck handles health check requests
 func (h *ExecutionHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -174,3 +608,45 @@ func (h *ExecutionHandler) HealthCheck(c *gin.Context) {
 		"service": "execution",
 	})
 }
+
+// recordAudit writes an audit entry, logging rather than failing the request on error
+func (h *ExecutionHandler) recordAudit(c *gin.Context, event audit.Event) {
+	if err := h.auditRecorder.Record(c.Request.Context(), event); err != nil {
+		logger.WithRequestID(event.RequestID).Err(err).
+			Str("action", event.Action).
+			Msg("Failed to write audit entry")
+	}
+}
+
+// isAdmin reports whether the gateway authenticated the caller as an admin
+func isAdmin(c *gin.Context) bool {
+	return c.GetHeader("X-User-Role") == "admin"
+}
+
+// environmentErrorStatus maps environment use case errors to HTTP statuses
+func environmentErrorStatus(err error) int {
+	if errors.Is(err, entities.ErrInvalidEnvironment) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// environmentSnapshot returns an audit-safe view of an environment.
+// Auth config values are credentials, so only their keys are kept.
+func environmentSnapshot(env *entities.Environment) gin.H {
+	authConfig := make(map[string]string, len(env.AuthConfig))
+	for key := range env.AuthConfig {
+		authConfig[key] = "[REDACTED]"
+	}
+	if entities.InsecureSkipVerifyRequested(env.AuthConfig) {
+		// Keep the flag visible in the audit trail
+		authConfig[entities.TLSInsecureSkipVerifyField] = "true"
+	}
+	return gin.H{
+		"id":          env.ID,
+		"name":        env.Name,
+		"base_url":    env.BaseURL,
+		"auth_config": authConfig,
+		"active":      env.Active,
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/audit"
+)
+
+// StartLoadTest starts a load test in the background and returns its run.
+// Follow its progress with GetLoadTest.
+func (h *ExecutionHandler) StartLoadTest(c *gin.Context) {
+	var config entities.LoadTestConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.loadTests.Start(c.Request.Context(), callerID(c), config)
+	if err != nil {
+		c.JSON(loadTestErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "load_test.start", "load_test", run.ID.String())
+	event.After = gin.H{
+		"name":             run.Name,
+		"environment_id":   run.Config.EnvironmentID,
+		"mode":             run.Config.Mode,
+		"target_rps":       run.Config.TargetRPS,
+		"concurrency":      run.Config.Concurrency,
+		"duration_seconds": run.Config.DurationSeconds,
+		"steps":            len(run.Config.Scenario),
+	}
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// ListLoadTests lists the latest runs: the caller's own, or everyone's for
+// admins
+func (h *ExecutionHandler) ListLoadTests(c *gin.Context) {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	var userID *uuid.UUID
+	if !isAdmin(c) {
+		userID = callerID(c)
+		if userID == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "listing load tests requires a user"})
+			return
+		}
+	}
+
+	runs, err := h.loadTests.List(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"load_tests": runs,
+		"count":      len(runs),
+	})
+}
+
+// GetLoadTest returns a run with its stats, so far while it is running
+// (its owner or an admin)
+func (h *ExecutionHandler) GetLoadTest(c *gin.Context) {
+	run, ok := h.ownedLoadTest(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// StopLoadTest stops a running load test; it stores its stats up to then
+// (its owner or an admin)
+func (h *ExecutionHandler) StopLoadTest(c *gin.Context) {
+	run, ok := h.ownedLoadTest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.loadTests.Stop(run.ID); err != nil {
+		c.JSON(loadTestErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.FromRequest(c.Request, "load_test.stop", "load_test", run.ID.String())
+	h.recordAudit(c, event)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "load test stopping"})
+}
+
+// ownedLoadTest loads the run in the path, writing an error response unless
+// the caller started it or is an admin
+func (h *ExecutionHandler) ownedLoadTest(c *gin.Context) (*entities.LoadTestRun, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid load test ID"})
+		return nil, false
+	}
+
+	run, err := h.loadTests.Get(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(loadTestErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	userID := callerID(c)
+	if !isAdmin(c) && (userID == nil || run.UserID == nil || *run.UserID != *userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the load test's owner or an admin can access it"})
+		return nil, false
+	}
+	return run, true
+}
+
+// loadTestErrorStatus maps load test use case errors to HTTP statuses
+func loadTestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, entities.ErrLoadTestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entities.ErrInvalidLoadTest):
+		return http.StatusBadRequest
+	case errors.Is(err, entities.ErrLoadTestCapacity):
+		return http.StatusTooManyRequests
+	case errors.Is(err, entities.ErrLoadTestNotRunning):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
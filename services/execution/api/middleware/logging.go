@@ -14,7 +14,12 @@ func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
-		requestID := uuid.New().String()
+
+		// Reuse an incoming request ID so logs correlate across services
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
 
 		// Set request ID
 		c.Set("request_id", requestID)
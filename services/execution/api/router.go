@@ -18,11 +18,51 @@ func SetupRouter(handler *handlers.ExecutionHandler) *gin.Engine {
 	// Health check
 	router.GET("/health", handler.HealthCheck)
 
+	// Callbacks sent by the APIs under test, relayed by the gateway
+	router.Any("/callbacks/:token", handler.ReceiveCallback)
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		// Execution endpoints
 		v1.POST("/execute", handler.ExecuteAPICall)
+		v1.GET("/execute/limits", handler.GetExecutionLimits)
+		v1.PUT("/execute/limits", handler.UpdateExecutionLimits)
+		v1.GET("/execute/egress", handler.GetEgressPolicy)
+		v1.PUT("/execute/egress", handler.UpdateEgressPolicy)
+		v1.POST("/execute/:id/replay", handler.ReplayExecution)
+		v1.GET("/execute/idempotency/:api_spec_id", handler.GetIdempotencySettings)
+		v1.PUT("/execute/idempotency/:api_spec_id", handler.UpdateIdempotencySettings)
+
+		// VCR cassettes
+		cassettes := v1.Group("/execute/cassettes")
+		{
+			cassettes.GET("", handler.ListCassettes)
+			cassettes.POST("", handler.CreateCassette)
+			cassettes.GET("/:id", handler.GetCassette)
+			cassettes.PUT("/:id", handler.UpdateCassette)
+			cassettes.DELETE("/:id", handler.DeleteCassette)
+			cassettes.DELETE("/:id/interactions", handler.ClearCassette)
+			cassettes.POST("/:id/rewind", handler.RewindCassette)
+		}
+
+		// Callback receivers
+		callbacks := v1.Group("/execute/callbacks")
+		{
+			callbacks.POST("", handler.CreateCallbackReceiver)
+			callbacks.GET("/:id", handler.GetCallbackReceiver)
+			callbacks.DELETE("/:id", handler.CloseCallbackReceiver)
+			callbacks.POST("/:id/wait", handler.WaitForCallback)
+		}
+
+		// Load tests
+		loadTests := v1.Group("/execute/load")
+		{
+			loadTests.POST("", handler.StartLoadTest)
+			loadTests.GET("", handler.ListLoadTests)
+			loadTests.GET("/:id", handler.GetLoadTest)
+			loadTests.POST("/:id/stop", handler.StopLoadTest)
+		}
 
 		// Environment management
 		environments := v1.Group("/environments")
@@ -32,6 +72,11 @@ func SetupRouter(handler *handlers.ExecutionHandler) *gin.Engine {
 			environments.POST("", handler.CreateEnvironment)
 			environments.PUT("/:id", handler.UpdateEnvironment)
 			environments.DELETE("/:id", handler.DeleteEnvironment)
+			environments.POST("/:id/auth/test", handler.TestEnvironmentAuth)
+
+			// Secrets key management (admin only)
+			environments.GET("/secrets/status", handler.SecretStatus)
+			environments.POST("/secrets/rotate", handler.RotateSecrets)
 		}
 	}
 
@@ -0,0 +1,369 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// callbackRecheckInterval bounds how long a waiter sleeps between looks at
+// the store, for callbacks received by another instance of the service
+const callbackRecheckInterval = time.Second
+
+// CallbackUseCase hands out callback receivers with unique URLs, stores the
+// callbacks sent to them and lets tests wait for a callback. Waiters are
+// woken as soon as a callback arrives at this instance.
+type CallbackUseCase struct {
+	repo         repositories.CallbackRepository
+	environments *ManageEnvironmentsUseCase
+	baseURL      string
+
+	mu      sync.Mutex
+	arrived map[uuid.UUID]chan struct{}
+}
+
+// NewCallbackUseCase creates a new callback use case. baseURL is where
+// senders reach the receivers, e.g. https://testpilot.example.com/callbacks.
+func NewCallbackUseCase(repo repositories.CallbackRepository, environments *ManageEnvironmentsUseCase, baseURL string) *CallbackUseCase {
+	return &CallbackUseCase{
+		repo:         repo,
+		environments: environments,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		arrived:      make(map[uuid.UUID]chan struct{}),
+	}
+}
+
+// Open creates a receiver. Signatures are checked with the webhook secret
+// of environmentID, which is required when options carry a signature.
+func (uc *CallbackUseCase) Open(ctx context.Context, userID, environmentID *uuid.UUID, options *entities.CallbackOptions) (*entities.CallbackReceiver, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if options.Signature != nil && environmentID == nil {
+		return nil, fmt.Errorf("%w: checking signatures needs an environment holding the secret", entities.ErrInvalidCallback)
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate callback token: %w", err)
+	}
+
+	now := time.Now()
+	receiver := &entities.CallbackReceiver{
+		ID:            uuid.New(),
+		Token:         base64.RawURLEncoding.EncodeToString(token),
+		UserID:        userID,
+		EnvironmentID: environmentID,
+		Signature:     options.Signature,
+		ExpiresAt:     now.Add(time.Duration(options.TTLSeconds) * time.Second),
+		CreatedAt:     now,
+	}
+	if err := uc.repo.CreateReceiver(ctx, receiver); err != nil {
+		return nil, err
+	}
+	receiver.URL = uc.url(receiver)
+	return receiver, nil
+}
+
+// FindReceiver returns a receiver without its callbacks
+func (uc *CallbackUseCase) FindReceiver(ctx context.Context, id uuid.UUID) (*entities.CallbackReceiver, error) {
+	receiver, err := uc.repo.FindReceiverByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	receiver.URL = uc.url(receiver)
+	return receiver, nil
+}
+
+// GetReceiver returns a receiver with its callbacks
+func (uc *CallbackUseCase) GetReceiver(ctx context.Context, id uuid.UUID) (*entities.CallbackReceiver, error) {
+	receiver, err := uc.FindReceiver(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	receiver.Callbacks, err = uc.repo.ListCallbacks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return receiver, nil
+}
+
+// Close stops a receiver from accepting further callbacks
+func (uc *CallbackUseCase) Close(ctx context.Context, id uuid.UUID) error {
+	return uc.repo.ExpireReceiver(ctx, id, time.Now())
+}
+
+// Link records the execution that opened a receiver
+func (uc *CallbackUseCase) Link(ctx context.Context, receiverID, executionID uuid.UUID) error {
+	return uc.repo.LinkExecution(ctx, receiverID, executionID)
+}
+
+// Receive stores a callback sent to the receiver with the given token.
+// callback carries the request's method, query, headers and origin; the
+// body is decoded and its signature checked here.
+func (uc *CallbackUseCase) Receive(ctx context.Context, token string, callback *entities.ReceivedCallback, rawBody []byte) error {
+	receiver, err := uc.repo.FindReceiverByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if receiver.Expired(time.Now()) {
+		return entities.ErrCallbackReceiverExpired
+	}
+	// Saves the signature check for receivers that are already full; the
+	// repository enforces the cap against concurrent senders
+	if receiver.ReceivedCount >= entities.MaxCallbacksPerReceiver {
+		return entities.ErrCallbackReceiverFull
+	}
+
+	callback.ID = uuid.New()
+	callback.ReceiverID = receiver.ID
+	callback.ReceivedAt = time.Now()
+	callback.Body = decodeCallbackBody(rawBody)
+	if receiver.Signature != nil {
+		valid, reason := uc.verifySignature(ctx, receiver, callback.Headers, rawBody)
+		callback.SignatureValid = &valid
+		callback.SignatureError = reason
+	}
+
+	if err := uc.repo.SaveCallback(ctx, callback); err != nil {
+		return err
+	}
+	uc.notify(receiver.ID)
+	return nil
+}
+
+// Wait waits up to the wait's timeout for a callback that passes its checks,
+// looking at callbacks already received first
+func (uc *CallbackUseCase) Wait(ctx context.Context, id uuid.UUID, wait *entities.CallbackWait) (*entities.CallbackWaitResult, error) {
+	if err := wait.Validate(); err != nil {
+		return nil, err
+	}
+	conditions := make([]*pollCondition, len(wait.Assertions))
+	for i, assertion := range wait.Assertions {
+		compiled, err := compilePollCondition(&entities.PollCondition{
+			Path:     assertion.Path,
+			Operator: assertion.Operator,
+			Value:    assertion.Value,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: assertions[%d]: %v", entities.ErrInvalidCallback, i, err)
+		}
+		conditions[i] = compiled
+	}
+
+	start := time.Now()
+	deadline := start.Add(time.Duration(wait.TimeoutSeconds) * time.Second)
+	defer uc.forget(id)
+
+	for {
+		// Take the arrival channel before looking, so a callback stored in
+		// between still wakes us
+		arrived := uc.arrival(id)
+		callbacks, err := uc.repo.ListCallbacks(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		result := &entities.CallbackWaitResult{ReceivedCount: len(callbacks)}
+		for i := range callbacks {
+			matched, assertions := checkCallback(&callbacks[i], wait, conditions)
+			result.Callback, result.Assertions = &callbacks[i], assertions
+			if matched {
+				result.Matched = true
+				break
+			}
+		}
+
+		remaining := time.Until(deadline)
+		if result.Matched || remaining <= 0 {
+			result.WaitedMs = time.Since(start).Milliseconds()
+			return result, nil
+		}
+
+		timer := time.NewTimer(min(remaining, callbackRecheckInterval))
+		select {
+		case <-arrived:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			result.WaitedMs = time.Since(start).Milliseconds()
+			return result, ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// checkCallback evaluates a wait's signature requirement and assertions
+// against one callback
+func checkCallback(callback *entities.ReceivedCallback, wait *entities.CallbackWait, conditions []*pollCondition) (bool, []entities.CallbackAssertionResult) {
+	matched := !wait.RequireValidSignature || (callback.SignatureValid != nil && *callback.SignatureValid)
+	results := make([]entities.CallbackAssertionResult, len(conditions))
+	for i, condition := range conditions {
+		passed, observed := condition.holds(callback.Body)
+		results[i] = entities.CallbackAssertionResult{
+			CallbackAssertion: wait.Assertions[i],
+			Observed:          observed,
+			Passed:            passed,
+		}
+		matched = matched && passed
+	}
+	return matched, results
+}
+
+// verifySignature checks a callback's signature header against an HMAC of
+// the raw body, and returns why it failed
+func (uc *CallbackUseCase) verifySignature(ctx context.Context, receiver *entities.CallbackReceiver, headers http.Header, rawBody []byte) (bool, string) {
+	signature := receiver.Signature
+	received := strings.TrimSpace(headers.Get(signature.Header))
+	if received == "" {
+		return false, fmt.Sprintf("missing %s header", signature.Header)
+	}
+	if receiver.EnvironmentID == nil {
+		return false, "no environment holds the webhook secret"
+	}
+
+	env, err := uc.environments.ResolveEnvironment(ctx, *receiver.EnvironmentID)
+	if err != nil {
+		logger.WithContext(ctx).Err(err).
+			Str("receiver_id", receiver.ID.String()).
+			Msg("Failed to resolve environment for callback signature")
+		return false, "failed to load the webhook secret"
+	}
+	secret := configString(env.AuthConfig, signature.SecretField)
+	if secret == "" {
+		return false, fmt.Sprintf("environment %s has no %s", env.Name, signature.SecretField)
+	}
+
+	var newHash func() hash.Hash = sha256.New
+	if signature.Algorithm == entities.CallbackSignatureHMACSHA512 {
+		newHash = sha512.New
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(rawBody)
+	sum := mac.Sum(nil)
+
+	expected := hex.EncodeToString(sum)
+	if signature.Encoding == entities.SignatureEncodingBase64 {
+		expected = base64.StdEncoding.EncodeToString(sum)
+	}
+	expected = signature.Prefix + expected
+
+	// Hex digests are compared case-insensitively
+	if signature.Encoding == entities.SignatureEncodingHex {
+		received, expected = strings.ToLower(received), strings.ToLower(expected)
+	}
+	if !hmac.Equal([]byte(received), []byte(expected)) {
+		return false, "signature does not match the body"
+	}
+	return true, ""
+}
+
+// decodeCallbackBody keeps JSON bodies decoded, so assertions can look into
+// them, and other bodies as text
+func decodeCallbackBody(rawBody []byte) interface{} {
+	if len(rawBody) == 0 {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(rawBody, &decoded); err == nil {
+		return decoded
+	}
+	if utf8.Valid(rawBody) {
+		return string(rawBody)
+	}
+	return base64.StdEncoding.EncodeToString(rawBody)
+}
+
+func (uc *CallbackUseCase) url(receiver *entities.CallbackReceiver) string {
+	return uc.baseURL + "/" + receiver.Token
+}
+
+// arrival returns the channel closed when a callback next arrives for id
+func (uc *CallbackUseCase) arrival(id uuid.UUID) chan struct{} {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	ch, ok := uc.arrived[id]
+	if !ok {
+		ch = make(chan struct{})
+		uc.arrived[id] = ch
+	}
+	return ch
+}
+
+// notify wakes everyone waiting for a callback to id
+func (uc *CallbackUseCase) notify(id uuid.UUID) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if ch, ok := uc.arrived[id]; ok {
+		close(ch)
+		delete(uc.arrived, id)
+	}
+}
+
+// forget drops the arrival channel of a finished wait. Other waiters on the
+// same receiver then rely on their periodic look at the store.
+func (uc *CallbackUseCase) forget(id uuid.UUID) {
+	uc.mu.Lock()
+	delete(uc.arrived, id)
+	uc.mu.Unlock()
+}
+
+// withCallbackURL returns a copy of request with from replaced by to in its
+// URL, query parameters, headers and body
+func withCallbackURL(request *entities.APIRequest, from, to string) *entities.APIRequest {
+	replaced := *request
+	replaced.URL = strings.ReplaceAll(request.URL, from, to)
+
+	if request.Headers != nil {
+		replaced.Headers = make(map[string]string, len(request.Headers))
+		for name, value := range request.Headers {
+			replaced.Headers[name] = strings.ReplaceAll(value, from, to)
+		}
+	}
+	if request.QueryParams != nil {
+		replaced.QueryParams = make(map[string]interface{}, len(request.QueryParams))
+		for key, value := range request.QueryParams {
+			replaced.QueryParams[key] = replaceInValue(value, from, to)
+		}
+	}
+	replaced.Body = replaceInValue(request.Body, from, to)
+	return &replaced
+}
+
+// replaceInValue replaces from with to in every string of a decoded JSON value
+func replaceInValue(value interface{}, from, to string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return strings.ReplaceAll(v, from, to)
+	case map[string]interface{}:
+		replaced := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			replaced[key] = replaceInValue(item, from, to)
+		}
+		return replaced
+	case []interface{}:
+		replaced := make([]interface{}, len(v))
+		for i, item := range v {
+			replaced[i] = replaceInValue(item, from, to)
+		}
+		return replaced
+	}
+	return value
+}
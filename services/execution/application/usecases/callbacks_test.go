@@ -0,0 +1,431 @@
+package usecases
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+)
+
+// memoryCallbacks keeps receivers and callbacks in memory and enforces the
+// per-receiver cap on save, as the database does
+type memoryCallbacks struct {
+	mu        sync.Mutex
+	receivers map[uuid.UUID]*entities.CallbackReceiver
+	callbacks map[uuid.UUID][]entities.ReceivedCallback
+}
+
+func newMemoryCallbacks() *memoryCallbacks {
+	return &memoryCallbacks{
+		receivers: make(map[uuid.UUID]*entities.CallbackReceiver),
+		callbacks: make(map[uuid.UUID][]entities.ReceivedCallback),
+	}
+}
+
+func (m *memoryCallbacks) CreateReceiver(_ context.Context, receiver *entities.CallbackReceiver) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *receiver
+	m.receivers[receiver.ID] = &stored
+	return nil
+}
+
+func (m *memoryCallbacks) find(match func(*entities.CallbackReceiver) bool) (*entities.CallbackReceiver, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, receiver := range m.receivers {
+		if match(receiver) {
+			found := *receiver
+			found.ReceivedCount = len(m.callbacks[receiver.ID])
+			return &found, nil
+		}
+	}
+	return nil, entities.ErrCallbackReceiverNotFound
+}
+
+func (m *memoryCallbacks) FindReceiverByID(_ context.Context, id uuid.UUID) (*entities.CallbackReceiver, error) {
+	return m.find(func(r *entities.CallbackReceiver) bool { return r.ID == id })
+}
+
+func (m *memoryCallbacks) FindReceiverByToken(_ context.Context, token string) (*entities.CallbackReceiver, error) {
+	return m.find(func(r *entities.CallbackReceiver) bool { return r.Token == token })
+}
+
+func (m *memoryCallbacks) LinkExecution(_ context.Context, receiverID, executionID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.receivers[receiverID].ExecutionID = &executionID
+	return nil
+}
+
+func (m *memoryCallbacks) ExpireReceiver(_ context.Context, id uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if at.Before(m.receivers[id].ExpiresAt) {
+		m.receivers[id].ExpiresAt = at
+	}
+	return nil
+}
+
+func (m *memoryCallbacks) SaveCallback(_ context.Context, callback *entities.ReceivedCallback) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.callbacks[callback.ReceiverID]) >= entities.MaxCallbacksPerReceiver {
+		return entities.ErrCallbackReceiverFull
+	}
+	m.callbacks[callback.ReceiverID] = append(m.callbacks[callback.ReceiverID], *callback)
+	return nil
+}
+
+func (m *memoryCallbacks) ListCallbacks(_ context.Context, receiverID uuid.UUID) ([]entities.ReceivedCallback, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]entities.ReceivedCallback(nil), m.callbacks[receiverID]...), nil
+}
+
+// webhookEnvironment is an environment repository holding one environment
+// with a webhook secret and no encrypted secrets
+type webhookEnvironment struct {
+	repositories.EnvironmentRepository
+	repositories.SecretRepository
+	env *entities.Environment
+}
+
+func (w *webhookEnvironment) FindEnvironmentByID(_ context.Context, id uuid.UUID) (*entities.Environment, error) {
+	if id != w.env.ID {
+		return nil, entities.ErrEnvironmentNotFound
+	}
+	env := *w.env
+	return &env, nil
+}
+
+func (w *webhookEnvironment) FindSecrets(context.Context, uuid.UUID) ([]*entities.EncryptedSecret, error) {
+	return nil, nil
+}
+
+func newTestCallbackUseCase(secret string) (*CallbackUseCase, uuid.UUID) {
+	env := &entities.Environment{
+		ID:         uuid.New(),
+		Name:       "staging",
+		AuthConfig: map[string]interface{}{entities.DefaultCallbackSecretField: secret},
+	}
+	repo := &webhookEnvironment{env: env}
+	environments := NewManageEnvironmentsUseCase(repo, repo, nil)
+	return NewCallbackUseCase(newMemoryCallbacks(), environments, "https://testpilot.example.com/callbacks/"), env.ID
+}
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"event":"payment.captured","amount":100}`)
+	sign := func(newHash func() hash.Hash, key string) []byte {
+		mac := hmac.New(newHash, []byte(key))
+		mac.Write(body)
+		return mac.Sum(nil)
+	}
+	sha256Sum := sign(sha256.New, secret)
+	sha512Sum := sign(sha512.New, secret)
+	wrongSum := sign(sha256.New, "other")
+
+	tests := []struct {
+		name       string
+		signature  entities.CallbackSignature
+		header     string
+		noEnv      bool
+		want       bool
+		wantReason string
+	}{
+		{
+			name:      "sha256 hex",
+			signature: entities.CallbackSignature{Header: "X-Signature"},
+			header:    hex.EncodeToString(sha256Sum),
+			want:      true,
+		},
+		{
+			name:      "sha256 hex in upper case with spaces",
+			signature: entities.CallbackSignature{Header: "X-Signature"},
+			header:    " " + strings.ToUpper(hex.EncodeToString(sha256Sum)) + " ",
+			want:      true,
+		},
+		{
+			name:      "sha512 hex with a prefix",
+			signature: entities.CallbackSignature{Header: "X-Hub-Signature", Algorithm: "HMAC-SHA512", Prefix: "sha512="},
+			header:    "sha512=" + hex.EncodeToString(sha512Sum),
+			want:      true,
+		},
+		{
+			name:      "sha256 base64 with a prefix",
+			signature: entities.CallbackSignature{Header: "X-Signature", Encoding: "base64", Prefix: "v1,"},
+			header:    "v1," + base64.StdEncoding.EncodeToString(sha256Sum),
+			want:      true,
+		},
+		{
+			name:      "sha512 base64",
+			signature: entities.CallbackSignature{Header: "X-Signature", Algorithm: "hmac-sha512", Encoding: "base64"},
+			header:    base64.StdEncoding.EncodeToString(sha512Sum),
+			want:      true,
+		},
+		{
+			name:       "base64 is case sensitive",
+			signature:  entities.CallbackSignature{Header: "X-Signature", Encoding: "base64"},
+			header:     base64.StdEncoding.EncodeToString(sha256Sum) + "x",
+			wantReason: "signature does not match the body",
+		},
+		{
+			name:       "prefix missing",
+			signature:  entities.CallbackSignature{Header: "X-Signature", Prefix: "sha256="},
+			header:     hex.EncodeToString(sha256Sum),
+			wantReason: "signature does not match the body",
+		},
+		{
+			name:       "other algorithm",
+			signature:  entities.CallbackSignature{Header: "X-Signature", Algorithm: "hmac-sha512"},
+			header:     hex.EncodeToString(sha256Sum),
+			wantReason: "signature does not match the body",
+		},
+		{
+			name:       "wrong secret",
+			signature:  entities.CallbackSignature{Header: "X-Signature"},
+			header:     hex.EncodeToString(wrongSum),
+			wantReason: "signature does not match the body",
+		},
+		{
+			name:       "missing header",
+			signature:  entities.CallbackSignature{Header: "X-Signature"},
+			wantReason: "missing X-Signature header",
+		},
+		{
+			name:       "no environment",
+			signature:  entities.CallbackSignature{Header: "X-Signature"},
+			header:     hex.EncodeToString(sha256Sum),
+			noEnv:      true,
+			wantReason: "no environment holds the webhook secret",
+		},
+		{
+			name:       "secret field not set",
+			signature:  entities.CallbackSignature{Header: "X-Signature", SecretField: "stripe_secret"},
+			header:     hex.EncodeToString(sha256Sum),
+			wantReason: "environment staging has no stripe_secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, envID := newTestCallbackUseCase(secret)
+			if err := tt.signature.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			receiver := &entities.CallbackReceiver{ID: uuid.New(), EnvironmentID: &envID, Signature: &tt.signature}
+			if tt.noEnv {
+				receiver.EnvironmentID = nil
+			}
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set(tt.signature.Header, tt.header)
+			}
+
+			valid, reason := uc.verifySignature(context.Background(), receiver, headers, body)
+			if valid != tt.want || reason != tt.wantReason {
+				t.Errorf("verifySignature = %v, %q; want %v, %q", valid, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCallbackReceiveAndWait(t *testing.T) {
+	uc, envID := newTestCallbackUseCase("whsec_test")
+	ctx := context.Background()
+	receiver, err := uc.Open(ctx, nil, &envID, &entities.CallbackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receiver.URL != "https://testpilot.example.com/callbacks/"+receiver.Token {
+		t.Errorf("receiver url = %s", receiver.URL)
+	}
+
+	type waitResult struct {
+		result  *entities.CallbackWaitResult
+		err     error
+		elapsed time.Duration
+	}
+	done := make(chan waitResult, 1)
+	go func() {
+		start := time.Now()
+		result, err := uc.Wait(ctx, receiver.ID, &entities.CallbackWait{
+			TimeoutSeconds: 10,
+			Assertions:     []entities.CallbackAssertion{{Path: "$.status", Value: "captured"}},
+		})
+		done <- waitResult{result, err, time.Since(start)}
+	}()
+
+	// A callback failing the assertion does not end the wait
+	time.Sleep(50 * time.Millisecond)
+	if err := uc.Receive(ctx, receiver.Token, &entities.ReceivedCallback{Method: "POST"}, []byte(`{"status":"pending"}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-done:
+		t.Fatalf("wait returned on a callback failing its assertion: %+v", r.result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sent := time.Now()
+	if err := uc.Receive(ctx, receiver.Token, &entities.ReceivedCallback{Method: "POST"}, []byte(`{"status":"captured"}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-done:
+		if r.err != nil || !r.result.Matched || r.result.ReceivedCount != 2 {
+			t.Fatalf("wait = %+v, %v", r.result, r.err)
+		}
+		// Well below the recheck interval, so the arrival woke the wait
+		if woke := time.Since(sent); woke >= callbackRecheckInterval/2 {
+			t.Errorf("wait woke %v after the callback, want it woken by the arrival", woke)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wait did not return after a matching callback")
+	}
+}
+
+func TestCallbackWaitTimesOut(t *testing.T) {
+	uc, envID := newTestCallbackUseCase("whsec_test")
+	ctx := context.Background()
+	receiver, err := uc.Open(ctx, nil, &envID, &entities.CallbackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Receive(ctx, receiver.Token, &entities.ReceivedCallback{Method: "POST"}, []byte(`{"status":"pending"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	result, err := uc.Wait(ctx, receiver.ID, &entities.CallbackWait{
+		TimeoutSeconds: 1,
+		Assertions:     []entities.CallbackAssertion{{Path: "$.status", Value: "captured"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched || result.ReceivedCount != 1 || len(result.Assertions) != 1 || result.Assertions[0].Passed {
+		t.Errorf("wait = %+v, want the last callback reported unmatched", result)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("wait took %v, want about its 1s timeout", elapsed)
+	}
+}
+
+func TestCallbackReceiveCap(t *testing.T) {
+	uc, envID := newTestCallbackUseCase("whsec_test")
+	ctx := context.Background()
+	receiver, err := uc.Open(ctx, nil, &envID, &entities.CallbackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent senders can race past the count check in Receive; the store
+	// refuses those beyond the cap
+	senders := entities.MaxCallbacksPerReceiver + 50
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	full := 0
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := uc.Receive(ctx, receiver.Token, &entities.ReceivedCallback{Method: "POST"}, nil)
+			if err != nil && !errors.Is(err, entities.ErrCallbackReceiverFull) {
+				t.Error(err)
+			}
+			if err != nil {
+				mu.Lock()
+				full++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := uc.repo.ListCallbacks(ctx, receiver.ID)
+	if len(stored) != entities.MaxCallbacksPerReceiver || full != senders-entities.MaxCallbacksPerReceiver {
+		t.Errorf("stored %d callbacks and refused %d, want %d and %d",
+			len(stored), full, entities.MaxCallbacksPerReceiver, senders-entities.MaxCallbacksPerReceiver)
+	}
+
+	if err := uc.Close(ctx, receiver.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.Receive(ctx, receiver.Token, &entities.ReceivedCallback{Method: "POST"}, nil); !errors.Is(err, entities.ErrCallbackReceiverExpired) {
+		t.Errorf("receive after close = %v, want ErrCallbackReceiverExpired", err)
+	}
+}
+
+func TestWithCallbackURL(t *testing.T) {
+	const placeholder = entities.CallbackURLPlaceholder
+	const target = "https://testpilot.example.com/callbacks/tok"
+	request := &entities.APIRequest{
+		URL: "https://api.example.com/subscribe?notify=" + placeholder,
+		Headers: map[string]string{
+			"X-Callback-URL": placeholder,
+			"X-Links":        "<" + placeholder + ">; rel=a, <" + placeholder + "/b>; rel=b",
+			"Accept":         "application/json",
+		},
+		QueryParams: map[string]interface{}{
+			"callback": placeholder,
+			"urls":     []interface{}{placeholder, "other"},
+			"limit":    float64(5),
+		},
+		Body: map[string]interface{}{
+			"webhook": map[string]interface{}{"url": placeholder + "?source=test", "events": []interface{}{"paid", placeholder}},
+			"retries": float64(3),
+			"enabled": true,
+		},
+	}
+
+	replaced := withCallbackURL(request, placeholder, target)
+
+	want := &entities.APIRequest{
+		URL: "https://api.example.com/subscribe?notify=" + target,
+		Headers: map[string]string{
+			"X-Callback-URL": target,
+			"X-Links":        "<" + target + ">; rel=a, <" + target + "/b>; rel=b",
+			"Accept":         "application/json",
+		},
+		QueryParams: map[string]interface{}{
+			"callback": target,
+			"urls":     []interface{}{target, "other"},
+			"limit":    float64(5),
+		},
+		Body: map[string]interface{}{
+			"webhook": map[string]interface{}{"url": target + "?source=test", "events": []interface{}{"paid", target}},
+			"retries": float64(3),
+			"enabled": true,
+		},
+	}
+	if !reflect.DeepEqual(replaced, want) {
+		t.Errorf("withCallbackURL =\n  %+v\nwant\n  %+v", replaced, want)
+	}
+
+	// The original request is left alone, so it can be recorded with the placeholder
+	if request.Headers["X-Callback-URL"] != placeholder || request.QueryParams["callback"] != placeholder ||
+		request.Body.(map[string]interface{})["webhook"].(map[string]interface{})["url"] != placeholder+"?source=test" {
+		t.Errorf("original request was changed: %+v", request)
+	}
+
+	// A string body and a request without headers or query
+	replaced = withCallbackURL(&entities.APIRequest{URL: "https://api.example.com", Body: "notify " + placeholder}, placeholder, target)
+	if replaced.Body != "notify "+target || replaced.Headers != nil || replaced.QueryParams != nil {
+		t.Errorf("withCallbackURL of a text body = %+v", replaced)
+	}
+}
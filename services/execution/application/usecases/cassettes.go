@@ -0,0 +1,430 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// CassetteUseCase records executions into cassettes and replays them
+// without contacting the target. A replay walks through the matching
+// interactions in recorded order, so a poll that saw "pending" and then
+// "done" replays the same way; once all of them were played the last one
+// is repeated. Rewind starts a cassette over.
+type CassetteUseCase struct {
+	repo repositories.CassetteRepository
+
+	// recordMu serializes recording, which creates cassettes on first use
+	// and numbers their interactions
+	recordMu sync.Mutex
+
+	mu     sync.Mutex
+	played map[uuid.UUID]map[uuid.UUID]bool
+}
+
+// NewCassetteUseCase creates a new cassette use case
+func NewCassetteUseCase(repo repositories.CassetteRepository) *CassetteUseCase {
+	return &CassetteUseCase{
+		repo:   repo,
+		played: make(map[uuid.UUID]map[uuid.UUID]bool),
+	}
+}
+
+// ListCassettes returns all cassettes without their interactions
+func (uc *CassetteUseCase) ListCassettes(ctx context.Context) ([]*entities.Cassette, error) {
+	return uc.repo.ListCassettes(ctx)
+}
+
+// FindCassette returns a cassette without its interactions
+func (uc *CassetteUseCase) FindCassette(ctx context.Context, id uuid.UUID) (*entities.Cassette, error) {
+	return uc.repo.FindCassetteByID(ctx, id)
+}
+
+// GetCassette returns a cassette with its interactions
+func (uc *CassetteUseCase) GetCassette(ctx context.Context, id uuid.UUID) (*entities.Cassette, error) {
+	cassette, err := uc.repo.FindCassetteByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	cassette.Interactions, err = uc.repo.ListInteractions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return cassette, nil
+}
+
+// CreateCassette validates and stores a new, empty cassette
+func (uc *CassetteUseCase) CreateCassette(ctx context.Context, cassette *entities.Cassette) error {
+	if err := cassette.Validate(); err != nil {
+		return err
+	}
+	if err := uc.checkNameFree(ctx, cassette.Name, uuid.Nil); err != nil {
+		return err
+	}
+
+	cassette.ID = uuid.New()
+	cassette.CreatedAt = time.Now()
+	cassette.UpdatedAt = cassette.CreatedAt
+	cassette.InteractionCount = 0
+	cassette.Interactions = nil
+	return uc.repo.CreateCassette(ctx, cassette)
+}
+
+// UpdateCassette changes a cassette's name, matching and strict mode.
+// Recorded interactions are kept, and the cassette is rewound.
+func (uc *CassetteUseCase) UpdateCassette(ctx context.Context, cassette *entities.Cassette) error {
+	if err := cassette.Validate(); err != nil {
+		return err
+	}
+	if err := uc.checkNameFree(ctx, cassette.Name, cassette.ID); err != nil {
+		return err
+	}
+
+	cassette.UpdatedAt = time.Now()
+	if err := uc.repo.UpdateCassette(ctx, cassette); err != nil {
+		return err
+	}
+	uc.Rewind(cassette.ID)
+	return nil
+}
+
+// DeleteCassette deletes a cassette with its interactions
+func (uc *CassetteUseCase) DeleteCassette(ctx context.Context, id uuid.UUID) error {
+	if err := uc.repo.DeleteCassette(ctx, id); err != nil {
+		return err
+	}
+	uc.Rewind(id)
+	return nil
+}
+
+// ClearCassette deletes a cassette's interactions so it can be recorded
+// afresh, and returns how many were deleted
+func (uc *CassetteUseCase) ClearCassette(ctx context.Context, id uuid.UUID) (int64, error) {
+	if _, err := uc.repo.FindCassetteByID(ctx, id); err != nil {
+		return 0, err
+	}
+	cleared, err := uc.repo.ClearInteractions(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	uc.Rewind(id)
+	return cleared, nil
+}
+
+// Rewind makes the next replays of a cassette start from its first interactions
+func (uc *CassetteUseCase) Rewind(id uuid.UUID) {
+	uc.mu.Lock()
+	delete(uc.played, id)
+	uc.mu.Unlock()
+}
+
+// Record appends an executed request and its response to the request's
+// cassette, creating the cassette with the default matching if needed
+func (uc *CassetteUseCase) Record(ctx context.Context, request *entities.APIRequest, response *entities.APIResponse) (*entities.CassetteInteraction, error) {
+	recorded, err := recordedRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.recordMu.Lock()
+	defer uc.recordMu.Unlock()
+
+	cassette, err := uc.repo.FindCassetteByName(ctx, request.VCR.Cassette)
+	if err != nil {
+		return nil, err
+	}
+	if cassette == nil {
+		cassette = &entities.Cassette{Name: request.VCR.Cassette, CreatedBy: request.UserID}
+		if err := uc.CreateCassette(ctx, cassette); err != nil {
+			return nil, err
+		}
+		logger.WithContext(ctx).Info().
+			Str("cassette", cassette.Name).
+			Msg("Created cassette for recording")
+	}
+
+	interaction := &entities.CassetteInteraction{
+		ID:         uuid.New(),
+		CassetteID: cassette.ID,
+		Request:    recorded,
+		Response: entities.RecordedResponse{
+			StatusCode: response.StatusCode,
+			Headers:    response.Headers,
+			Body:       response.Body,
+			BinaryBody: response.BinaryBody,
+			Truncated:  response.Truncated,
+			GRPC:       response.GRPC,
+		},
+		RecordedAt: time.Now(),
+	}
+	if err := uc.repo.AppendInteraction(ctx, interaction); err != nil {
+		return nil, err
+	}
+	return interaction, nil
+}
+
+// Play answers a request from its cassette. It returns a nil response and
+// no error when no interaction matches and the cassette is not strict, in
+// which case the request should be sent to the target. A strict miss is
+// returned as a failed response with ErrCassetteMiss.
+func (uc *CassetteUseCase) Play(ctx context.Context, request *entities.APIRequest) (*entities.APIResponse, error) {
+	startTime := time.Now()
+	response := entities.NewAPIResponse(request.ID)
+	response.VCR = &entities.VCRResult{Cassette: request.VCR.Cassette, Mode: entities.VCRModeReplay}
+	fail := func(err error, errorType string) (*entities.APIResponse, error) {
+		response.Error = err.Error()
+		response.ErrorType = errorType
+		response.Success = false
+		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		return response, err
+	}
+
+	cassette, err := uc.repo.FindCassetteByName(ctx, request.VCR.Cassette)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load cassette %s: %w", request.VCR.Cassette, err), "")
+	}
+	if cassette == nil {
+		return fail(fmt.Errorf("%w: %s", entities.ErrCassetteNotFound, request.VCR.Cassette), entities.ErrorTypeCassetteMiss)
+	}
+	interactions, err := uc.repo.ListInteractions(ctx, cassette.ID)
+	if err != nil {
+		return fail(fmt.Errorf("failed to load cassette %s: %w", cassette.Name, err), "")
+	}
+	actual, err := recordedRequest(request)
+	if err != nil {
+		return fail(err, "")
+	}
+
+	interaction := uc.next(cassette, interactions, actual)
+	if interaction == nil {
+		if !cassette.Strict {
+			return nil, nil
+		}
+		return fail(fmt.Errorf("%w: %s %s in cassette %s", entities.ErrCassetteMiss, actual.Method, actual.URL, cassette.Name), entities.ErrorTypeCassetteMiss)
+	}
+
+	response.VCR.InteractionID = &interaction.ID
+	response.StatusCode = interaction.Response.StatusCode
+	if interaction.Response.Headers != nil {
+		response.Headers = interaction.Response.Headers
+	}
+	response.Body = interaction.Response.Body
+	response.BinaryBody = interaction.Response.BinaryBody
+	response.Truncated = interaction.Response.Truncated
+	response.Success = response.IsSuccessful()
+	checkGraphQLErrors(request, response)
+	if grpcResult := interaction.Response.GRPC; grpcResult != nil {
+		response.GRPC = grpcResult
+		response.Success = grpcResult.OK()
+		if !response.Success {
+			response.ErrorType = entities.ErrorTypeGRPC
+			response.Error = grpcResult.Failure()
+		}
+	}
+	response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+	return response, nil
+}
+
+// next picks the first matching interaction not played yet, or the last
+// matching one when all were played, and marks it played
+func (uc *CassetteUseCase) next(cassette *entities.Cassette, interactions []entities.CassetteInteraction, actual entities.RecordedRequest) *entities.CassetteInteraction {
+	matching := cassette.Matching
+	if matching == nil {
+		matching = &entities.DefaultCassetteMatching
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	played := uc.played[cassette.ID]
+	if played == nil {
+		played = make(map[uuid.UUID]bool)
+		uc.played[cassette.ID] = played
+	}
+
+	var match *entities.CassetteInteraction
+	for i := range interactions {
+		if !interactionMatches(matching, interactions[i].Request, actual) {
+			continue
+		}
+		match = &interactions[i]
+		if !played[match.ID] {
+			break
+		}
+	}
+	if match != nil {
+		played[match.ID] = true
+	}
+	return match
+}
+
+// checkNameFree rejects a name used by another cassette than id
+func (uc *CassetteUseCase) checkNameFree(ctx context.Context, name string, id uuid.UUID) error {
+	existing, err := uc.repo.FindCassetteByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != id {
+		return fmt.Errorf("%w: %s", entities.ErrCassetteExists, name)
+	}
+	return nil
+}
+
+// interactionMatches compares a recorded request with a request on the
+// parts the cassette's matching selects. The method always has to agree.
+func interactionMatches(matching *entities.CassetteMatching, recorded, actual entities.RecordedRequest) bool {
+	if !strings.EqualFold(recorded.Method, actual.Method) {
+		return false
+	}
+
+	recordedURL, recordedErr := url.Parse(recorded.URL)
+	actualURL, actualErr := url.Parse(actual.URL)
+	if recordedErr != nil || actualErr != nil {
+		return recorded.URL == actual.URL
+	}
+	if matching.Host && !strings.EqualFold(recordedURL.Host, actualURL.Host) {
+		return false
+	}
+	if matching.Path && strings.TrimSuffix(recordedURL.EscapedPath(), "/") != strings.TrimSuffix(actualURL.EscapedPath(), "/") {
+		return false
+	}
+	// Encode sorts by key, so parameter order does not matter
+	if matching.Query && recordedURL.Query().Encode() != actualURL.Query().Encode() {
+		return false
+	}
+	if matching.Body && recorded.Body != actual.Body {
+		return false
+	}
+	for _, name := range matching.Headers {
+		if headerValue(recorded.Headers, name) != headerValue(actual.Headers, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// credentialHeaders are request headers, in lower case, that carry
+// credentials and are never stored in a cassette
+var credentialHeaders = map[string]bool{
+	"authorization":        true,
+	"proxy-authorization":  true,
+	"cookie":               true,
+	"x-api-key":            true,
+	"x-auth-token":         true,
+	"x-amz-security-token": true,
+}
+
+const redactedHeaderValue = "[REDACTED]"
+
+// recordedRequest captures what a request sends, for recording and matching.
+// Environment credentials are not part of it: they are added later, and
+// must not end up in a cassette. Credentials the caller sent in headers are
+// redacted, so matching on such a header only checks that it was sent.
+// A callback URL, unique to each run, is put back as its placeholder so
+// recordings keep matching.
+func recordedRequest(request *entities.APIRequest) (entities.RecordedRequest, error) {
+	if request.CallbackURL != "" {
+		request = withCallbackURL(request, request.CallbackURL, entities.CallbackURLPlaceholder)
+	}
+	target, err := buildRequestURL(request)
+	if err != nil {
+		return entities.RecordedRequest{}, err
+	}
+	if request.GRPC != nil {
+		// Calls to one server differ by method only
+		target = strings.TrimSuffix(target, "/") + "/" + strings.TrimPrefix(request.GRPC.Method, "/")
+	}
+	body, err := normalizedBody(request)
+	if err != nil {
+		return entities.RecordedRequest{}, err
+	}
+
+	headers := make(map[string]string, len(request.Headers))
+	for name, value := range request.Headers {
+		if credentialHeaders[strings.ToLower(name)] {
+			value = redactedHeaderValue
+		}
+		headers[name] = value
+	}
+	return entities.RecordedRequest{
+		Method:  strings.ToUpper(request.Method),
+		URL:     target,
+		Headers: headers,
+		Body:    body,
+	}, nil
+}
+
+// normalizedBody renders a request body so that equivalent bodies compare
+// equal: JSON with sorted keys and no whitespace, form fields in key order.
+// Binary and multipart bodies are reduced to a hash of their content, as
+// multipart boundaries change on every request.
+func normalizedBody(request *entities.APIRequest) (string, error) {
+	if request.Body == nil && len(request.Files) == 0 {
+		return "", nil
+	}
+
+	switch request.ResolvedBodyType() {
+	case entities.BodyTypeJSON, entities.BodyTypeGraphQL:
+		body := request.Body
+		if text, ok := body.(string); ok {
+			// A JSON document sent as a string is compared by value too
+			var decoded interface{}
+			if json.Unmarshal([]byte(text), &decoded) == nil {
+				body = decoded
+			}
+		}
+		encoded, err := json.Marshal(normalizeJSON(body))
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal body: %w", err)
+		}
+		return string(encoded), nil
+
+	case entities.BodyTypeForm:
+		encoded, _, err := encodeRequestBody(request)
+		if err != nil {
+			return "", err
+		}
+		values, err := url.ParseQuery(string(encoded))
+		if err != nil {
+			return string(encoded), nil
+		}
+		return values.Encode(), nil
+
+	case entities.BodyTypeMultipart:
+		encoded, err := json.Marshal(struct {
+			Fields interface{}         `json:"fields"`
+			Files  []entities.FilePart `json:"files"`
+		}{normalizeJSON(request.Body), request.Files})
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal body: %w", err)
+		}
+		return contentHash(encoded), nil
+
+	case entities.BodyTypeBinary:
+		encoded, _ := request.Body.(string)
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("%w: binary body is not valid base64", entities.ErrInvalidBody)
+		}
+		return contentHash(decoded), nil
+	}
+
+	text, _ := request.Body.(string)
+	return text, nil
+}
+
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
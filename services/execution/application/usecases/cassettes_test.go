@@ -0,0 +1,392 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// memoryCassettes keeps cassettes and their interactions in memory
+type memoryCassettes struct {
+	cassettes    map[uuid.UUID]*entities.Cassette
+	interactions map[uuid.UUID][]entities.CassetteInteraction
+}
+
+func newMemoryCassettes() *memoryCassettes {
+	return &memoryCassettes{
+		cassettes:    make(map[uuid.UUID]*entities.Cassette),
+		interactions: make(map[uuid.UUID][]entities.CassetteInteraction),
+	}
+}
+
+func (m *memoryCassettes) CreateCassette(_ context.Context, cassette *entities.Cassette) error {
+	m.cassettes[cassette.ID] = cassette
+	return nil
+}
+
+func (m *memoryCassettes) UpdateCassette(_ context.Context, cassette *entities.Cassette) error {
+	m.cassettes[cassette.ID] = cassette
+	return nil
+}
+
+func (m *memoryCassettes) DeleteCassette(_ context.Context, id uuid.UUID) error {
+	delete(m.cassettes, id)
+	delete(m.interactions, id)
+	return nil
+}
+
+func (m *memoryCassettes) FindCassetteByID(_ context.Context, id uuid.UUID) (*entities.Cassette, error) {
+	if cassette, ok := m.cassettes[id]; ok {
+		return cassette, nil
+	}
+	return nil, entities.ErrCassetteNotFound
+}
+
+func (m *memoryCassettes) FindCassetteByName(_ context.Context, name string) (*entities.Cassette, error) {
+	for _, cassette := range m.cassettes {
+		if cassette.Name == name {
+			return cassette, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryCassettes) ListCassettes(context.Context) ([]*entities.Cassette, error) {
+	var cassettes []*entities.Cassette
+	for _, cassette := range m.cassettes {
+		cassettes = append(cassettes, cassette)
+	}
+	return cassettes, nil
+}
+
+func (m *memoryCassettes) ListInteractions(_ context.Context, cassetteID uuid.UUID) ([]entities.CassetteInteraction, error) {
+	return append([]entities.CassetteInteraction(nil), m.interactions[cassetteID]...), nil
+}
+
+func (m *memoryCassettes) AppendInteraction(_ context.Context, interaction *entities.CassetteInteraction) error {
+	interaction.Sequence = len(m.interactions[interaction.CassetteID]) + 1
+	m.interactions[interaction.CassetteID] = append(m.interactions[interaction.CassetteID], *interaction)
+	return nil
+}
+
+func (m *memoryCassettes) ClearInteractions(_ context.Context, cassetteID uuid.UUID) (int64, error) {
+	cleared := int64(len(m.interactions[cassetteID]))
+	delete(m.interactions, cassetteID)
+	return cleared, nil
+}
+
+// ordersCassette creates a cassette holding a GET /orders/42 that answered
+// "pending" and then "shipped", and a POST /orders
+func ordersCassette(t *testing.T, uc *CassetteUseCase, strict bool) {
+	t.Helper()
+	ctx := context.Background()
+	if err := uc.CreateCassette(ctx, &entities.Cassette{Name: "orders", Strict: strict}); err != nil {
+		t.Fatal(err)
+	}
+	record := func(request *entities.APIRequest, status string) {
+		request.VCR = &entities.VCROptions{Cassette: "orders", Mode: entities.VCRModeRecord}
+		response := &entities.APIResponse{StatusCode: http.StatusOK, Body: map[string]interface{}{"status": status}}
+		if _, err := uc.Record(ctx, request, response); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(orderRequest("GET", "https://staging.example.com/orders/42", nil), "pending")
+	record(orderRequest("POST", "https://staging.example.com/orders", map[string]interface{}{"item": "book"}), "created")
+	record(orderRequest("GET", "https://staging.example.com/orders/42", nil), "shipped")
+}
+
+func orderRequest(method, target string, body interface{}) *entities.APIRequest {
+	return &entities.APIRequest{
+		Method:  method,
+		URL:     target,
+		Headers: map[string]string{"Accept": "application/json"},
+		Body:    body,
+		VCR:     &entities.VCROptions{Cassette: "orders", Mode: entities.VCRModeReplay},
+	}
+}
+
+func TestInteractionMatches(t *testing.T) {
+	recorded := entities.RecordedRequest{
+		Method:  "GET",
+		URL:     "https://staging.example.com/orders?status=open&page=2",
+		Headers: map[string]string{"Accept": "application/json", "X-Tenant": "acme"},
+		Body:    `{"a":1}`,
+	}
+	all := &entities.CassetteMatching{Host: true, Path: true, Query: true, Body: true, Headers: []string{"x-tenant"}}
+
+	tests := []struct {
+		name     string
+		matching *entities.CassetteMatching
+		modify   func(r *entities.RecordedRequest)
+		want     bool
+	}{
+		{name: "identical", matching: all, want: true},
+		{name: "method differs", matching: &entities.CassetteMatching{}, modify: func(r *entities.RecordedRequest) { r.Method = "POST" }},
+		{name: "method case", matching: all, modify: func(r *entities.RecordedRequest) { r.Method = "get" }, want: true},
+		{
+			name:     "host differs",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://prod.example.com/orders?status=open&page=2" },
+		},
+		{
+			name:     "host ignored by default",
+			matching: &entities.DefaultCassetteMatching,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://prod.example.com/orders?status=open&page=2" },
+			want:     true,
+		},
+		{
+			name:     "path differs",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/invoices?status=open&page=2" },
+		},
+		{
+			name:     "trailing slash",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/orders/?status=open&page=2" },
+			want:     true,
+		},
+		{
+			name:     "path ignored",
+			matching: &entities.CassetteMatching{Query: true},
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/invoices?status=open&page=2" },
+			want:     true,
+		},
+		{
+			name:     "query differs",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/orders?status=open&page=3" },
+		},
+		{
+			name:     "query order does not matter",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/orders?page=2&status=open" },
+			want:     true,
+		},
+		{
+			name:     "query ignored",
+			matching: &entities.CassetteMatching{Path: true},
+			modify:   func(r *entities.RecordedRequest) { r.URL = "https://staging.example.com/orders" },
+			want:     true,
+		},
+		{name: "body differs", matching: all, modify: func(r *entities.RecordedRequest) { r.Body = `{"a":2}` }},
+		{
+			name:     "body ignored",
+			matching: &entities.CassetteMatching{Path: true, Query: true},
+			modify:   func(r *entities.RecordedRequest) { r.Body = `{"a":2}` },
+			want:     true,
+		},
+		{
+			name:     "selected header differs",
+			matching: all,
+			modify: func(r *entities.RecordedRequest) {
+				r.Headers = map[string]string{"Accept": "application/json", "X-Tenant": "other"}
+			},
+		},
+		{
+			name:     "selected header missing",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.Headers = map[string]string{"Accept": "application/json"} },
+		},
+		{
+			name:     "selected header name case",
+			matching: all,
+			modify:   func(r *entities.RecordedRequest) { r.Headers = map[string]string{"x-tenant": "acme"} },
+			want:     true,
+		},
+		{
+			name:     "other headers are ignored",
+			matching: all,
+			modify: func(r *entities.RecordedRequest) {
+				r.Headers = map[string]string{"Accept": "text/plain", "X-Tenant": "acme"}
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := recorded
+			if tt.modify != nil {
+				tt.modify(&actual)
+			}
+			if got := interactionMatches(tt.matching, recorded, actual); got != tt.want {
+				t.Errorf("interactionMatches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCassettePlay(t *testing.T) {
+	ctx := context.Background()
+	status := func(t *testing.T, response *entities.APIResponse) string {
+		t.Helper()
+		body, _ := response.Body.(map[string]interface{})
+		return body["status"].(string)
+	}
+
+	for _, strict := range []bool{false, true} {
+		uc := NewCassetteUseCase(newMemoryCassettes())
+		ordersCassette(t, uc, strict)
+		poll := orderRequest("GET", "https://prod.example.com/orders/42", nil)
+
+		// Matching interactions play in recorded order, the last one repeats
+		for i, want := range []string{"pending", "shipped", "shipped"} {
+			response, err := uc.Play(ctx, poll)
+			if err != nil || response == nil {
+				t.Fatalf("strict=%v: play %d = %v, %v", strict, i, response, err)
+			}
+			if got := status(t, response); got != want {
+				t.Errorf("strict=%v: play %d answered %q, want %q", strict, i, got, want)
+			}
+			if response.VCR == nil || response.VCR.InteractionID == nil || !response.Success {
+				t.Errorf("strict=%v: play %d response = %+v", strict, i, response)
+			}
+		}
+
+		// Other requests do not advance the poll
+		uc.Rewind(mustCassette(t, uc).ID)
+		response, err := uc.Play(ctx, poll)
+		if err != nil || status(t, response) != "pending" {
+			t.Fatalf("strict=%v: play after rewind = %v, %v", strict, response, err)
+		}
+		response, err = uc.Play(ctx, orderRequest("POST", "https://prod.example.com/orders", map[string]interface{}{"item": "book"}))
+		if err != nil || status(t, response) != "created" {
+			t.Fatalf("strict=%v: play of the POST = %v, %v", strict, response, err)
+		}
+		response, err = uc.Play(ctx, poll)
+		if err != nil || status(t, response) != "shipped" {
+			t.Fatalf("strict=%v: second poll = %v, %v", strict, response, err)
+		}
+
+		// A request without a recording
+		response, err = uc.Play(ctx, orderRequest("POST", "https://prod.example.com/orders", map[string]interface{}{"item": "pen"}))
+		if !strict {
+			if response != nil || err != nil {
+				t.Errorf("non-strict miss = %v, %v; want no response so the request is sent", response, err)
+			}
+			continue
+		}
+		if !errors.Is(err, entities.ErrCassetteMiss) || response == nil || response.Success ||
+			response.ErrorType != entities.ErrorTypeCassetteMiss {
+			t.Errorf("strict miss = %+v, %v; want a failed response with ErrCassetteMiss", response, err)
+		}
+	}
+
+	uc := NewCassetteUseCase(newMemoryCassettes())
+	response, err := uc.Play(ctx, orderRequest("GET", "https://prod.example.com/orders/42", nil))
+	if !errors.Is(err, entities.ErrCassetteNotFound) || response == nil || response.ErrorType != entities.ErrorTypeCassetteMiss {
+		t.Errorf("play of a missing cassette = %+v, %v", response, err)
+	}
+}
+
+func mustCassette(t *testing.T, uc *CassetteUseCase) *entities.Cassette {
+	t.Helper()
+	cassette, err := uc.repo.FindCassetteByName(context.Background(), "orders")
+	if err != nil || cassette == nil {
+		t.Fatalf("cassette orders = %v, %v", cassette, err)
+	}
+	return cassette
+}
+
+// TestCassetteReplayFallsBackToTarget replays from a non-strict cassette
+// against a live server: only requests without a recording reach it
+func TestCassetteReplayFallsBackToTarget(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"live"}`))
+	}))
+	defer server.Close()
+
+	policy := entities.EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8"}}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	egress := NewEgressPolicyUseCase(staticSettings{}, policy, nil)
+	cassettes := NewCassetteUseCase(newMemoryCassettes())
+	ordersCassette(t, cassettes, false)
+	uc := &ExecuteAPICallUseCase{
+		egress:     egress,
+		transports: NewTransportPool(TransportSettings{ConnectTimeout: 2 * time.Second}, egress),
+		cassettes:  cassettes,
+	}
+	ctx := context.Background()
+
+	response, _, err := uc.attempt(ctx, orderRequest("GET", server.URL+"/orders/42", nil), nil, 5*time.Second, 1<<20)
+	if err != nil || hits.Load() != 0 {
+		t.Fatalf("recorded request: err = %v, server hits = %d", err, hits.Load())
+	}
+	if body, _ := response.Body.(map[string]interface{}); body["status"] != "pending" {
+		t.Errorf("recorded request answered %v", response.Body)
+	}
+
+	response, _, err = uc.attempt(ctx, orderRequest("GET", server.URL+"/orders/43", nil), nil, 5*time.Second, 1<<20)
+	if err != nil || hits.Load() != 1 {
+		t.Fatalf("unrecorded request: err = %v, server hits = %d", err, hits.Load())
+	}
+	if body, _ := response.Body.(map[string]interface{}); body["status"] != "live" {
+		t.Errorf("unrecorded request answered %v", response.Body)
+	}
+	if response.VCR == nil || response.VCR.Mode != entities.VCRModeReplay || response.VCR.InteractionID != nil {
+		t.Errorf("vcr result = %+v, want a replay without an interaction", response.VCR)
+	}
+}
+
+func TestCassetteRecordRedactsCredentials(t *testing.T) {
+	ctx := context.Background()
+	uc := NewCassetteUseCase(newMemoryCassettes())
+
+	request := orderRequest("GET", "https://staging.example.com/orders/42", nil)
+	request.VCR.Mode = entities.VCRModeRecord
+	request.Headers = map[string]string{
+		"Accept":        "application/json",
+		"Authorization": "Bearer live-token",
+		"cookie":        "session=abc",
+		"X-API-Key":     "key-123",
+		"X-Tenant":      "acme",
+	}
+	interaction, err := uc.Record(ctx, request, &entities.APIResponse{StatusCode: http.StatusOK})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"Accept":        "application/json",
+		"Authorization": redactedHeaderValue,
+		"cookie":        redactedHeaderValue,
+		"X-API-Key":     redactedHeaderValue,
+		"X-Tenant":      "acme",
+	}
+	stored, _ := uc.repo.ListInteractions(ctx, interaction.CassetteID)
+	for name, value := range want {
+		if got := stored[0].Request.Headers[name]; got != value {
+			t.Errorf("recorded %s = %q, want %q", name, got, value)
+		}
+	}
+	if request.Headers["Authorization"] != "Bearer live-token" {
+		t.Errorf("the request's own headers were changed: %v", request.Headers)
+	}
+
+	// Matching on a credential header only checks that it is sent
+	cassette := mustCassette(t, uc)
+	cassette.Matching.Headers = []string{"Authorization"}
+	if err := uc.UpdateCassette(ctx, cassette); err != nil {
+		t.Fatal(err)
+	}
+	replay := orderRequest("GET", "https://staging.example.com/orders/42", nil)
+	replay.Headers["Authorization"] = "Bearer rotated-token"
+	if response, err := uc.Play(ctx, replay); err != nil || response == nil {
+		t.Errorf("replay with another token = %v, %v", response, err)
+	}
+	replay = orderRequest("GET", "https://staging.example.com/orders/42", nil)
+	if response, err := uc.Play(ctx, replay); err != nil || response != nil {
+		t.Errorf("replay without a token = %v, %v; want a miss", response, err)
+	}
+}
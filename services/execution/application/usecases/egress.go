@@ -0,0 +1,129 @@
+package usecases
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/egress"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// EgressPolicyUseCase serves the admin-configured egress policy, caching it
+// like the execution limits
+type EgressPolicyUseCase struct {
+	settingsRepo repositories.SettingsRepository
+	defaults     entities.EgressPolicy
+	internal     []string
+
+	mu         sync.RWMutex
+	current    entities.EgressPolicy
+	lastLoaded time.Time
+}
+
+// NewEgressPolicyUseCase creates a new use case instance. defaults apply
+// until an admin stores a policy. internalHosts are services run alongside
+// the platform, like the ingestion mock server: calls may always reach them,
+// on whatever address they resolve to.
+func NewEgressPolicyUseCase(repo repositories.SettingsRepository, defaults entities.EgressPolicy, internalHosts []string) *EgressPolicyUseCase {
+	internal := make([]string, 0, len(internalHosts))
+	for _, host := range internalHosts {
+		internal = append(internal, egress.NormalizeHost(host))
+	}
+	return &EgressPolicyUseCase{
+		settingsRepo: repo,
+		defaults:     defaults,
+		internal:     internal,
+		current:      defaults,
+	}
+}
+
+// Policy returns the current policy. Storage errors keep the previous one.
+func (uc *EgressPolicyUseCase) Policy(ctx context.Context) entities.EgressPolicy {
+	uc.mu.RLock()
+	current, fresh := uc.current, time.Since(uc.lastLoaded) < limitsRefreshInterval
+	uc.mu.RUnlock()
+
+	if fresh {
+		return current
+	}
+
+	policy, err := uc.settingsRepo.FindEgressPolicy(ctx)
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.lastLoaded = time.Now()
+	switch {
+	case err != nil:
+		logger.WithContext(ctx).Warn().Err(err).Msg("Failed to load egress policy, keeping previous one")
+	case policy == nil:
+		uc.current = uc.defaults
+	default:
+		uc.current = *policy
+	}
+	return uc.current
+}
+
+// UpdatePolicy validates and stores a new policy
+func (uc *EgressPolicyUseCase) UpdatePolicy(ctx context.Context, policy entities.EgressPolicy) (entities.EgressPolicy, error) {
+	if err := policy.Validate(); err != nil {
+		return policy, err
+	}
+	policy.UpdatedAt = time.Now()
+	if err := uc.settingsRepo.SaveEgressPolicy(ctx, &policy); err != nil {
+		return policy, err
+	}
+
+	uc.mu.Lock()
+	uc.current = policy
+	uc.lastLoaded = time.Now()
+	uc.mu.Unlock()
+	return policy, nil
+}
+
+// Bind attaches the rules for calls to env to ctx. Connections dialed under
+// the returned context, including OAuth2 token requests, follow them.
+func (uc *EgressPolicyUseCase) Bind(ctx context.Context, env *entities.Environment) context.Context {
+	return context.WithValue(ctx, egressRulesKey{}, uc.rules(ctx, env))
+}
+
+// rulesFrom returns the rules bound to ctx, or the global rules
+func (uc *EgressPolicyUseCase) rulesFrom(ctx context.Context) *egress.Rules {
+	if rules, ok := ctx.Value(egressRulesKey{}).(*egress.Rules); ok {
+		return rules
+	}
+	return uc.rules(ctx, nil)
+}
+
+func (uc *EgressPolicyUseCase) rules(ctx context.Context, env *entities.Environment) *egress.Rules {
+	policy := uc.Policy(ctx)
+	hosts, cidrs := policy.ForEnvironment(env)
+
+	rules := &egress.Rules{
+		Hosts:    hosts,
+		Internal: uc.internal,
+		Hint:     "an admin can allow its range in the egress policy",
+	}
+	for _, cidr := range cidrs {
+		// Validated on save; a bad stored entry only loses its own range
+		if prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr)); err == nil {
+			rules.Ranges = append(rules.Ranges, prefix.Masked())
+		}
+	}
+	return rules
+}
+
+type egressRulesKey struct{}
+
+// newGuardedDialer returns a dialer checking connections against the rules
+// bound to their context. Without a policy they are not checked.
+func newGuardedDialer(dialer *net.Dialer, uc *EgressPolicyUseCase) *egress.Dialer {
+	if uc == nil {
+		return egress.NewDialer(dialer, nil)
+	}
+	return egress.NewDialer(dialer, uc.rulesFrom)
+}
@@ -0,0 +1,143 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// staticSettings is a settings repository holding no stored policy, so the
+// egress defaults apply
+type staticSettings struct{}
+
+func (staticSettings) FindExecutionLimits(context.Context) (*entities.ExecutionLimits, error) {
+	return nil, nil
+}
+
+func (staticSettings) SaveExecutionLimits(context.Context, *entities.ExecutionLimits) error {
+	return nil
+}
+
+func (staticSettings) FindEgressPolicy(context.Context) (*entities.EgressPolicy, error) {
+	return nil, nil
+}
+
+func (staticSettings) SaveEgressPolicy(context.Context, *entities.EgressPolicy) error {
+	return nil
+}
+
+// TestEgressDialedAddress sends real requests through a pooled transport to
+// a server on the loopback interface. Whether the call goes through depends
+// on the address dialed, whatever the host name says.
+func TestEgressDialedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	envID := uuid.New()
+	tests := []struct {
+		name     string
+		policy   entities.EgressPolicy
+		internal []string
+		env      *entities.Environment
+		host     string
+		blocked  bool
+	}{
+		{
+			name:    "loopback ip is refused",
+			host:    "127.0.0.1",
+			blocked: true,
+		},
+		{
+			name:    "allowed host name resolving to loopback is refused",
+			policy:  entities.EgressPolicy{AllowedHosts: []string{"localhost"}},
+			host:    "localhost",
+			blocked: true,
+		},
+		{
+			name:   "loopback range allowed globally",
+			policy: entities.EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8"}},
+			host:   "127.0.0.1",
+		},
+		{
+			name:   "host name resolving to an allowed range",
+			policy: entities.EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8", "::1/128"}},
+			host:   "localhost",
+		},
+		{
+			name: "loopback range allowed for the environment",
+			policy: entities.EgressPolicy{Environments: map[string]entities.EnvironmentEgress{
+				envID.String(): {AllowedCIDRs: []string{"127.0.0.1/32"}},
+			}},
+			env:  &entities.Environment{ID: envID, Name: "local"},
+			host: "127.0.0.1",
+		},
+		{
+			name: "range of another environment does not apply",
+			policy: entities.EgressPolicy{Environments: map[string]entities.EnvironmentEgress{
+				envID.String(): {AllowedCIDRs: []string{"127.0.0.1/32"}},
+			}},
+			env:     &entities.Environment{ID: uuid.New(), Name: "other"},
+			host:    "127.0.0.1",
+			blocked: true,
+		},
+		{
+			name:    "host outside the allowlist is refused before dialing",
+			policy:  entities.EgressPolicy{AllowedHosts: []string{"api.example.com"}, AllowedCIDRs: []string{"127.0.0.0/8"}},
+			host:    "127.0.0.1",
+			blocked: true,
+		},
+		{
+			name:     "internal host on a non-public address",
+			policy:   entities.EgressPolicy{AllowedHosts: []string{"api.example.com"}},
+			internal: []string{"LocalHost."},
+			host:     "localhost",
+		},
+		{
+			name:     "internal host does not open its address to other names",
+			internal: []string{"localhost"},
+			host:     "127.0.0.1",
+			blocked:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			egress := NewEgressPolicyUseCase(staticSettings{}, tt.policy, tt.internal)
+			pool := NewTransportPool(TransportSettings{ConnectTimeout: 2 * time.Second}, egress)
+			client, err := pool.Client(tt.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := egress.Bind(context.Background(), tt.env)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+net.JoinHostPort(tt.host, port)+"/", nil)
+			resp, err := client.Do(req)
+			if tt.blocked {
+				if !errors.Is(err, entities.ErrEgressBlocked) {
+					t.Fatalf("request error = %v, want ErrEgressBlocked", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("status = %d", resp.StatusCode)
+			}
+		})
+	}
+}
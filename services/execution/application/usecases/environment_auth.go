@@ -0,0 +1,95 @@
+package usecases
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// Supported auth_config "type" values
+const (
+	AuthTypeNone   = "none"
+	AuthTypeAPIKey = "api_key"
+	AuthTypeBearer = "bearer"
+	AuthTypeBasic  = "basic"
+	AuthTypeOAuth2 = "oauth2"
+)
+
+// applyEnvironmentAuth adds the environment's credentials to the outgoing
+// request. It runs at request-build time with the resolved (decrypted) config,
+// so credentials never appear in the stored request or in logs. OAuth2
+// environments get a cached (or freshly fetched) access token from tokens.
+func applyEnvironmentAuth(httpReq *http.Request, env *entities.Environment, tokens *OAuth2TokenProvider) error {
+	if env == nil || len(env.AuthConfig) == 0 {
+		return nil
+	}
+
+	cfg := env.AuthConfig
+	authType := strings.ToLower(configString(cfg, "type"))
+
+	switch authType {
+	case "", AuthTypeNone:
+		return nil
+
+	case AuthTypeAPIKey:
+		key := configString(cfg, "api_key")
+		if key == "" {
+			return nil
+		}
+		if strings.ToLower(configString(cfg, "in")) == "query" {
+			param := configString(cfg, "param")
+			if param == "" {
+				param = "api_key"
+			}
+			pair := url.QueryEscape(param) + "=" + url.QueryEscape(key)
+			httpReq.URL.RawQuery = mergeQuery(httpReq.URL.RawQuery, []string{param}, []string{pair})
+			return nil
+		}
+		header := configString(cfg, "header")
+		if header == "" {
+			header = "X-API-Key"
+		}
+		httpReq.Header.Set(header, key)
+		return nil
+
+	case AuthTypeBearer:
+		if token := configString(cfg, "token"); token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+
+	case AuthTypeBasic:
+		username := configString(cfg, "username")
+		if username != "" {
+			httpReq.SetBasicAuth(username, configString(cfg, "password"))
+		}
+		return nil
+
+	case AuthTypeOAuth2:
+		token, err := tokens.Token(httpReq.Context(), env)
+		if err != nil {
+			return fmt.Errorf("failed to obtain OAuth2 token for environment %s: %w", env.Name, err)
+		}
+		httpReq.Header.Set("Authorization", token.AuthorizationHeader())
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported auth type %q for environment %s", authType, env.Name)
+	}
+}
+
+// usesOAuth2 reports whether an environment authenticates with OAuth2
+func usesOAuth2(env *entities.Environment) bool {
+	return env != nil && strings.ToLower(configString(env.AuthConfig, "type")) == AuthTypeOAuth2
+}
+
+// configString reads a string value from an auth config
+func configString(cfg map[string]interface{}, key string) string {
+	if value, ok := cfg[key].(string); ok {
+		return value
+	}
+	return ""
+}
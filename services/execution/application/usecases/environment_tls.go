@@ -0,0 +1,88 @@
+package usecases
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// buildTLSConfig creates a client TLS config from environment settings
+func buildTLSConfig(settings *entities.TLSSettings) (*tls.Config, error) {
+	// InsecureSkipVerify can only be set by admins, see the environment handler
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         settings.ServerName,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+
+	if settings.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(settings.CABundle)) {
+			return nil, fmt.Errorf("%s contains no PEM certificates", entities.TLSCABundleField)
+		}
+		config.RootCAs = pool
+	}
+
+	if settings.ClientCert != "" || settings.ClientKey != "" {
+		if settings.ClientCert == "" || settings.ClientKey == "" {
+			return nil, fmt.Errorf("%s and %s must be set together", entities.TLSClientCertField, entities.TLSClientKeyField)
+		}
+		certificate, err := tls.X509KeyPair([]byte(settings.ClientCert), []byte(settings.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// validateTLSSettings checks TLS material supplied in an environment create or
+// update. Masked values stand for already stored secrets and are skipped.
+func validateTLSSettings(authConfig map[string]interface{}) error {
+	settings := entities.TLSSettingsFromAuthConfig(authConfig)
+	if settings == nil {
+		return nil
+	}
+
+	if settings.CABundle != "" && !containsPEM(settings.CABundle, "CERTIFICATE") {
+		return fmt.Errorf("%w: %s contains no PEM certificates", entities.ErrInvalidEnvironment, entities.TLSCABundleField)
+	}
+
+	cert, key := settings.ClientCert, settings.ClientKey
+	if cert == entities.SecretMask || key == entities.SecretMask {
+		// One side is stored already; the pair is checked when first used
+		return nil
+	}
+	if cert == "" && key == "" {
+		return nil
+	}
+	if cert == "" || key == "" {
+		return fmt.Errorf("%w: %s and %s must be set together", entities.ErrInvalidEnvironment, entities.TLSClientCertField, entities.TLSClientKeyField)
+	}
+	if _, err := tls.X509KeyPair([]byte(cert), []byte(key)); err != nil {
+		return fmt.Errorf("%w: invalid client certificate or key: %v", entities.ErrInvalidEnvironment, err)
+	}
+	return nil
+}
+
+// containsPEM reports whether data holds at least one PEM block of the given type
+func containsPEM(data, blockType string) bool {
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return false
+		}
+		if block.Type == blockType {
+			return true
+		}
+	}
+}
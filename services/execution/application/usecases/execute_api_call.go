@@ -1,57 +1,376 @@
 package usecases
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/testpilot-ai/execution/domain/entities"
 	"github.com/testpilot-ai/execution/domain/repositories"
 	"github.com/testpilot-ai/shared/logger"
 )
 
+// RequestSigners signs outgoing requests for environments whose auth type is
+// a signing scheme (e.g. HMAC, AWS SigV4)
+type RequestSigners interface {
+	Supports(authType string) bool
+	Sign(authType string, authConfig map[string]interface{}, req *http.Request) error
+}
+
 // ExecuteAPICallUseCase handles API execution logic
 type ExecuteAPICallUseCase struct {
-	executionRepo repositories.ExecutionRepository
-	httpClient    *http.Client
+	executionRepo   repositories.ExecutionRepository
+	environments    *ManageEnvironmentsUseCase
+	tokens          *OAuth2TokenProvider
+	signers         RequestSigners
+	transports      *TransportPool
+	limits          *ExecutionLimitsUseCase
+	idempotency     *IdempotencyGuard
+	egress          *EgressPolicyUseCase
+	cassettes       *CassetteUseCase
+	callbacks       *CallbackUseCase
+	grpcDescriptors *GRPCDescriptorCache
 }
 
 // NewExecuteAPICallUseCase creates a new use case instance
-func NewExecuteAPICallUseCase(repo repositories.ExecutionRepository) *ExecuteAPICallUseCase {
+func NewExecuteAPICallUseCase(
+	repo repositories.ExecutionRepository,
+	environments *ManageEnvironmentsUseCase,
+	tokens *OAuth2TokenProvider,
+	signers RequestSigners,
+	transports *TransportPool,
+	limits *ExecutionLimitsUseCase,
+	idempotency *IdempotencyGuard,
+	egress *EgressPolicyUseCase,
+	cassettes *CassetteUseCase,
+	callbacks *CallbackUseCase,
+	grpcDescriptors *GRPCDescriptorCache,
+) *ExecuteAPICallUseCase {
 	return &ExecuteAPICallUseCase{
-		executionRepo: repo,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		executionRepo:   repo,
+		environments:    environments,
+		tokens:          tokens,
+		signers:         signers,
+		transports:      transports,
+		limits:          limits,
+		idempotency:     idempotency,
+		egress:          egress,
+		cassettes:       cassettes,
+		callbacks:       callbacks,
+		grpcDescriptors: grpcDescriptors,
 	}
 }
 
-// Execute executes an API call
+// Execute executes an API call. With a retry policy or poll condition the
+// call is repeated as needed; all attempts share one deadline and are
+// recorded on the stored execution.
 func (uc *ExecuteAPICallUseCase) Execute(ctx context.Context, request *entities.APIRequest) (*entities.APIResponse, error) {
 	// Validate request
 	if err := request.Validate(); err != nil {
 		return nil, err
 	}
+	var condition *pollCondition
+	if request.PollUntil != nil {
+		compiled, err := compilePollCondition(request.PollUntil)
+		if err != nil {
+			return nil, err
+		}
+		condition = compiled
+	}
+
+	// Attach an idempotency key and refuse accidental re-submissions
+	release, err := uc.idempotency.Prepare(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Open a callback receiver and put its URL in place of the placeholder;
+	// this comes after the idempotency key so the fingerprint stays stable
+	var receiver *entities.CallbackReceiver
+	if request.Callback != nil {
+		receiver, err = uc.callbacks.Open(ctx, request.UserID, request.EnvironmentID, request.Callback)
+		if err != nil {
+			return nil, err
+		}
+		request = withCallbackURL(request, entities.CallbackURLPlaceholder, receiver.URL)
+		request.CallbackURL = receiver.URL
+	}
 
 	// Prepare response
 	response := entities.NewAPIResponse(request.ID)
+	response.Callback = receiver
 	startTime := time.Now()
 
-	// Execute HTTP request
-	httpReq, err := uc.buildHTTPRequest(ctx, request)
+	// Resolve the target environment (with decrypted secrets) if one was chosen
+	var env *entities.Environment
+	if request.EnvironmentID != nil {
+		resolved, err := uc.environments.ResolveEnvironment(ctx, *request.EnvironmentID)
+		if err != nil {
+			response.Error = fmt.Sprintf("failed to resolve environment: %v", err)
+			response.Success = false
+			return response, err
+		}
+		env = resolved
+	}
+
+	// Each attempt gets its own deadline; the parent context is kept for
+	// saving the result, which must still work after a timeout
+	limits := uc.limits.Limits(ctx)
+	timeout := limits.Timeout(request.Timeout)
+	if request.Timeout > limits.MaxTimeoutSeconds {
+		logger.WithContext(ctx).Info().
+			Int("requested_seconds", request.Timeout).
+			Int("max_seconds", limits.MaxTimeoutSeconds).
+			Msg("Requested timeout exceeds the configured maximum, capping it")
+	}
+
+	if request.Retry == nil && condition == nil {
+		response, _, err := uc.attempt(ctx, request, env, timeout, limits.MaxResponseBytes)
+		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		response.Callback = receiver
+		uc.save(ctx, request, response)
+		return response, err
+	}
+
+	// Retries and polling run under one deadline: the poll timeout if set,
+	// otherwise the maximum execution timeout
+	budget := limits.Timeout(limits.MaxTimeoutSeconds)
+	if condition != nil && condition.TimeoutSeconds > 0 {
+		budget = limits.Timeout(condition.TimeoutSeconds)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	deadline, _ := runCtx.Deadline()
+
+	policy := request.Retry
+	if policy == nil {
+		policy = &entities.RetryPolicy{MaxAttempts: 1}
+	}
+
+	var attempts []entities.ExecutionAttempt
+	retries := 0
+	for number := 1; ; number++ {
+		attemptStart := time.Now()
+		var retryable bool
+		response, retryable, err = uc.attempt(runCtx, request, env, timeout, limits.MaxResponseBytes)
+		record := entities.ExecutionAttempt{
+			Number:     number,
+			StartedAt:  attemptStart,
+			StatusCode: response.StatusCode,
+			Error:      response.Error,
+			DurationMs: time.Since(attemptStart).Milliseconds(),
+		}
+
+		var delay time.Duration
+		switch {
+		case retryable || (err == nil && policy.RetriesStatus(response.StatusCode)):
+			if retries+1 < policy.MaxAttempts {
+				retries++
+				record.NextAction = entities.AttemptRetry
+				delay = backoff(policy, retries)
+				if after, ok := retryAfter(response.Headers, time.Now()); ok {
+					delay = after
+				}
+			}
+		case err == nil && condition != nil:
+			met, observed := condition.holds(response.Body)
+			record.ConditionMet = &met
+			record.Observed = observed
+			if !met {
+				record.NextAction = entities.AttemptPoll
+				delay = condition.Interval()
+			}
+		}
+
+		// Stop when the next attempt could not start before the deadline
+		if record.NextAction != "" && (runCtx.Err() != nil || time.Now().Add(delay).After(deadline)) {
+			record.NextAction = ""
+			delay = 0
+		}
+		if record.NextAction != "" {
+			record.DelayMs = delay.Milliseconds()
+		}
+		attempts = append(attempts, record)
+
+		if record.NextAction == "" || !wait(runCtx, delay) {
+			break
+		}
+		logger.WithContext(ctx).Info().
+			Str("request_id", request.ID.String()).
+			Int("attempt", number).
+			Str("next_action", record.NextAction).
+			Int64("delay_ms", record.DelayMs).
+			Msg("Repeating API call")
+	}
+
+	response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+	response.Attempts = attempts
+	response.Callback = receiver
+	if last := attempts[len(attempts)-1]; err == nil && condition != nil && (last.ConditionMet == nil || !*last.ConditionMet) {
+		response.Success = false
+		response.Error = fmt.Sprintf("poll condition on %s not met after %d attempts within %s", condition.Path, len(attempts), budget)
+	}
+	uc.save(ctx, request, response)
+	return response, err
+}
+
+// Replay executes a stored execution's request again. The stored headers,
+// and with them the idempotency key, are sent unchanged, so the target's
+// idempotency handling can be checked against the original response.
+func (uc *ExecuteAPICallUseCase) Replay(ctx context.Context, executionID uuid.UUID, userID *uuid.UUID, isAdmin bool) (*entities.ReplayResult, error) {
+	request, err := uc.executionRepo.FindExecutionRequest(ctx, executionID)
 	if err != nil {
+		return nil, err
+	}
+	// Users can only replay their own executions
+	if !isAdmin && (userID == nil || request.UserID == nil || *request.UserID != *userID) {
+		return nil, entities.ErrExecutionNotFound
+	}
+	original, err := uc.executionRepo.FindExecutionByID(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	request.ID = uuid.New()
+	request.CreatedAt = time.Now()
+	request.UserID = userID
+	request.ReplayOf = &executionID
+	// A replay checks the target, so it neither plays nor records a cassette
+	request.VCR = nil
+
+	response, err := uc.Execute(ctx, request)
+	if response == nil {
+		return nil, err
+	}
+
+	result := &entities.ReplayResult{
+		OriginalExecutionID: executionID,
+		IdempotencyKey:      response.IdempotencyKey,
+		OriginalStatusCode:  original.StatusCode,
+		StatusMatches:       response.StatusCode == original.StatusCode,
+		Response:            response,
+	}
+	if response.BinaryBody != nil && original.BinaryBody != nil {
+		result.BodyMatches = response.BinaryBody.SHA256 == original.BinaryBody.SHA256
+	} else {
+		result.BodyMatches = jsonEqual(normalizeJSON(response.Body), original.Body)
+	}
+	return result, err
+}
+
+// normalizeJSON round-trips a value through JSON so it compares equal to
+// the stored copy
+func normalizeJSON(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// Send sends a validated request once without storing it or applying
+// idempotency, cassettes or callbacks; load tests aggregate the results
+// themselves. Egress rules still apply.
+func (uc *ExecuteAPICallUseCase) Send(ctx context.Context, request *entities.APIRequest, env *entities.Environment, timeout time.Duration, maxResponseBytes int64) (*entities.APIResponse, error) {
+	response, _, err := uc.call(ctx, request, env, timeout, maxResponseBytes)
+	return response, err
+}
+
+// attempt makes one attempt of the request: answered from its cassette in
+// replay mode, sent otherwise and recorded in record mode. Each attempt of
+// a retried or polled request is recorded, and replayed, on its own.
+func (uc *ExecuteAPICallUseCase) attempt(ctx context.Context, request *entities.APIRequest, env *entities.Environment, timeout time.Duration, maxResponseBytes int64) (*entities.APIResponse, bool, error) {
+	if request.VCR.Replays() {
+		response, err := uc.cassettes.Play(ctx, request)
+		if response != nil {
+			return response, false, err
+		}
+		logger.WithContext(ctx).Info().
+			Str("request_id", request.ID.String()).
+			Str("cassette", request.VCR.Cassette).
+			Msg("No recorded interaction matches, sending request to the target")
+	}
+
+	response, retryable, err := uc.call(ctx, request, env, timeout, maxResponseBytes)
+	if request.VCR == nil {
+		return response, retryable, err
+	}
+
+	response.VCR = &entities.VCRResult{Cassette: request.VCR.Cassette, Mode: request.VCR.Mode}
+	if request.VCR.Records() && err == nil {
+		interaction, recordErr := uc.cassettes.Record(ctx, request, response)
+		if recordErr != nil {
+			logger.WithContext(ctx).Err(recordErr).
+				Str("request_id", request.ID.String()).
+				Str("cassette", request.VCR.Cassette).
+				Msg("Failed to record interaction")
+		} else {
+			response.VCR.InteractionID = &interaction.ID
+		}
+	}
+	return response, retryable, err
+}
+
+// call sends the request once under its own timeout and reads the
+// response. retryable reports a failure on the network side, as opposed
+// to a request that could not be built or signed.
+func (uc *ExecuteAPICallUseCase) call(ctx context.Context, request *entities.APIRequest, env *entities.Environment, timeout time.Duration, maxResponseBytes int64) (*entities.APIResponse, bool, error) {
+	response := entities.NewAPIResponse(request.ID)
+	response.IdempotencyKey = request.IdempotencyKey
+	response.ReplayOf = request.ReplayOf
+	startTime := time.Now()
+
+	// Every connection of the attempt, token requests included, follows
+	// the environment's egress rules
+	execCtx, cancel := context.WithTimeout(uc.egress.Bind(ctx, env), timeout)
+	defer cancel()
+
+	fail := func(err error, httpResp *http.Response, trace *timingTrace, retryable bool) (*entities.APIResponse, bool, error) {
 		response.Error = err.Error()
+		if errors.Is(err, entities.ErrEgressBlocked) {
+			response.ErrorType = entities.ErrorTypeEgressBlocked
+		}
 		response.Success = false
-		return response, err
+		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		if trace != nil {
+			response.Timing = trace.timing(time.Now(), httpResp)
+		}
+		return response, retryable, err
 	}
 
-	// Set timeout
-	if request.Timeout > 0 {
-		uc.httpClient.Timeout = time.Duration(request.Timeout) * time.Second
+	if request.Stream != nil {
+		retryable, err := uc.callStream(execCtx, request, env, maxResponseBytes, response)
+		if err != nil {
+			return fail(timeoutError(execCtx, err, timeout), nil, nil, retryable)
+		}
+		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		return response, false, nil
+	}
+
+	if request.GRPC != nil {
+		retryable, err := uc.callGRPC(execCtx, request, env, maxResponseBytes, response)
+		if err != nil {
+			return fail(timeoutError(execCtx, err, timeout), nil, nil, retryable)
+		}
+		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		return response, false, nil
+	}
+
+	// Execute HTTP request
+	httpReq, err := uc.buildHTTPRequest(execCtx, request, env)
+	if err != nil {
+		return fail(err, nil, nil, false)
 	}
 
 	// Make the request
@@ -62,11 +381,10 @@ func (uc *ExecuteAPICallUseCase) Execute(ctx context.Context, request *entities.
 		Interface("headers", request.Headers).
 		Msg("[DEBUG-H1] About to execute HTTP request")
 	// #endregion
-	httpResp, err := uc.httpClient.Do(httpReq)
+	httpReq, trace := traceRequest(httpReq)
+	httpResp, retryable, err := uc.send(httpReq, env)
 	if err != nil {
-		response.Error = err.Error()
-		response.Success = false
-		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+		err = timeoutError(execCtx, err, timeout)
 
 		// #region agent log
 		logger.WithContext(ctx).Error().
@@ -74,7 +392,7 @@ func (uc *ExecuteAPICallUseCase) Execute(ctx context.Context, request *entities.
 			Str("method", request.Method).
 			Str("url", request.URL).
 			Str("error_type", fmt.Sprintf("%T", err)).
-			Int64("execution_time_ms", response.ExecutionTimeMs).
+			Int64("execution_time_ms", time.Since(startTime).Milliseconds()).
 			Msg("[DEBUG-H1-H2-H4] HTTP request failed - check DNS/timeout/network")
 		// #endregion
 
@@ -83,10 +401,30 @@ func (uc *ExecuteAPICallUseCase) Execute(ctx context.Context, request *entities.
 			Str("url", request.URL).
 			Msg("HTTP request failed")
 
-		// Save failed execution
-		_ = uc.executionRepo.SaveExecution(ctx, request, response)
-		return response, err
+		return fail(err, nil, trace, retryable)
 	}
+
+	// A 401 for an OAuth2 environment usually means the token was revoked
+	// or expired early; retry once with a freshly issued token
+	if httpResp.StatusCode == http.StatusUnauthorized && usesOAuth2(env) {
+		httpResp.Body.Close()
+		uc.tokens.Invalidate(env.ID)
+
+		logger.WithContext(ctx).Info().
+			Str("environment", env.Name).
+			Msg("Target rejected OAuth2 token, retrying with a new token")
+
+		httpReq, err = uc.buildHTTPRequest(execCtx, request, env)
+		if err != nil {
+			return fail(err, nil, trace, false)
+		}
+		httpReq, trace = traceRequest(httpReq)
+		httpResp, retryable, err = uc.send(httpReq, env)
+		if err != nil {
+			return fail(timeoutError(execCtx, err, timeout), nil, trace, retryable)
+		}
+	}
+
 	// #region agent log
 	logger.WithContext(ctx).Debug().
 		Str("method", request.Method).
@@ -96,65 +434,69 @@ func (uc *ExecuteAPICallUseCase) Execute(ctx context.Context, request *entities.
 	// #endregion
 	defer httpResp.Body.Close()
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(httpResp.Body)
+	// Read response body, up to the configured limit
+	bodyBytes, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseBytes+1))
 	if err != nil {
-		response.Error = fmt.Sprintf("failed to read response body: %v", err)
-		response.Success = false
-		response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
-		return response, err
+		err = timeoutError(execCtx, err, timeout)
+		return fail(fmt.Errorf("failed to read response body: %w", err), httpResp, trace, true)
+	}
+	if int64(len(bodyBytes)) > maxResponseBytes {
+		bodyBytes = bodyBytes[:maxResponseBytes]
+		response.Truncated = true
+		logger.WithContext(ctx).Warn().
+			Str("request_id", request.ID.String()).
+			Int64("max_response_bytes", maxResponseBytes).
+			Msg("Response body exceeds the size limit, truncating")
 	}
 
 	// Parse response
 	response.StatusCode = httpResp.StatusCode
 	response.Headers = httpResp.Header
 	response.ExecutionTimeMs = time.Since(startTime).Milliseconds()
+	response.Timing = trace.timing(time.Now(), httpResp)
 
-	// Try to parse as JSON
-	var bodyJSON interface{}
-	if err := json.Unmarshal(bodyBytes, &bodyJSON); err == nil {
-		response.Body = bodyJSON
-	} else {
-		response.Body = string(bodyBytes)
-	}
+	// Parse JSON, keep text as is and describe binary content
+	decodeResponseBody(response, httpResp.Header.Get("Content-Type"), bodyBytes)
 
 	response.Success = response.IsSuccessful()
+	checkGraphQLErrors(request, response)
+	return response, false, nil
+}
 
-	// Save execution to database
+// save stores the execution; a failure is logged but does not fail the call
+func (uc *ExecuteAPICallUseCase) save(ctx context.Context, request *entities.APIRequest, response *entities.APIResponse) {
 	if err := uc.executionRepo.SaveExecution(ctx, request, response); err != nil {
-		// Log error but don't fail the execution
 		logger.WithContext(ctx).Err(err).
 			Str("request_id", request.ID.String()).
 			Msg("Failed to save execution to database")
+		return
+	}
+	if response.Callback != nil {
+		if err := uc.callbacks.Link(ctx, response.Callback.ID, response.ID); err != nil {
+			logger.WithContext(ctx).Err(err).
+				Str("receiver_id", response.Callback.ID.String()).
+				Msg("Failed to link callback receiver to execution")
+		}
+		response.Callback.ExecutionID = &response.ID
 	}
-
-	return response, nil
 }
 
 // buildHTTPRequest creates an HTTP request from APIRequest entity
-func (uc *ExecuteAPICallUseCase) buildHTTPRequest(ctx context.Context, request *entities.APIRequest) (*http.Request, error) {
+func (uc *ExecuteAPICallUseCase) buildHTTPRequest(ctx context.Context, request *entities.APIRequest, env *entities.Environment) (*http.Request, error) {
 	// Build URL with query params
-	url := request.URL
-	if len(request.QueryParams) > 0 {
-		params := []string{}
-		for k, v := range request.QueryParams {
-			params = append(params, fmt.Sprintf("%s=%v", k, v))
-		}
-		if strings.Contains(url, "?") {
-			url = url + "&" + strings.Join(params, "&")
-		} else {
-			url = url + "?" + strings.Join(params, "&")
-		}
+	url, err := buildRequestURL(request)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare body
+	bodyBytes, contentType, err := encodeRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
 	var bodyReader io.Reader
-	if request.Body != nil {
-		bodyBytes, err := json.Marshal(request.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal body: %w", err)
-		}
-		bodyReader = strings.NewReader(string(bodyBytes))
+	if bodyBytes != nil {
+		bodyReader = bytes.NewReader(bodyBytes)
 	}
 
 	// Create request
@@ -168,10 +510,58 @@ func (uc *ExecuteAPICallUseCase) buildHTTPRequest(ctx context.Context, request *
 		httpReq.Header.Set(k, v)
 	}
 
-	// Default content type if not set
-	if request.Body != nil && httpReq.Header.Get("Content-Type") == "" {
-		httpReq.Header.Set("Content-Type", "application/json")
+	// Default content type if not set; multipart needs its boundary
+	if contentType != "" && (httpReq.Header.Get("Content-Type") == "" || request.ResolvedBodyType() == entities.BodyTypeMultipart) {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+
+	// Environment credentials are applied last so they override placeholders.
+	// Signing schemes are applied in send, once the request is final.
+	if !uc.signs(env) {
+		if err := applyEnvironmentAuth(httpReq, env, uc.tokens); err != nil {
+			return nil, err
+		}
 	}
 
 	return httpReq, nil
 }
+
+// send signs the request if the environment requires it and sends it over
+// the environment's pooled transport. Signing happens here so the signature covers
+// exactly what goes on the wire. retryable is true for transport errors other
+// than egress refusals.
+func (uc *ExecuteAPICallUseCase) send(httpReq *http.Request, env *entities.Environment) (*http.Response, bool, error) {
+	// The dialer enforces the egress rules too, but a reused connection
+	// is never dialed, so check the host up front
+	if err := uc.egress.rulesFrom(httpReq.Context()).CheckHost(httpReq.URL.Hostname()); err != nil {
+		return nil, false, err
+	}
+
+	if uc.signs(env) {
+		authType := configString(env.AuthConfig, "type")
+		if err := uc.signers.Sign(authType, env.AuthConfig, httpReq); err != nil {
+			return nil, false, fmt.Errorf("failed to sign request for environment %s: %w", env.Name, err)
+		}
+	}
+
+	client, err := uc.transports.Client(env)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := client.Do(httpReq)
+	return resp, err != nil && !errors.Is(err, entities.ErrEgressBlocked), err
+}
+
+// timeoutError reports an execution that ran past its deadline as
+// ErrTimeout, keeping other errors as they are
+func timeoutError(ctx context.Context, err error, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: no complete response within %s", entities.ErrTimeout, timeout)
+	}
+	return err
+}
+
+// signs reports whether the environment's auth type is a signing scheme
+func (uc *ExecuteAPICallUseCase) signs(env *entities.Environment) bool {
+	return env != nil && uc.signers != nil && uc.signers.Supports(configString(env.AuthConfig, "type"))
+}
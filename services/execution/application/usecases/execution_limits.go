@@ -0,0 +1,95 @@
+package usecases
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// limitsRefreshInterval controls how often limits are re-read from storage
+const limitsRefreshInterval = 30 * time.Second
+
+// ExecutionLimitsUseCase serves the admin-configured execution limits,
+// caching them so executions do not hit the database each time
+type ExecutionLimitsUseCase struct {
+	settingsRepo repositories.SettingsRepository
+	defaults     entities.ExecutionLimits
+
+	mu         sync.RWMutex
+	current    entities.ExecutionLimits
+	lastLoaded time.Time
+}
+
+// NewExecutionLimitsUseCase creates a new use case instance. defaults apply
+// until an admin stores limits, and fill any fields missing from storage.
+func NewExecutionLimitsUseCase(repo repositories.SettingsRepository, defaults entities.ExecutionLimits) *ExecutionLimitsUseCase {
+	return &ExecutionLimitsUseCase{
+		settingsRepo: repo,
+		defaults:     defaults,
+		current:      defaults,
+	}
+}
+
+// Limits returns the current limits. Storage errors keep the previous values.
+func (uc *ExecutionLimitsUseCase) Limits(ctx context.Context) entities.ExecutionLimits {
+	uc.mu.RLock()
+	current, fresh := uc.current, time.Since(uc.lastLoaded) < limitsRefreshInterval
+	uc.mu.RUnlock()
+
+	if fresh {
+		return current
+	}
+
+	limits, err := uc.load(ctx)
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.lastLoaded = time.Now()
+	if err != nil {
+		logger.WithContext(ctx).Warn().Err(err).Msg("Failed to load execution limits, keeping previous values")
+	} else {
+		uc.current = limits
+	}
+	return uc.current
+}
+
+// UpdateLimits validates and stores new limits
+func (uc *ExecutionLimitsUseCase) UpdateLimits(ctx context.Context, limits entities.ExecutionLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	if err := uc.settingsRepo.SaveExecutionLimits(ctx, &limits); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	uc.current = limits
+	uc.lastLoaded = time.Now()
+	uc.mu.Unlock()
+	return nil
+}
+
+func (uc *ExecutionLimitsUseCase) load(ctx context.Context) (entities.ExecutionLimits, error) {
+	stored, err := uc.settingsRepo.FindExecutionLimits(ctx)
+	if err != nil || stored == nil {
+		return uc.defaults, err
+	}
+
+	limits := *stored
+	if limits.DefaultTimeoutSeconds <= 0 {
+		limits.DefaultTimeoutSeconds = uc.defaults.DefaultTimeoutSeconds
+	}
+	if limits.MaxTimeoutSeconds <= 0 {
+		limits.MaxTimeoutSeconds = uc.defaults.MaxTimeoutSeconds
+	}
+	if limits.MaxResponseBytes <= 0 {
+		limits.MaxResponseBytes = uc.defaults.MaxResponseBytes
+	}
+	if limits.DefaultTimeoutSeconds > limits.MaxTimeoutSeconds {
+		limits.DefaultTimeoutSeconds = limits.MaxTimeoutSeconds
+	}
+	return limits, nil
+}
@@ -0,0 +1,360 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxCachedDescriptorSets bounds the compiled descriptor sets kept in memory
+const maxCachedDescriptorSets = 64
+
+// grpcStatusCodes maps gRPC status codes to the HTTP status codes stored
+// for the call, so status assertions and retry policies work on gRPC calls
+// too. The mapping follows google.rpc.Code; Canceled uses nginx's 499.
+var grpcStatusCodes = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// GRPCDescriptorCache provides the protobuf descriptors of gRPC calls,
+// compiled once per distinct descriptor set. Ingested sets are read on
+// every call, so a re-ingested spec takes effect at once.
+type GRPCDescriptorCache struct {
+	repo repositories.GRPCDescriptorRepository
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*grpcDescriptors
+}
+
+// grpcDescriptors are the files of a descriptor set and the message types
+// they define, which also resolve google.protobuf.Any values
+type grpcDescriptors struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// NewGRPCDescriptorCache creates a descriptor cache backed by the
+// descriptor sets stored at ingestion
+func NewGRPCDescriptorCache(repo repositories.GRPCDescriptorRepository) *GRPCDescriptorCache {
+	return &GRPCDescriptorCache{
+		repo:    repo,
+		entries: make(map[[sha256.Size]byte]*grpcDescriptors),
+	}
+}
+
+// method returns the descriptor of the request's method, from its inline
+// descriptor set or the one ingested for its API spec
+func (c *GRPCDescriptorCache) method(ctx context.Context, request *entities.APIRequest) (protoreflect.MethodDescriptor, *grpcDescriptors, error) {
+	var data []byte
+	if request.GRPC.DescriptorSet != "" {
+		decoded, err := base64.StdEncoding.DecodeString(request.GRPC.DescriptorSet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: grpc descriptor_set is not valid base64", entities.ErrInvalidBody)
+		}
+		data = decoded
+	} else {
+		stored, err := c.repo.FindDescriptorSet(ctx, *request.APISpecID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load descriptors of API spec %s: %w", request.APISpecID, err)
+		}
+		data = stored
+	}
+
+	descriptors, err := c.compile(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serviceName, methodName := request.GRPC.ServiceMethod()
+	found, err := descriptors.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: grpc service %s is not in the descriptors", entities.ErrInvalidBody, serviceName)
+	}
+	service, ok := found.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s is not a grpc service", entities.ErrInvalidBody, serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, nil, fmt.Errorf("%w: grpc service %s has no method %s", entities.ErrInvalidBody, serviceName, methodName)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, nil, fmt.Errorf("%w: grpc method %s is streaming; only unary calls are supported", entities.ErrInvalidBody, request.GRPC.Method)
+	}
+	return method, descriptors, nil
+}
+
+func (c *GRPCDescriptorCache) compile(data []byte) (*grpcDescriptors, error) {
+	key := sha256.Sum256(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.entries[key]; ok {
+		return cached, nil
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: grpc descriptor set is not a serialized FileDescriptorSet: %v", entities.ErrInvalidBody, err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid grpc descriptor set: %v", entities.ErrInvalidBody, err)
+	}
+
+	if len(c.entries) >= maxCachedDescriptorSets {
+		clear(c.entries)
+	}
+	descriptors := &grpcDescriptors{files: files, types: dynamicpb.NewTypes(files)}
+	c.entries[key] = descriptors
+	return descriptors, nil
+}
+
+// callGRPC makes the request as a unary gRPC call and fills in response.
+// A status other than OK is a response, not an error; errors are calls
+// that could not be made, with retryable set like send does.
+func (uc *ExecuteAPICallUseCase) callGRPC(ctx context.Context, request *entities.APIRequest, env *entities.Environment, maxResponseBytes int64, response *entities.APIResponse) (bool, error) {
+	method, descriptors, err := uc.grpcDescriptors.method(ctx, request)
+	if err != nil {
+		return false, err
+	}
+
+	input := dynamicpb.NewMessage(method.Input())
+	if request.Body != nil {
+		body, err := json.Marshal(request.Body)
+		if err != nil {
+			return false, fmt.Errorf("failed to encode grpc request message: %w", err)
+		}
+		if err := (protojson.UnmarshalOptions{Resolver: descriptors.types}).Unmarshal(body, input); err != nil {
+			return false, fmt.Errorf("%w: body does not match %s: %v", entities.ErrInvalidBody, method.Input().FullName(), err)
+		}
+	}
+
+	target, secure, err := entities.GRPCTarget(request.URL)
+	if err != nil {
+		return false, err
+	}
+	host, _, _ := net.SplitHostPort(target)
+	rules := uc.egress.rulesFrom(ctx)
+	if err := rules.CheckHost(host); err != nil {
+		return false, err
+	}
+
+	creds := insecure.NewCredentials()
+	if secure {
+		tlsConfig, err := uc.transports.TLSConfig(env)
+		if err != nil {
+			return false, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	// gRPC dials in the background, outside the call's context, so the
+	// egress rules are passed on here; the dial error is kept because the
+	// call itself only reports Unavailable
+	var dialMu sync.Mutex
+	var dialErr error
+	conn, err := grpc.NewClient("passthrough:///"+target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithNoProxy(),
+		grpc.WithContextDialer(func(dialCtx context.Context, addr string) (net.Conn, error) {
+			conn, err := uc.transports.dialer.DialContext(context.WithValue(dialCtx, egressRulesKey{}, rules), "tcp", addr)
+			if err != nil {
+				dialMu.Lock()
+				dialErr = err
+				dialMu.Unlock()
+			}
+			return conn, err
+		}),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create grpc client for %s: %w", target, err)
+	}
+	defer conn.Close()
+
+	fullMethod := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	output := dynamicpb.NewMessage(method.Output())
+	var header, trailer metadata.MD
+	invoke := func() error {
+		md, err := uc.grpcMetadata(ctx, request, env, target)
+		if err != nil {
+			return err
+		}
+		header, trailer = nil, nil
+		return conn.Invoke(metadata.NewOutgoingContext(ctx, md), fullMethod, input, output,
+			grpc.Header(&header),
+			grpc.Trailer(&trailer),
+			grpc.MaxCallRecvMsgSize(int(min(maxResponseBytes, math.MaxInt32))),
+		)
+	}
+
+	callErr := invoke()
+	st, isStatus := status.FromError(callErr)
+	if !isStatus {
+		return false, callErr
+	}
+
+	// As over HTTP, a rejected OAuth2 token is renewed and the call retried once
+	if st.Code() == codes.Unauthenticated && usesOAuth2(env) {
+		uc.tokens.Invalidate(env.ID)
+		logger.WithContext(ctx).Info().
+			Str("environment", env.Name).
+			Msg("Target rejected OAuth2 token, retrying gRPC call with a new token")
+		callErr = invoke()
+		if st, isStatus = status.FromError(callErr); !isStatus {
+			return false, callErr
+		}
+	}
+
+	if st.Code() == codes.Unavailable {
+		dialMu.Lock()
+		err := dialErr
+		dialMu.Unlock()
+		if err != nil {
+			return !errors.Is(err, entities.ErrEgressBlocked), fmt.Errorf("failed to connect to %s: %w", target, err)
+		}
+	}
+	if st.Code() == codes.DeadlineExceeded && ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	result := &entities.GRPCResult{
+		Code:    int(st.Code()),
+		Status:  grpcStatusName(st.Code()),
+		Message: st.Message(),
+		Header:  header,
+		Trailer: trailer,
+	}
+	for _, detail := range st.Proto().GetDetails() {
+		result.Details = append(result.Details, grpcJSON(detail, descriptors.types))
+	}
+
+	response.GRPC = result
+	if header != nil {
+		response.Headers = header
+	}
+	response.StatusCode = http.StatusInternalServerError
+	if code, ok := grpcStatusCodes[st.Code()]; ok {
+		response.StatusCode = code
+	}
+	if st.Code() == codes.OK {
+		response.Body = grpcJSON(output, descriptors.types)
+		response.Success = true
+		return false, nil
+	}
+	response.Success = false
+	response.ErrorType = entities.ErrorTypeGRPC
+	response.Error = result.Failure()
+	return false, nil
+}
+
+// grpcMetadata returns the call's metadata: the request headers, then the
+// environment's credentials. Credentials that are not headers cannot be
+// sent over gRPC.
+func (uc *ExecuteAPICallUseCase) grpcMetadata(ctx context.Context, request *entities.APIRequest, env *entities.Environment, target string) (metadata.MD, error) {
+	if uc.signs(env) {
+		return nil, fmt.Errorf("environment %s signs requests, which gRPC calls do not support", env.Name)
+	}
+
+	// Credentials are applied to a stand-in HTTP request and copied over
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range request.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	if err := applyEnvironmentAuth(httpReq, env, uc.tokens); err != nil {
+		return nil, err
+	}
+	if httpReq.URL.RawQuery != "" {
+		return nil, fmt.Errorf("environment %s sends its API key as a query parameter, which gRPC calls do not support", env.Name)
+	}
+
+	md := metadata.MD{}
+	for k, values := range httpReq.Header {
+		if strings.EqualFold(k, "Content-Type") {
+			continue
+		}
+		md.Append(k, values...)
+	}
+	return md, nil
+}
+
+// grpcJSON converts a message to its protobuf JSON form as a generic value.
+// Messages the descriptors cannot describe, like Any details of unknown
+// types, keep their type and raw bytes.
+func grpcJSON(message proto.Message, types *dynamicpb.Types) interface{} {
+	data, err := protojson.MarshalOptions{Resolver: types}.Marshal(message)
+	if err == nil {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value
+		}
+	}
+	raw, _ := proto.Marshal(message)
+	if reflected := message.ProtoReflect(); reflected.Descriptor().FullName() == "google.protobuf.Any" {
+		fields := reflected.Descriptor().Fields()
+		return map[string]interface{}{
+			"@type": reflected.Get(fields.ByName("type_url")).String(),
+			"value": base64.StdEncoding.EncodeToString(reflected.Get(fields.ByName("value")).Bytes()),
+		}
+	}
+	return map[string]interface{}{"value": base64.StdEncoding.EncodeToString(raw)}
+}
+
+// grpcStatusName returns the canonical name of a code, e.g. NOT_FOUND
+func grpcStatusName(code codes.Code) string {
+	name := code.String()
+	if strings.HasPrefix(name, "Code(") {
+		return name
+	}
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && r >= 'A' && r <= 'Z' && name[i-1] >= 'a' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String())
+}
@@ -0,0 +1,255 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// echoDescriptorSet describes test.v1.Echo with a unary Say method and a
+// streaming Watch method:
+//
+//	message EchoRequest { string text = 1; int32 times = 2; }
+//	message EchoReply { string text = 1; string tenant = 2; }
+func echoDescriptorSet(t *testing.T) (*descriptorpb.FileDescriptorSet, protoreflect.ServiceDescriptor) {
+	t.Helper()
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/echo.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("EchoRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("times", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			}},
+			{Name: proto.String("EchoReply"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("tenant", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Say"), InputType: proto.String(".test.v1.EchoRequest"), OutputType: proto.String(".test.v1.EchoReply")},
+				{Name: proto.String("Watch"), InputType: proto.String(".test.v1.EchoRequest"), OutputType: proto.String(".test.v1.EchoReply"), ServerStreaming: proto.Bool(true)},
+			},
+		}},
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := files.FindDescriptorByName("test.v1.Echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set, found.(protoreflect.ServiceDescriptor)
+}
+
+// startEchoServer serves test.v1.Echo/Say on a loopback port, built from the
+// descriptors alone. Say repeats text times times and echoes the x-tenant
+// metadata; an empty text fails with NOT_FOUND and the request as detail.
+func startEchoServer(t *testing.T, service protoreflect.ServiceDescriptor) string {
+	t.Helper()
+	say := service.Methods().ByName("Say")
+
+	handler := func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := dynamicpb.NewMessage(say.Input())
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		text := in.Get(say.Input().Fields().ByName("text")).String()
+		if text == "" {
+			st, err := status.New(codes.NotFound, "nothing to echo").WithDetails(protoadapt.MessageV1Of(in))
+			if err != nil {
+				return nil, err
+			}
+			return nil, st.Err()
+		}
+
+		grpc.SetHeader(ctx, metadata.Pairs("x-echo", "1"))
+		md, _ := metadata.FromIncomingContext(ctx)
+		out := dynamicpb.NewMessage(say.Output())
+		times := int(in.Get(say.Input().Fields().ByName("times")).Int())
+		out.Set(say.Output().Fields().ByName("text"), protoreflect.ValueOfString(strings.Repeat(text, max(times, 1))))
+		if tenant := md.Get("x-tenant"); len(tenant) > 0 {
+			out.Set(say.Output().Fields().ByName("tenant"), protoreflect.ValueOfString(tenant[0]))
+		}
+		return out, nil
+	}
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: string(service.FullName()),
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Say", Handler: handler}},
+	}, struct{}{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestCallGRPC(t *testing.T) {
+	set, service := echoDescriptorSet(t)
+	data, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descriptorSet := base64.StdEncoding.EncodeToString(data)
+	addr := startEchoServer(t, service)
+
+	loopback := entities.EgressPolicy{AllowedCIDRs: []string{"127.0.0.0/8"}}
+	tests := []struct {
+		name       string
+		policy     entities.EgressPolicy
+		method     string
+		headers    map[string]string
+		body       interface{}
+		wantErr    error
+		wantStatus int
+		wantCode   string
+		wantBody   map[string]interface{}
+		wantDetail map[string]interface{}
+	}{
+		{
+			name:       "unary call",
+			policy:     loopback,
+			method:     "test.v1.Echo/Say",
+			headers:    map[string]string{"X-Tenant": "acme"},
+			body:       map[string]interface{}{"text": "hi", "times": 3},
+			wantStatus: http.StatusOK,
+			wantCode:   "OK",
+			wantBody:   map[string]interface{}{"text": "hihihi", "tenant": "acme"},
+		},
+		{
+			name:       "status with details",
+			policy:     loopback,
+			method:     "test.v1.Echo/Say",
+			body:       map[string]interface{}{"times": 2},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "NOT_FOUND",
+			wantDetail: map[string]interface{}{"@type": "type.googleapis.com/test.v1.EchoRequest", "times": float64(2)},
+		},
+		{
+			name:    "body not matching the input message",
+			policy:  loopback,
+			method:  "test.v1.Echo/Say",
+			body:    map[string]interface{}{"unknown": true},
+			wantErr: entities.ErrInvalidBody,
+		},
+		{
+			name:    "unknown method",
+			policy:  loopback,
+			method:  "test.v1.Echo/Shout",
+			wantErr: entities.ErrInvalidBody,
+		},
+		{
+			name:    "streaming method",
+			policy:  loopback,
+			method:  "test.v1.Echo/Watch",
+			wantErr: entities.ErrInvalidBody,
+		},
+		{
+			name:    "loopback server refused by the egress policy",
+			method:  "test.v1.Echo/Say",
+			body:    map[string]interface{}{"text": "hi"},
+			wantErr: entities.ErrEgressBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			egress := NewEgressPolicyUseCase(staticSettings{}, tt.policy, nil)
+			uc := &ExecuteAPICallUseCase{
+				transports:      NewTransportPool(TransportSettings{ConnectTimeout: 2 * time.Second}, egress),
+				egress:          egress,
+				grpcDescriptors: NewGRPCDescriptorCache(nil),
+			}
+			request := &entities.APIRequest{
+				Method:  http.MethodPost,
+				URL:     "grpc://" + addr,
+				Headers: tt.headers,
+				Body:    tt.body,
+				GRPC:    &entities.GRPCOptions{Method: tt.method, DescriptorSet: descriptorSet},
+			}
+
+			ctx, cancel := context.WithTimeout(egress.Bind(context.Background(), nil), 5*time.Second)
+			defer cancel()
+			response := &entities.APIResponse{}
+			_, err := uc.callGRPC(ctx, request, nil, 1<<20, response)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("callGRPC error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("callGRPC: %v", err)
+			}
+
+			if response.StatusCode != tt.wantStatus || response.GRPC.Status != tt.wantCode {
+				t.Fatalf("status = %d %s, want %d %s (%s)", response.StatusCode, response.GRPC.Status, tt.wantStatus, tt.wantCode, response.Error)
+			}
+			if response.Success != (tt.wantCode == "OK") {
+				t.Errorf("Success = %v", response.Success)
+			}
+			if tt.wantBody != nil {
+				body, _ := response.Body.(map[string]interface{})
+				for key, want := range tt.wantBody {
+					if body[key] != want {
+						t.Errorf("body %s = %v, want %v", key, body[key], want)
+					}
+				}
+				if got := response.GRPC.Header["x-echo"]; len(got) != 1 || got[0] != "1" {
+					t.Errorf("header x-echo = %v", got)
+				}
+			}
+			if tt.wantDetail != nil {
+				if response.ErrorType != entities.ErrorTypeGRPC || len(response.GRPC.Details) != 1 {
+					t.Fatalf("error type %q with details %v", response.ErrorType, response.GRPC.Details)
+				}
+				detail, _ := response.GRPC.Details[0].(map[string]interface{})
+				for key, want := range tt.wantDetail {
+					if detail[key] != want {
+						t.Errorf("detail %s = %v, want %v", key, detail[key], want)
+					}
+				}
+			}
+		})
+	}
+}
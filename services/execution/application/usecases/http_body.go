@@ -0,0 +1,200 @@
+package usecases
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// encodeRequestBody serializes the request body according to its body type
+// and returns the payload with the content type to send when the request
+// sets none. Multipart bodies always use the returned content type, as it
+// carries the boundary.
+func encodeRequestBody(request *entities.APIRequest) ([]byte, string, error) {
+	bodyType := request.ResolvedBodyType()
+	if request.Body == nil && len(request.Files) == 0 {
+		return nil, "", nil
+	}
+
+	switch bodyType {
+	case entities.BodyTypeForm:
+		if encoded, ok := request.Body.(string); ok {
+			return []byte(encoded), "application/x-www-form-urlencoded", nil
+		}
+		fields, _ := request.Body.(map[string]interface{})
+		values := url.Values{}
+		for key, value := range fields {
+			values[key] = fieldValues(value)
+		}
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+
+	case entities.BodyTypeMultipart:
+		return encodeMultipart(request)
+
+	case entities.BodyTypeXML:
+		body, _ := request.Body.(string)
+		return []byte(body), "application/xml", nil
+
+	case entities.BodyTypeText:
+		body, _ := request.Body.(string)
+		return []byte(body), "text/plain; charset=utf-8", nil
+
+	case entities.BodyTypeBinary:
+		encoded, _ := request.Body.(string)
+		body, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: binary body is not valid base64", entities.ErrInvalidBody)
+		}
+		return body, "application/octet-stream", nil
+	}
+
+	body, err := json.Marshal(request.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal body: %w", err)
+	}
+	return body, "application/json", nil
+}
+
+// encodeMultipart writes the body fields as text parts, in key order, followed
+// by the file parts
+func encodeMultipart(request *entities.APIRequest) ([]byte, string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fields, _ := request.Body.(map[string]interface{})
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range fieldValues(fields[key]) {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, "", fmt.Errorf("failed to write multipart field: %w", err)
+			}
+		}
+	}
+
+	for i, file := range request.Files {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: files[%d] content is not valid base64", entities.ErrInvalidBody, i)
+		}
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(file.Field), quoteEscaper.Replace(file.Filename)))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart file: %w", err)
+		}
+		if _, err := part.Write(content); err != nil {
+			return nil, "", fmt.Errorf("failed to write multipart file: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to write multipart body: %w", err)
+	}
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// fieldValues turns a form field value into its string values; lists repeat
+// the field
+func fieldValues(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return []string{""}
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fieldValues(item)...)
+		}
+		return values
+	case map[string]interface{}:
+		encoded, _ := json.Marshal(v)
+		return []string{string(encoded)}
+	}
+	return []string{fmt.Sprintf("%v", value)}
+}
+
+// decodeResponseBody sets the response body: JSON is parsed, text is kept as
+// a string and binary content is reduced to its size and hash
+func decodeResponseBody(response *entities.APIResponse, contentType string, body []byte) {
+	if len(body) == 0 {
+		response.Body = ""
+		return
+	}
+
+	var bodyJSON interface{}
+	if err := json.Unmarshal(body, &bodyJSON); err == nil {
+		response.Body = bodyJSON
+		return
+	}
+
+	if isBinaryContent(contentType, body) {
+		sum := sha256.Sum256(body)
+		response.BinaryBody = &entities.BinaryBody{
+			ContentType: contentType,
+			SizeBytes:   int64(len(body)),
+			SHA256:      hex.EncodeToString(sum[:]),
+		}
+		return
+	}
+	response.Body = string(body)
+}
+
+// checkGraphQLErrors fails a GraphQL call whose response reports errors,
+// whatever its status code
+func checkGraphQLErrors(request *entities.APIRequest, response *entities.APIResponse) {
+	if request.ResolvedBodyType() != entities.BodyTypeGraphQL {
+		return
+	}
+	messages := entities.GraphQLErrors(response.Body)
+	if len(messages) == 0 {
+		return
+	}
+	response.Success = false
+	response.ErrorType = entities.ErrorTypeGraphQL
+	response.Error = "graphql errors: " + strings.Join(messages, "; ")
+}
+
+// isBinaryContent reports whether a response body should not be treated as
+// text. A declared non-text media type wins; without one, the bytes decide.
+func isBinaryContent(contentType string, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		return !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0
+	}
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded", "application/graphql", "application/x-ndjson":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package usecases
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+func TestEncodeRequestBody(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G', 0, 0xff}
+	tests := []struct {
+		name            string
+		request         entities.APIRequest
+		want            string
+		wantContentType string
+		wantErr         error
+	}{
+		{name: "no body", request: entities.APIRequest{}},
+		{
+			name:            "json by default",
+			request:         entities.APIRequest{Body: map[string]interface{}{"b": []interface{}{1, "x"}, "a": nil}},
+			want:            `{"a":null,"b":[1,"x"]}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "json string is sent as a json string",
+			request:         entities.APIRequest{Body: `{"a":1}`},
+			want:            `"{\"a\":1}"`,
+			wantContentType: "application/json",
+		},
+		{
+			name: "form fields",
+			request: entities.APIRequest{BodyType: "form", Body: map[string]interface{}{
+				"name":  "Jane Doe",
+				"tags":  []interface{}{"a&b", "c"},
+				"count": float64(2),
+				"meta":  map[string]interface{}{"k": "v"},
+				"empty": nil,
+			}},
+			want:            "count=2&empty=&meta=%7B%22k%22%3A%22v%22%7D&name=Jane+Doe&tags=a%26b&tags=c",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:            "form already encoded",
+			request:         entities.APIRequest{BodyType: "form", Body: "a=1&b=%20"},
+			want:            "a=1&b=%20",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name: "form inferred from the content type header",
+			request: entities.APIRequest{
+				Headers: map[string]string{"content-type": "application/x-www-form-urlencoded; charset=utf-8"},
+				Body:    map[string]interface{}{"a": "1"},
+			},
+			want:            "a=1",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			name:            "xml",
+			request:         entities.APIRequest{Headers: map[string]string{"Content-Type": "application/soap+xml"}, Body: "<a/>"},
+			want:            "<a/>",
+			wantContentType: "application/xml",
+		},
+		{
+			name:            "text",
+			request:         entities.APIRequest{BodyType: "TEXT", Body: "héllo"},
+			want:            "héllo",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "binary is decoded from base64",
+			request:         entities.APIRequest{BodyType: "binary", Body: base64.StdEncoding.EncodeToString(png)},
+			want:            string(png),
+			wantContentType: "application/octet-stream",
+		},
+		{
+			name: "binary inferred from the content type header",
+			request: entities.APIRequest{
+				Headers: map[string]string{"Content-Type": "application/octet-stream"},
+				Body:    base64.StdEncoding.EncodeToString(png),
+			},
+			want:            string(png),
+			wantContentType: "application/octet-stream",
+		},
+		{
+			name:    "binary that is not base64",
+			request: entities.APIRequest{BodyType: "binary", Body: "not base64!"},
+			wantErr: entities.ErrInvalidBody,
+		},
+		{
+			name:    "url-safe base64 is refused",
+			request: entities.APIRequest{BodyType: "binary", Body: base64.URLEncoding.EncodeToString(png)},
+			wantErr: entities.ErrInvalidBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := encodeRequestBody(&tt.request)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.want || contentType != tt.wantContentType {
+				t.Errorf("encodeRequestBody = %q, %q; want %q, %q", body, contentType, tt.want, tt.wantContentType)
+			}
+		})
+	}
+}
+
+// multipartPart is a decoded part of a multipart body
+type multipartPart struct {
+	name, filename, contentType, content string
+}
+
+func readMultipart(t *testing.T, body []byte, contentType string) []multipartPart {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		t.Fatalf("content type = %q", contentType)
+	}
+	reader := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+	var parts []multipartPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, multipartPart{part.FormName(), part.FileName(), part.Header.Get("Content-Type"), string(content)})
+	}
+}
+
+func TestEncodeMultipart(t *testing.T) {
+	request := &entities.APIRequest{
+		Body: map[string]interface{}{
+			"title": "Report",
+			"tags":  []interface{}{"q1", "final"},
+			"draft": false,
+		},
+		Files: []entities.FilePart{
+			{Field: "file", Filename: "report.pdf", ContentType: "application/pdf", Content: base64.StdEncoding.EncodeToString([]byte("%PDF-1.7"))},
+			{Field: "attachments", Filename: `quote"d.bin`, Content: base64.StdEncoding.EncodeToString([]byte{0, 1, 2})},
+		},
+	}
+
+	body, contentType, err := encodeRequestBody(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []multipartPart{
+		{name: "draft", content: "false"},
+		{name: "tags", content: "q1"},
+		{name: "tags", content: "final"},
+		{name: "title", content: "Report"},
+		{name: "file", filename: "report.pdf", contentType: "application/pdf", content: "%PDF-1.7"},
+		{name: "attachments", filename: `quote"d.bin`, contentType: "application/octet-stream", content: "\x00\x01\x02"},
+	}
+	for i := range want[:4] {
+		// Text fields carry no content type
+		want[i].contentType = ""
+	}
+	if got := readMultipart(t, body, contentType); !reflect.DeepEqual(got, want) {
+		t.Errorf("parts =\n  %+v\nwant\n  %+v", got, want)
+	}
+
+	// Each body gets its own boundary
+	_, other, _ := encodeRequestBody(request)
+	if other == contentType {
+		t.Error("two multipart bodies share a boundary")
+	}
+
+	request.Files[1].Content = "%%%"
+	if _, _, err := encodeRequestBody(request); !errors.Is(err, entities.ErrInvalidBody) || !strings.Contains(err.Error(), "files[1]") {
+		t.Errorf("invalid file content error = %v", err)
+	}
+}
+
+func TestBuildHTTPRequestContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		request entities.APIRequest
+		want    string
+	}{
+		{
+			name:    "default for the body type",
+			request: entities.APIRequest{Body: map[string]interface{}{"a": 1}},
+			want:    "application/json",
+		},
+		{
+			name:    "caller's content type is kept",
+			request: entities.APIRequest{Headers: map[string]string{"Content-Type": "application/vnd.api+json"}, Body: map[string]interface{}{"a": 1}},
+			want:    "application/vnd.api+json",
+		},
+		{
+			name:    "caller's content type in another case is kept",
+			request: entities.APIRequest{Headers: map[string]string{"content-type": "text/csv"}, Body: "a,b"},
+			want:    "text/csv",
+		},
+		{
+			name: "caller's content type wins over the body type",
+			request: entities.APIRequest{
+				BodyType: "form",
+				Headers:  map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=iso-8859-1"},
+				Body:     map[string]interface{}{"a": "1"},
+			},
+			want: "application/x-www-form-urlencoded; charset=iso-8859-1",
+		},
+		{
+			name: "multipart replaces the caller's content type to carry the boundary",
+			request: entities.APIRequest{
+				Headers: map[string]string{"Content-Type": "multipart/form-data"},
+				Body:    map[string]interface{}{"a": "1"},
+			},
+			want: "multipart/form-data; boundary=",
+		},
+		{
+			name:    "no body, no content type",
+			request: entities.APIRequest{},
+		},
+	}
+	uc := &ExecuteAPICallUseCase{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Method = "POST"
+			tt.request.URL = "https://api.example.com/upload"
+			httpReq, err := uc.buildHTTPRequest(context.Background(), &tt.request, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := httpReq.Header.Values("Content-Type")
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("Content-Type = %q, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.HasPrefix(got[0], tt.want) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        interface{}
+		wantBinary  bool
+	}{
+		{name: "empty", want: ""},
+		{name: "json", contentType: "application/json", body: `{"a":1}`, want: map[string]interface{}{"a": float64(1)}},
+		{name: "json without a content type", body: `[true]`, want: []interface{}{true}},
+		{name: "text", contentType: "text/plain", body: "hello", want: "hello"},
+		{name: "problem json that does not parse", contentType: "application/problem+json", body: "{oops", want: "{oops"},
+		{name: "declared binary", contentType: "image/png", body: "PNG", wantBinary: true},
+		{name: "undeclared binary", body: "a\x00b", wantBinary: true},
+		{name: "undeclared invalid utf-8", body: "\xff\xfe", wantBinary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &entities.APIResponse{}
+			decodeResponseBody(response, tt.contentType, []byte(tt.body))
+			if tt.wantBinary {
+				if response.BinaryBody == nil || response.BinaryBody.SizeBytes != int64(len(tt.body)) || response.Body != nil {
+					t.Errorf("body = %v, binary = %+v; want a binary summary", response.Body, response.BinaryBody)
+				}
+				return
+			}
+			if response.BinaryBody != nil || !reflect.DeepEqual(response.Body, tt.want) {
+				t.Errorf("body = %#v, binary = %+v; want %#v", response.Body, response.BinaryBody, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package usecases
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// timingTrace records httptrace events for one HTTP attempt
+type timingTrace struct {
+	mu sync.Mutex
+
+	start                 time.Time
+	dnsStart, dnsDone     time.Time
+	connectStart, connect time.Time
+	tlsStart, tlsDone     time.Time
+	wroteRequest          time.Time
+	firstByte             time.Time
+
+	remoteAddr string
+	reused     bool
+}
+
+// traceRequest returns req with a client trace attached. It must be applied
+// after any token fetches so only the API call itself is measured.
+func traceRequest(req *http.Request) (*http.Request, *timingTrace) {
+	t := &timingTrace{start: time.Now()}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			// Happy Eyeballs may dial several addresses; keep the first start
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.mark(&t.connect)
+			}
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+func (t *timingTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+// timing summarises the trace, with end marking when the body was read
+func (t *timingTrace) timing(end time.Time, resp *http.Response) *entities.ExecutionTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timing := &entities.ExecutionTiming{
+		DNSMs:            phaseMs(t.dnsStart, t.dnsDone),
+		ConnectMs:        phaseMs(t.connectStart, t.connect),
+		TLSMs:            phaseMs(t.tlsStart, t.tlsDone),
+		TTFBMs:           phaseMs(t.wroteRequest, t.firstByte),
+		TransferMs:       phaseMs(t.firstByte, end),
+		TotalMs:          phaseMs(t.start, end),
+		RemoteAddr:       t.remoteAddr,
+		ConnectionReused: t.reused,
+	}
+	if resp != nil {
+		timing.Protocol = resp.Proto
+	}
+	return timing
+}
+
+// phaseMs returns the milliseconds between two marks, or 0 if either is missing
+func phaseMs(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return float64(to.Sub(from).Microseconds()) / 1000
+}
@@ -0,0 +1,190 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// IdempotencyGuard attaches idempotency keys to requests and refuses
+// accidental re-submissions of a request that was just executed. Requests
+// still running are tracked in memory, finished ones through their stored
+// fingerprint.
+type IdempotencyGuard struct {
+	settingsRepo  repositories.IdempotencyRepository
+	executionRepo repositories.ExecutionRepository
+	defaultWindow int
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// NewIdempotencyGuard creates a guard. defaultWindowSeconds applies to API
+// specs without stored settings and to requests without an API spec.
+func NewIdempotencyGuard(
+	settingsRepo repositories.IdempotencyRepository,
+	executionRepo repositories.ExecutionRepository,
+	defaultWindowSeconds int,
+) *IdempotencyGuard {
+	return &IdempotencyGuard{
+		settingsRepo:  settingsRepo,
+		executionRepo: executionRepo,
+		defaultWindow: defaultWindowSeconds,
+		inFlight:      make(map[string]bool),
+	}
+}
+
+// Settings returns the idempotency settings of an API spec, or the defaults
+func (g *IdempotencyGuard) Settings(ctx context.Context, apiSpecID *uuid.UUID) (*entities.IdempotencySettings, error) {
+	defaults := &entities.IdempotencySettings{DuplicateWindowSeconds: g.defaultWindow}
+	if apiSpecID != nil {
+		defaults.APISpecID = *apiSpecID
+		settings, err := g.settingsRepo.FindIdempotencySettings(ctx, *apiSpecID)
+		if err != nil {
+			return nil, err
+		}
+		if settings != nil {
+			defaults = settings
+		}
+	}
+	if err := defaults.Validate(); err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
+
+// UpdateSettings validates and stores an API spec's settings
+func (g *IdempotencyGuard) UpdateSettings(ctx context.Context, settings *entities.IdempotencySettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	settings.UpdatedAt = time.Now()
+	return g.settingsRepo.SaveIdempotencySettings(ctx, settings)
+}
+
+// Prepare runs before a request is sent. For guarded methods it records the
+// request's idempotency key, generating one if the settings ask for it, and
+// rejects the request if an identical one is running or finished within the
+// duplicate window. The returned release must be called once the execution
+// has been saved.
+func (g *IdempotencyGuard) Prepare(ctx context.Context, request *entities.APIRequest) (func(), error) {
+	noop := func() {}
+
+	settings, err := g.Settings(ctx, request.APISpecID)
+	if err != nil {
+		// Do not block executions on a settings lookup; fall back to defaults
+		logger.WithContext(ctx).Warn().Err(err).Msg("Failed to load idempotency settings, using defaults")
+		settings, _ = g.Settings(ctx, nil)
+	}
+	if !settings.Guards(request.Method) {
+		return noop, nil
+	}
+
+	key := headerValue(request.Headers, settings.HeaderName)
+	if key == "" && settings.GenerateKeys {
+		key = uuid.NewString()
+		if request.Headers == nil {
+			request.Headers = make(map[string]string)
+		}
+		request.Headers[settings.HeaderName] = key
+	}
+	request.IdempotencyKey = key
+
+	fingerprint, err := requestFingerprint(request, settings.HeaderName)
+	if err != nil {
+		return nil, err
+	}
+	request.Fingerprint = fingerprint
+
+	// Deliberate repeats, and cassette replays that never reach the
+	// target, skip the duplicate check
+	if request.AllowDuplicate || request.ReplayOf != nil || request.VCR.Replays() || settings.DuplicateWindowSeconds == 0 {
+		return noop, nil
+	}
+
+	g.mu.Lock()
+	if g.inFlight[fingerprint] {
+		g.mu.Unlock()
+		return nil, &entities.DuplicateExecutionError{}
+	}
+	g.inFlight[fingerprint] = true
+	g.mu.Unlock()
+	release := func() {
+		g.mu.Lock()
+		delete(g.inFlight, fingerprint)
+		g.mu.Unlock()
+	}
+
+	window := settings.DuplicateWindow()
+	previous, err := g.executionRepo.FindRecentDuplicate(ctx, request.UserID, fingerprint, time.Now().Add(-window))
+	if err != nil {
+		logger.WithContext(ctx).Warn().Err(err).Msg("Failed to look up duplicate executions")
+	}
+	if previous != nil {
+		release()
+		return nil, &entities.DuplicateExecutionError{ExecutionID: *previous, Window: window}
+	}
+	return release, nil
+}
+
+// requestFingerprint hashes what makes two requests the same call: the user,
+// target and payload. The idempotency key header is left out, so a re-click
+// that got a fresh key still matches.
+func requestFingerprint(request *entities.APIRequest, idempotencyHeader string) (string, error) {
+	headers := make(map[string]string, len(request.Headers))
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, idempotencyHeader) {
+			continue
+		}
+		headers[strings.ToLower(name)] = value
+	}
+
+	encoded, err := json.Marshal(struct {
+		UserID           *uuid.UUID             `json:"user_id"`
+		EnvironmentID    *uuid.UUID             `json:"environment_id"`
+		Method           string                 `json:"method"`
+		URL              string                 `json:"url"`
+		QueryParams      map[string]interface{} `json:"query_params"`
+		QueryParamStyles map[string]string      `json:"query_param_styles"`
+		Headers          map[string]string      `json:"headers"`
+		Body             interface{}            `json:"body"`
+		BodyType         string                 `json:"body_type"`
+		Files            []entities.FilePart    `json:"files"`
+	}{
+		UserID:           request.UserID,
+		EnvironmentID:    request.EnvironmentID,
+		Method:           strings.ToUpper(request.Method),
+		URL:              request.URL,
+		QueryParams:      request.QueryParams,
+		QueryParamStyles: request.QueryParamStyles,
+		Headers:          headers,
+		Body:             request.Body,
+		BodyType:         request.ResolvedBodyType(),
+		Files:            request.Files,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint request: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// headerValue looks a header up case-insensitively
+func headerValue(headers map[string]string, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,221 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+)
+
+// memoryIdempotencySettings holds per-API idempotency settings in memory
+type memoryIdempotencySettings map[uuid.UUID]*entities.IdempotencySettings
+
+func (m memoryIdempotencySettings) FindIdempotencySettings(_ context.Context, apiSpecID uuid.UUID) (*entities.IdempotencySettings, error) {
+	if settings, ok := m[apiSpecID]; ok {
+		copied := *settings
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m memoryIdempotencySettings) SaveIdempotencySettings(_ context.Context, settings *entities.IdempotencySettings) error {
+	m[settings.APISpecID] = settings
+	return nil
+}
+
+// fingerprintedExecutions is an execution repository that only remembers
+// the fingerprints of executions and when they ran
+type fingerprintedExecutions struct {
+	repositories.ExecutionRepository
+	executions []fingerprintedExecution
+}
+
+type fingerprintedExecution struct {
+	id          uuid.UUID
+	userID      *uuid.UUID
+	fingerprint string
+	createdAt   time.Time
+}
+
+func (r *fingerprintedExecutions) record(request *entities.APIRequest, createdAt time.Time) uuid.UUID {
+	id := uuid.New()
+	r.executions = append(r.executions, fingerprintedExecution{id, request.UserID, request.Fingerprint, createdAt})
+	return id
+}
+
+func (r *fingerprintedExecutions) FindRecentDuplicate(_ context.Context, userID *uuid.UUID, fingerprint string, since time.Time) (*uuid.UUID, error) {
+	var latest *fingerprintedExecution
+	for i, e := range r.executions {
+		sameUser := (e.userID == nil && userID == nil) || (e.userID != nil && userID != nil && *e.userID == *userID)
+		if sameUser && e.fingerprint == fingerprint && !e.createdAt.Before(since) &&
+			(latest == nil || e.createdAt.After(latest.createdAt)) {
+			latest = &r.executions[i]
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return &latest.id, nil
+}
+
+func paymentRequest(userID uuid.UUID, apiSpecID *uuid.UUID) *entities.APIRequest {
+	return &entities.APIRequest{
+		Method:    "POST",
+		URL:       "https://payments.example.com/v1/charges",
+		Headers:   map[string]string{"Content-Type": "application/json"},
+		Body:      map[string]interface{}{"amount": 1000, "currency": "EUR"},
+		UserID:    &userID,
+		APISpecID: apiSpecID,
+	}
+}
+
+func TestIdempotencyGuardDuplicateWindow(t *testing.T) {
+	userID := uuid.New()
+	apiSpecID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		settings *entities.IdempotencySettings
+		ranAgo   time.Duration
+		modify   func(r *entities.APIRequest)
+		wantDup  bool
+	}{
+		{name: "identical request inside the default window", ranAgo: 10 * time.Second, wantDup: true},
+		{name: "identical request after the default window", ranAgo: 61 * time.Second},
+		{
+			name:   "allow_duplicate overrides the check",
+			ranAgo: 10 * time.Second,
+			modify: func(r *entities.APIRequest) { r.AllowDuplicate = true },
+		},
+		{
+			name:    "fresh idempotency key is still a duplicate",
+			ranAgo:  10 * time.Second,
+			modify:  func(r *entities.APIRequest) { r.Headers["Idempotency-Key"] = uuid.NewString() },
+			wantDup: true,
+		},
+		{
+			name:   "different body",
+			ranAgo: 10 * time.Second,
+			modify: func(r *entities.APIRequest) { r.Body = map[string]interface{}{"amount": 2000, "currency": "EUR"} },
+		},
+		{
+			name:   "unguarded method",
+			ranAgo: 10 * time.Second,
+			modify: func(r *entities.APIRequest) { r.Method = "GET" },
+		},
+		{
+			name:   "another user",
+			ranAgo: 10 * time.Second,
+			modify: func(r *entities.APIRequest) { other := uuid.New(); r.UserID = &other },
+		},
+		{
+			name:     "longer window in the api settings",
+			settings: &entities.IdempotencySettings{APISpecID: apiSpecID, DuplicateWindowSeconds: 600},
+			ranAgo:   5 * time.Minute,
+			wantDup:  true,
+		},
+		{
+			name:     "api settings turn the check off",
+			settings: &entities.IdempotencySettings{APISpecID: apiSpecID, DuplicateWindowSeconds: 0},
+			ranAgo:   time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := memoryIdempotencySettings{}
+			if tt.settings != nil {
+				settings[apiSpecID] = tt.settings
+			}
+			executions := &fingerprintedExecutions{}
+			guard := NewIdempotencyGuard(settings, executions, 60)
+
+			first := paymentRequest(userID, &apiSpecID)
+			release, err := guard.Prepare(ctx, first)
+			if err != nil {
+				t.Fatalf("first Prepare: %v", err)
+			}
+			release()
+			firstID := executions.record(first, time.Now().Add(-tt.ranAgo))
+
+			second := paymentRequest(userID, &apiSpecID)
+			if tt.modify != nil {
+				tt.modify(second)
+			}
+			release, err = guard.Prepare(ctx, second)
+			if !tt.wantDup {
+				if err != nil {
+					t.Fatalf("second Prepare = %v, want it allowed", err)
+				}
+				release()
+				return
+			}
+			var dup *entities.DuplicateExecutionError
+			if !errors.As(err, &dup) {
+				t.Fatalf("second Prepare = %v, want a DuplicateExecutionError", err)
+			}
+			if dup.ExecutionID != firstID {
+				t.Errorf("duplicate_of = %s, want %s", dup.ExecutionID, firstID)
+			}
+		})
+	}
+}
+
+func TestIdempotencyGuardInFlight(t *testing.T) {
+	userID := uuid.New()
+	guard := NewIdempotencyGuard(memoryIdempotencySettings{}, &fingerprintedExecutions{}, 60)
+	ctx := context.Background()
+
+	release, err := guard.Prepare(ctx, paymentRequest(userID, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = guard.Prepare(ctx, paymentRequest(userID, nil))
+	var dup *entities.DuplicateExecutionError
+	if !errors.As(err, &dup) || dup.ExecutionID != uuid.Nil {
+		t.Fatalf("Prepare while the first is running = %v, want a DuplicateExecutionError without an execution", err)
+	}
+
+	release()
+	release, err = guard.Prepare(ctx, paymentRequest(userID, nil))
+	if err != nil {
+		t.Fatalf("Prepare after release = %v", err)
+	}
+	release()
+}
+
+func TestIdempotencyGuardGeneratesKeys(t *testing.T) {
+	apiSpecID := uuid.New()
+	settings := memoryIdempotencySettings{
+		apiSpecID: {APISpecID: apiSpecID, GenerateKeys: true, HeaderName: "Idempotency-Key", DuplicateWindowSeconds: 60},
+	}
+	guard := NewIdempotencyGuard(settings, &fingerprintedExecutions{}, 60)
+
+	request := paymentRequest(uuid.New(), &apiSpecID)
+	release, err := guard.Prepare(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if request.IdempotencyKey == "" || request.Headers["Idempotency-Key"] != request.IdempotencyKey {
+		t.Errorf("key = %q, header = %q", request.IdempotencyKey, request.Headers["Idempotency-Key"])
+	}
+
+	// A key the caller sent is kept
+	request = paymentRequest(uuid.New(), &apiSpecID)
+	request.Headers["idempotency-key"] = "client-key"
+	release, err = guard.Prepare(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if request.IdempotencyKey != "client-key" {
+		t.Errorf("key = %q, want client-key", request.IdempotencyKey)
+	}
+}
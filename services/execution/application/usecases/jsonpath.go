@@ -0,0 +1,96 @@
+package usecases
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// jsonPathStep is one step of a JSONPath: an object key or an array index
+type jsonPathStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parseJSONPath parses the JSONPath subset used by poll conditions: $ followed
+// by .key, ['key'] or ["key"] and [index] steps (negative indexes count from
+// the end), e.g. $.data.items[0]['state']
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%w: JSONPath must start with $", entities.ErrInvalidPolicy)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%w: empty key in JSONPath %s", entities.ErrInvalidPolicy, path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end]})
+			rest = rest[end:]
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unclosed [ in JSONPath %s", entities.ErrInvalidPolicy, path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("%w: unsupported selector [%s] in JSONPath %s", entities.ErrInvalidPolicy, inner, path)
+			}
+			steps = append(steps, jsonPathStep{index: index, isIndex: true})
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in JSONPath %s", entities.ErrInvalidPolicy, rest[0], path)
+		}
+	}
+	return steps, nil
+}
+
+// lookupJSONPath returns the value the steps lead to in a decoded JSON document
+func lookupJSONPath(document interface{}, steps []jsonPathStep) (interface{}, bool) {
+	current := document
+	for _, step := range steps {
+		if step.isIndex {
+			list, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index := step.index
+			if index < 0 {
+				index += len(list)
+			}
+			if index < 0 || index >= len(list) {
+				return nil, false
+			}
+			current = list[index]
+			continue
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,285 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// loadTestFlushInterval is how often the stats of a running load test are
+// stored, so progress can be followed while it runs
+const loadTestFlushInterval = 5 * time.Second
+
+// LoadTestUseCase runs requests and scenarios under load and stores each
+// run as one aggregated record. Runs go on in the background; the ones in
+// progress on this instance can be stopped.
+type LoadTestUseCase struct {
+	repo         repositories.LoadTestRepository
+	executor     *ExecuteAPICallUseCase
+	environments *ManageEnvironmentsUseCase
+	limits       *ExecutionLimitsUseCase
+
+	mu     sync.Mutex
+	active map[uuid.UUID]context.CancelFunc
+}
+
+// NewLoadTestUseCase creates a new load test use case
+func NewLoadTestUseCase(
+	repo repositories.LoadTestRepository,
+	executor *ExecuteAPICallUseCase,
+	environments *ManageEnvironmentsUseCase,
+	limits *ExecutionLimitsUseCase,
+) *LoadTestUseCase {
+	return &LoadTestUseCase{
+		repo:         repo,
+		executor:     executor,
+		environments: environments,
+		limits:       limits,
+		active:       make(map[uuid.UUID]context.CancelFunc),
+	}
+}
+
+// Recover marks runs left running by a previous process as interrupted
+func (uc *LoadTestUseCase) Recover(ctx context.Context) (int64, error) {
+	return uc.repo.InterruptRunning(ctx, time.Now())
+}
+
+// Start validates a load test and starts running it in the background
+func (uc *LoadTestUseCase) Start(ctx context.Context, userID *uuid.UUID, config entities.LoadTestConfig) (*entities.LoadTestRun, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Resolve the environment (with decrypted secrets) once for the whole run
+	var env *entities.Environment
+	if config.EnvironmentID != nil {
+		resolved, err := uc.environments.ResolveEnvironment(ctx, *config.EnvironmentID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to resolve environment: %v", entities.ErrInvalidLoadTest, err)
+		}
+		env = resolved
+	}
+
+	run := &entities.LoadTestRun{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      config.Name,
+		Config:    config,
+		Status:    entities.LoadTestStatusRunning,
+		StartedAt: time.Now(),
+	}
+
+	uc.mu.Lock()
+	if len(uc.active) >= entities.MaxActiveLoadTests {
+		uc.mu.Unlock()
+		return nil, entities.ErrLoadTestCapacity
+	}
+	// The run outlives the request that started it
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	uc.active[run.ID] = cancel
+	uc.mu.Unlock()
+
+	if err := uc.repo.CreateRun(ctx, run); err != nil {
+		uc.release(run.ID)
+		return nil, err
+	}
+
+	started := *run
+	go uc.run(runCtx, run, env)
+	return &started, nil
+}
+
+// Get returns a run with its stats so far
+func (uc *LoadTestUseCase) Get(ctx context.Context, id uuid.UUID) (*entities.LoadTestRun, error) {
+	return uc.repo.FindRunByID(ctx, id)
+}
+
+// List returns the latest runs, of one user if userID is set
+func (uc *LoadTestUseCase) List(ctx context.Context, userID *uuid.UUID, limit int) ([]*entities.LoadTestRun, error) {
+	return uc.repo.ListRuns(ctx, userID, limit)
+}
+
+// Stop stops a run in progress. The run stores its stats up to then with
+// status stopped.
+func (uc *LoadTestUseCase) Stop(id uuid.UUID) error {
+	uc.mu.Lock()
+	cancel, ok := uc.active[id]
+	uc.mu.Unlock()
+	if !ok {
+		return entities.ErrLoadTestNotRunning
+	}
+	cancel()
+	return nil
+}
+
+// run generates the load until the duration is over or the run is stopped,
+// then stores the final stats and threshold results
+func (uc *LoadTestUseCase) run(ctx context.Context, run *entities.LoadTestRun, env *entities.Environment) {
+	defer uc.release(run.ID)
+
+	config := &run.Config
+	limits := uc.limits.Limits(ctx)
+	storeCtx := context.WithoutCancel(ctx)
+	recorder := newLoadRecorder(config.Scenario, run.StartedAt)
+
+	logger.WithContext(ctx).Info().
+		Str("load_test_id", run.ID.String()).
+		Str("mode", config.Mode).
+		Int("duration_seconds", config.DurationSeconds).
+		Msg("Load test started")
+
+	// New iterations start until the deadline; iterations in flight then
+	// finish under ctx, which only a stop cancels
+	scheduleCtx, cancelSchedule := context.WithDeadline(ctx, run.StartedAt.Add(config.Duration()))
+	defer cancelSchedule()
+
+	iterate := func() {
+		for i := range config.Scenario {
+			request := config.Scenario[i].Request
+			sent := time.Now()
+			response, err := uc.executor.Send(ctx, &request, env, limits.Timeout(request.Timeout), limits.MaxResponseBytes)
+			if ctx.Err() != nil {
+				// Stopped: the request was cut short and says nothing about the target
+				return
+			}
+			recorder.request(i, time.Since(sent), response, err)
+		}
+		recorder.iteration()
+	}
+
+	done := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		uc.flush(storeCtx, run, recorder, done)
+	}()
+
+	if config.Mode == entities.LoadModeConcurrency {
+		runConcurrency(scheduleCtx, config, iterate)
+	} else {
+		runRate(scheduleCtx, config, recorder, iterate)
+	}
+	close(done)
+	<-flushed
+
+	finished := time.Now()
+	run.Stats = recorder.snapshot(finished)
+	run.FinishedAt = &finished
+	run.Status = entities.LoadTestStatusCompleted
+	if ctx.Err() != nil {
+		run.Status = entities.LoadTestStatusStopped
+	}
+	results, passed := evaluateThresholds(config.Thresholds, run.Stats)
+	run.Thresholds = results
+	run.Passed = &passed
+
+	if err := uc.repo.UpdateRun(storeCtx, run); err != nil {
+		logger.WithContext(ctx).Err(err).
+			Str("load_test_id", run.ID.String()).
+			Msg("Failed to store load test results")
+	}
+
+	logger.WithContext(ctx).Info().
+		Str("load_test_id", run.ID.String()).
+		Str("status", run.Status).
+		Int64("requests", run.Stats.Requests).
+		Float64("throughput_rps", run.Stats.ThroughputRPS).
+		Float64("p95_ms", run.Stats.Latency.P95Ms).
+		Float64("error_rate", run.Stats.ErrorRate).
+		Bool("passed", passed).
+		Msg("Load test finished")
+}
+
+// flush stores the stats so far every loadTestFlushInterval until done is closed
+func (uc *LoadTestUseCase) flush(ctx context.Context, run *entities.LoadTestRun, recorder *loadRecorder, done <-chan struct{}) {
+	ticker := time.NewTicker(loadTestFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			progress := *run
+			progress.Stats = recorder.snapshot(now)
+			if err := uc.repo.UpdateRun(ctx, &progress); err != nil {
+				logger.WithContext(ctx).Warn().
+					Err(err).
+					Str("load_test_id", run.ID.String()).
+					Msg("Failed to store load test progress")
+			}
+		}
+	}
+}
+
+// release forgets a run that ended
+func (uc *LoadTestUseCase) release(id uuid.UUID) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if cancel, ok := uc.active[id]; ok {
+		cancel()
+		delete(uc.active, id)
+	}
+}
+
+// runConcurrency runs the scenario back to back in each virtual user until
+// ctx ends. Users join evenly spread over the ramp-up.
+func runConcurrency(ctx context.Context, config *entities.LoadTestConfig, iterate func()) {
+	var wg sync.WaitGroup
+	for user := 0; user < config.Concurrency; user++ {
+		delay := config.RampUp() * time.Duration(user) / time.Duration(config.Concurrency)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !wait(ctx, delay) {
+				return
+			}
+			for ctx.Err() == nil {
+				iterate()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runRate starts iterations at the target rate until ctx ends. At most
+// config.Concurrency iterations are in flight; iterations due while all
+// are busy are dropped rather than queued, so the rate stays honest.
+func runRate(ctx context.Context, config *entities.LoadTestConfig, recorder *loadRecorder, iterate func()) {
+	slots := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	next := start
+	for wait(ctx, time.Until(next)) {
+		select {
+		case slots <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				iterate()
+			}()
+		default:
+			recorder.drop()
+		}
+		next = next.Add(time.Duration(float64(time.Second) / rateAt(config, next.Sub(start))))
+	}
+	wg.Wait()
+}
+
+// rateAt returns the iteration rate elapsed into a run, rising linearly to
+// the target over the ramp-up
+func rateAt(config *entities.LoadTestConfig, elapsed time.Duration) float64 {
+	rampUp := config.RampUp()
+	if rampUp <= 0 || elapsed >= rampUp {
+		return config.TargetRPS
+	}
+	return math.Max(config.TargetRPS*float64(elapsed)/float64(rampUp), math.Min(config.TargetRPS, 1))
+}
@@ -0,0 +1,296 @@
+package usecases
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// histogramSubBuckets splits each power of two of microseconds into equal
+// buckets; 16 keeps percentiles within about 3% of the exact value
+const histogramSubBuckets = 16
+
+// histogramBoundsMs are the reported histogram buckets
+var histogramBoundsMs = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// latencyHistogram records latencies in log-linear buckets, so percentiles
+// of long runs are computed in constant memory
+type latencyHistogram struct {
+	counts   []int64
+	reported []int64
+	count    int64
+	sumUs    int64
+	minUs    int64
+	maxUs    int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{reported: make([]int64, len(histogramBoundsMs)+1)}
+}
+
+func (h *latencyHistogram) record(latency time.Duration) {
+	us := max(latency.Microseconds(), 0)
+	index := histogramIndex(us)
+	if index >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, index+1-len(h.counts))...)
+	}
+	h.counts[index]++
+
+	ms := float64(us) / 1000
+	bucket := len(histogramBoundsMs)
+	for i, bound := range histogramBoundsMs {
+		if ms <= bound {
+			bucket = i
+			break
+		}
+	}
+	h.reported[bucket]++
+
+	if h.count == 0 || us < h.minUs {
+		h.minUs = us
+	}
+	if us > h.maxUs {
+		h.maxUs = us
+	}
+	h.count++
+	h.sumUs += us
+}
+
+// histogramIndex maps microseconds to a bucket: values below
+// histogramSubBuckets get their own, larger ones share a bucket with the
+// values of the same power of two and sub-bucket
+func histogramIndex(us int64) int {
+	if us < histogramSubBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(uint64(us)) - 5
+	return (shift+1)*histogramSubBuckets + int(us>>shift) - histogramSubBuckets
+}
+
+// histogramValue returns the middle of a bucket in microseconds
+func histogramValue(index int) float64 {
+	if index < histogramSubBuckets {
+		return float64(index)
+	}
+	shift := index/histogramSubBuckets - 1
+	low := int64(index%histogramSubBuckets+histogramSubBuckets) << shift
+	return float64(low) + float64(int64(1)<<shift)/2
+}
+
+// percentile returns the latency in microseconds below which the fraction
+// q of recorded latencies falls
+func (h *latencyHistogram) percentile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(q * float64(h.count)))
+	var seen int64
+	for index, count := range h.counts {
+		seen += count
+		if seen >= target {
+			value := histogramValue(index)
+			return math.Min(math.Max(value, float64(h.minUs)), float64(h.maxUs))
+		}
+	}
+	return float64(h.maxUs)
+}
+
+func (h *latencyHistogram) summary() entities.LatencySummary {
+	if h.count == 0 {
+		return entities.LatencySummary{}
+	}
+	return entities.LatencySummary{
+		MinMs:  roundMs(float64(h.minUs)),
+		MeanMs: roundMs(float64(h.sumUs) / float64(h.count)),
+		P50Ms:  roundMs(h.percentile(0.50)),
+		P90Ms:  roundMs(h.percentile(0.90)),
+		P95Ms:  roundMs(h.percentile(0.95)),
+		P99Ms:  roundMs(h.percentile(0.99)),
+		MaxMs:  roundMs(float64(h.maxUs)),
+	}
+}
+
+// buckets returns the reported histogram up to the slowest request. The
+// bound of the last bucket past histogramBoundsMs is the slowest latency.
+func (h *latencyHistogram) buckets() []entities.HistogramBucket {
+	last := -1
+	for i, count := range h.reported {
+		if count > 0 {
+			last = i
+		}
+	}
+	buckets := make([]entities.HistogramBucket, 0, last+1)
+	for i := 0; i <= last; i++ {
+		bound := roundMs(float64(h.maxUs))
+		if i < len(histogramBoundsMs) {
+			bound = histogramBoundsMs[i]
+		}
+		buckets = append(buckets, entities.HistogramBucket{UpToMs: bound, Count: h.reported[i]})
+	}
+	return buckets
+}
+
+// roundMs converts microseconds to milliseconds with two decimals
+func roundMs(us float64) float64 {
+	return math.Round(us/10) / 100
+}
+
+// loadStepRecorder aggregates the requests of one scenario step
+type loadStepRecorder struct {
+	name        string
+	latency     *latencyHistogram
+	errors      int64
+	statusCodes map[string]int64
+}
+
+// loadRecorder aggregates the requests of a load test run. It is shared by
+// all virtual users of the run.
+type loadRecorder struct {
+	mu         sync.Mutex
+	started    time.Time
+	latency    *latencyHistogram
+	steps      []*loadStepRecorder
+	iterations int64
+	dropped    int64
+	errors     int64
+	statuses   map[string]int64
+	errorTypes map[string]int64
+}
+
+func newLoadRecorder(scenario []entities.LoadStep, started time.Time) *loadRecorder {
+	recorder := &loadRecorder{
+		started:    started,
+		latency:    newLatencyHistogram(),
+		statuses:   make(map[string]int64),
+		errorTypes: make(map[string]int64),
+	}
+	for _, step := range scenario {
+		recorder.steps = append(recorder.steps, &loadStepRecorder{
+			name:        step.Name,
+			latency:     newLatencyHistogram(),
+			statusCodes: make(map[string]int64),
+		})
+	}
+	return recorder
+}
+
+// request records one request of the given step
+func (r *loadRecorder) request(step int, latency time.Duration, response *entities.APIResponse, err error) {
+	status := entities.LoadStatusError
+	if err == nil && response.StatusCode > 0 {
+		status = strconv.Itoa(response.StatusCode)
+	}
+	failed := err != nil || !response.Success
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latency.record(latency)
+	r.statuses[status]++
+	stepRecorder := r.steps[step]
+	stepRecorder.latency.record(latency)
+	stepRecorder.statusCodes[status]++
+	if failed {
+		r.errors++
+		stepRecorder.errors++
+	}
+	if err != nil || response.ErrorType != "" {
+		errorType := "request_failed"
+		switch {
+		case response != nil && response.ErrorType != "":
+			errorType = response.ErrorType
+		case errors.Is(err, entities.ErrTimeout):
+			errorType = "timeout"
+		}
+		r.errorTypes[errorType]++
+	}
+}
+
+// iteration records a completed run through the scenario
+func (r *loadRecorder) iteration() {
+	r.mu.Lock()
+	r.iterations++
+	r.mu.Unlock()
+}
+
+// drop records an iteration not started because all slots were busy
+func (r *loadRecorder) drop() {
+	r.mu.Lock()
+	r.dropped++
+	r.mu.Unlock()
+}
+
+// snapshot returns the stats so far
+func (r *loadRecorder) snapshot(now time.Time) *entities.LoadStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := now.Sub(r.started)
+	stats := &entities.LoadStats{
+		Iterations:        r.iterations,
+		DroppedIterations: r.dropped,
+		Requests:          r.latency.count,
+		Errors:            r.errors,
+		ElapsedMs:         elapsed.Milliseconds(),
+		Latency:           r.latency.summary(),
+		Histogram:         r.latency.buckets(),
+		StatusCodes:       copyCounts(r.statuses),
+		ErrorTypes:        copyCounts(r.errorTypes),
+	}
+	if stats.Requests > 0 {
+		stats.ErrorRate = math.Round(float64(stats.Errors)/float64(stats.Requests)*10000) / 10000
+	}
+	if elapsed > 0 {
+		stats.ThroughputRPS = math.Round(float64(stats.Requests)/elapsed.Seconds()*100) / 100
+	}
+	for _, step := range r.steps {
+		stats.Steps = append(stats.Steps, entities.LoadStepStats{
+			Name:        step.name,
+			Requests:    step.latency.count,
+			Errors:      step.errors,
+			Latency:     step.latency.summary(),
+			StatusCodes: copyCounts(step.statusCodes),
+		})
+	}
+	return stats
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	copied := make(map[string]int64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
+
+// evaluateThresholds checks a run's stats against its SLO thresholds
+func evaluateThresholds(thresholds *entities.LoadThresholds, stats *entities.LoadStats) ([]entities.ThresholdResult, bool) {
+	if thresholds == nil {
+		return nil, true
+	}
+
+	var results []entities.ThresholdResult
+	passed := true
+	check := func(metric string, limit *float64, observed float64, atMost bool) {
+		if limit == nil {
+			return
+		}
+		ok := observed <= *limit
+		if !atMost {
+			ok = observed >= *limit
+		}
+		results = append(results, entities.ThresholdResult{Metric: metric, Limit: *limit, Observed: observed, Passed: ok})
+		passed = passed && ok
+	}
+	check("p50_ms", thresholds.P50Ms, stats.Latency.P50Ms, true)
+	check("p95_ms", thresholds.P95Ms, stats.Latency.P95Ms, true)
+	check("p99_ms", thresholds.P99Ms, stats.Latency.P99Ms, true)
+	check("max_error_rate", thresholds.MaxErrorRate, stats.ErrorRate, true)
+	check("min_throughput_rps", thresholds.MinThroughput, stats.ThroughputRPS, false)
+	return results, passed
+}
@@ -0,0 +1,306 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+func TestLatencyHistogramPercentiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies func(i int) time.Duration
+		n         int
+	}{
+		{"uniform milliseconds", func(i int) time.Duration { return time.Duration(i+1) * time.Millisecond }, 1000},
+		{"sub-millisecond", func(i int) time.Duration { return time.Duration(i+1) * time.Microsecond }, 900},
+		{"long tail", func(i int) time.Duration { return time.Duration(math.Pow(1.01, float64(i))) * time.Millisecond }, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newLatencyHistogram()
+			for i := 0; i < tt.n; i++ {
+				h.record(tt.latencies(i))
+			}
+			for _, q := range []float64{0.5, 0.9, 0.95, 0.99, 1} {
+				// Latencies are recorded in increasing order
+				exact := float64(tt.latencies(int(math.Ceil(q*float64(tt.n))) - 1).Microseconds())
+				got := h.percentile(q)
+				if math.Abs(got-exact)/exact > 0.04 {
+					t.Errorf("p%v = %.0fus, want %.0fus within 4%%", q*100, got, exact)
+				}
+			}
+		})
+	}
+
+	if summary := newLatencyHistogram().summary(); summary != (entities.LatencySummary{}) {
+		t.Errorf("summary of no requests = %+v", summary)
+	}
+}
+
+func TestLoadRecorderSnapshot(t *testing.T) {
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	recorder := newLoadRecorder([]entities.LoadStep{{Name: "create"}, {Name: "fetch"}}, started)
+
+	// 100 requests of 1 to 100 ms, alternating between the steps. Every
+	// 20th fails with a 500, two time out and one is refused by egress rules.
+	for i := 1; i <= 100; i++ {
+		step := i % 2
+		response := &entities.APIResponse{StatusCode: 200, Success: true}
+		var err error
+		switch {
+		case i%20 == 0:
+			response = &entities.APIResponse{StatusCode: 500}
+		case i == 33 || i == 67:
+			response, err = &entities.APIResponse{}, fmt.Errorf("request timed out: %w", entities.ErrTimeout)
+		case i == 50:
+			response = &entities.APIResponse{ErrorType: entities.ErrorTypeEgressBlocked}
+		}
+		recorder.request(step, time.Duration(i)*time.Millisecond, response, err)
+	}
+	for i := 0; i < 50; i++ {
+		recorder.iteration()
+	}
+	recorder.drop()
+
+	stats := recorder.snapshot(started.Add(8 * time.Second))
+	if stats.Requests != 100 || stats.Iterations != 50 || stats.DroppedIterations != 1 || stats.ElapsedMs != 8000 {
+		t.Errorf("counts = %d requests, %d iterations, %d dropped, %d ms", stats.Requests, stats.Iterations, stats.DroppedIterations, stats.ElapsedMs)
+	}
+	if stats.Errors != 8 || stats.ErrorRate != 0.08 {
+		t.Errorf("errors = %d, rate %v; want 8, 0.08", stats.Errors, stats.ErrorRate)
+	}
+	if stats.ThroughputRPS != 12.5 {
+		t.Errorf("throughput = %v, want 12.5", stats.ThroughputRPS)
+	}
+
+	latency := stats.Latency
+	if latency.MinMs != 1 || latency.MaxMs != 100 || latency.MeanMs != 50.5 {
+		t.Errorf("min/mean/max = %v/%v/%v, want 1/50.5/100", latency.MinMs, latency.MeanMs, latency.MaxMs)
+	}
+	for _, p := range []struct {
+		name      string
+		got, want float64
+	}{{"p50", latency.P50Ms, 50}, {"p90", latency.P90Ms, 90}, {"p95", latency.P95Ms, 95}, {"p99", latency.P99Ms, 99}} {
+		if math.Abs(p.got-p.want)/p.want > 0.04 {
+			t.Errorf("%s = %v ms, want %v within 4%%", p.name, p.got, p.want)
+		}
+	}
+
+	wantHistogram := []entities.HistogramBucket{
+		{UpToMs: 1, Count: 1}, {UpToMs: 2, Count: 1}, {UpToMs: 5, Count: 3}, {UpToMs: 10, Count: 5},
+		{UpToMs: 25, Count: 15}, {UpToMs: 50, Count: 25}, {UpToMs: 100, Count: 50},
+	}
+	if !reflect.DeepEqual(stats.Histogram, wantHistogram) {
+		t.Errorf("histogram = %v, want %v", stats.Histogram, wantHistogram)
+	}
+	wantStatuses := map[string]int64{"200": 92, "500": 5, entities.LoadStatusError: 3}
+	if !reflect.DeepEqual(stats.StatusCodes, wantStatuses) {
+		t.Errorf("status codes = %v, want %v", stats.StatusCodes, wantStatuses)
+	}
+	wantErrorTypes := map[string]int64{"timeout": 2, entities.ErrorTypeEgressBlocked: 1}
+	if !reflect.DeepEqual(stats.ErrorTypes, wantErrorTypes) {
+		t.Errorf("error types = %v, want %v", stats.ErrorTypes, wantErrorTypes)
+	}
+
+	if len(stats.Steps) != 2 || stats.Steps[0].Name != "create" || stats.Steps[1].Name != "fetch" {
+		t.Fatalf("steps = %+v", stats.Steps)
+	}
+	// Step 0 got the even requests: the 500s and the refused one
+	if stats.Steps[0].Requests != 50 || stats.Steps[0].Errors != 6 || stats.Steps[1].Errors != 2 {
+		t.Errorf("step requests/errors = %d/%d and %d/%d",
+			stats.Steps[0].Requests, stats.Steps[0].Errors, stats.Steps[1].Requests, stats.Steps[1].Errors)
+	}
+
+	// Snapshots are copies
+	stats.StatusCodes["200"] = 0
+	if again := recorder.snapshot(started.Add(8 * time.Second)); again.StatusCodes["200"] != 92 {
+		t.Error("changing a snapshot changed the recorder")
+	}
+	if empty := newLoadRecorder(nil, started).snapshot(started); empty.ErrorRate != 0 || empty.ThroughputRPS != 0 {
+		t.Errorf("snapshot of an empty run = %+v", empty)
+	}
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	limit := func(v float64) *float64 { return &v }
+	stats := &entities.LoadStats{
+		Latency:       entities.LatencySummary{P50Ms: 40, P95Ms: 180, P99Ms: 420},
+		ErrorRate:     0.02,
+		ThroughputRPS: 95.5,
+	}
+
+	tests := []struct {
+		name       string
+		thresholds *entities.LoadThresholds
+		want       []entities.ThresholdResult
+		wantPassed bool
+	}{
+		{name: "no thresholds", wantPassed: true},
+		{
+			name:       "all met",
+			thresholds: &entities.LoadThresholds{P50Ms: limit(50), P95Ms: limit(200), P99Ms: limit(500), MaxErrorRate: limit(0.05), MinThroughput: limit(90)},
+			want: []entities.ThresholdResult{
+				{Metric: "p50_ms", Limit: 50, Observed: 40, Passed: true},
+				{Metric: "p95_ms", Limit: 200, Observed: 180, Passed: true},
+				{Metric: "p99_ms", Limit: 500, Observed: 420, Passed: true},
+				{Metric: "max_error_rate", Limit: 0.05, Observed: 0.02, Passed: true},
+				{Metric: "min_throughput_rps", Limit: 90, Observed: 95.5, Passed: true},
+			},
+			wantPassed: true,
+		},
+		{
+			name:       "limits are inclusive",
+			thresholds: &entities.LoadThresholds{P95Ms: limit(180), MaxErrorRate: limit(0.02), MinThroughput: limit(95.5)},
+			want: []entities.ThresholdResult{
+				{Metric: "p95_ms", Limit: 180, Observed: 180, Passed: true},
+				{Metric: "max_error_rate", Limit: 0.02, Observed: 0.02, Passed: true},
+				{Metric: "min_throughput_rps", Limit: 95.5, Observed: 95.5, Passed: true},
+			},
+			wantPassed: true,
+		},
+		{
+			name:       "latency exceeded",
+			thresholds: &entities.LoadThresholds{P50Ms: limit(50), P99Ms: limit(400)},
+			want: []entities.ThresholdResult{
+				{Metric: "p50_ms", Limit: 50, Observed: 40, Passed: true},
+				{Metric: "p99_ms", Limit: 400, Observed: 420, Passed: false},
+			},
+		},
+		{
+			name:       "error rate exceeded",
+			thresholds: &entities.LoadThresholds{MaxErrorRate: limit(0.01)},
+			want:       []entities.ThresholdResult{{Metric: "max_error_rate", Limit: 0.01, Observed: 0.02, Passed: false}},
+		},
+		{
+			name:       "throughput too low",
+			thresholds: &entities.LoadThresholds{MinThroughput: limit(100)},
+			want:       []entities.ThresholdResult{{Metric: "min_throughput_rps", Limit: 100, Observed: 95.5, Passed: false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, passed := evaluateThresholds(tt.thresholds, stats)
+			if passed != tt.wantPassed || !reflect.DeepEqual(results, tt.want) {
+				t.Errorf("evaluateThresholds = %+v, %v; want %+v, %v", results, passed, tt.want, tt.wantPassed)
+			}
+		})
+	}
+}
+
+func TestRateAt(t *testing.T) {
+	tests := []struct {
+		target  float64
+		rampUp  int
+		elapsed time.Duration
+		want    float64
+	}{
+		{100, 0, 0, 100},
+		{100, 10, 0, 1},
+		{100, 10, 5 * time.Second, 50},
+		{100, 10, 10 * time.Second, 100},
+		{100, 10, time.Minute, 100},
+		{0.5, 10, 0, 0.5},
+		{0.5, 10, 5 * time.Second, 0.5},
+	}
+	for _, tt := range tests {
+		config := &entities.LoadTestConfig{TargetRPS: tt.target, RampUpSeconds: tt.rampUp}
+		if got := rateAt(config, tt.elapsed); got != tt.want {
+			t.Errorf("rateAt(%v rps, %ds ramp-up, %v) = %v, want %v", tt.target, tt.rampUp, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestRunRate(t *testing.T) {
+	t.Run("starts iterations at the target rate", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		recorder := newLoadRecorder(nil, time.Now())
+		var started atomic.Int64
+		runRate(ctx, &entities.LoadTestConfig{TargetRPS: 100, Concurrency: 10}, recorder, func() { started.Add(1) })
+
+		// 50 are due in 500ms; allow for a slow scheduler
+		if n := started.Load(); n < 35 || n > 51 {
+			t.Errorf("started %d iterations, want about 50", n)
+		}
+		if recorder.dropped != 0 {
+			t.Errorf("dropped %d iterations with free slots", recorder.dropped)
+		}
+	})
+
+	t.Run("drops iterations when all slots are busy", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		recorder := newLoadRecorder(nil, time.Now())
+		var running, peak, started atomic.Int64
+		runRate(ctx, &entities.LoadTestConfig{TargetRPS: 100, Concurrency: 2}, recorder, func() {
+			started.Add(1)
+			now := running.Add(1)
+			for {
+				if p := peak.Load(); now <= p || peak.CompareAndSwap(p, now) {
+					break
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+			running.Add(-1)
+		})
+
+		if peak.Load() > 2 {
+			t.Errorf("%d iterations ran at once, want at most 2", peak.Load())
+		}
+		// About 30 are due: two every 100ms start, the others are dropped
+		if n := started.Load(); n < 2 || n > 8 {
+			t.Errorf("started %d iterations, want about 6", n)
+		}
+		if recorder.dropped < 15 || started.Load()+recorder.dropped > 31 {
+			t.Errorf("dropped %d iterations of about 30 due, %d started", recorder.dropped, started.Load())
+		}
+	})
+
+	t.Run("returns once running iterations finish", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var finished atomic.Bool
+		runRate(ctx, &entities.LoadTestConfig{TargetRPS: 1, Concurrency: 1}, newLoadRecorder(nil, time.Now()), func() {
+			time.Sleep(150 * time.Millisecond)
+			finished.Store(true)
+		})
+		if !finished.Load() {
+			t.Error("runRate returned before the running iteration finished")
+		}
+	})
+}
+
+func TestRunConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var mu sync.Mutex
+	running, peak, iterations := 0, 0, 0
+	runConcurrency(ctx, &entities.LoadTestConfig{Concurrency: 3}, func() {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		iterations++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	if peak != 3 {
+		t.Errorf("%d virtual users ran at once, want 3", peak)
+	}
+	// Each user runs back to back: about 20 iterations in 200ms
+	if iterations < 30 || iterations > 63 {
+		t.Errorf("%d iterations, want about 60", iterations)
+	}
+}
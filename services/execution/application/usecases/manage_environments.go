@@ -2,21 +2,45 @@ package usecases
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/testpilot-ai/execution/domain/entities"
 	"github.com/testpilot-ai/execution/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
 )
 
-// ManageEnvironmentsUseCase handles environment management logic
+// SecretCipher seals and opens envelope-encrypted secret values
+type SecretCipher interface {
+	Seal(environmentID uuid.UUID, name string, plaintext []byte) (*entities.EncryptedSecret, error)
+	Open(secret *entities.EncryptedSecret) ([]byte, error)
+	Rewrap(secret *entities.EncryptedSecret) (bool, error)
+	ActiveKeyID() string
+}
+
+// SecretKeyStatus summarises which master keys protect stored secrets
+type SecretKeyStatus struct {
+	ActiveKeyID  string         `json:"active_key_id"`
+	SecretsByKey map[string]int `json:"secrets_by_key"`
+}
+
+// ManageEnvironmentsUseCase handles environment management logic.
+// Credential fields of an environment's auth config are split off and
+// stored encrypted; reads only ever return them masked.
 type ManageEnvironmentsUseCase struct {
-	envRepo repositories.EnvironmentRepository
+	envRepo    repositories.EnvironmentRepository
+	secretRepo repositories.SecretRepository
+	cipher     SecretCipher
 }
 
 // NewManageEnvironmentsUseCase creates a new use case instance
-func NewManageEnvironmentsUseCase(repo repositories.EnvironmentRepository) *ManageEnvironmentsUseCase {
+func NewManageEnvironmentsUseCase(repo repositories.EnvironmentRepository, secretRepo repositories.SecretRepository, cipher SecretCipher) *ManageEnvironmentsUseCase {
 	return &ManageEnvironmentsUseCase{
-		envRepo: repo,
+		envRepo:    repo,
+		secretRepo: secretRepo,
+		cipher:     cipher,
 	}
 }
 
@@ -25,34 +49,287 @@ func (uc *ManageEnvironmentsUseCase) CreateEnvironment(ctx context.Context, env
 	if err := env.Validate(); err != nil {
 		return err
 	}
-	return uc.envRepo.CreateEnvironment(ctx, env)
+	if err := validateTLSSettings(env.AuthConfig); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if env.ID == uuid.Nil {
+		env.ID = uuid.New()
+	}
+	env.CreatedAt = now
+	env.UpdatedAt = now
+
+	plain, secrets := splitAuthConfig(env.AuthConfig)
+	env.AuthConfig = plain
+	if err := uc.envRepo.CreateEnvironment(ctx, env); err != nil {
+		return err
+	}
+
+	for name, value := range secrets {
+		if isMasked(value) || isEmpty(value) {
+			continue
+		}
+		if err := uc.storeSecret(ctx, env.ID, name, value); err != nil {
+			return err
+		}
+	}
+
+	return uc.maskSecrets(ctx, env)
 }
 
-// GetEnvironmentByID retrieves an environment by ID
+// GetEnvironmentByID retrieves an environment by ID with secrets masked
 func (uc *ManageEnvironmentsUseCase) GetEnvironmentByID(ctx context.Context, id uuid.UUID) (*entities.Environment, error) {
-	return uc.envRepo.FindEnvironmentByID(ctx, id)
+	env, err := uc.envRepo.FindEnvironmentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return env, uc.maskSecrets(ctx, env)
 }
 
-// GetEnvironmentByName retrieves an environment by name
+// GetEnvironmentByName retrieves an environment by name with secrets masked
 func (uc *ManageEnvironmentsUseCase) GetEnvironmentByName(ctx context.Context, name string) (*entities.Environment, error) {
-	return uc.envRepo.FindEnvironmentByName(ctx, name)
+	env, err := uc.envRepo.FindEnvironmentByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return env, uc.maskSecrets(ctx, env)
 }
 
-// UpdateEnvironment updates an environment
+// UpdateEnvironment updates an environment. Secret fields are write-only:
+// a masked value keeps the stored secret, an empty or missing value removes
+// it and any other value replaces it.
 func (uc *ManageEnvironmentsUseCase) UpdateEnvironment(ctx context.Context, env *entities.Environment) error {
 	if err := env.Validate(); err != nil {
 		return err
 	}
-	return uc.envRepo.UpdateEnvironment(ctx, env)
+	if err := validateTLSSettings(env.AuthConfig); err != nil {
+		return err
+	}
+
+	existing, err := uc.secretRepo.FindSecrets(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	plain, secrets := splitAuthConfig(env.AuthConfig)
+	env.AuthConfig = plain
+	if err := uc.envRepo.UpdateEnvironment(ctx, env); err != nil {
+		return err
+	}
+
+	for _, secret := range existing {
+		value, ok := secrets[secret.Name]
+		if !ok || isEmpty(value) {
+			if err := uc.secretRepo.DeleteSecret(ctx, env.ID, secret.Name); err != nil {
+				return fmt.Errorf("failed to delete secret %q: %w", secret.Name, err)
+			}
+		}
+	}
+	for name, value := range secrets {
+		if isMasked(value) || isEmpty(value) {
+			continue
+		}
+		if err := uc.storeSecret(ctx, env.ID, name, value); err != nil {
+			return err
+		}
+	}
+
+	return uc.maskSecrets(ctx, env)
 }
 
-// DeleteEnvironment deletes an environment
+// DeleteEnvironment deletes an environment (its secrets cascade)
 func (uc *ManageEnvironmentsUseCase) DeleteEnvironment(ctx context.Context, id uuid.UUID) error {
 	return uc.envRepo.DeleteEnvironment(ctx, id)
 }
 
-// ListEnvironments retrieves all environments
+// ListEnvironments retrieves all environments with secrets masked
 func (uc *ManageEnvironmentsUseCase) ListEnvironments(ctx context.Context) ([]*entities.Environment, error) {
-	return uc.envRepo.ListEnvironments(ctx)
+	environments, err := uc.envRepo.ListEnvironments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range environments {
+		if err := uc.maskSecrets(ctx, env); err != nil {
+			return nil, err
+		}
+	}
+	return environments, nil
 }
 
+// ResolveEnvironment returns an environment with its secrets decrypted into
+// the auth config. Only the request builder may use this; the result must
+// never be returned to clients or logged.
+func (uc *ManageEnvironmentsUseCase) ResolveEnvironment(ctx context.Context, id uuid.UUID) (*entities.Environment, error) {
+	env, err := uc.envRepo.FindEnvironmentByID(ctx, id)
+	if err != nil {
+		return nil, entities.ErrEnvironmentNotFound
+	}
+
+	secrets, err := uc.secretRepo.FindSecrets(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secrets: %w", err)
+	}
+
+	if env.AuthConfig == nil {
+		env.AuthConfig = make(map[string]interface{})
+	}
+	for _, secret := range secrets {
+		plaintext, err := uc.cipher.Open(secret)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := json.Unmarshal(plaintext, &value); err != nil {
+			return nil, fmt.Errorf("failed to decode secret %q: %w", secret.Name, err)
+		}
+		env.AuthConfig[secret.Name] = value
+	}
+
+	return env, nil
+}
+
+// RotateSecrets re-wraps every data key that is not protected by the active
+// master key. Secret values are not re-encrypted. It returns how many
+// secrets were rotated.
+func (uc *ManageEnvironmentsUseCase) RotateSecrets(ctx context.Context) (int, error) {
+	secrets, err := uc.secretRepo.ListSecrets(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	rotated := 0
+	for _, secret := range secrets {
+		changed, err := uc.cipher.Rewrap(secret)
+		if err != nil {
+			return rotated, err
+		}
+		if !changed {
+			continue
+		}
+		if err := uc.secretRepo.UpdateWrappedKey(ctx, secret); err != nil {
+			return rotated, fmt.Errorf("failed to store rotated secret %q: %w", secret.Name, err)
+		}
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+// SecretKeyStatus reports the active master key and how many secrets each key protects
+func (uc *ManageEnvironmentsUseCase) SecretKeyStatus(ctx context.Context) (*SecretKeyStatus, error) {
+	secrets, err := uc.secretRepo.ListSecrets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	status := &SecretKeyStatus{
+		ActiveKeyID:  uc.cipher.ActiveKeyID(),
+		SecretsByKey: make(map[string]int),
+	}
+	for _, secret := range secrets {
+		status.SecretsByKey[secret.KeyID]++
+	}
+	return status, nil
+}
+
+// MigratePlaintextSecrets encrypts credential values left in the auth_config
+// column by earlier versions and strips them from the column
+func (uc *ManageEnvironmentsUseCase) MigratePlaintextSecrets(ctx context.Context) error {
+	environments, err := uc.envRepo.ListEnvironments(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, env := range environments {
+		plain, secrets := splitAuthConfig(env.AuthConfig)
+		migrated := 0
+		for name, value := range secrets {
+			if isMasked(value) || isEmpty(value) {
+				continue
+			}
+			if err := uc.storeSecret(ctx, env.ID, name, value); err != nil {
+				return err
+			}
+			migrated++
+		}
+		if len(secrets) == 0 {
+			continue
+		}
+
+		env.AuthConfig = plain
+		if err := uc.envRepo.UpdateEnvironment(ctx, env); err != nil {
+			return err
+		}
+		logger.WithContext(ctx).Info().
+			Str("environment", env.Name).
+			Int("secrets", migrated).
+			Msg("Migrated plaintext environment secrets to encrypted storage")
+	}
+
+	return nil
+}
+
+// storeSecret seals and persists a single secret value
+func (uc *ManageEnvironmentsUseCase) storeSecret(ctx context.Context, environmentID uuid.UUID, name string, value interface{}) error {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode secret %q: %w", name, err)
+	}
+	secret, err := uc.cipher.Seal(environmentID, name, plaintext)
+	if err != nil {
+		return err
+	}
+	if err := uc.secretRepo.SaveSecret(ctx, secret); err != nil {
+		return fmt.Errorf("failed to save secret %q: %w", name, err)
+	}
+	return nil
+}
+
+// maskSecrets adds a masked placeholder for every stored secret
+func (uc *ManageEnvironmentsUseCase) maskSecrets(ctx context.Context, env *entities.Environment) error {
+	secrets, err := uc.secretRepo.FindSecrets(ctx, env.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets: %w", err)
+	}
+	if env.AuthConfig == nil {
+		env.AuthConfig = make(map[string]interface{})
+	}
+	for key, value := range env.AuthConfig {
+		// Never echo a credential that slipped into the plain config
+		if entities.IsSecretField(key) && !isEmpty(value) {
+			env.AuthConfig[key] = entities.SecretMask
+		}
+	}
+	for _, secret := range secrets {
+		env.AuthConfig[secret.Name] = entities.SecretMask
+	}
+	return nil
+}
+
+// splitAuthConfig separates credential fields from the rest of the auth config
+func splitAuthConfig(authConfig map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	plain := make(map[string]interface{})
+	secrets := make(map[string]interface{})
+	for key, value := range authConfig {
+		if entities.IsSecretField(key) {
+			secrets[key] = value
+		} else {
+			plain[key] = value
+		}
+	}
+	return plain, secrets
+}
+
+func isMasked(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && s == entities.SecretMask
+}
+
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
@@ -0,0 +1,149 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// TokenFetcher obtains tokens from an OAuth2 token endpoint
+type TokenFetcher interface {
+	FetchToken(ctx context.Context, cfg *entities.OAuth2Config) (*entities.OAuth2Token, error)
+	RefreshToken(ctx context.Context, cfg *entities.OAuth2Config, refreshToken string) (*entities.OAuth2Token, error)
+}
+
+// OAuth2TokenProvider caches access tokens per environment and refreshes
+// them shortly before they expire
+type OAuth2TokenProvider struct {
+	environments *ManageEnvironmentsUseCase
+	fetcher      TokenFetcher
+	leeway       time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*tokenEntry
+}
+
+// tokenEntry holds one environment's token. Its mutex serialises fetches so
+// concurrent requests share a single token request.
+type tokenEntry struct {
+	mu          sync.Mutex
+	fingerprint string
+	token       *entities.OAuth2Token
+}
+
+// NewOAuth2TokenProvider creates a token provider. Tokens are treated as
+// expired leeway before their reported expiry.
+func NewOAuth2TokenProvider(environments *ManageEnvironmentsUseCase, fetcher TokenFetcher, leeway time.Duration) *OAuth2TokenProvider {
+	return &OAuth2TokenProvider{
+		environments: environments,
+		fetcher:      fetcher,
+		leeway:       leeway,
+		entries:      make(map[uuid.UUID]*tokenEntry),
+	}
+}
+
+// Token returns a valid access token for a resolved environment, using the
+// cached token when possible
+func (p *OAuth2TokenProvider) Token(ctx context.Context, env *entities.Environment) (*entities.OAuth2Token, error) {
+	return p.token(ctx, env, false)
+}
+
+// Invalidate drops the cached token for an environment, e.g. after the
+// target API rejected it
+func (p *OAuth2TokenProvider) Invalidate(environmentID uuid.UUID) {
+	p.mu.Lock()
+	delete(p.entries, environmentID)
+	p.mu.Unlock()
+}
+
+// TestEnvironment fetches a fresh token for an environment so its OAuth2
+// settings can be checked. The returned token's secrets are not serialised.
+func (p *OAuth2TokenProvider) TestEnvironment(ctx context.Context, environmentID uuid.UUID) (*entities.OAuth2Token, error) {
+	env, err := p.environments.ResolveEnvironment(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	return p.token(ctx, env, true)
+}
+
+func (p *OAuth2TokenProvider) token(ctx context.Context, env *entities.Environment, force bool) (*entities.OAuth2Token, error) {
+	cfg, err := entities.ParseOAuth2Config(env.AuthConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := p.entry(env.ID)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	// A changed config (new client, secret or scope) invalidates the token
+	fingerprint := oauth2Fingerprint(cfg)
+	if entry.fingerprint != fingerprint {
+		entry.fingerprint = fingerprint
+		entry.token = nil
+	}
+
+	now := time.Now()
+	if !force && entry.token.Valid(now, p.leeway) {
+		return entry.token, nil
+	}
+
+	if !force && entry.token != nil && entry.token.RefreshToken != "" {
+		token, err := p.fetcher.RefreshToken(ctx, cfg, entry.token.RefreshToken)
+		if err == nil {
+			entry.token = token
+			return token, nil
+		}
+		// Refresh tokens expire or get revoked; fall back to the full grant
+		logger.WithContext(ctx).Warn().
+			Err(err).
+			Str("environment", env.Name).
+			Msg("OAuth2 token refresh failed, requesting a new token")
+	}
+
+	token, err := p.fetcher.FetchToken(ctx, cfg)
+	if err != nil {
+		entry.token = nil
+		return nil, err
+	}
+	entry.token = token
+
+	logger.WithContext(ctx).Debug().
+		Str("environment", env.Name).
+		Str("grant_type", cfg.GrantType).
+		Time("expires_at", token.ExpiresAt).
+		Msg("Obtained OAuth2 access token")
+
+	return token, nil
+}
+
+func (p *OAuth2TokenProvider) entry(environmentID uuid.UUID) *tokenEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[environmentID]
+	if !ok {
+		entry = &tokenEntry{}
+		p.entries[environmentID] = entry
+	}
+	return entry
+}
+
+// oauth2Fingerprint identifies the settings a token was issued for
+func oauth2Fingerprint(cfg *entities.OAuth2Config) string {
+	h := sha256.New()
+	for _, part := range []string{
+		cfg.GrantType, cfg.TokenURL, cfg.ClientID, cfg.ClientSecret,
+		cfg.Username, cfg.Password, cfg.Scope, cfg.Audience, cfg.ClientAuth,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/execution/infrastructure/oauth2"
+)
+
+// mockTokenEndpoint is a local OAuth2 token endpoint issuing numbered
+// tokens. refreshFails makes refresh_token grants fail with invalid_grant.
+type mockTokenEndpoint struct {
+	*httptest.Server
+	mu           sync.Mutex
+	grants       []string
+	expiresIn    int
+	refreshFails bool
+}
+
+func newMockTokenEndpoint(t *testing.T, expiresIn int) *mockTokenEndpoint {
+	t.Helper()
+	m := &mockTokenEndpoint{expiresIn: expiresIn}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		grant := r.PostForm.Get("grant_type")
+
+		m.mu.Lock()
+		m.grants = append(m.grants, grant)
+		n := len(m.grants)
+		refreshFails := m.refreshFails
+		m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if grant == entities.GrantTypeRefreshToken && refreshFails {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("access-%d", n),
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+			"token_type":    "bearer",
+			"expires_in":    m.expiresIn,
+		})
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockTokenEndpoint) grantLog() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.grants...)
+}
+
+func oauth2Environment(tokenURL string) *entities.Environment {
+	return &entities.Environment{
+		ID:   uuid.New(),
+		Name: "staging",
+		AuthConfig: map[string]interface{}{
+			"type":          "oauth2",
+			"token_url":     tokenURL,
+			"client_id":     "cid",
+			"client_secret": "cs",
+		},
+	}
+}
+
+func TestOAuth2TokenProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		expiresIn    int
+		leeway       time.Duration
+		refreshFails bool
+		calls        int
+		wantGrants   []string
+		wantToken    string
+	}{
+		{
+			name:       "valid token is cached",
+			expiresIn:  3600,
+			leeway:     30 * time.Second,
+			calls:      3,
+			wantGrants: []string{"client_credentials"},
+			wantToken:  "access-1",
+		},
+		{
+			name:       "token inside the leeway is refreshed",
+			expiresIn:  10,
+			leeway:     30 * time.Second,
+			calls:      3,
+			wantGrants: []string{"client_credentials", "refresh_token", "refresh_token"},
+			wantToken:  "access-3",
+		},
+		{
+			name:         "failed refresh falls back to the grant",
+			expiresIn:    10,
+			leeway:       30 * time.Second,
+			refreshFails: true,
+			calls:        2,
+			wantGrants:   []string{"client_credentials", "refresh_token", "client_credentials"},
+			wantToken:    "access-3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := newMockTokenEndpoint(t, tt.expiresIn)
+			endpoint.refreshFails = tt.refreshFails
+			provider := NewOAuth2TokenProvider(nil, oauth2.NewClient(5*time.Second, http.DefaultTransport), tt.leeway)
+			env := oauth2Environment(endpoint.URL)
+
+			var token *entities.OAuth2Token
+			for i := 0; i < tt.calls; i++ {
+				var err error
+				if token, err = provider.Token(context.Background(), env); err != nil {
+					t.Fatalf("Token call %d: %v", i+1, err)
+				}
+			}
+
+			if got := endpoint.grantLog(); fmt.Sprint(got) != fmt.Sprint(tt.wantGrants) {
+				t.Errorf("grants = %v, want %v", got, tt.wantGrants)
+			}
+			if token.AccessToken != tt.wantToken {
+				t.Errorf("AccessToken = %q, want %q", token.AccessToken, tt.wantToken)
+			}
+			if got := token.AuthorizationHeader(); got != "Bearer "+tt.wantToken {
+				t.Errorf("AuthorizationHeader = %q", got)
+			}
+		})
+	}
+}
+
+func TestOAuth2TokenProviderInvalidation(t *testing.T) {
+	endpoint := newMockTokenEndpoint(t, 3600)
+	provider := NewOAuth2TokenProvider(nil, oauth2.NewClient(5*time.Second, http.DefaultTransport), 0)
+	env := oauth2Environment(endpoint.URL)
+	ctx := context.Background()
+
+	first, err := provider.Token(ctx, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A changed client secret must not reuse the token issued for the old one
+	env.AuthConfig["client_secret"] = "rotated"
+	second, err := provider.Token(ctx, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.AccessToken == first.AccessToken {
+		t.Error("token reused after the client secret changed")
+	}
+
+	// A token the target API rejected is dropped
+	provider.Invalidate(env.ID)
+	third, err := provider.Token(ctx, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.AccessToken == second.AccessToken {
+		t.Error("token reused after Invalidate")
+	}
+
+	if got := len(endpoint.grantLog()); got != 3 {
+		t.Errorf("token requests = %d, want 3", got)
+	}
+}
+
+func TestOAuth2TokenProviderConcurrentFetch(t *testing.T) {
+	endpoint := newMockTokenEndpoint(t, 3600)
+	provider := NewOAuth2TokenProvider(nil, oauth2.NewClient(5*time.Second, http.DefaultTransport), 0)
+	env := oauth2Environment(endpoint.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := provider.Token(context.Background(), env); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(endpoint.grantLog()); got != 1 {
+		t.Errorf("token requests = %d, want 1 shared by all callers", got)
+	}
+}
@@ -0,0 +1,112 @@
+package usecases
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// buildRequestURL adds the request's query parameters to its URL. Values are
+// URL-encoded and arrays are rendered in the parameter's style.
+func buildRequestURL(request *entities.APIRequest) (string, error) {
+	if len(request.QueryParams) == 0 {
+		return request.URL, nil
+	}
+
+	parsed, err := url.Parse(request.URL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", entities.ErrInvalidURL, err)
+	}
+
+	keys := make([]string, 0, len(request.QueryParams))
+	for key := range request.QueryParams {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		style := strings.ToLower(request.QueryParamStyles[key])
+		pairs = append(pairs, encodeQueryParam(key, request.QueryParams[key], style)...)
+	}
+
+	parsed.RawQuery = mergeQuery(parsed.RawQuery, keys, pairs)
+	return parsed.String(), nil
+}
+
+// encodeQueryParam renders one parameter as encoded key=value pairs
+func encodeQueryParam(key string, value interface{}, style string) []string {
+	items, isList := value.([]interface{})
+	if !isList {
+		return []string{url.QueryEscape(key) + "=" + url.QueryEscape(queryValue(value))}
+	}
+
+	switch style {
+	case entities.QueryStyleComma:
+		// Commas are the delimiter, so only the items are escaped
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			values = append(values, url.QueryEscape(queryValue(item)))
+		}
+		return []string{url.QueryEscape(key) + "=" + strings.Join(values, ",")}
+	case entities.QueryStyleBrackets:
+		key += "[]"
+	}
+
+	pairs := make([]string, 0, len(items))
+	for _, item := range items {
+		pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(queryValue(item)))
+	}
+	return pairs
+}
+
+// queryValue formats a scalar query value. Numbers keep their plain decimal
+// form and objects are sent as JSON.
+func queryValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// mergeQuery appends pairs to an existing raw query. Existing parameters named
+// in keys (including their brackets form) are replaced; the others are kept
+// exactly as written.
+func mergeQuery(rawQuery string, keys []string, pairs []string) string {
+	replaced := make(map[string]bool, len(keys)*2)
+	for _, key := range keys {
+		replaced[key] = true
+		replaced[key+"[]"] = true
+	}
+
+	var merged []string
+	for _, segment := range strings.Split(rawQuery, "&") {
+		if segment == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(segment, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if replaced[name] {
+			continue
+		}
+		merged = append(merged, segment)
+	}
+	return strings.Join(append(merged, pairs...), "&")
+}
@@ -0,0 +1,156 @@
+package usecases
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+func TestBuildRequestURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		params  map[string]interface{}
+		styles  map[string]string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "no parameters keeps the url as written",
+			url:  "https://api.example.com/search?q=a+b&Z=1",
+			want: "https://api.example.com/search?q=a+b&Z=1",
+		},
+		{
+			name:   "keys are sorted",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"b": "2", "a": "1"},
+			want:   "https://api.example.com/search?a=1&b=2",
+		},
+		{
+			name: "reserved characters are escaped",
+			url:  "https://api.example.com/search",
+			params: map[string]interface{}{
+				"q":        "a&b=c d",
+				"redirect": "https://x.example.com/?k=v#frag",
+				"plus+key": "50%",
+			},
+			want: "https://api.example.com/search?plus%2Bkey=50%25&q=a%26b%3Dc+d&redirect=https%3A%2F%2Fx.example.com%2F%3Fk%3Dv%23frag",
+		},
+		{
+			name:   "unicode",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"city": "Zürich"},
+			want:   "https://api.example.com/search?city=Z%C3%BCrich",
+		},
+		{
+			name: "scalars",
+			url:  "https://api.example.com/search",
+			params: map[string]interface{}{
+				"limit": float64(100), "ratio": 0.25, "big": float64(12345678901), "active": true, "cursor": nil,
+			},
+			want: "https://api.example.com/search?active=true&big=12345678901&cursor=&limit=100&ratio=0.25",
+		},
+		{
+			name:   "array repeats the key by default",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"tag": []interface{}{"a", "b c", float64(3)}},
+			want:   "https://api.example.com/search?tag=a&tag=b+c&tag=3",
+		},
+		{
+			name:   "array in form style",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"tag": []interface{}{"a", "b"}},
+			styles: map[string]string{"tag": entities.QueryStyleForm},
+			want:   "https://api.example.com/search?tag=a&tag=b",
+		},
+		{
+			name:   "array in comma style escapes items only",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"tag": []interface{}{"a", "b,c", "d e"}},
+			styles: map[string]string{"tag": entities.QueryStyleComma},
+			want:   "https://api.example.com/search?tag=a,b%2Cc,d+e",
+		},
+		{
+			name:   "array in brackets style",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"tag": []interface{}{"a", "b"}},
+			styles: map[string]string{"tag": "Brackets"},
+			want:   "https://api.example.com/search?tag%5B%5D=a&tag%5B%5D=b",
+		},
+		{
+			name:   "empty array sends nothing",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"tag": []interface{}{}, "q": "x"},
+			want:   "https://api.example.com/search?q=x",
+		},
+		{
+			name: "nested objects are sent as json",
+			url:  "https://api.example.com/search",
+			params: map[string]interface{}{
+				"filter": map[string]interface{}{"status": "open", "amount": map[string]interface{}{"gt": float64(10)}},
+			},
+			want: "https://api.example.com/search?filter=%7B%22amount%22%3A%7B%22gt%22%3A10%7D%2C%22status%22%3A%22open%22%7D",
+		},
+		{
+			name:   "objects inside an array",
+			url:    "https://api.example.com/search",
+			params: map[string]interface{}{"sort": []interface{}{map[string]interface{}{"by": "date"}}},
+			want:   "https://api.example.com/search?sort=%7B%22by%22%3A%22date%22%7D",
+		},
+		{
+			name:   "merged after the query in the url",
+			url:    "https://api.example.com/search?q=a%20b&page=2",
+			params: map[string]interface{}{"limit": "10"},
+			want:   "https://api.example.com/search?q=a%20b&page=2&limit=10",
+		},
+		{
+			name:   "parameters replace the same key in the url",
+			url:    "https://api.example.com/search?page=1&q=x&page=3",
+			params: map[string]interface{}{"page": "2"},
+			want:   "https://api.example.com/search?q=x&page=2",
+		},
+		{
+			name:   "brackets form in the url is replaced too",
+			url:    "https://api.example.com/search?tag%5B%5D=old&tag[]=older&q=x",
+			params: map[string]interface{}{"tag": []interface{}{"new"}},
+			styles: map[string]string{"tag": entities.QueryStyleBrackets},
+			want:   "https://api.example.com/search?q=x&tag%5B%5D=new",
+		},
+		{
+			name:   "escaped key in the url is replaced",
+			url:    "https://api.example.com/search?a%20b=1&c=2",
+			params: map[string]interface{}{"a b": "3"},
+			want:   "https://api.example.com/search?c=2&a+b=3",
+		},
+		{
+			name:   "fragment is kept",
+			url:    "https://api.example.com/search?q=x#results",
+			params: map[string]interface{}{"page": "2"},
+			want:   "https://api.example.com/search?q=x&page=2#results",
+		},
+		{
+			name:    "invalid url",
+			url:     "https://api.example.com/%zz",
+			params:  map[string]interface{}{"q": "x"},
+			wantErr: entities.ErrInvalidURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildRequestURL(&entities.APIRequest{URL: tt.url, QueryParams: tt.params, QueryParamStyles: tt.styles})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("buildRequestURL error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("buildRequestURL =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
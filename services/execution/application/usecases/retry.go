@@ -0,0 +1,109 @@
+package usecases
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// pollCondition is a poll_until condition with its path parsed
+type pollCondition struct {
+	*entities.PollCondition
+	steps []jsonPathStep
+}
+
+// compilePollCondition parses the condition's JSONPath up front, so a bad
+// path fails the request before anything is sent
+func compilePollCondition(condition *entities.PollCondition) (*pollCondition, error) {
+	steps, err := parseJSONPath(condition.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &pollCondition{PollCondition: condition, steps: steps}, nil
+}
+
+// holds evaluates the condition against a response body and returns the
+// value found at the path
+func (c *pollCondition) holds(body interface{}) (bool, interface{}) {
+	value, found := lookupJSONPath(body, c.steps)
+
+	switch c.Op() {
+	case entities.PollOpExists:
+		return found, value
+	case entities.PollOpNotEquals:
+		return found && !jsonEqual(value, c.Value), value
+	case entities.PollOpIn:
+		if !found {
+			return false, nil
+		}
+		candidates, _ := c.Value.([]interface{})
+		for _, candidate := range candidates {
+			if jsonEqual(value, candidate) {
+				return true, value
+			}
+		}
+		return false, value
+	}
+	return found && jsonEqual(value, c.Value), value
+}
+
+// jsonEqual compares decoded JSON values
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// backoff returns the delay before retry number retry (1-based): exponential
+// growth from the initial delay, capped at the maximum, with half of it
+// randomized so concurrent callers spread out
+func backoff(policy *entities.RetryPolicy, retry int) time.Duration {
+	delay := policy.InitialDelay()
+	for i := 1; i < retry && delay < policy.MaxDelay(); i++ {
+		delay *= 2
+	}
+	if delay > policy.MaxDelay() {
+		delay = policy.MaxDelay()
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter reads a Retry-After header given in seconds or as an HTTP date
+func retryAfter(headers http.Header, now time.Time) (time.Duration, bool) {
+	value := headers.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// wait sleeps for delay, returning false if ctx ends first
+func wait(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,607 @@
+package usecases
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// errStreamSizeLimit ends a stream whose received data passed the response
+// size limit
+var errStreamSizeLimit = errors.New("stream exceeds the response size limit")
+
+// websocketHandshakeHeaders are set by the WebSocket dialer itself
+var websocketHandshakeHeaders = map[string]bool{
+	"Upgrade":                  true,
+	"Connection":               true,
+	"Sec-Websocket-Key":        true,
+	"Sec-Websocket-Version":    true,
+	"Sec-Websocket-Extensions": true,
+	"Sec-Websocket-Protocol":   true,
+	"Content-Type":             true,
+	"Content-Length":           true,
+}
+
+// streamCondition is a compiled until condition or assertion
+type streamCondition struct {
+	*pollCondition
+	event string
+}
+
+// check evaluates the condition against a received message; checked is
+// false for messages of another type than the condition's
+func (c *streamCondition) check(message entities.StreamMessage) (checked, held bool) {
+	if c.event != "" && message.Type != c.event {
+		return false, false
+	}
+	held, _ = c.holds(message.Data)
+	return true, held
+}
+
+func compileStreamCondition(condition entities.StreamCondition, field string) (*streamCondition, error) {
+	compiled, err := compilePollCondition(&entities.PollCondition{
+		Path:     condition.Path,
+		Operator: condition.Operator,
+		Value:    condition.Value,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", entities.ErrInvalidStream, field, err)
+	}
+	return &streamCondition{pollCondition: compiled, event: condition.Event}, nil
+}
+
+// streamFrame is a received message, or the error that ended the stream
+type streamFrame struct {
+	message entities.StreamMessage
+	size    int64
+	err     error
+}
+
+// streamSession collects the transcript of one streaming test
+type streamSession struct {
+	options    *entities.StreamOptions
+	until      []*streamCondition
+	assertions []*streamCondition
+	maxBytes   int64
+
+	opened        time.Time
+	transcript    []entities.StreamMessage
+	receivedBytes int64
+	result        *entities.StreamResult
+	truncated     bool
+	failure       error
+}
+
+func newStreamSession(options *entities.StreamOptions, maxBytes int64) (*streamSession, error) {
+	session := &streamSession{
+		options:    options,
+		maxBytes:   maxBytes,
+		transcript: []entities.StreamMessage{},
+		result:     &entities.StreamResult{Protocol: options.Protocol},
+	}
+	for i, condition := range options.Until {
+		compiled, err := compileStreamCondition(condition, fmt.Sprintf("until[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		session.until = append(session.until, compiled)
+	}
+	for i, assertion := range options.Assertions {
+		compiled, err := compileStreamCondition(assertion.StreamCondition, fmt.Sprintf("assertions[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		session.assertions = append(session.assertions, compiled)
+	}
+	return session, nil
+}
+
+// since returns the milliseconds since the stream opened
+func (s *streamSession) since() int64 {
+	return time.Since(s.opened).Milliseconds()
+}
+
+// run sends the script and collects received frames until ctx ends, the
+// stream stops or closes. send is nil for streams that only receive.
+func (s *streamSession) run(ctx context.Context, frames <-chan streamFrame, send func(data interface{}) error) {
+	script := s.options.Send
+	var sendTimer <-chan time.Time
+	schedule := func() {
+		sendTimer = nil
+		if len(script) > 0 && send != nil {
+			sendTimer = time.After(time.Duration(script[0].DelayMs) * time.Millisecond)
+		}
+	}
+	schedule()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.result.StopReason = entities.StreamStopDuration
+			return
+		case <-sendTimer:
+			if err := send(script[0].Data); err != nil {
+				s.failure = fmt.Errorf("failed to send message %d: %w", s.result.SentCount+1, err)
+				s.result.StopReason = entities.StreamStopClosed
+				return
+			}
+			s.transcript = append(s.transcript, entities.StreamMessage{
+				Direction: entities.StreamDirectionSent,
+				Type:      entities.StreamMessageText,
+				Data:      script[0].Data,
+				AtMs:      s.since(),
+			})
+			s.result.SentCount++
+			script = script[1:]
+			schedule()
+		case frame := <-frames:
+			if frame.err != nil {
+				s.stop(ctx, frame.err)
+				return
+			}
+			if stop := s.receive(frame); stop != "" {
+				s.result.StopReason = stop
+				return
+			}
+		}
+	}
+}
+
+// receive adds a received frame to the transcript and returns why the
+// stream stops after it, if it does
+func (s *streamSession) receive(frame streamFrame) string {
+	s.receivedBytes += frame.size
+	if s.receivedBytes > s.maxBytes {
+		s.truncated = true
+		return entities.StreamStopSizeLimit
+	}
+	s.transcript = append(s.transcript, frame.message)
+	s.result.ReceivedCount++
+
+	if len(s.until) > 0 {
+		met := true
+		for _, condition := range s.until {
+			if _, held := condition.check(frame.message); !held {
+				met = false
+				break
+			}
+		}
+		if met {
+			s.result.ConditionMet = &met
+			return entities.StreamStopCondition
+		}
+	}
+	if s.result.ReceivedCount >= s.options.MaxMessages {
+		return entities.StreamStopMaxMessages
+	}
+	return ""
+}
+
+// stop records the error that ended the stream
+func (s *streamSession) stop(ctx context.Context, err error) {
+	var closeErr *websocket.CloseError
+	switch {
+	case ctx.Err() != nil:
+		s.result.StopReason = entities.StreamStopDuration
+	case errors.Is(err, errStreamSizeLimit) || errors.Is(err, websocket.ErrReadLimit):
+		s.truncated = true
+		s.result.StopReason = entities.StreamStopSizeLimit
+	case errors.As(err, &closeErr):
+		s.result.StopReason = entities.StreamStopClosed
+		s.result.CloseCode = closeErr.Code
+		s.result.CloseReason = closeErr.Text
+	case errors.Is(err, io.EOF):
+		s.result.StopReason = entities.StreamStopClosed
+	default:
+		s.result.StopReason = entities.StreamStopClosed
+		s.failure = fmt.Errorf("stream failed: %w", err)
+	}
+}
+
+// finish checks the transcript and fills in the response with it
+func (s *streamSession) finish(response *entities.APIResponse) {
+	s.result.DurationMs = s.since()
+	var failures []string
+	if s.failure != nil {
+		failures = append(failures, s.failure.Error())
+	}
+
+	if len(s.until) > 0 && s.result.ConditionMet == nil {
+		met := false
+		s.result.ConditionMet = &met
+		failures = append(failures, fmt.Sprintf("stream stopped (%s) before a message met the until conditions", s.result.StopReason))
+	}
+	if s.result.ReceivedCount < s.options.MinMessages {
+		failures = append(failures, fmt.Sprintf("received %d messages, expected at least %d", s.result.ReceivedCount, s.options.MinMessages))
+	}
+
+	for i, condition := range s.assertions {
+		result := entities.StreamAssertionResult{StreamAssertion: s.options.Assertions[i]}
+		for _, message := range s.transcript {
+			if message.Direction != entities.StreamDirectionReceived {
+				continue
+			}
+			checked, held := condition.check(message)
+			if checked {
+				result.Checked++
+			}
+			if held {
+				result.Matched++
+			}
+		}
+		switch result.MatchMode() {
+		case entities.StreamMatchAll:
+			result.Passed = result.Checked > 0 && result.Matched == result.Checked
+		case entities.StreamMatchNone:
+			result.Passed = result.Matched == 0
+		default:
+			result.Passed = result.Matched > 0
+		}
+		if !result.Passed {
+			failures = append(failures, fmt.Sprintf("assertion %s %s failed for match %s: %d of %d messages matched",
+				result.Path, result.Op(), result.MatchMode(), result.Matched, result.Checked))
+		}
+		s.result.Assertions = append(s.result.Assertions, result)
+	}
+
+	response.Body = s.transcript
+	response.Stream = s.result
+	response.Truncated = s.truncated
+	response.Success = len(failures) == 0
+	if !response.Success {
+		response.ErrorType = entities.ErrorTypeStream
+		response.Error = strings.Join(failures, "; ")
+	}
+}
+
+// callStream runs the request as a streaming test and fills in response
+// with its transcript. A refused handshake or a response that is not an
+// event stream is a failed response, not an error; errors are streams that
+// could not be opened, with retryable set like send does.
+func (uc *ExecuteAPICallUseCase) callStream(ctx context.Context, request *entities.APIRequest, env *entities.Environment, maxResponseBytes int64, response *entities.APIResponse) (bool, error) {
+	session, err := newStreamSession(request.Stream, maxResponseBytes)
+	if err != nil {
+		return false, err
+	}
+	if request.Stream.Protocol == entities.StreamProtocolSSE {
+		return uc.streamEvents(ctx, request, env, session, maxResponseBytes, response)
+	}
+	return uc.streamWebSocket(ctx, request, env, session, maxResponseBytes, response)
+}
+
+// streamWebSocket opens a WebSocket connection, sends the script and
+// collects the messages received
+func (uc *ExecuteAPICallUseCase) streamWebSocket(ctx context.Context, request *entities.APIRequest, env *entities.Environment, session *streamSession, maxResponseBytes int64, response *entities.APIResponse) (bool, error) {
+	conn, handshake, retryable, err := uc.dialWebSocket(ctx, request, env)
+	if err != nil {
+		return retryable, err
+	}
+	// As over HTTP, a rejected OAuth2 token is renewed and the handshake retried once
+	if conn == nil && handshake.StatusCode == http.StatusUnauthorized && usesOAuth2(env) {
+		handshake.Body.Close()
+		uc.tokens.Invalidate(env.ID)
+		logger.WithContext(ctx).Info().
+			Str("environment", env.Name).
+			Msg("Target rejected OAuth2 token, retrying WebSocket handshake with a new token")
+		conn, handshake, retryable, err = uc.dialWebSocket(ctx, request, env)
+		if err != nil {
+			return retryable, err
+		}
+	}
+
+	response.StatusCode = handshake.StatusCode
+	response.Headers = handshake.Header
+	if conn == nil {
+		rejectStream(handshake, maxResponseBytes, response, fmt.Sprintf("websocket handshake refused with status %d", handshake.StatusCode))
+		return false, nil
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxResponseBytes)
+
+	session.opened = time.Now()
+	streamCtx, stop := context.WithTimeout(ctx, time.Duration(request.Stream.DurationSeconds)*time.Second)
+	defer stop()
+
+	frames := make(chan streamFrame)
+	done := make(chan struct{})
+	defer close(done)
+	go readWebSocket(conn, session, frames, done)
+
+	session.run(streamCtx, frames, func(data interface{}) error {
+		message, err := streamMessageBytes(data)
+		if err != nil {
+			return err
+		}
+		if deadline, ok := streamCtx.Deadline(); ok {
+			conn.SetWriteDeadline(deadline)
+		}
+		return conn.WriteMessage(websocket.TextMessage, message)
+	})
+
+	// The close handshake is best effort; the peer may have closed already
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+	session.finish(response)
+	return false, nil
+}
+
+// dialWebSocket opens the connection. The handshake is built and
+// authenticated like an HTTP request to the same URL. A refused handshake
+// returns its response and no connection.
+func (uc *ExecuteAPICallUseCase) dialWebSocket(ctx context.Context, request *entities.APIRequest, env *entities.Environment) (*websocket.Conn, *http.Response, bool, error) {
+	handshakeRequest := *request
+	handshakeRequest.URL = websocketURL(request.URL, false)
+	httpReq, err := uc.buildHTTPRequest(ctx, &handshakeRequest, env)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := uc.egress.rulesFrom(ctx).CheckHost(httpReq.URL.Hostname()); err != nil {
+		return nil, nil, false, err
+	}
+	if uc.signs(env) {
+		authType := configString(env.AuthConfig, "type")
+		if err := uc.signers.Sign(authType, env.AuthConfig, httpReq); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to sign request for environment %s: %w", env.Name, err)
+		}
+	}
+
+	tlsConfig, err := uc.transports.TLSConfig(env)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	// The pooled transports offer HTTP/2, which cannot be upgraded
+	tlsConfig.NextProtos = nil
+
+	header := http.Header{}
+	for k, values := range httpReq.Header {
+		if !websocketHandshakeHeaders[http.CanonicalHeaderKey(k)] {
+			header[k] = values
+		}
+	}
+
+	dialer := websocket.Dialer{
+		NetDialContext:  uc.transports.dialer.DialContext,
+		Proxy:           uc.transports.dialer.Proxy,
+		TLSClientConfig: tlsConfig,
+		Subprotocols:    request.Stream.Subprotocols,
+	}
+	target := websocketURL(httpReq.URL.String(), true)
+	conn, handshake, err := dialer.DialContext(ctx, target, header)
+	if err != nil {
+		if handshake != nil && errors.Is(err, websocket.ErrBadHandshake) {
+			return nil, handshake, false, nil
+		}
+		return nil, nil, !errors.Is(err, entities.ErrEgressBlocked), fmt.Errorf("failed to connect to %s: %w", httpReq.URL.Host, err)
+	}
+	return conn, handshake, false, nil
+}
+
+// readWebSocket feeds received messages to frames until the connection
+// fails or closes, or done is closed
+func readWebSocket(conn *websocket.Conn, session *streamSession, frames chan<- streamFrame, done <-chan struct{}) {
+	for {
+		kind, data, err := conn.ReadMessage()
+		frame := streamFrame{err: err}
+		if err == nil {
+			frame.size = int64(len(data))
+			frame.message = entities.StreamMessage{
+				Direction: entities.StreamDirectionReceived,
+				Type:      entities.StreamMessageText,
+				Data:      streamMessageData(data),
+				AtMs:      session.since(),
+			}
+			if kind == websocket.BinaryMessage {
+				frame.message.Type = entities.StreamMessageBinary
+				frame.message.Data = base64.StdEncoding.EncodeToString(data)
+			}
+		}
+		select {
+		case frames <- frame:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// streamEvents makes the request and collects the server-sent events of its
+// response
+func (uc *ExecuteAPICallUseCase) streamEvents(ctx context.Context, request *entities.APIRequest, env *entities.Environment, session *streamSession, maxResponseBytes int64, response *entities.APIResponse) (bool, error) {
+	// Cancelling the request is what ends reading its body
+	requestCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	open := func() (*http.Response, bool, error) {
+		httpReq, err := uc.buildHTTPRequest(requestCtx, request, env)
+		if err != nil {
+			return nil, false, err
+		}
+		if httpReq.Header.Get("Accept") == "" {
+			httpReq.Header.Set("Accept", "text/event-stream")
+		}
+		return uc.send(httpReq, env)
+	}
+	httpResp, retryable, err := open()
+	if err != nil {
+		return retryable, err
+	}
+	// As over HTTP, a rejected OAuth2 token is renewed and the request retried once
+	if httpResp.StatusCode == http.StatusUnauthorized && usesOAuth2(env) {
+		httpResp.Body.Close()
+		uc.tokens.Invalidate(env.ID)
+		logger.WithContext(ctx).Info().
+			Str("environment", env.Name).
+			Msg("Target rejected OAuth2 token, retrying event stream with a new token")
+		httpResp, retryable, err = open()
+		if err != nil {
+			return retryable, err
+		}
+	}
+	defer httpResp.Body.Close()
+
+	response.StatusCode = httpResp.StatusCode
+	response.Headers = httpResp.Header
+	mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 || mediaType != "text/event-stream" {
+		rejectStream(httpResp, maxResponseBytes, response, fmt.Sprintf("expected an event stream, got status %d with content type %q", httpResp.StatusCode, mediaType))
+		return false, nil
+	}
+
+	session.opened = time.Now()
+	streamCtx, stop := context.WithTimeout(requestCtx, time.Duration(request.Stream.DurationSeconds)*time.Second)
+	defer stop()
+
+	frames := make(chan streamFrame)
+	done := make(chan struct{})
+	defer close(done)
+	go readEvents(&streamLimitReader{r: httpResp.Body, remaining: maxResponseBytes}, session, frames, done)
+
+	session.run(streamCtx, frames, nil)
+	cancel()
+	session.finish(response)
+	return false, nil
+}
+
+// readEvents parses server-sent events and feeds them to frames until the
+// body ends or fails, or done is closed. A frame's size is the size of the
+// lines that made up its event.
+func readEvents(body io.Reader, session *streamSession, frames chan<- streamFrame, done <-chan struct{}) {
+	emit := func(frame streamFrame) bool {
+		select {
+		case frames <- frame:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	reader := bufio.NewReader(body)
+	var eventType, id string
+	var data []string
+	var size int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			emit(streamFrame{err: err})
+			return
+		}
+		size += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line dispatches the event; events without data are dropped
+			if data != nil {
+				message := entities.StreamMessage{
+					Direction: entities.StreamDirectionReceived,
+					Type:      entities.StreamEventMessage,
+					Data:      streamMessageData([]byte(strings.Join(data, "\n"))),
+					ID:        id,
+					AtMs:      session.since(),
+				}
+				if eventType != "" {
+					message.Type = eventType
+				}
+				if !emit(streamFrame{message: message, size: size}) {
+					return
+				}
+			}
+			eventType, data, size = "", nil, 0
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			// The last event ID carries over to later events
+			id = value
+		}
+	}
+}
+
+// streamLimitReader fails reads past the response size limit, so a stream
+// without line breaks cannot grow without bound
+type streamLimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *streamLimitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, errStreamSizeLimit
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// rejectStream fills in response for a stream that did not open
+func rejectStream(httpResp *http.Response, maxResponseBytes int64, response *entities.APIResponse, reason string) {
+	bodyBytes, _ := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseBytes))
+	decodeResponseBody(response, httpResp.Header.Get("Content-Type"), bodyBytes)
+	response.Success = false
+	response.ErrorType = entities.ErrorTypeStream
+	response.Error = reason
+}
+
+// streamMessageData decodes a text message when it is JSON
+func streamMessageData(data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err == nil {
+		return value
+	}
+	return string(data)
+}
+
+// streamMessageBytes encodes a scripted message: strings as they are,
+// other values as JSON
+func streamMessageBytes(data interface{}) ([]byte, error) {
+	if text, ok := data.(string); ok {
+		return []byte(text), nil
+	}
+	message, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	return message, nil
+}
+
+// websocketURL switches a URL between the WebSocket schemes and the HTTP
+// schemes of its handshake
+func websocketURL(rawURL string, toWebSocket bool) string {
+	schemes := map[string]string{"ws://": "http://", "wss://": "https://"}
+	if toWebSocket {
+		schemes = map[string]string{"http://": "ws://", "https://": "wss://"}
+	}
+	for from, to := range schemes {
+		if len(rawURL) >= len(from) && strings.EqualFold(rawURL[:len(from)], from) {
+			return to + rawURL[len(from):]
+		}
+	}
+	return rawURL
+}
@@ -0,0 +1,137 @@
+package usecases
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	"github.com/testpilot-ai/shared/egress"
+)
+
+// TransportSettings are the connection-level timeouts and pooling limits of
+// outgoing API calls. The overall per-request deadline comes from
+// ExecutionLimits; these bound the individual phases.
+type TransportSettings struct {
+	ConnectTimeout        time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration // 0 leaves it to the request deadline
+	IdleConnTimeout       time.Duration
+	MaxIdleConnsPerHost   int
+}
+
+// TransportPool hands out HTTP transports: one per environment, so
+// connections are reused between calls to the same environment and
+// environments with their own TLS settings never share connections, plus a
+// shared one for calls without an environment
+type TransportPool struct {
+	settings TransportSettings
+	dialer   *egress.Dialer
+	shared   *http.Transport
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*cachedTransport
+}
+
+type cachedTransport struct {
+	fingerprint string
+	transport   *http.Transport
+}
+
+// NewTransportPool creates a transport pool. Every connection it makes is
+// checked against the egress policy.
+func NewTransportPool(settings TransportSettings, egress *EgressPolicyUseCase) *TransportPool {
+	pool := &TransportPool{
+		settings: settings,
+		dialer: newGuardedDialer(&net.Dialer{
+			Timeout:   settings.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}, egress),
+		entries: make(map[uuid.UUID]*cachedTransport),
+	}
+	pool.shared = pool.newTransport()
+	return pool
+}
+
+// Shared returns the transport for calls without an environment, for
+// clients outside the pool such as the OAuth2 token client
+func (p *TransportPool) Shared() http.RoundTripper {
+	return p.shared
+}
+
+// Client returns an HTTP client for calls to env (nil for ad-hoc calls).
+// Clients carry no timeout of their own; callers bound each request with a
+// context deadline, so concurrent executions never affect each other.
+func (p *TransportPool) Client(env *entities.Environment) (*http.Client, error) {
+	transport, err := p.transportFor(env)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// TLSConfig returns the client TLS config of calls to env (nil for ad-hoc
+// calls), for connections the pool's transports do not make, like gRPC
+func (p *TransportPool) TLSConfig(env *entities.Environment) (*tls.Config, error) {
+	transport, err := p.transportFor(env)
+	if err != nil {
+		return nil, err
+	}
+	if transport.TLSClientConfig == nil {
+		return &tls.Config{MinVersion: tls.VersionTLS12}, nil
+	}
+	return transport.TLSClientConfig.Clone(), nil
+}
+
+func (p *TransportPool) transportFor(env *entities.Environment) (*http.Transport, error) {
+	if env == nil {
+		return p.shared, nil
+	}
+
+	settings := entities.TLSSettingsFromAuthConfig(env.AuthConfig)
+	fingerprint := ""
+	if settings != nil {
+		fingerprint = settings.Fingerprint()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[env.ID]; ok {
+		if entry.fingerprint == fingerprint {
+			return entry.transport, nil
+		}
+		// Settings changed; drop connections made with the old certificates
+		entry.transport.CloseIdleConnections()
+		delete(p.entries, env.ID)
+	}
+
+	transport := p.newTransport()
+	if settings != nil {
+		tlsConfig, err := buildTLSConfig(settings)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS settings for environment %s: %w", env.Name, err)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	p.entries[env.ID] = &cachedTransport{fingerprint: fingerprint, transport: transport}
+	return transport, nil
+}
+
+func (p *TransportPool) newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy:                 p.dialer.Proxy,
+		DialContext:           p.dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   p.settings.MaxIdleConnsPerHost,
+		IdleConnTimeout:       p.settings.IdleConnTimeout,
+		TLSHandshakeTimeout:   p.settings.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: p.settings.ResponseHeaderTimeout,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
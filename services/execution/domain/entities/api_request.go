@@ -1,6 +1,10 @@
 package entities
 
 import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,14 +17,62 @@ type APIRequest struct {
 	URL                    string                 `json:"url"`
 	Headers                map[string]string      `json:"headers"`
 	QueryParams            map[string]interface{} `json:"query_params"`
+	QueryParamStyles       map[string]string      `json:"query_param_styles,omitempty"`
 	Body                   interface{}            `json:"body,omitempty"`
+	BodyType               string                 `json:"body_type,omitempty"`
+	Files                  []FilePart             `json:"files,omitempty"`
 	Timeout                int                    `json:"timeout"` // in seconds
+	Retry                  *RetryPolicy           `json:"retry,omitempty"`
+	PollUntil              *PollCondition         `json:"poll_until,omitempty"`
+	VCR                    *VCROptions            `json:"vcr,omitempty"`
+	Callback               *CallbackOptions       `json:"callback,omitempty"`
+	GRPC                   *GRPCOptions           `json:"grpc,omitempty"`
+	Stream                 *StreamOptions         `json:"stream,omitempty"`
 	APISpecID              *uuid.UUID             `json:"api_spec_id,omitempty"`
 	APIName                string                 `json:"api_name,omitempty"`
 	EndpointName           string                 `json:"endpoint_name,omitempty"`
 	UserID                 *uuid.UUID             `json:"user_id,omitempty"`
+	EnvironmentID          *uuid.UUID             `json:"environment_id,omitempty"`
 	NaturalLanguageRequest string                 `json:"natural_language_request,omitempty"`
+	AllowDuplicate         bool                   `json:"allow_duplicate,omitempty"`
 	CreatedAt              time.Time              `json:"created_at"`
+
+	// Set during execution, see IdempotencySettings
+	IdempotencyKey string     `json:"-"`
+	Fingerprint    string     `json:"-"`
+	ReplayOf       *uuid.UUID `json:"-"`
+	// CallbackURL is the URL of the receiver opened for Callback
+	CallbackURL string `json:"-"`
+}
+
+// Request body types. When body_type is not set it is inferred from the
+// Content-Type header, falling back to JSON. GraphQL is never inferred: it
+// is JSON on the wire, but its response errors fail the call.
+const (
+	BodyTypeJSON      = "json"
+	BodyTypeForm      = "form"
+	BodyTypeMultipart = "multipart"
+	BodyTypeXML       = "xml"
+	BodyTypeText      = "text"
+	BodyTypeBinary    = "binary"
+	BodyTypeGraphQL   = "graphql"
+)
+
+// Array styles for query parameters, usually taken from the spec's parameter
+// definitions. Form (the default) repeats the key: ids=1&ids=2; comma joins
+// the values: ids=1,2; brackets repeats the key with []: ids[]=1&ids[]=2.
+const (
+	QueryStyleForm     = "form"
+	QueryStyleComma    = "comma"
+	QueryStyleBrackets = "brackets"
+)
+
+// FilePart is a file sent in a multipart/form-data body. Content is base64 encoded.
+type FilePart struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Content     string `json:"content"`
 }
 
 // NewAPIRequest creates a new API request entity
@@ -44,7 +96,7 @@ func (r *APIRequest) Validate() error {
 	if r.URL == "" {
 		return ErrInvalidURL
 	}
-	validMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	validMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
 	valid := false
 	for _, m := range validMethods {
 		if r.Method == m {
@@ -55,6 +107,123 @@ func (r *APIRequest) Validate() error {
 	if !valid {
 		return ErrInvalidMethod
 	}
-	return nil
+	for name, style := range r.QueryParamStyles {
+		switch strings.ToLower(style) {
+		case "", QueryStyleForm, QueryStyleComma, QueryStyleBrackets:
+		default:
+			return fmt.Errorf("%w: unknown style %q for query parameter %s", ErrInvalidURL, style, name)
+		}
+	}
+	if r.Retry != nil {
+		if err := r.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.PollUntil != nil {
+		if err := r.PollUntil.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.VCR != nil {
+		if err := r.VCR.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Callback != nil {
+		if err := r.Callback.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Stream != nil {
+		return r.validateStream()
+	}
+	if r.GRPC != nil {
+		return r.validateGRPC()
+	}
+	return r.validateBody()
+}
+
+// ResolvedBodyType returns the body type to encode the body with
+func (r *APIRequest) ResolvedBodyType() string {
+	if r.BodyType != "" {
+		return strings.ToLower(r.BodyType)
+	}
+	if len(r.Files) > 0 {
+		return BodyTypeMultipart
+	}
+
+	var contentType string
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = v
+			break
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return BodyTypeForm
+	case mediaType == "multipart/form-data":
+		return BodyTypeMultipart
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return BodyTypeXML
+	case strings.HasPrefix(mediaType, "text/"):
+		return BodyTypeText
+	case mediaType == "application/octet-stream":
+		return BodyTypeBinary
+	}
+	return BodyTypeJSON
+}
+
+// validateBody checks that the body has the shape its body type needs
+func (r *APIRequest) validateBody() error {
+	bodyType := r.ResolvedBodyType()
+	if len(r.Files) > 0 && bodyType != BodyTypeMultipart {
+		return fmt.Errorf("%w: files require body_type multipart", ErrInvalidBody)
+	}
+
+	switch bodyType {
+	case BodyTypeJSON:
+		return nil
+	case BodyTypeForm, BodyTypeMultipart:
+		switch r.Body.(type) {
+		case nil, map[string]interface{}:
+		case string:
+			if bodyType == BodyTypeMultipart {
+				return fmt.Errorf("%w: multipart body must be an object of fields", ErrInvalidBody)
+			}
+		default:
+			return fmt.Errorf("%w: %s body must be an object of fields", ErrInvalidBody, bodyType)
+		}
+		for i, file := range r.Files {
+			if file.Field == "" {
+				return fmt.Errorf("%w: files[%d] has no field name", ErrInvalidBody, i)
+			}
+			if _, err := base64.StdEncoding.DecodeString(file.Content); err != nil {
+				return fmt.Errorf("%w: files[%d] content is not valid base64", ErrInvalidBody, i)
+			}
+		}
+		return nil
+	case BodyTypeXML, BodyTypeText:
+		if _, ok := r.Body.(string); !ok && r.Body != nil {
+			return fmt.Errorf("%w: %s body must be a string", ErrInvalidBody, bodyType)
+		}
+		return nil
+	case BodyTypeGraphQL:
+		return r.validateGraphQL()
+	case BodyTypeBinary:
+		if r.Body == nil {
+			return nil
+		}
+		encoded, ok := r.Body.(string)
+		if !ok {
+			return fmt.Errorf("%w: binary body must be a base64 string", ErrInvalidBody)
+		}
+		if _, err := base64.StdEncoding.DecodeString(encoded); err != nil {
+			return fmt.Errorf("%w: binary body is not valid base64", ErrInvalidBody)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown body_type %q", ErrInvalidBody, r.BodyType)
 }
 
@@ -13,12 +13,31 @@ type APIResponse struct {
 	StatusCode      int                    `json:"status_code"`
 	Headers         map[string][]string    `json:"headers"`
 	Body            interface{}            `json:"body"`
+	BinaryBody      *BinaryBody            `json:"binary_body,omitempty"`
 	ExecutionTimeMs int64                  `json:"execution_time_ms"`
+	Timing          *ExecutionTiming       `json:"timing,omitempty"`
 	Error           string                 `json:"error,omitempty"`
+	ErrorType       string                 `json:"error_type,omitempty"`
+	Truncated       bool                   `json:"truncated,omitempty"`
+	Attempts        []ExecutionAttempt     `json:"attempts,omitempty"`
+	IdempotencyKey  string                 `json:"idempotency_key,omitempty"`
+	ReplayOf        *uuid.UUID             `json:"replay_of,omitempty"`
+	VCR             *VCRResult             `json:"vcr,omitempty"`
+	Callback        *CallbackReceiver      `json:"callback,omitempty"`
+	GRPC            *GRPCResult            `json:"grpc,omitempty"`
+	Stream          *StreamResult          `json:"stream,omitempty"`
 	Success         bool                   `json:"success"`
 	Timestamp       time.Time              `json:"timestamp"`
 }
 
+// BinaryBody describes a binary response body, which is not returned or
+// stored itself. Size and hash cover the bytes read, see Truncated.
+type BinaryBody struct {
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SHA256      string `json:"sha256"`
+}
+
 // NewAPIResponse creates a new API response entity
 func NewAPIResponse(requestID uuid.UUID) *APIResponse {
 	return &APIResponse{
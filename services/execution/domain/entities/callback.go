@@ -0,0 +1,207 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CallbackURLPlaceholder is replaced with the receiver's URL in the URL,
+// query parameters, headers and body of a request that opens a receiver
+const CallbackURLPlaceholder = "{{callback_url}}"
+
+// Bounds for callback receivers. A receiver stores at most
+// MaxCallbacksPerReceiver callbacks; waits are capped below the gateway's
+// timeout for the execution service.
+const (
+	DefaultCallbackTTLSeconds  = 3600
+	MaxCallbackTTLSeconds      = 24 * 3600
+	MaxCallbacksPerReceiver    = 100
+	MaxCallbackBodyBytes       = 1 << 20
+	DefaultCallbackWaitSeconds = 30
+	MaxCallbackWaitSeconds     = 60
+)
+
+// Callback signature schemes and encodings
+const (
+	CallbackSignatureHMACSHA256 = "hmac-sha256"
+	CallbackSignatureHMACSHA512 = "hmac-sha512"
+	SignatureEncodingHex        = "hex"
+	SignatureEncodingBase64     = "base64"
+	// DefaultCallbackSecretField is the environment auth_config field holding
+	// the webhook secret; like other *_secret fields it is stored encrypted
+	DefaultCallbackSecretField = "webhook_secret"
+)
+
+var (
+	ErrCallbackReceiverNotFound = errors.New("callback receiver not found")
+	ErrCallbackReceiverExpired  = errors.New("callback receiver has expired")
+	ErrCallbackReceiverFull     = errors.New("callback receiver is full")
+	ErrInvalidCallback          = errors.New("invalid callback settings")
+)
+
+// CallbackOptions opens a callback receiver for a request. Its URL replaces
+// CallbackURLPlaceholder in the request, and the receiver is linked to the
+// execution.
+type CallbackOptions struct {
+	TTLSeconds int                `json:"ttl_seconds,omitempty"`
+	Signature  *CallbackSignature `json:"signature,omitempty"`
+}
+
+// Validate checks the options and fills in defaults
+func (o *CallbackOptions) Validate() error {
+	if o.TTLSeconds == 0 {
+		o.TTLSeconds = DefaultCallbackTTLSeconds
+	}
+	if o.TTLSeconds < 0 || o.TTLSeconds > MaxCallbackTTLSeconds {
+		return fmt.Errorf("%w: ttl_seconds must be between 1 and %d", ErrInvalidCallback, MaxCallbackTTLSeconds)
+	}
+	if o.Signature != nil {
+		return o.Signature.Validate()
+	}
+	return nil
+}
+
+// CallbackSignature describes how the sender signs callbacks: an HMAC of
+// the raw body, encoded and optionally prefixed (e.g. "sha256="), sent in
+// Header. The secret is read from the environment's auth_config.
+type CallbackSignature struct {
+	Header      string `json:"header"`
+	Algorithm   string `json:"algorithm,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+	SecretField string `json:"secret_field,omitempty"`
+}
+
+// Validate checks the signature settings and fills in defaults
+func (s *CallbackSignature) Validate() error {
+	s.Header = strings.TrimSpace(s.Header)
+	if s.Header == "" || strings.ContainsAny(s.Header, " :\t\r\n") {
+		return fmt.Errorf("%w: signature.header must be a header name", ErrInvalidCallback)
+	}
+	s.Algorithm = strings.ToLower(s.Algorithm)
+	if s.Algorithm == "" {
+		s.Algorithm = CallbackSignatureHMACSHA256
+	}
+	if s.Algorithm != CallbackSignatureHMACSHA256 && s.Algorithm != CallbackSignatureHMACSHA512 {
+		return fmt.Errorf("%w: unknown signature.algorithm %q", ErrInvalidCallback, s.Algorithm)
+	}
+	s.Encoding = strings.ToLower(s.Encoding)
+	if s.Encoding == "" {
+		s.Encoding = SignatureEncodingHex
+	}
+	if s.Encoding != SignatureEncodingHex && s.Encoding != SignatureEncodingBase64 {
+		return fmt.Errorf("%w: unknown signature.encoding %q", ErrInvalidCallback, s.Encoding)
+	}
+	if s.SecretField == "" {
+		s.SecretField = DefaultCallbackSecretField
+	}
+	return nil
+}
+
+// CallbackReceiver hands out a unique URL and stores the callbacks sent to
+// it until it expires
+type CallbackReceiver struct {
+	ID            uuid.UUID          `json:"id"`
+	Token         string             `json:"-"`
+	URL           string             `json:"url"`
+	UserID        *uuid.UUID         `json:"user_id,omitempty"`
+	ExecutionID   *uuid.UUID         `json:"execution_id,omitempty"`
+	EnvironmentID *uuid.UUID         `json:"environment_id,omitempty"`
+	Signature     *CallbackSignature `json:"signature,omitempty"`
+	ReceivedCount int                `json:"received_count"`
+	Callbacks     []ReceivedCallback `json:"callbacks,omitempty"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+// Expired reports whether the receiver no longer accepts callbacks
+func (r *CallbackReceiver) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+// ReceivedCallback is one request sent to a receiver. SignatureValid is
+// unset when the receiver checks no signature.
+type ReceivedCallback struct {
+	ID             uuid.UUID           `json:"id"`
+	ReceiverID     uuid.UUID           `json:"receiver_id"`
+	Method         string              `json:"method"`
+	Query          map[string][]string `json:"query,omitempty"`
+	Headers        map[string][]string `json:"headers"`
+	Body           interface{}         `json:"body,omitempty"`
+	Truncated      bool                `json:"truncated,omitempty"`
+	SignatureValid *bool               `json:"signature_valid,omitempty"`
+	SignatureError string              `json:"signature_error,omitempty"`
+	RemoteAddr     string              `json:"remote_addr,omitempty"`
+	ReceivedAt     time.Time           `json:"received_at"`
+}
+
+// CallbackAssertion checks the value at a JSONPath in a callback body,
+// with the operators of poll conditions
+type CallbackAssertion struct {
+	Path     string      `json:"path"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value,omitempty"`
+}
+
+// Op returns the operator, defaulting to equals
+func (a CallbackAssertion) Op() string {
+	if a.Operator == "" {
+		return PollOpEquals
+	}
+	return strings.ToLower(a.Operator)
+}
+
+// CallbackWait waits for a callback that has a valid signature, if required,
+// and passes all assertions
+type CallbackWait struct {
+	TimeoutSeconds        int                 `json:"timeout_seconds,omitempty"`
+	Assertions            []CallbackAssertion `json:"assertions,omitempty"`
+	RequireValidSignature bool                `json:"require_valid_signature,omitempty"`
+}
+
+// Validate checks the wait and fills in the default timeout
+func (w *CallbackWait) Validate() error {
+	if w.TimeoutSeconds == 0 {
+		w.TimeoutSeconds = DefaultCallbackWaitSeconds
+	}
+	if w.TimeoutSeconds < 0 || w.TimeoutSeconds > MaxCallbackWaitSeconds {
+		return fmt.Errorf("%w: timeout_seconds must be between 1 and %d", ErrInvalidCallback, MaxCallbackWaitSeconds)
+	}
+	for i, assertion := range w.Assertions {
+		if !strings.HasPrefix(assertion.Path, "$") {
+			return fmt.Errorf("%w: assertions[%d].path must be a JSONPath starting with $", ErrInvalidCallback, i)
+		}
+		switch assertion.Op() {
+		case PollOpEquals, PollOpNotEquals, PollOpExists:
+		case PollOpIn:
+			if _, ok := assertion.Value.([]interface{}); !ok {
+				return fmt.Errorf("%w: assertions[%d].value must be a list for operator in", ErrInvalidCallback, i)
+			}
+		default:
+			return fmt.Errorf("%w: unknown assertions[%d].operator %q", ErrInvalidCallback, i, assertion.Operator)
+		}
+	}
+	return nil
+}
+
+// CallbackWaitResult is the outcome of a wait. Matched is false when no
+// callback qualified within the timeout; Callback and Assertions then
+// describe the latest callback, if any arrived.
+type CallbackWaitResult struct {
+	Matched       bool                      `json:"matched"`
+	Callback      *ReceivedCallback         `json:"callback,omitempty"`
+	Assertions    []CallbackAssertionResult `json:"assertions,omitempty"`
+	ReceivedCount int                       `json:"received_count"`
+	WaitedMs      int64                     `json:"waited_ms"`
+}
+
+// CallbackAssertionResult is one assertion evaluated against a callback
+type CallbackAssertionResult struct {
+	CallbackAssertion
+	Observed interface{} `json:"observed,omitempty"`
+	Passed   bool        `json:"passed"`
+}
@@ -0,0 +1,152 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VCR modes. Record sends the request and appends the exchange to the
+// cassette; replay answers from the cassette without contacting the target.
+const (
+	VCRModeRecord = "record"
+	VCRModeReplay = "replay"
+)
+
+// MaxCassetteNameLength bounds cassette names, which identify cassettes in requests
+const MaxCassetteNameLength = 255
+
+// ErrorTypeCassetteMiss marks responses of strict replays without a
+// matching interaction
+const ErrorTypeCassetteMiss = "cassette_miss"
+
+var (
+	ErrCassetteNotFound = errors.New("cassette not found")
+	ErrCassetteExists   = errors.New("a cassette with this name already exists")
+	ErrCassetteMiss     = errors.New("no recorded interaction matches the request")
+	ErrInvalidCassette  = errors.New("invalid cassette")
+)
+
+// VCROptions records a request into, or replays it from, a named cassette
+type VCROptions struct {
+	Cassette string `json:"cassette"`
+	Mode     string `json:"mode"`
+}
+
+// Validate checks the options
+func (o *VCROptions) Validate() error {
+	o.Cassette = strings.TrimSpace(o.Cassette)
+	if o.Cassette == "" || len(o.Cassette) > MaxCassetteNameLength {
+		return fmt.Errorf("%w: vcr.cassette must be 1 to %d characters", ErrInvalidCassette, MaxCassetteNameLength)
+	}
+	o.Mode = strings.ToLower(strings.TrimSpace(o.Mode))
+	switch o.Mode {
+	case VCRModeRecord, VCRModeReplay:
+		return nil
+	}
+	return fmt.Errorf("%w: vcr.mode must be %q or %q", ErrInvalidCassette, VCRModeRecord, VCRModeReplay)
+}
+
+// Records reports whether the request is recorded into a cassette
+func (o *VCROptions) Records() bool {
+	return o != nil && o.Mode == VCRModeRecord
+}
+
+// Replays reports whether the request is answered from a cassette
+func (o *VCROptions) Replays() bool {
+	return o != nil && o.Mode == VCRModeReplay
+}
+
+// CassetteMatching selects what a recorded request must share with a
+// request to answer it. The method always has to match.
+type CassetteMatching struct {
+	Host bool `json:"host"`
+	Path bool `json:"path"`
+	// Query compares query parameters regardless of their order
+	Query bool `json:"query"`
+	// Body compares bodies after normalization: JSON is compared by value
+	// and form fields regardless of their order
+	Body bool `json:"body"`
+	// Headers are compared case-insensitively by name
+	Headers []string `json:"headers,omitempty"`
+}
+
+// DefaultCassetteMatching ignores the host, so a cassette recorded against
+// one environment replays against any other
+var DefaultCassetteMatching = CassetteMatching{Path: true, Query: true, Body: true}
+
+// Cassette is a named set of recorded request/response pairs. In strict
+// mode a replayed request without a matching interaction fails; otherwise
+// it is sent to the target.
+type Cassette struct {
+	ID               uuid.UUID             `json:"id"`
+	Name             string                `json:"name"`
+	Matching         *CassetteMatching     `json:"matching"`
+	Strict           bool                  `json:"strict"`
+	InteractionCount int                   `json:"interaction_count"`
+	Interactions     []CassetteInteraction `json:"interactions,omitempty"`
+	CreatedBy        *uuid.UUID            `json:"created_by,omitempty"`
+	CreatedAt        time.Time             `json:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at"`
+}
+
+// Validate checks the cassette and fills in the default matching
+func (c *Cassette) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || len(c.Name) > MaxCassetteNameLength {
+		return fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidCassette, MaxCassetteNameLength)
+	}
+	if c.Matching == nil {
+		matching := DefaultCassetteMatching
+		c.Matching = &matching
+	}
+	for i, header := range c.Matching.Headers {
+		c.Matching.Headers[i] = strings.TrimSpace(header)
+		if c.Matching.Headers[i] == "" || strings.ContainsAny(c.Matching.Headers[i], " :\t\r\n") {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidCassette, header)
+		}
+	}
+	return nil
+}
+
+// CassetteInteraction is one recorded exchange. Interactions are replayed
+// in Sequence order.
+type CassetteInteraction struct {
+	ID         uuid.UUID        `json:"id"`
+	CassetteID uuid.UUID        `json:"cassette_id"`
+	Sequence   int              `json:"sequence"`
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recorded_at"`
+}
+
+// RecordedRequest is a request as sent, before environment credentials were
+// added. URL includes the encoded query; Body is normalized for matching.
+type RecordedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response as the execution returned it
+type RecordedResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       interface{}         `json:"body,omitempty"`
+	BinaryBody *BinaryBody         `json:"binary_body,omitempty"`
+	Truncated  bool                `json:"truncated,omitempty"`
+	GRPC       *GRPCResult         `json:"grpc,omitempty"`
+}
+
+// VCRResult reports how a response relates to a cassette. InteractionID is
+// the interaction that was recorded or replayed; it is unset when recording
+// failed or a replay found no match and was sent to the target.
+type VCRResult struct {
+	Cassette      string     `json:"cassette"`
+	Mode          string     `json:"mode"`
+	InteractionID *uuid.UUID `json:"interaction_id,omitempty"`
+}
@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/shared/egress"
+)
+
+// EgressPolicyKey is the system_config key holding the egress policy
+const EgressPolicyKey = "egress_policy"
+
+// ErrEgressBlocked is returned when the egress policy forbids a call
+var ErrEgressBlocked = egress.ErrBlocked
+
+// ErrorTypeEgressBlocked marks responses of calls the egress policy refused
+const ErrorTypeEgressBlocked = "egress_blocked"
+
+// EgressPolicy controls where API calls may go. Loopback, private,
+// link-local and other non-public addresses are always refused unless they
+// fall in an allowed CIDR; on top of that, host allowlists can restrict
+// calls to known hosts.
+type EgressPolicy struct {
+	// AllowedHosts restricts every call to these hosts; empty allows any
+	// public host. "*.example.com" matches subdomains of example.com.
+	AllowedHosts []string `json:"allowed_hosts"`
+	// AllowedCIDRs are non-public ranges calls may reach, e.g. 10.20.0.0/16
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	// Environments adds hosts and ranges for calls to one environment,
+	// keyed by environment ID
+	Environments map[string]EnvironmentEgress `json:"environments,omitempty"`
+	UpdatedAt    time.Time                    `json:"updated_at"`
+}
+
+// EnvironmentEgress are the extra hosts and ranges of one environment. An
+// environment with hosts of its own is restricted to those and the global
+// ones, even if the global list is empty.
+type EnvironmentEgress struct {
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"`
+}
+
+// Validate checks the policy and normalizes host patterns
+func (p *EgressPolicy) Validate() error {
+	var err error
+	if p.AllowedHosts, err = normalizeHostPatterns(p.AllowedHosts); err != nil {
+		return err
+	}
+	if err := validateCIDRs(p.AllowedCIDRs); err != nil {
+		return err
+	}
+	for id, env := range p.Environments {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("invalid environment ID %q", id)
+		}
+		if env.AllowedHosts, err = normalizeHostPatterns(env.AllowedHosts); err != nil {
+			return err
+		}
+		if err := validateCIDRs(env.AllowedCIDRs); err != nil {
+			return err
+		}
+		p.Environments[id] = env
+	}
+	return nil
+}
+
+// ForEnvironment returns the hosts and ranges that apply to calls to env
+// (nil for calls without an environment)
+func (p *EgressPolicy) ForEnvironment(env *Environment) (hosts, cidrs []string) {
+	hosts = append(hosts, p.AllowedHosts...)
+	cidrs = append(cidrs, p.AllowedCIDRs...)
+	if env == nil {
+		return hosts, cidrs
+	}
+	if extra, ok := p.Environments[env.ID.String()]; ok {
+		hosts = append(hosts, extra.AllowedHosts...)
+		cidrs = append(cidrs, extra.AllowedCIDRs...)
+	}
+	return hosts, cidrs
+}
+
+func normalizeHostPatterns(patterns []string) ([]string, error) {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		host := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pattern), "."))
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		name := strings.TrimPrefix(host, "*.")
+		if name == "" || (strings.ContainsAny(name, "*/:@ ") && !isIPv6Literal(name)) {
+			return nil, fmt.Errorf("invalid host pattern %q: use a host name, an IP or *.domain", pattern)
+		}
+		normalized = append(normalized, host)
+	}
+	return normalized, nil
+}
+
+func isIPv6Literal(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	return err == nil && addr.Is6()
+}
+
+func validateCIDRs(cidrs []string) error {
+	for _, cidr := range cidrs {
+		if _, err := netip.ParsePrefix(strings.TrimSpace(cidr)); err != nil {
+			return fmt.Errorf("invalid CIDR %q", cidr)
+		}
+	}
+	return nil
+}
+
+// EgressBlockedError reports a call the egress policy refused: a host that
+// is not allowed, or a host resolving to a non-public address
+type EgressBlockedError = egress.BlockedError
@@ -5,9 +5,14 @@ import "errors"
 var (
 	ErrInvalidMethod      = errors.New("invalid HTTP method")
 	ErrInvalidURL         = errors.New("invalid URL")
+	ErrInvalidBody        = errors.New("invalid request body")
+	ErrInvalidPolicy      = errors.New("invalid retry or poll policy")
 	ErrInvalidEnvironment = errors.New("invalid environment")
 	ErrExecutionFailed    = errors.New("execution failed")
+	ErrExecutionNotFound  = errors.New("execution not found")
 	ErrTimeout            = errors.New("request timeout")
 	ErrEnvironmentNotFound = errors.New("environment not found")
+	ErrSecretsUnavailable  = errors.New("secrets store is not configured")
+	ErrUnknownSecretKey    = errors.New("secret is encrypted with an unknown master key")
 )
 
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"errors"
+	"time"
+)
+
+// ExecutionLimitsKey is the system_config key holding the execution limits
+const ExecutionLimitsKey = "execution_limits"
+
+// ExecutionLimits are the admin-configurable bounds applied to every API call
+type ExecutionLimits struct {
+	// DefaultTimeoutSeconds applies when a request does not set a timeout
+	DefaultTimeoutSeconds int `json:"default_timeout_seconds"`
+	// MaxTimeoutSeconds caps the timeout a request may ask for
+	MaxTimeoutSeconds int `json:"max_timeout_seconds"`
+	// MaxResponseBytes caps how much of a response body is read and stored
+	MaxResponseBytes int64 `json:"max_response_bytes"`
+}
+
+// Validate checks that the limits are usable
+func (l ExecutionLimits) Validate() error {
+	if l.DefaultTimeoutSeconds <= 0 || l.MaxTimeoutSeconds <= 0 {
+		return errors.New("timeouts must be positive")
+	}
+	if l.DefaultTimeoutSeconds > l.MaxTimeoutSeconds {
+		return errors.New("default_timeout_seconds must not exceed max_timeout_seconds")
+	}
+	if l.MaxResponseBytes <= 0 {
+		return errors.New("max_response_bytes must be positive")
+	}
+	return nil
+}
+
+// Timeout returns the timeout for a request asking for requested seconds,
+// using the default when unset and capping it at the maximum
+func (l ExecutionLimits) Timeout(requested int) time.Duration {
+	seconds := requested
+	if seconds <= 0 {
+		seconds = l.DefaultTimeoutSeconds
+	}
+	if seconds > l.MaxTimeoutSeconds {
+		seconds = l.MaxTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,17 @@
+package entities
+
+// ExecutionTiming breaks an API call's duration down into HTTP phases.
+// Durations are in milliseconds; phases that did not happen (DNS, connect
+// and TLS on a reused connection) are zero.
+type ExecutionTiming struct {
+	DNSMs      float64 `json:"dns_ms"`
+	ConnectMs  float64 `json:"connect_ms"`
+	TLSMs      float64 `json:"tls_ms"`
+	TTFBMs     float64 `json:"ttfb_ms"`     // request written until first response byte
+	TransferMs float64 `json:"transfer_ms"` // first response byte until body read
+	TotalMs    float64 `json:"total_ms"`
+
+	RemoteAddr       string `json:"remote_addr,omitempty"`
+	Protocol         string `json:"protocol,omitempty"`
+	ConnectionReused bool   `json:"connection_reused"`
+}
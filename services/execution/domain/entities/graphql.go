@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorTypeGraphQL marks responses of GraphQL calls whose body reports
+// errors, which GraphQL servers usually send with status 200
+const ErrorTypeGraphQL = "graphql_errors"
+
+// validateGraphQL checks that a GraphQL request is a POST of an operation:
+// a query document with optional variables and operation name
+func (r *APIRequest) validateGraphQL() error {
+	if r.Method != "POST" {
+		return fmt.Errorf("%w: graphql requests must be sent as POST", ErrInvalidBody)
+	}
+	body, ok := r.Body.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: graphql body must be an object with a query", ErrInvalidBody)
+	}
+	if query, _ := body["query"].(string); strings.TrimSpace(query) == "" {
+		return fmt.Errorf("%w: graphql body has no query", ErrInvalidBody)
+	}
+	switch body["variables"].(type) {
+	case nil, map[string]interface{}:
+	default:
+		return fmt.Errorf("%w: graphql variables must be an object", ErrInvalidBody)
+	}
+	switch body["operationName"].(type) {
+	case nil, string:
+	default:
+		return fmt.Errorf("%w: graphql operationName must be a string", ErrInvalidBody)
+	}
+	if len(r.Files) > 0 {
+		return fmt.Errorf("%w: graphql requests cannot send files", ErrInvalidBody)
+	}
+	return nil
+}
+
+// GraphQLErrors returns the messages of the errors a GraphQL response body
+// reports, with their paths when given. Partial data alongside errors does
+// not make the call a success.
+func GraphQLErrors(body interface{}) []string {
+	object, ok := body.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, ok := object["errors"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	messages := make([]string, 0, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			messages = append(messages, fmt.Sprintf("%v", item))
+			continue
+		}
+		message, _ := entry["message"].(string)
+		if message == "" {
+			message = "unknown error"
+		}
+		if path, ok := entry["path"].([]interface{}); ok && len(path) > 0 {
+			segments := make([]string, len(path))
+			for i, segment := range path {
+				segments[i] = fmt.Sprintf("%v", segment)
+			}
+			message = strings.Join(segments, ".") + ": " + message
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
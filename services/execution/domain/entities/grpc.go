@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrGRPCDescriptorsNotFound is returned when a gRPC call has no inline
+// descriptors and none were ingested for its API spec
+var ErrGRPCDescriptorsNotFound = errors.New("gRPC descriptors not found")
+
+// ErrorTypeGRPC marks responses of gRPC calls that ended with a status
+// other than OK
+const ErrorTypeGRPC = "grpc_status"
+
+// GRPCOptions make a request a unary gRPC call. The body is the request
+// message in protobuf JSON, headers are sent as metadata and the URL is the
+// server address: grpc://host:port for plaintext, grpcs://host:port (or
+// https://) for TLS.
+type GRPCOptions struct {
+	// Method is the full method name, package.Service/Method
+	Method string `json:"method"`
+	// DescriptorSet is a base64 serialized FileDescriptorSet holding the
+	// method. Without it the descriptors ingested for the request's
+	// api_spec_id are used.
+	DescriptorSet string `json:"descriptor_set,omitempty"`
+}
+
+// GRPCResult is the status and metadata a gRPC call ended with
+type GRPCResult struct {
+	Code    int                 `json:"code"`
+	Status  string              `json:"status"` // e.g. NOT_FOUND
+	Message string              `json:"message,omitempty"`
+	Details []interface{}       `json:"details,omitempty"`
+	Header  map[string][]string `json:"header,omitempty"`
+	Trailer map[string][]string `json:"trailer,omitempty"`
+}
+
+// OK reports whether the call succeeded
+func (r *GRPCResult) OK() bool {
+	return r.Status == "OK"
+}
+
+// Failure describes a status other than OK as a response error
+func (r *GRPCResult) Failure() string {
+	return fmt.Sprintf("grpc status %s: %s", r.Status, r.Message)
+}
+
+// ServiceMethod splits the full method name into service and method
+func (o *GRPCOptions) ServiceMethod() (string, string) {
+	service, method, _ := strings.Cut(strings.TrimPrefix(o.Method, "/"), "/")
+	return service, method
+}
+
+// validateGRPC checks a gRPC call: a POST (as on the wire) of an object
+// body to a server address, with a method named service/method
+func (r *APIRequest) validateGRPC() error {
+	if r.Method != "POST" {
+		return fmt.Errorf("%w: grpc calls must use method POST", ErrInvalidMethod)
+	}
+	if service, method := r.GRPC.ServiceMethod(); service == "" || method == "" || strings.Contains(method, "/") {
+		return fmt.Errorf("%w: grpc method must be package.Service/Method", ErrInvalidBody)
+	}
+	if _, _, err := GRPCTarget(r.URL); err != nil {
+		return err
+	}
+	if r.GRPC.DescriptorSet != "" {
+		if _, err := base64.StdEncoding.DecodeString(r.GRPC.DescriptorSet); err != nil {
+			return fmt.Errorf("%w: grpc descriptor_set is not valid base64", ErrInvalidBody)
+		}
+	} else if r.APISpecID == nil {
+		return fmt.Errorf("%w: grpc calls need a descriptor_set or an api_spec_id with ingested descriptors", ErrInvalidBody)
+	}
+	switch r.Body.(type) {
+	case nil, map[string]interface{}:
+	default:
+		return fmt.Errorf("%w: grpc body must be the request message as a JSON object", ErrInvalidBody)
+	}
+	if len(r.Files) > 0 || len(r.QueryParams) > 0 {
+		return fmt.Errorf("%w: grpc calls cannot send files or query parameters", ErrInvalidBody)
+	}
+	return nil
+}
+
+// GRPCTarget returns the host:port to dial for a gRPC URL and whether the
+// connection uses TLS. Without a port, TLS targets use 443 and plaintext
+// ones 80.
+func GRPCTarget(rawURL string) (string, bool, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "grpc://" + rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "", false, fmt.Errorf("%w: grpc URL must be grpc://host:port or grpcs://host:port", ErrInvalidURL)
+	}
+
+	var secure bool
+	switch strings.ToLower(parsed.Scheme) {
+	case "grpc", "http":
+	case "grpcs", "https":
+		secure = true
+	default:
+		return "", false, fmt.Errorf("%w: unsupported grpc URL scheme %q", ErrInvalidURL, parsed.Scheme)
+	}
+	if parsed.Path != "" && parsed.Path != "/" {
+		return "", false, fmt.Errorf("%w: grpc URL must not have a path; set grpc.method instead", ErrInvalidURL)
+	}
+
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if secure {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(parsed.Hostname(), port), secure, nil
+}
@@ -0,0 +1,111 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Idempotency defaults, used for API specs without stored settings
+const (
+	DefaultIdempotencyHeader = "Idempotency-Key"
+	MaxDuplicateWindow       = 24 * time.Hour
+)
+
+// DefaultIdempotencyMethods are the methods guarded when settings list none
+var DefaultIdempotencyMethods = []string{"POST", "PATCH"}
+
+// ErrDuplicateExecution is returned when the same request was just executed
+var ErrDuplicateExecution = errors.New("duplicate execution")
+
+// IdempotencySettings control idempotency keys and duplicate protection for
+// the requests of one API spec
+type IdempotencySettings struct {
+	APISpecID uuid.UUID `json:"api_spec_id"`
+	// GenerateKeys attaches a fresh key in HeaderName to guarded requests
+	// that do not carry one
+	GenerateKeys bool   `json:"generate_keys"`
+	HeaderName   string `json:"header_name"`
+	// Methods are the guarded methods
+	Methods []string `json:"methods"`
+	// DuplicateWindowSeconds rejects a guarded request identical to one the
+	// same user executed this recently; 0 turns the check off
+	DuplicateWindowSeconds int       `json:"duplicate_window_seconds"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// Validate checks the settings and fills in defaults
+func (s *IdempotencySettings) Validate() error {
+	s.HeaderName = strings.TrimSpace(s.HeaderName)
+	if s.HeaderName == "" {
+		s.HeaderName = DefaultIdempotencyHeader
+	}
+	if strings.ContainsAny(s.HeaderName, " :\t\r\n") {
+		return fmt.Errorf("invalid header_name %q", s.HeaderName)
+	}
+
+	if len(s.Methods) == 0 {
+		s.Methods = append([]string(nil), DefaultIdempotencyMethods...)
+	}
+	for i, method := range s.Methods {
+		s.Methods[i] = strings.ToUpper(strings.TrimSpace(method))
+		switch s.Methods[i] {
+		case "GET", "POST", "PUT", "PATCH", "DELETE":
+		default:
+			return fmt.Errorf("invalid method %q", method)
+		}
+	}
+
+	if s.DuplicateWindowSeconds < 0 || time.Duration(s.DuplicateWindowSeconds)*time.Second > MaxDuplicateWindow {
+		return fmt.Errorf("duplicate_window_seconds must be between 0 and %d", int(MaxDuplicateWindow.Seconds()))
+	}
+	return nil
+}
+
+// Guards reports whether requests with this method are guarded
+func (s *IdempotencySettings) Guards(method string) bool {
+	for _, m := range s.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// DuplicateWindow returns how far back identical requests are looked for
+func (s *IdempotencySettings) DuplicateWindow() time.Duration {
+	return time.Duration(s.DuplicateWindowSeconds) * time.Second
+}
+
+// DuplicateExecutionError reports a re-submitted request. ExecutionID is the
+// earlier execution, or uuid.Nil if it is still running.
+type DuplicateExecutionError struct {
+	ExecutionID uuid.UUID
+	Window      time.Duration
+}
+
+func (e *DuplicateExecutionError) Error() string {
+	if e.ExecutionID == uuid.Nil {
+		return fmt.Sprintf("%v: an identical request is still running", ErrDuplicateExecution)
+	}
+	return fmt.Sprintf("%v: an identical request was executed within the last %s as %s; set allow_duplicate to send it again",
+		ErrDuplicateExecution, e.Window, e.ExecutionID)
+}
+
+func (e *DuplicateExecutionError) Unwrap() error {
+	return ErrDuplicateExecution
+}
+
+// ReplayResult is a stored request executed again with the same idempotency
+// key, compared with the original execution
+type ReplayResult struct {
+	OriginalExecutionID uuid.UUID    `json:"original_execution_id"`
+	IdempotencyKey      string       `json:"idempotency_key,omitempty"`
+	OriginalStatusCode  int          `json:"original_status_code"`
+	StatusMatches       bool         `json:"status_matches"`
+	BodyMatches         bool         `json:"body_matches"`
+	Response            *APIResponse `json:"response"`
+}
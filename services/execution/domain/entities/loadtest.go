@@ -0,0 +1,248 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Load generation modes: a target rate of scenario iterations, or a fixed
+// number of virtual users each running the scenario back to back
+const (
+	LoadModeRPS         = "rps"
+	LoadModeConcurrency = "concurrency"
+)
+
+// Load test run statuses
+const (
+	LoadTestStatusRunning     = "running"
+	LoadTestStatusCompleted   = "completed"
+	LoadTestStatusStopped     = "stopped"
+	LoadTestStatusFailed      = "failed"
+	LoadTestStatusInterrupted = "interrupted"
+)
+
+// Bounds for load tests. MaxLoadConcurrency also caps the requests in
+// flight in rps mode; MaxActiveLoadTests is per service instance.
+const (
+	MaxLoadRPS             = 1000
+	MaxLoadConcurrency     = 200
+	MaxLoadDurationSeconds = 1800
+	MaxLoadSteps           = 20
+	MaxActiveLoadTests     = 5
+)
+
+// LoadStatusError is the status code bucket of requests that got no response
+const LoadStatusError = "error"
+
+var (
+	ErrLoadTestNotFound   = errors.New("load test not found")
+	ErrInvalidLoadTest    = errors.New("invalid load test")
+	ErrLoadTestCapacity   = errors.New("too many load tests running")
+	ErrLoadTestNotRunning = errors.New("load test is not running")
+)
+
+// LoadTestConfig describes a load test: a single request or a scenario of
+// steps run in order, generated at a target rate or concurrency for a
+// duration, and the SLO thresholds the run must meet
+type LoadTestConfig struct {
+	Name            string          `json:"name,omitempty"`
+	EnvironmentID   *uuid.UUID      `json:"environment_id,omitempty"`
+	Request         *APIRequest     `json:"request,omitempty"`
+	Scenario        []LoadStep      `json:"scenario,omitempty"`
+	Mode            string          `json:"mode"`
+	TargetRPS       float64         `json:"target_rps,omitempty"`
+	Concurrency     int             `json:"concurrency,omitempty"`
+	DurationSeconds int             `json:"duration_seconds"`
+	RampUpSeconds   int             `json:"ramp_up_seconds,omitempty"`
+	Thresholds      *LoadThresholds `json:"thresholds,omitempty"`
+}
+
+// LoadStep is one request of a load test scenario
+type LoadStep struct {
+	Name    string     `json:"name,omitempty"`
+	Request APIRequest `json:"request"`
+}
+
+// Validate checks the config, turns a single request into a one-step
+// scenario and takes the environment from the steps if none is set
+func (c *LoadTestConfig) Validate() error {
+	if c.Request != nil {
+		if len(c.Scenario) > 0 {
+			return fmt.Errorf("%w: set either request or scenario", ErrInvalidLoadTest)
+		}
+		c.Scenario = []LoadStep{{Name: "request", Request: *c.Request}}
+		c.Request = nil
+	}
+	if len(c.Scenario) == 0 {
+		return fmt.Errorf("%w: request or scenario is required", ErrInvalidLoadTest)
+	}
+	if len(c.Scenario) > MaxLoadSteps {
+		return fmt.Errorf("%w: a scenario has at most %d steps", ErrInvalidLoadTest, MaxLoadSteps)
+	}
+
+	for i := range c.Scenario {
+		step := &c.Scenario[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		request := &step.Request
+		if err := request.Validate(); err != nil {
+			return fmt.Errorf("%w: scenario[%d]: %v", ErrInvalidLoadTest, i, err)
+		}
+		if request.Retry != nil || request.PollUntil != nil || request.VCR != nil || request.Callback != nil {
+			return fmt.Errorf("%w: scenario[%d]: retry, poll_until, vcr and callback are not supported in load tests", ErrInvalidLoadTest, i)
+		}
+		if request.EnvironmentID != nil {
+			if c.EnvironmentID == nil {
+				c.EnvironmentID = request.EnvironmentID
+			}
+			if *request.EnvironmentID != *c.EnvironmentID {
+				return fmt.Errorf("%w: all steps must use the load test's environment", ErrInvalidLoadTest)
+			}
+		}
+	}
+
+	c.Mode = strings.ToLower(c.Mode)
+	switch c.Mode {
+	case LoadModeRPS:
+		if c.TargetRPS <= 0 || c.TargetRPS > MaxLoadRPS {
+			return fmt.Errorf("%w: target_rps must be above 0 and at most %d", ErrInvalidLoadTest, MaxLoadRPS)
+		}
+		// In rps mode concurrency caps the iterations in flight
+		if c.Concurrency == 0 {
+			c.Concurrency = MaxLoadConcurrency
+		}
+	case LoadModeConcurrency:
+		if c.TargetRPS != 0 {
+			return fmt.Errorf("%w: target_rps only applies to rps mode", ErrInvalidLoadTest)
+		}
+	default:
+		return fmt.Errorf("%w: mode must be %s or %s", ErrInvalidLoadTest, LoadModeRPS, LoadModeConcurrency)
+	}
+	if c.Concurrency < 1 || c.Concurrency > MaxLoadConcurrency {
+		return fmt.Errorf("%w: concurrency must be between 1 and %d", ErrInvalidLoadTest, MaxLoadConcurrency)
+	}
+	if c.DurationSeconds < 1 || c.DurationSeconds > MaxLoadDurationSeconds {
+		return fmt.Errorf("%w: duration_seconds must be between 1 and %d", ErrInvalidLoadTest, MaxLoadDurationSeconds)
+	}
+	if c.RampUpSeconds < 0 || c.RampUpSeconds > c.DurationSeconds {
+		return fmt.Errorf("%w: ramp_up_seconds must be between 0 and duration_seconds", ErrInvalidLoadTest)
+	}
+	return c.Thresholds.Validate()
+}
+
+// Duration returns how long load is generated
+func (c *LoadTestConfig) Duration() time.Duration {
+	return time.Duration(c.DurationSeconds) * time.Second
+}
+
+// RampUp returns how long the load takes to reach its target
+func (c *LoadTestConfig) RampUp() time.Duration {
+	return time.Duration(c.RampUpSeconds) * time.Second
+}
+
+// LoadThresholds are the SLOs a run must meet to pass. Unset thresholds
+// are not checked; latencies are in milliseconds and the error rate is a
+// fraction of requests.
+type LoadThresholds struct {
+	P50Ms         *float64 `json:"p50_ms,omitempty"`
+	P95Ms         *float64 `json:"p95_ms,omitempty"`
+	P99Ms         *float64 `json:"p99_ms,omitempty"`
+	MaxErrorRate  *float64 `json:"max_error_rate,omitempty"`
+	MinThroughput *float64 `json:"min_throughput_rps,omitempty"`
+}
+
+// Validate checks the thresholds
+func (t *LoadThresholds) Validate() error {
+	if t == nil {
+		return nil
+	}
+	for name, value := range map[string]*float64{
+		"p50_ms":             t.P50Ms,
+		"p95_ms":             t.P95Ms,
+		"p99_ms":             t.P99Ms,
+		"min_throughput_rps": t.MinThroughput,
+	} {
+		if value != nil && *value <= 0 {
+			return fmt.Errorf("%w: thresholds.%s must be positive", ErrInvalidLoadTest, name)
+		}
+	}
+	if t.MaxErrorRate != nil && (*t.MaxErrorRate < 0 || *t.MaxErrorRate > 1) {
+		return fmt.Errorf("%w: thresholds.max_error_rate must be between 0 and 1", ErrInvalidLoadTest)
+	}
+	return nil
+}
+
+// LoadTestRun is one run of a load test, stored as a single aggregated
+// record. Stats are updated while the run is in progress.
+type LoadTestRun struct {
+	ID         uuid.UUID         `json:"id"`
+	UserID     *uuid.UUID        `json:"user_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Config     LoadTestConfig    `json:"config"`
+	Status     string            `json:"status"`
+	Stats      *LoadStats        `json:"stats,omitempty"`
+	Thresholds []ThresholdResult `json:"thresholds,omitempty"`
+	Passed     *bool             `json:"passed,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// LoadStats aggregates the requests of a run. A request counts as an error
+// when it got no response or a status outside 2xx; StatusCodes counts the
+// former under LoadStatusError.
+type LoadStats struct {
+	Iterations        int64             `json:"iterations"`
+	DroppedIterations int64             `json:"dropped_iterations,omitempty"`
+	Requests          int64             `json:"requests"`
+	Errors            int64             `json:"errors"`
+	ErrorRate         float64           `json:"error_rate"`
+	ThroughputRPS     float64           `json:"throughput_rps"`
+	ElapsedMs         int64             `json:"elapsed_ms"`
+	Latency           LatencySummary    `json:"latency"`
+	Histogram         []HistogramBucket `json:"histogram,omitempty"`
+	StatusCodes       map[string]int64  `json:"status_codes"`
+	ErrorTypes        map[string]int64  `json:"error_types,omitempty"`
+	Steps             []LoadStepStats   `json:"steps,omitempty"`
+}
+
+// LatencySummary summarizes request latencies in milliseconds. Percentiles
+// come from a histogram and are accurate to about 3%.
+type LatencySummary struct {
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// HistogramBucket counts the requests that took more than the previous
+// bucket's bound and at most UpToMs
+type HistogramBucket struct {
+	UpToMs float64 `json:"le_ms"`
+	Count  int64   `json:"count"`
+}
+
+// LoadStepStats aggregates the requests of one scenario step
+type LoadStepStats struct {
+	Name        string           `json:"name"`
+	Requests    int64            `json:"requests"`
+	Errors      int64            `json:"errors"`
+	Latency     LatencySummary   `json:"latency"`
+	StatusCodes map[string]int64 `json:"status_codes"`
+}
+
+// ThresholdResult is one SLO threshold checked against a run
+type ThresholdResult struct {
+	Metric   string  `json:"metric"`
+	Limit    float64 `json:"limit"`
+	Observed float64 `json:"observed"`
+	Passed   bool    `json:"passed"`
+}
@@ -0,0 +1,144 @@
+package entities
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported OAuth2 grant types
+const (
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypePassword          = "password"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// How the client authenticates to the token endpoint
+const (
+	ClientAuthBasic = "basic"
+	ClientAuthBody  = "body"
+)
+
+// OAuth2Config describes how to obtain an access token for an environment.
+// It is read from an auth_config with "type": "oauth2".
+type OAuth2Config struct {
+	GrantType    string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+	Scope        string
+	Audience     string
+	ClientAuth   string
+}
+
+// ParseOAuth2Config reads and validates an OAuth2 auth config
+func ParseOAuth2Config(authConfig map[string]interface{}) (*OAuth2Config, error) {
+	str := func(key string) string {
+		value, _ := authConfig[key].(string)
+		return strings.TrimSpace(value)
+	}
+
+	cfg := &OAuth2Config{
+		GrantType:    strings.ToLower(str("grant_type")),
+		TokenURL:     str("token_url"),
+		ClientID:     str("client_id"),
+		ClientSecret: str("client_secret"),
+		Username:     str("username"),
+		Password:     str("password"),
+		Audience:     str("audience"),
+		ClientAuth:   strings.ToLower(str("client_auth")),
+	}
+
+	// scope may be a space-separated string or a list
+	switch scope := authConfig["scope"].(type) {
+	case string:
+		cfg.Scope = strings.TrimSpace(scope)
+	case []interface{}:
+		scopes := make([]string, 0, len(scope))
+		for _, s := range scope {
+			if s, ok := s.(string); ok && s != "" {
+				scopes = append(scopes, s)
+			}
+		}
+		cfg.Scope = strings.Join(scopes, " ")
+	}
+
+	if cfg.GrantType == "" {
+		cfg.GrantType = GrantTypeClientCredentials
+	}
+	if cfg.ClientAuth == "" {
+		cfg.ClientAuth = ClientAuthBasic
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the config has what its grant type needs
+func (c *OAuth2Config) Validate() error {
+	if c.TokenURL == "" {
+		return fmt.Errorf("%w: oauth2 token_url is required", ErrInvalidEnvironment)
+	}
+	u, err := url.Parse(c.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: oauth2 token_url must be an http(s) URL", ErrInvalidEnvironment)
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("%w: oauth2 client_id is required", ErrInvalidEnvironment)
+	}
+
+	switch c.GrantType {
+	case GrantTypeClientCredentials:
+	case GrantTypePassword:
+		if c.Username == "" || c.Password == "" {
+			return fmt.Errorf("%w: oauth2 password grant requires username and password", ErrInvalidEnvironment)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported oauth2 grant_type %q", ErrInvalidEnvironment, c.GrantType)
+	}
+
+	switch c.ClientAuth {
+	case ClientAuthBasic, ClientAuthBody:
+	default:
+		return fmt.Errorf("%w: oauth2 client_auth must be %q or %q", ErrInvalidEnvironment, ClientAuthBasic, ClientAuthBody)
+	}
+	return nil
+}
+
+// OAuth2Token is an access token returned by a token endpoint
+type OAuth2Token struct {
+	AccessToken  string    `json:"-"`
+	RefreshToken string    `json:"-"`
+	TokenType    string    `json:"token_type"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	ObtainedAt   time.Time `json:"obtained_at"`
+}
+
+// Valid reports whether the token can still be used, treating it as expired
+// leeway before its actual expiry. Tokens without an expiry never expire.
+func (t *OAuth2Token) Valid(now time.Time, leeway time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	if t.ExpiresAt.IsZero() {
+		return true
+	}
+	return now.Add(leeway).Before(t.ExpiresAt)
+}
+
+// AuthorizationHeader returns the Authorization header value for the token
+func (t *OAuth2Token) AuthorizationHeader() string {
+	// Servers commonly return "bearer"; the header scheme is case-insensitive
+	// but some APIs only accept the canonical form.
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
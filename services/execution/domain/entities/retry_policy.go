@@ -0,0 +1,155 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bounds for retry and poll policies
+const (
+	MaxRetryAttempts    = 10
+	MinPollIntervalMs   = 250
+	DefaultPollInterval = time.Second
+)
+
+// DefaultRetryStatusCodes are retried when a policy does not list its own
+var DefaultRetryStatusCodes = []int{429, 502, 503, 504}
+
+// Poll condition operators
+const (
+	PollOpEquals    = "equals"
+	PollOpNotEquals = "not_equals"
+	PollOpExists    = "exists"
+	PollOpIn        = "in"
+)
+
+// RetryPolicy retries a request that fails with a network error or one of
+// StatusCodes, with exponential backoff and jitter between attempts. A
+// Retry-After header on the response takes precedence over the backoff.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt too
+	MaxAttempts    int   `json:"max_attempts"`
+	StatusCodes    []int `json:"status_codes,omitempty"`
+	InitialDelayMs int   `json:"initial_delay_ms,omitempty"`
+	MaxDelayMs     int   `json:"max_delay_ms,omitempty"`
+}
+
+// Validate checks the policy bounds
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 || p.MaxAttempts > MaxRetryAttempts {
+		return fmt.Errorf("%w: retry.max_attempts must be between 1 and %d", ErrInvalidPolicy, MaxRetryAttempts)
+	}
+	if p.InitialDelayMs < 0 || p.MaxDelayMs < 0 {
+		return fmt.Errorf("%w: retry delays must not be negative", ErrInvalidPolicy)
+	}
+	for _, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("%w: invalid retry status code %d", ErrInvalidPolicy, code)
+		}
+	}
+	return nil
+}
+
+// RetriesStatus reports whether a response with this status should be retried
+func (p *RetryPolicy) RetriesStatus(statusCode int) bool {
+	codes := p.StatusCodes
+	if len(codes) == 0 {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// InitialDelay is the backoff before the first retry (default 200ms)
+func (p *RetryPolicy) InitialDelay() time.Duration {
+	if p.InitialDelayMs == 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(p.InitialDelayMs) * time.Millisecond
+}
+
+// MaxDelay caps the backoff between attempts (default 5s)
+func (p *RetryPolicy) MaxDelay() time.Duration {
+	if p.MaxDelayMs == 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(p.MaxDelayMs) * time.Millisecond
+}
+
+// PollCondition repeats a request until the value at a JSONPath in the
+// response body satisfies the operator, or the deadline passes
+type PollCondition struct {
+	// Path is a JSONPath such as $.status or $.items[0].state
+	Path     string      `json:"path"`
+	Operator string      `json:"operator,omitempty"` // equals (default), not_equals, exists, in
+	Value    interface{} `json:"value,omitempty"`
+	// IntervalMs is the wait between attempts (default 1s)
+	IntervalMs int `json:"interval_ms,omitempty"`
+	// TimeoutSeconds bounds all attempts together; it defaults to and is
+	// capped at the maximum execution timeout
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// Validate checks the condition's operator and bounds
+func (c *PollCondition) Validate() error {
+	if !strings.HasPrefix(c.Path, "$") {
+		return fmt.Errorf("%w: poll_until.path must be a JSONPath starting with $", ErrInvalidPolicy)
+	}
+	switch c.Op() {
+	case PollOpEquals, PollOpNotEquals, PollOpExists:
+	case PollOpIn:
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("%w: poll_until.value must be a list for operator in", ErrInvalidPolicy)
+		}
+	default:
+		return fmt.Errorf("%w: unknown poll_until.operator %q", ErrInvalidPolicy, c.Operator)
+	}
+	if c.IntervalMs != 0 && c.IntervalMs < MinPollIntervalMs {
+		return fmt.Errorf("%w: poll_until.interval_ms must be at least %d", ErrInvalidPolicy, MinPollIntervalMs)
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("%w: poll_until.timeout_seconds must not be negative", ErrInvalidPolicy)
+	}
+	return nil
+}
+
+// Op returns the operator, defaulting to equals
+func (c *PollCondition) Op() string {
+	if c.Operator == "" {
+		return PollOpEquals
+	}
+	return strings.ToLower(c.Operator)
+}
+
+// Interval returns the wait between poll attempts
+func (c *PollCondition) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return DefaultPollInterval
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// ExecutionAttempt records one attempt of an execution that retried or polled
+type ExecutionAttempt struct {
+	Number       int         `json:"number"`
+	StartedAt    time.Time   `json:"started_at"`
+	StatusCode   int         `json:"status_code,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	DurationMs   int64       `json:"duration_ms"`
+	ConditionMet *bool       `json:"condition_met,omitempty"`
+	Observed     interface{} `json:"observed,omitempty"` // value found at the poll path
+	// NextAction is retry or poll when another attempt followed
+	NextAction string `json:"next_action,omitempty"`
+	DelayMs    int64  `json:"delay_ms,omitempty"`
+}
+
+// Next actions recorded on attempts
+const (
+	AttemptRetry = "retry"
+	AttemptPoll  = "poll"
+)
@@ -0,0 +1,49 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecretMask replaces secret values in API responses
+const SecretMask = "********"
+
+// secretFields are auth_config keys whose values are credentials. They are
+// never stored in the auth_config column; they live encrypted in environment_secrets.
+var secretFields = map[string]bool{
+	"api_key":       true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"private_key":   true,
+
+	"secret_access_key": true,
+	"session_token":     true,
+	TLSClientCertField:  true,
+	TLSClientKeyField:   true,
+}
+
+// IsSecretField reports whether an auth_config key holds a credential
+func IsSecretField(key string) bool {
+	key = strings.ToLower(key)
+	return secretFields[key] || strings.HasSuffix(key, "_secret") || strings.HasSuffix(key, "_password")
+}
+
+// EncryptedSecret is a single envelope-encrypted secret value. The value is
+// sealed with a per-secret data key, which is itself sealed with a master key.
+type EncryptedSecret struct {
+	EnvironmentID uuid.UUID `json:"environment_id"`
+	Name          string    `json:"name"`
+	Ciphertext    []byte    `json:"-"`
+	Nonce         []byte    `json:"-"`
+	WrappedKey    []byte    `json:"-"`
+	KeyNonce      []byte    `json:"-"`
+	KeyID         string    `json:"key_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,231 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidStream is returned for stream options that cannot be run
+var ErrInvalidStream = errors.New("invalid stream options")
+
+// Streaming protocols
+const (
+	StreamProtocolWebSocket = "websocket"
+	StreamProtocolSSE       = "sse"
+)
+
+// Which received messages an assertion must pass for
+const (
+	StreamMatchAny  = "any"
+	StreamMatchAll  = "all"
+	StreamMatchNone = "none"
+)
+
+// Why a stream stopped collecting messages
+const (
+	StreamStopDuration    = "duration"
+	StreamStopCondition   = "condition"
+	StreamStopMaxMessages = "max_messages"
+	StreamStopSizeLimit   = "size_limit"
+	StreamStopClosed      = "closed"
+)
+
+// Transcript message directions and types. Server-sent events have their
+// event type instead, message when the event does not name one.
+const (
+	StreamDirectionSent     = "sent"
+	StreamDirectionReceived = "received"
+	StreamMessageText       = "text"
+	StreamMessageBinary     = "binary"
+	StreamEventMessage      = "message"
+)
+
+// Bounds of stream collection
+const (
+	DefaultStreamDurationSeconds = 10
+	MaxStreamMessages            = 1000
+)
+
+// ErrorTypeStream marks failed streams: ones that did not open, or whose
+// transcript missed its until condition, assertions or minimum messages
+const ErrorTypeStream = "stream_failed"
+
+// StreamOptions make a request a streaming test: a WebSocket connection
+// that sends the scripted messages, or a server-sent events request, whose
+// received messages are collected for the duration, up to max_messages or
+// until a message meets every until condition. The transcript is the
+// response body; assertions are checked against it afterwards.
+type StreamOptions struct {
+	Protocol     string   `json:"protocol"` // websocket or sse
+	Subprotocols []string `json:"subprotocols,omitempty"`
+	// Send is the script of WebSocket messages, sent in order
+	Send []StreamSend `json:"send,omitempty"`
+	// DurationSeconds bounds collection (default 10); the request timeout
+	// still applies
+	DurationSeconds int               `json:"duration_seconds,omitempty"`
+	MaxMessages     int               `json:"max_messages,omitempty"` // default and cap 1000
+	Until           []StreamCondition `json:"until,omitempty"`
+	Assertions      []StreamAssertion `json:"assertions,omitempty"`
+	MinMessages     int               `json:"min_messages,omitempty"`
+}
+
+// StreamSend is a scripted WebSocket message. Strings are sent as they
+// are, other values as JSON.
+type StreamSend struct {
+	Data interface{} `json:"data"`
+	// DelayMs is the wait after the connection opened or the previous
+	// message was sent
+	DelayMs int `json:"delay_ms,omitempty"`
+}
+
+// StreamCondition checks one received message: a JSONPath into its data,
+// which is decoded when it is JSON. Event restricts it to messages of that
+// type, a server-sent event type or text or binary for WebSocket messages.
+type StreamCondition struct {
+	Path     string      `json:"path"`
+	Operator string      `json:"operator,omitempty"` // equals (default), not_equals, exists, in
+	Value    interface{} `json:"value,omitempty"`
+	Event    string      `json:"event,omitempty"`
+}
+
+// StreamAssertion is a condition the transcript must meet for any (the
+// default), all or none of its received messages
+type StreamAssertion struct {
+	StreamCondition
+	Match string `json:"match,omitempty"`
+}
+
+// StreamMessage is one message of a transcript. Data is decoded JSON when
+// the message is JSON, text otherwise, and base64 for binary messages.
+type StreamMessage struct {
+	Direction string      `json:"direction"` // sent or received
+	Type      string      `json:"type"`      // text or binary; the event type for server-sent events
+	Data      interface{} `json:"data"`
+	ID        string      `json:"id,omitempty"` // server-sent event ID
+	AtMs      int64       `json:"at_ms"`        // since the connection opened
+}
+
+// StreamResult summarizes a streaming test. The transcript itself is the
+// response body.
+type StreamResult struct {
+	Protocol      string                  `json:"protocol"`
+	SentCount     int                     `json:"sent_count"`
+	ReceivedCount int                     `json:"received_count"`
+	StopReason    string                  `json:"stop_reason"`
+	ConditionMet  *bool                   `json:"condition_met,omitempty"`
+	CloseCode     int                     `json:"close_code,omitempty"`
+	CloseReason   string                  `json:"close_reason,omitempty"`
+	Assertions    []StreamAssertionResult `json:"assertions,omitempty"`
+	DurationMs    int64                   `json:"duration_ms"`
+}
+
+// StreamAssertionResult is an assertion checked against a transcript
+type StreamAssertionResult struct {
+	StreamAssertion
+	Checked int  `json:"checked"` // received messages of the asserted type
+	Matched int  `json:"matched"` // those the condition held for
+	Passed  bool `json:"passed"`
+}
+
+// Op returns the operator, defaulting to equals
+func (c StreamCondition) Op() string {
+	if c.Operator == "" {
+		return PollOpEquals
+	}
+	return strings.ToLower(c.Operator)
+}
+
+// MatchMode returns which messages must pass, defaulting to any
+func (a StreamAssertion) MatchMode() string {
+	if a.Match == "" {
+		return StreamMatchAny
+	}
+	return strings.ToLower(a.Match)
+}
+
+// validate checks a condition the way poll conditions are checked
+func (c StreamCondition) validate(field string) error {
+	if !strings.HasPrefix(c.Path, "$") {
+		return fmt.Errorf("%w: %s.path must be a JSONPath starting with $", ErrInvalidStream, field)
+	}
+	switch c.Op() {
+	case PollOpEquals, PollOpNotEquals, PollOpExists:
+	case PollOpIn:
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("%w: %s.value must be a list for operator in", ErrInvalidStream, field)
+		}
+	default:
+		return fmt.Errorf("%w: unknown %s.operator %q", ErrInvalidStream, field, c.Operator)
+	}
+	return nil
+}
+
+// validateStream checks a streaming request and fills in the defaults.
+// WebSocket tests open with a GET and send only their script; server-sent
+// events are an ordinary request whose response is read as a stream.
+func (r *APIRequest) validateStream() error {
+	s := r.Stream
+	if s.DurationSeconds == 0 {
+		s.DurationSeconds = DefaultStreamDurationSeconds
+	}
+	if s.MaxMessages == 0 {
+		s.MaxMessages = MaxStreamMessages
+	}
+	if s.DurationSeconds < 0 {
+		return fmt.Errorf("%w: duration_seconds must be positive", ErrInvalidStream)
+	}
+	if s.MaxMessages < 0 || s.MaxMessages > MaxStreamMessages {
+		return fmt.Errorf("%w: max_messages must be between 1 and %d", ErrInvalidStream, MaxStreamMessages)
+	}
+	if s.MinMessages < 0 || s.MinMessages > s.MaxMessages {
+		return fmt.Errorf("%w: min_messages must be between 0 and max_messages", ErrInvalidStream)
+	}
+	for i, condition := range s.Until {
+		if err := condition.validate(fmt.Sprintf("until[%d]", i)); err != nil {
+			return err
+		}
+	}
+	for i, assertion := range s.Assertions {
+		if err := assertion.validate(fmt.Sprintf("assertions[%d]", i)); err != nil {
+			return err
+		}
+		switch assertion.MatchMode() {
+		case StreamMatchAny, StreamMatchAll, StreamMatchNone:
+		default:
+			return fmt.Errorf("%w: unknown assertions[%d].match %q", ErrInvalidStream, i, assertion.Match)
+		}
+	}
+	if r.GRPC != nil || r.VCR != nil {
+		return fmt.Errorf("%w: stream requests cannot be gRPC calls or use cassettes", ErrInvalidStream)
+	}
+
+	switch strings.ToLower(s.Protocol) {
+	case StreamProtocolWebSocket:
+		s.Protocol = StreamProtocolWebSocket
+		if r.Method != "GET" {
+			return fmt.Errorf("%w: websocket connections open with GET", ErrInvalidMethod)
+		}
+		parsed, err := url.Parse(r.URL)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+		}
+		switch strings.ToLower(parsed.Scheme) {
+		case "ws", "wss", "http", "https":
+		default:
+			return fmt.Errorf("%w: websocket URL must use ws:// or wss://", ErrInvalidURL)
+		}
+		if r.Body != nil || len(r.Files) > 0 {
+			return fmt.Errorf("%w: websocket messages go in stream.send, not the body", ErrInvalidBody)
+		}
+		return nil
+	case StreamProtocolSSE:
+		s.Protocol = StreamProtocolSSE
+		if len(s.Send) > 0 {
+			return fmt.Errorf("%w: server-sent event streams cannot send messages", ErrInvalidStream)
+		}
+		return r.validateBody()
+	}
+	return fmt.Errorf("%w: protocol must be websocket or sse", ErrInvalidStream)
+}
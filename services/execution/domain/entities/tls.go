@@ -0,0 +1,76 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Auth config keys for per-environment TLS settings. The client certificate
+// and key are secret fields and are stored encrypted like other credentials.
+const (
+	TLSClientCertField         = "tls_client_cert"
+	TLSClientKeyField          = "tls_client_key"
+	TLSCABundleField           = "tls_ca_bundle"
+	TLSServerNameField         = "tls_server_name"
+	TLSInsecureSkipVerifyField = "tls_insecure_skip_verify"
+)
+
+// TLSSettings are the TLS options of an environment: a PEM client
+// certificate and key for mutual TLS, a PEM CA bundle trusted in addition to
+// the system roots, an SNI/verification server name override and an
+// insecure-skip-verify flag that only admins may set.
+type TLSSettings struct {
+	ClientCert         string
+	ClientKey          string
+	CABundle           string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// TLSSettingsFromAuthConfig reads TLS settings from an auth config. It
+// returns nil when the environment uses the default TLS configuration.
+func TLSSettingsFromAuthConfig(authConfig map[string]interface{}) *TLSSettings {
+	str := func(key string) string {
+		value, _ := authConfig[key].(string)
+		return strings.TrimSpace(value)
+	}
+
+	settings := &TLSSettings{
+		ClientCert:         str(TLSClientCertField),
+		ClientKey:          str(TLSClientKeyField),
+		CABundle:           str(TLSCABundleField),
+		ServerName:         str(TLSServerNameField),
+		InsecureSkipVerify: InsecureSkipVerifyRequested(authConfig),
+	}
+	if *settings == (TLSSettings{}) {
+		return nil
+	}
+	return settings
+}
+
+// InsecureSkipVerifyRequested reports whether an auth config turns off
+// server certificate verification
+func InsecureSkipVerifyRequested(authConfig map[string]interface{}) bool {
+	switch value := authConfig[TLSInsecureSkipVerifyField].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+// Fingerprint identifies the settings, so cached transports can be rebuilt
+// when they change
+func (s *TLSSettings) Fingerprint() string {
+	h := sha256.New()
+	for _, part := range []string{s.ClientCert, s.ClientKey, s.CABundle, s.ServerName} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	if s.InsecureSkipVerify {
+		h.Write([]byte{1})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
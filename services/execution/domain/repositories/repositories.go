@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/testpilot-ai/execution/domain/entities"
@@ -17,6 +18,13 @@ type ExecutionRepository interface {
 	
 	// ListExecutions retrieves executions with pagination
 	ListExecutions(ctx context.Context, limit, offset int) ([]*entities.APIResponse, error)
+
+	// FindExecutionRequest rebuilds the stored request of an execution
+	FindExecutionRequest(ctx context.Context, id uuid.UUID) (*entities.APIRequest, error)
+
+	// FindRecentDuplicate returns the latest execution by userID with the given
+	// request fingerprint created since the given time, or nil if there is none
+	FindRecentDuplicate(ctx context.Context, userID *uuid.UUID, fingerprint string, since time.Time) (*uuid.UUID, error)
 }
 
 // EnvironmentRepository defines the interface for environment operations
@@ -40,3 +48,124 @@ type EnvironmentRepository interface {
 	ListEnvironments(ctx context.Context) ([]*entities.Environment, error)
 }
 
+// SecretRepository defines the interface for encrypted environment secrets
+type SecretRepository interface {
+	// SaveSecret creates or replaces a secret
+	SaveSecret(ctx context.Context, secret *entities.EncryptedSecret) error
+
+	// DeleteSecret removes a secret by environment and name
+	DeleteSecret(ctx context.Context, environmentID uuid.UUID, name string) error
+
+	// FindSecrets retrieves all secrets for an environment
+	FindSecrets(ctx context.Context, environmentID uuid.UUID) ([]*entities.EncryptedSecret, error)
+
+	// ListSecrets retrieves every stored secret (used for key rotation)
+	ListSecrets(ctx context.Context) ([]*entities.EncryptedSecret, error)
+
+	// UpdateWrappedKey stores a re-wrapped data key after rotation
+	UpdateWrappedKey(ctx context.Context, secret *entities.EncryptedSecret) error
+}
+
+// SettingsRepository defines the interface for admin-managed execution settings
+type SettingsRepository interface {
+	// FindExecutionLimits retrieves the stored limits, or nil if none are stored
+	FindExecutionLimits(ctx context.Context) (*entities.ExecutionLimits, error)
+
+	// SaveExecutionLimits creates or replaces the stored limits
+	SaveExecutionLimits(ctx context.Context, limits *entities.ExecutionLimits) error
+
+	// FindEgressPolicy retrieves the stored egress policy, or nil if none is stored
+	FindEgressPolicy(ctx context.Context) (*entities.EgressPolicy, error)
+
+	// SaveEgressPolicy creates or replaces the stored egress policy
+	SaveEgressPolicy(ctx context.Context, policy *entities.EgressPolicy) error
+}
+
+// IdempotencyRepository defines the interface for per-API idempotency settings
+type IdempotencyRepository interface {
+	// FindIdempotencySettings retrieves an API spec's settings, or nil if none are stored
+	FindIdempotencySettings(ctx context.Context, apiSpecID uuid.UUID) (*entities.IdempotencySettings, error)
+
+	// SaveIdempotencySettings creates or replaces an API spec's settings
+	SaveIdempotencySettings(ctx context.Context, settings *entities.IdempotencySettings) error
+}
+
+// CassetteRepository defines the interface for VCR cassettes and their interactions
+type CassetteRepository interface {
+	// CreateCassette creates a new cassette
+	CreateCassette(ctx context.Context, cassette *entities.Cassette) error
+
+	// UpdateCassette updates a cassette's name, matching and strict mode
+	UpdateCassette(ctx context.Context, cassette *entities.Cassette) error
+
+	// DeleteCassette deletes a cassette with its interactions
+	DeleteCassette(ctx context.Context, id uuid.UUID) error
+
+	// FindCassetteByID retrieves a cassette without its interactions
+	FindCassetteByID(ctx context.Context, id uuid.UUID) (*entities.Cassette, error)
+
+	// FindCassetteByName retrieves a cassette without its interactions, or nil if there is none
+	FindCassetteByName(ctx context.Context, name string) (*entities.Cassette, error)
+
+	// ListCassettes retrieves all cassettes without their interactions
+	ListCassettes(ctx context.Context) ([]*entities.Cassette, error)
+
+	// ListInteractions retrieves a cassette's interactions in sequence order
+	ListInteractions(ctx context.Context, cassetteID uuid.UUID) ([]entities.CassetteInteraction, error)
+
+	// AppendInteraction stores an interaction after the cassette's last one and sets its sequence
+	AppendInteraction(ctx context.Context, interaction *entities.CassetteInteraction) error
+
+	// ClearInteractions deletes all of a cassette's interactions
+	ClearInteractions(ctx context.Context, cassetteID uuid.UUID) (int64, error)
+}
+
+// CallbackRepository defines the interface for callback receivers and the callbacks they received
+type CallbackRepository interface {
+	// CreateReceiver creates a new receiver
+	CreateReceiver(ctx context.Context, receiver *entities.CallbackReceiver) error
+
+	// FindReceiverByID retrieves a receiver without its callbacks
+	FindReceiverByID(ctx context.Context, id uuid.UUID) (*entities.CallbackReceiver, error)
+
+	// FindReceiverByToken retrieves the receiver with the given URL token
+	FindReceiverByToken(ctx context.Context, token string) (*entities.CallbackReceiver, error)
+
+	// LinkExecution records the execution that opened a receiver
+	LinkExecution(ctx context.Context, receiverID, executionID uuid.UUID) error
+
+	// ExpireReceiver stops a receiver from accepting callbacks from the given time
+	ExpireReceiver(ctx context.Context, id uuid.UUID, at time.Time) error
+
+	// SaveCallback stores a received callback, or returns ErrCallbackReceiverFull
+	// when the receiver already holds MaxCallbacksPerReceiver callbacks
+	SaveCallback(ctx context.Context, callback *entities.ReceivedCallback) error
+
+	// ListCallbacks retrieves a receiver's callbacks, oldest first
+	ListCallbacks(ctx context.Context, receiverID uuid.UUID) ([]entities.ReceivedCallback, error)
+}
+
+// LoadTestRepository defines the interface for load test runs
+type LoadTestRepository interface {
+	// CreateRun stores a new run
+	CreateRun(ctx context.Context, run *entities.LoadTestRun) error
+
+	// UpdateRun stores a run's status, stats and outcome
+	UpdateRun(ctx context.Context, run *entities.LoadTestRun) error
+
+	// FindRunByID retrieves a run
+	FindRunByID(ctx context.Context, id uuid.UUID) (*entities.LoadTestRun, error)
+
+	// ListRuns retrieves the latest runs, of one user if userID is set
+	ListRuns(ctx context.Context, userID *uuid.UUID, limit int) ([]*entities.LoadTestRun, error)
+
+	// InterruptRunning marks runs still running, left over from a restart, as interrupted
+	InterruptRunning(ctx context.Context, at time.Time) (int64, error)
+}
+
+// GRPCDescriptorRepository defines the interface for the protobuf
+// descriptors ingested with gRPC API specs
+type GRPCDescriptorRepository interface {
+	// FindDescriptorSet retrieves the serialized FileDescriptorSet of an API spec
+	FindDescriptorSet(ctx context.Context, apiSpecID uuid.UUID) ([]byte, error)
+}
@@ -4,14 +4,26 @@ go 1.23
 
 require (
 	github.com/gin-gonic/gin v1.9.1
-	github.com/google/uuid v1.5.0
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/jackc/pgx/v5 v5.5.0
 	github.com/joho/godotenv v1.5.1
+	github.com/testpilot-ai/shared/audit v0.0.0-00010101000000-000000000000
+	github.com/testpilot-ai/shared/crypto v0.0.0-00010101000000-000000000000
+	github.com/testpilot-ai/shared/egress v0.0.0-00010101000000-000000000000
 	github.com/testpilot-ai/shared/logger v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.71.1
+	google.golang.org/protobuf v1.36.4
 )
 
 replace github.com/testpilot-ai/shared/logger => ../../shared/logger
 
+replace github.com/testpilot-ai/shared/audit => ../../shared/audit
+
+replace github.com/testpilot-ai/shared/crypto => ../../shared/crypto
+
+replace github.com/testpilot-ai/shared/egress => ../../shared/egress
+
 require (
 	github.com/bytedance/sonic v1.9.1 // indirect
 	github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
@@ -38,11 +50,11 @@ require (
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.11 // indirect
 	golang.org/x/arch v0.3.0 // indirect
-	golang.org/x/crypto v0.17.0 // indirect
-	golang.org/x/net v0.19.0 // indirect
-	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/sys v0.26.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
-	google.golang.org/protobuf v1.30.0 // indirect
+	golang.org/x/crypto v0.32.0 // indirect
+	golang.org/x/net v0.34.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
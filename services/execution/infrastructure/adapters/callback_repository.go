@@ -0,0 +1,251 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// CallbackRepository implements callback receiver storage using PostgreSQL
+type CallbackRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewCallbackRepository creates a new callback repository
+func NewCallbackRepository(pool *pgxpool.Pool) *CallbackRepository {
+	return &CallbackRepository{
+		pool: pool,
+	}
+}
+
+const receiverColumns = `
+	r.id, r.token, r.user_id, r.execution_id, r.environment_id, r.signature, r.expires_at, r.created_at,
+	(SELECT COUNT(*) FROM received_callbacks rc WHERE rc.receiver_id = r.id)
+`
+
+// CreateReceiver creates a new receiver
+func (r *CallbackRepository) CreateReceiver(ctx context.Context, receiver *entities.CallbackReceiver) error {
+	query := `
+		INSERT INTO callback_receivers (id, token, user_id, environment_id, signature, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	var signature []byte
+	if receiver.Signature != nil {
+		var err error
+		signature, err = json.Marshal(receiver.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to marshal signature: %w", err)
+		}
+	}
+
+	_, err := r.pool.Exec(ctx, query,
+		receiver.ID,
+		receiver.Token,
+		receiver.UserID,
+		receiver.EnvironmentID,
+		signature,
+		receiver.ExpiresAt,
+		receiver.CreatedAt,
+	)
+	return err
+}
+
+// FindReceiverByID retrieves a receiver without its callbacks
+func (r *CallbackRepository) FindReceiverByID(ctx context.Context, id uuid.UUID) (*entities.CallbackReceiver, error) {
+	query := `SELECT ` + receiverColumns + ` FROM callback_receivers r WHERE r.id = $1`
+
+	receiver, err := scanReceiver(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrCallbackReceiverNotFound
+	}
+	return receiver, err
+}
+
+// FindReceiverByToken retrieves the receiver with the given URL token
+func (r *CallbackRepository) FindReceiverByToken(ctx context.Context, token string) (*entities.CallbackReceiver, error) {
+	query := `SELECT ` + receiverColumns + ` FROM callback_receivers r WHERE r.token = $1`
+
+	receiver, err := scanReceiver(r.pool.QueryRow(ctx, query, token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrCallbackReceiverNotFound
+	}
+	return receiver, err
+}
+
+// LinkExecution records the execution that opened a receiver
+func (r *CallbackRepository) LinkExecution(ctx context.Context, receiverID, executionID uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE callback_receivers SET execution_id = $2 WHERE id = $1`, receiverID, executionID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrCallbackReceiverNotFound
+	}
+	return nil
+}
+
+// ExpireReceiver stops a receiver from accepting callbacks from the given time
+func (r *CallbackRepository) ExpireReceiver(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE callback_receivers SET expires_at = LEAST(expires_at, $2) WHERE id = $1`
+
+	tag, err := r.pool.Exec(ctx, query, id, at)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrCallbackReceiverNotFound
+	}
+	return nil
+}
+
+// SaveCallback stores a received callback, or returns
+// ErrCallbackReceiverFull when the receiver already holds
+// MaxCallbacksPerReceiver callbacks. The receiver row is locked while
+// counting, so concurrent senders cannot push it past the cap.
+func (r *CallbackRepository) SaveCallback(ctx context.Context, callback *entities.ReceivedCallback) error {
+	query := `
+		INSERT INTO received_callbacks (
+			id, receiver_id, method, query, headers, body, truncated,
+			signature_valid, signature_error, remote_addr, received_at
+		)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		WHERE (SELECT COUNT(*) FROM received_callbacks WHERE receiver_id = $2) < $12
+	`
+
+	queryJSON, err := json.Marshal(callback.Query)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+	headersJSON, err := json.Marshal(callback.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+	bodyJSON, err := json.Marshal(callback.Body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The count runs in a statement of its own after the lock is held, so
+	// it sees the callbacks of senders that held it before
+	var receiverID uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM callback_receivers WHERE id = $1 FOR UPDATE`, callback.ReceiverID).Scan(&receiverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entities.ErrCallbackReceiverNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock callback receiver: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, query,
+		callback.ID,
+		callback.ReceiverID,
+		callback.Method,
+		queryJSON,
+		headersJSON,
+		bodyJSON,
+		callback.Truncated,
+		callback.SignatureValid,
+		callback.SignatureError,
+		callback.RemoteAddr,
+		callback.ReceivedAt,
+		entities.MaxCallbacksPerReceiver,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrCallbackReceiverFull
+	}
+	return tx.Commit(ctx)
+}
+
+// ListCallbacks retrieves a receiver's callbacks, oldest first
+func (r *CallbackRepository) ListCallbacks(ctx context.Context, receiverID uuid.UUID) ([]entities.ReceivedCallback, error) {
+	query := `
+		SELECT id, receiver_id, method, query, headers, body, truncated,
+		       signature_valid, COALESCE(signature_error, ''), COALESCE(remote_addr, ''), received_at
+		FROM received_callbacks
+		WHERE receiver_id = $1
+		ORDER BY received_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var callbacks []entities.ReceivedCallback
+	for rows.Next() {
+		var callback entities.ReceivedCallback
+		var queryJSON, headersJSON, bodyJSON []byte
+		if err := rows.Scan(
+			&callback.ID,
+			&callback.ReceiverID,
+			&callback.Method,
+			&queryJSON,
+			&headersJSON,
+			&bodyJSON,
+			&callback.Truncated,
+			&callback.SignatureValid,
+			&callback.SignatureError,
+			&callback.RemoteAddr,
+			&callback.ReceivedAt,
+		); err != nil {
+			return nil, err
+		}
+		if len(queryJSON) > 0 {
+			if err := json.Unmarshal(queryJSON, &callback.Query); err != nil {
+				return nil, fmt.Errorf("invalid query of callback %s: %w", callback.ID, err)
+			}
+		}
+		if err := json.Unmarshal(headersJSON, &callback.Headers); err != nil {
+			return nil, fmt.Errorf("invalid headers of callback %s: %w", callback.ID, err)
+		}
+		if len(bodyJSON) > 0 {
+			if err := json.Unmarshal(bodyJSON, &callback.Body); err != nil {
+				return nil, fmt.Errorf("invalid body of callback %s: %w", callback.ID, err)
+			}
+		}
+		callbacks = append(callbacks, callback)
+	}
+	return callbacks, rows.Err()
+}
+
+func scanReceiver(row pgx.Row) (*entities.CallbackReceiver, error) {
+	var receiver entities.CallbackReceiver
+	var signatureJSON []byte
+	if err := row.Scan(
+		&receiver.ID,
+		&receiver.Token,
+		&receiver.UserID,
+		&receiver.ExecutionID,
+		&receiver.EnvironmentID,
+		&signatureJSON,
+		&receiver.ExpiresAt,
+		&receiver.CreatedAt,
+		&receiver.ReceivedCount,
+	); err != nil {
+		return nil, err
+	}
+	if len(signatureJSON) > 0 {
+		if err := json.Unmarshal(signatureJSON, &receiver.Signature); err != nil {
+			return nil, fmt.Errorf("invalid signature of receiver %s: %w", receiver.ID, err)
+		}
+	}
+	return &receiver, nil
+}
@@ -0,0 +1,236 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// CassetteRepository implements VCR cassette storage using PostgreSQL
+type CassetteRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewCassetteRepository creates a new cassette repository
+func NewCassetteRepository(pool *pgxpool.Pool) *CassetteRepository {
+	return &CassetteRepository{
+		pool: pool,
+	}
+}
+
+const cassetteColumns = `
+	c.id, c.name, c.matching, c.strict, c.created_by, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM cassette_interactions i WHERE i.cassette_id = c.id)
+`
+
+// CreateCassette creates a new cassette
+func (r *CassetteRepository) CreateCassette(ctx context.Context, cassette *entities.Cassette) error {
+	query := `
+		INSERT INTO cassettes (id, name, matching, strict, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	matching, err := json.Marshal(cassette.Matching)
+	if err != nil {
+		return fmt.Errorf("failed to marshal matching: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, query,
+		cassette.ID,
+		cassette.Name,
+		matching,
+		cassette.Strict,
+		cassette.CreatedBy,
+		cassette.CreatedAt,
+		cassette.UpdatedAt,
+	)
+	return err
+}
+
+// UpdateCassette updates a cassette's name, matching and strict mode
+func (r *CassetteRepository) UpdateCassette(ctx context.Context, cassette *entities.Cassette) error {
+	query := `
+		UPDATE cassettes
+		SET name = $2, matching = $3, strict = $4, updated_at = $5
+		WHERE id = $1
+	`
+
+	matching, err := json.Marshal(cassette.Matching)
+	if err != nil {
+		return fmt.Errorf("failed to marshal matching: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, query,
+		cassette.ID,
+		cassette.Name,
+		matching,
+		cassette.Strict,
+		cassette.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrCassetteNotFound
+	}
+	return nil
+}
+
+// DeleteCassette deletes a cassette with its interactions
+func (r *CassetteRepository) DeleteCassette(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM cassettes WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrCassetteNotFound
+	}
+	return nil
+}
+
+// FindCassetteByID retrieves a cassette without its interactions
+func (r *CassetteRepository) FindCassetteByID(ctx context.Context, id uuid.UUID) (*entities.Cassette, error) {
+	query := `SELECT ` + cassetteColumns + ` FROM cassettes c WHERE c.id = $1`
+
+	cassette, err := scanCassette(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrCassetteNotFound
+	}
+	return cassette, err
+}
+
+// FindCassetteByName retrieves a cassette without its interactions, or nil if there is none
+func (r *CassetteRepository) FindCassetteByName(ctx context.Context, name string) (*entities.Cassette, error) {
+	query := `SELECT ` + cassetteColumns + ` FROM cassettes c WHERE c.name = $1`
+
+	cassette, err := scanCassette(r.pool.QueryRow(ctx, query, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return cassette, err
+}
+
+// ListCassettes retrieves all cassettes without their interactions
+func (r *CassetteRepository) ListCassettes(ctx context.Context) ([]*entities.Cassette, error) {
+	query := `SELECT ` + cassetteColumns + ` FROM cassettes c ORDER BY c.name`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cassettes []*entities.Cassette
+	for rows.Next() {
+		cassette, err := scanCassette(rows)
+		if err != nil {
+			return nil, err
+		}
+		cassettes = append(cassettes, cassette)
+	}
+	return cassettes, rows.Err()
+}
+
+// ListInteractions retrieves a cassette's interactions in sequence order
+func (r *CassetteRepository) ListInteractions(ctx context.Context, cassetteID uuid.UUID) ([]entities.CassetteInteraction, error) {
+	query := `
+		SELECT id, cassette_id, sequence, request, response, recorded_at
+		FROM cassette_interactions
+		WHERE cassette_id = $1
+		ORDER BY sequence
+	`
+
+	rows, err := r.pool.Query(ctx, query, cassetteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []entities.CassetteInteraction
+	for rows.Next() {
+		var interaction entities.CassetteInteraction
+		var requestJSON, responseJSON []byte
+		if err := rows.Scan(
+			&interaction.ID,
+			&interaction.CassetteID,
+			&interaction.Sequence,
+			&requestJSON,
+			&responseJSON,
+			&interaction.RecordedAt,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(requestJSON, &interaction.Request); err != nil {
+			return nil, fmt.Errorf("invalid recorded request %s: %w", interaction.ID, err)
+		}
+		if err := json.Unmarshal(responseJSON, &interaction.Response); err != nil {
+			return nil, fmt.Errorf("invalid recorded response %s: %w", interaction.ID, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, rows.Err()
+}
+
+// AppendInteraction stores an interaction after the cassette's last one and sets its sequence
+func (r *CassetteRepository) AppendInteraction(ctx context.Context, interaction *entities.CassetteInteraction) error {
+	query := `
+		INSERT INTO cassette_interactions (id, cassette_id, sequence, request, response, recorded_at)
+		SELECT $1, $2, COALESCE(MAX(sequence), 0) + 1, $3, $4, $5
+		FROM cassette_interactions
+		WHERE cassette_id = $2
+		RETURNING sequence
+	`
+
+	requestJSON, err := json.Marshal(interaction.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recorded request: %w", err)
+	}
+	responseJSON, err := json.Marshal(interaction.Response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recorded response: %w", err)
+	}
+
+	return r.pool.QueryRow(ctx, query,
+		interaction.ID,
+		interaction.CassetteID,
+		requestJSON,
+		responseJSON,
+		interaction.RecordedAt,
+	).Scan(&interaction.Sequence)
+}
+
+// ClearInteractions deletes all of a cassette's interactions
+func (r *CassetteRepository) ClearInteractions(ctx context.Context, cassetteID uuid.UUID) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM cassette_interactions WHERE cassette_id = $1`, cassetteID)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanCassette(row pgx.Row) (*entities.Cassette, error) {
+	var cassette entities.Cassette
+	var matchingJSON []byte
+	if err := row.Scan(
+		&cassette.ID,
+		&cassette.Name,
+		&matchingJSON,
+		&cassette.Strict,
+		&cassette.CreatedBy,
+		&cassette.CreatedAt,
+		&cassette.UpdatedAt,
+		&cassette.InteractionCount,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(matchingJSON, &cassette.Matching); err != nil {
+		return nil, fmt.Errorf("invalid matching of cassette %s: %w", cassette.ID, err)
+	}
+	return &cassette, nil
+}
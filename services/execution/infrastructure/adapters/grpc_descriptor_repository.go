@@ -0,0 +1,40 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// GRPCDescriptorRepository reads the descriptor sets the ingestion service
+// stores for gRPC API specs
+type GRPCDescriptorRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewGRPCDescriptorRepository creates a new gRPC descriptor repository
+func NewGRPCDescriptorRepository(pool *pgxpool.Pool) *GRPCDescriptorRepository {
+	return &GRPCDescriptorRepository{
+		pool: pool,
+	}
+}
+
+// FindDescriptorSet retrieves the serialized FileDescriptorSet of an API spec
+func (r *GRPCDescriptorRepository) FindDescriptorSet(ctx context.Context, apiSpecID uuid.UUID) ([]byte, error) {
+	var descriptorSet []byte
+	err := r.pool.QueryRow(ctx,
+		"SELECT descriptor_set FROM grpc_descriptor_sets WHERE api_spec_id = $1",
+		apiSpecID,
+	).Scan(&descriptorSet)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrGRPCDescriptorsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return descriptorSet, nil
+}
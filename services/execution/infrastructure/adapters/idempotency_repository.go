@@ -0,0 +1,74 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// IdempotencyRepository implements per-API idempotency settings storage
+type IdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewIdempotencyRepository creates a new idempotency settings repository
+func NewIdempotencyRepository(pool *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		pool: pool,
+	}
+}
+
+// FindIdempotencySettings retrieves an API spec's settings, or nil if none are stored
+func (r *IdempotencyRepository) FindIdempotencySettings(ctx context.Context, apiSpecID uuid.UUID) (*entities.IdempotencySettings, error) {
+	query := `
+		SELECT api_spec_id, generate_keys, header_name, methods, duplicate_window_seconds, updated_at
+		FROM idempotency_settings
+		WHERE api_spec_id = $1
+	`
+
+	var settings entities.IdempotencySettings
+	err := r.pool.QueryRow(ctx, query, apiSpecID).Scan(
+		&settings.APISpecID,
+		&settings.GenerateKeys,
+		&settings.HeaderName,
+		&settings.Methods,
+		&settings.DuplicateWindowSeconds,
+		&settings.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveIdempotencySettings creates or replaces an API spec's settings
+func (r *IdempotencyRepository) SaveIdempotencySettings(ctx context.Context, settings *entities.IdempotencySettings) error {
+	query := `
+		INSERT INTO idempotency_settings (
+			api_spec_id, generate_keys, header_name, methods, duplicate_window_seconds, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (api_spec_id) DO UPDATE SET
+			generate_keys = EXCLUDED.generate_keys,
+			header_name = EXCLUDED.header_name,
+			methods = EXCLUDED.methods,
+			duplicate_window_seconds = EXCLUDED.duplicate_window_seconds,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		settings.APISpecID,
+		settings.GenerateKeys,
+		settings.HeaderName,
+		settings.Methods,
+		settings.DuplicateWindowSeconds,
+		settings.UpdatedAt,
+	)
+	return err
+}
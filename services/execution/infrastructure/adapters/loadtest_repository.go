@@ -0,0 +1,176 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// LoadTestRepository implements load test run storage using PostgreSQL
+type LoadTestRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewLoadTestRepository creates a new load test repository
+func NewLoadTestRepository(pool *pgxpool.Pool) *LoadTestRepository {
+	return &LoadTestRepository{
+		pool: pool,
+	}
+}
+
+const loadTestRunColumns = `
+	id, user_id, COALESCE(name, ''), config, status, stats, thresholds, passed,
+	COALESCE(error, ''), started_at, finished_at
+`
+
+// CreateRun stores a new run
+func (r *LoadTestRepository) CreateRun(ctx context.Context, run *entities.LoadTestRun) error {
+	query := `
+		INSERT INTO load_test_runs (id, user_id, name, config, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	config, err := json.Marshal(run.Config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, query,
+		run.ID,
+		run.UserID,
+		run.Name,
+		config,
+		run.Status,
+		run.StartedAt,
+	)
+	return err
+}
+
+// UpdateRun stores a run's status, stats and outcome
+func (r *LoadTestRepository) UpdateRun(ctx context.Context, run *entities.LoadTestRun) error {
+	query := `
+		UPDATE load_test_runs
+		SET status = $2, stats = $3, thresholds = $4, passed = $5, error = $6, finished_at = $7
+		WHERE id = $1
+	`
+
+	stats, err := json.Marshal(run.Stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats: %w", err)
+	}
+	thresholds, err := json.Marshal(run.Thresholds)
+	if err != nil {
+		return fmt.Errorf("failed to marshal thresholds: %w", err)
+	}
+
+	tag, err := r.pool.Exec(ctx, query,
+		run.ID,
+		run.Status,
+		stats,
+		thresholds,
+		run.Passed,
+		run.Error,
+		run.FinishedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return entities.ErrLoadTestNotFound
+	}
+	return nil
+}
+
+// FindRunByID retrieves a run
+func (r *LoadTestRepository) FindRunByID(ctx context.Context, id uuid.UUID) (*entities.LoadTestRun, error) {
+	query := `SELECT ` + loadTestRunColumns + ` FROM load_test_runs WHERE id = $1`
+
+	run, err := scanLoadTestRun(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrLoadTestNotFound
+	}
+	return run, err
+}
+
+// ListRuns retrieves the latest runs, of one user if userID is set
+func (r *LoadTestRepository) ListRuns(ctx context.Context, userID *uuid.UUID, limit int) ([]*entities.LoadTestRun, error) {
+	query := `
+		SELECT ` + loadTestRunColumns + `
+		FROM load_test_runs
+		WHERE $1::uuid IS NULL OR user_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*entities.LoadTestRun
+	for rows.Next() {
+		run, err := scanLoadTestRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// InterruptRunning marks runs still running, left over from a restart, as interrupted
+func (r *LoadTestRepository) InterruptRunning(ctx context.Context, at time.Time) (int64, error) {
+	query := `
+		UPDATE load_test_runs
+		SET status = $1, finished_at = $2, error = 'the execution service restarted during the run'
+		WHERE status = $3
+	`
+
+	tag, err := r.pool.Exec(ctx, query, entities.LoadTestStatusInterrupted, at, entities.LoadTestStatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanLoadTestRun(row pgx.Row) (*entities.LoadTestRun, error) {
+	var run entities.LoadTestRun
+	var configJSON, statsJSON, thresholdsJSON []byte
+	if err := row.Scan(
+		&run.ID,
+		&run.UserID,
+		&run.Name,
+		&configJSON,
+		&run.Status,
+		&statsJSON,
+		&thresholdsJSON,
+		&run.Passed,
+		&run.Error,
+		&run.StartedAt,
+		&run.FinishedAt,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(configJSON, &run.Config); err != nil {
+		return nil, fmt.Errorf("invalid config of load test %s: %w", run.ID, err)
+	}
+	if len(statsJSON) > 0 {
+		if err := json.Unmarshal(statsJSON, &run.Stats); err != nil {
+			return nil, fmt.Errorf("invalid stats of load test %s: %w", run.ID, err)
+		}
+	}
+	if len(thresholdsJSON) > 0 {
+		if err := json.Unmarshal(thresholdsJSON, &run.Thresholds); err != nil {
+			return nil, fmt.Errorf("invalid thresholds of load test %s: %w", run.ID, err)
+		}
+	}
+	return &run, nil
+}
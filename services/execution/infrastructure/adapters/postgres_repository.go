@@ -3,10 +3,12 @@ package adapters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testpilot-ai/execution/domain/entities"
 )
@@ -27,19 +29,31 @@ func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
 func (r *PostgresRepository) SaveExecution(ctx context.Context, request *entities.APIRequest, response *entities.APIResponse) error {
 	query := `
 		INSERT INTO test_executions (
-			id, user_id, api_spec_id, natural_language_request,
+			id, user_id, api_spec_id, environment_id, natural_language_request,
 			constructed_request, response, validation_result,
-			status, execution_time_ms, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			status, execution_time_ms, timing, attempts,
+			request_fingerprint, idempotency_key, replay_of, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 	`
 
 	// Marshal request and response to JSON
 	constructedReq, err := json.Marshal(map[string]interface{}{
-		"method":        request.Method,
-		"url":           request.URL,
-		"headers":       request.Headers,
-		"query_params":  request.QueryParams,
-		"body":          request.Body,
+		"method":             request.Method,
+		"url":                request.URL,
+		"headers":            request.Headers,
+		"query_params":       request.QueryParams,
+		"body":               request.Body,
+		"body_type":          request.BodyType,
+		"files":              request.Files,
+		"query_param_styles": request.QueryParamStyles,
+		"retry":              request.Retry,
+		"poll_until":         request.PollUntil,
+		"vcr":                request.VCR,
+		"grpc":               request.GRPC,
+		"stream":             request.Stream,
+		"timeout":            request.Timeout,
+		"api_name":           request.APIName,
+		"endpoint_name":      request.EndpointName,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
@@ -49,13 +63,36 @@ func (r *PostgresRepository) SaveExecution(ctx context.Context, request *entitie
 		"status_code":       response.StatusCode,
 		"headers":           response.Headers,
 		"body":              response.Body,
+		"binary_body":       response.BinaryBody,
 		"execution_time_ms": response.ExecutionTimeMs,
 		"error":             response.Error,
+		"error_type":        response.ErrorType,
+		"vcr":               response.VCR,
+		"grpc":              response.GRPC,
+		"stream":            response.Stream,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
 	}
 
+	// Stored separately so analytics can aggregate the phases
+	var timingJSON []byte
+	if response.Timing != nil {
+		timingJSON, err = json.Marshal(response.Timing)
+		if err != nil {
+			return fmt.Errorf("failed to marshal timing: %w", err)
+		}
+	}
+
+	// Only executions that retried or polled record their attempts
+	var attemptsJSON []byte
+	if len(response.Attempts) > 0 {
+		attemptsJSON, err = json.Marshal(response.Attempts)
+		if err != nil {
+			return fmt.Errorf("failed to marshal attempts: %w", err)
+		}
+	}
+
 	status := "success"
 	if !response.Success {
 		status = "failed"
@@ -65,12 +102,18 @@ func (r *PostgresRepository) SaveExecution(ctx context.Context, request *entitie
 		response.ID,
 		request.UserID,
 		request.APISpecID,
+		request.EnvironmentID,
 		request.NaturalLanguageRequest,
 		constructedReq,
 		responseJSON,
 		nil, // validation_result - set by validation service
 		status,
 		response.ExecutionTimeMs,
+		timingJSON,
+		attemptsJSON,
+		nullIfEmpty(request.Fingerprint),
+		nullIfEmpty(request.IdempotencyKey),
+		request.ReplayOf,
 		time.Now(),
 	)
 
@@ -98,6 +141,9 @@ func (r *PostgresRepository) FindExecutionByID(ctx context.Context, id uuid.UUID
 		&response.ExecutionTimeMs,
 		&createdAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrExecutionNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +156,31 @@ func (r *PostgresRepository) FindExecutionByID(ctx context.Context, id uuid.UUID
 		if errMsg, ok := respData["error"].(string); ok {
 			response.Error = errMsg
 		}
+		response.ErrorType, _ = respData["error_type"].(string)
+		if binary, ok := respData["binary_body"].(map[string]interface{}); ok {
+			response.BinaryBody = &entities.BinaryBody{}
+			response.BinaryBody.ContentType, _ = binary["content_type"].(string)
+			response.BinaryBody.SHA256, _ = binary["sha256"].(string)
+			if size, ok := binary["size_bytes"].(float64); ok {
+				response.BinaryBody.SizeBytes = int64(size)
+			}
+		}
+		if grpcData, ok := respData["grpc"]; ok && grpcData != nil {
+			if raw, err := json.Marshal(grpcData); err == nil {
+				response.GRPC = &entities.GRPCResult{}
+				if err := json.Unmarshal(raw, response.GRPC); err != nil {
+					response.GRPC = nil
+				}
+			}
+		}
+		if streamData, ok := respData["stream"]; ok && streamData != nil {
+			if raw, err := json.Marshal(streamData); err == nil {
+				response.Stream = &entities.StreamResult{}
+				if err := json.Unmarshal(raw, response.Stream); err != nil {
+					response.Stream = nil
+				}
+			}
+		}
 	}
 
 	response.Success = status == "success"
@@ -170,3 +241,64 @@ func (r *PostgresRepository) ListExecutions(ctx context.Context, limit, offset i
 	return executions, nil
 }
 
+// FindExecutionRequest rebuilds the stored request of an execution
+func (r *PostgresRepository) FindExecutionRequest(ctx context.Context, id uuid.UUID) (*entities.APIRequest, error) {
+	query := `
+		SELECT user_id, api_spec_id, environment_id, natural_language_request, constructed_request
+		FROM test_executions
+		WHERE id = $1
+	`
+
+	var request entities.APIRequest
+	var constructedReq []byte
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&request.UserID,
+		&request.APISpecID,
+		&request.EnvironmentID,
+		&request.NaturalLanguageRequest,
+		&constructedReq,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, entities.ErrExecutionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(constructedReq, &request); err != nil {
+		return nil, fmt.Errorf("failed to parse stored request: %w", err)
+	}
+	return &request, nil
+}
+
+// FindRecentDuplicate returns the latest execution by userID with the given
+// request fingerprint created since the given time, or nil if there is none
+func (r *PostgresRepository) FindRecentDuplicate(ctx context.Context, userID *uuid.UUID, fingerprint string, since time.Time) (*uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM test_executions
+		WHERE request_fingerprint = $1
+		  AND user_id IS NOT DISTINCT FROM $2
+		  AND created_at >= $3
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var id uuid.UUID
+	err := r.pool.QueryRow(ctx, query, fingerprint, userID, since).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// nullIfEmpty stores empty strings as NULL
+func nullIfEmpty(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,125 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// SecretRepository implements encrypted secret storage using PostgreSQL
+type SecretRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewSecretRepository creates a new secret repository
+func NewSecretRepository(pool *pgxpool.Pool) *SecretRepository {
+	return &SecretRepository{
+		pool: pool,
+	}
+}
+
+// SaveSecret creates or replaces a secret
+func (r *SecretRepository) SaveSecret(ctx context.Context, secret *entities.EncryptedSecret) error {
+	query := `
+		INSERT INTO environment_secrets (
+			environment_id, name, ciphertext, nonce, wrapped_key, key_nonce, key_id, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (environment_id, name) DO UPDATE SET
+			ciphertext = EXCLUDED.ciphertext,
+			nonce = EXCLUDED.nonce,
+			wrapped_key = EXCLUDED.wrapped_key,
+			key_nonce = EXCLUDED.key_nonce,
+			key_id = EXCLUDED.key_id,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	now := time.Now()
+	_, err := r.pool.Exec(ctx, query,
+		secret.EnvironmentID,
+		secret.Name,
+		secret.Ciphertext,
+		secret.Nonce,
+		secret.WrappedKey,
+		secret.KeyNonce,
+		secret.KeyID,
+		now,
+	)
+	return err
+}
+
+// DeleteSecret removes a secret by environment and name
+func (r *SecretRepository) DeleteSecret(ctx context.Context, environmentID uuid.UUID, name string) error {
+	query := `DELETE FROM environment_secrets WHERE environment_id = $1 AND name = $2`
+	_, err := r.pool.Exec(ctx, query, environmentID, name)
+	return err
+}
+
+// FindSecrets retrieves all secrets for an environment
+func (r *SecretRepository) FindSecrets(ctx context.Context, environmentID uuid.UUID) ([]*entities.EncryptedSecret, error) {
+	query := `
+		SELECT environment_id, name, ciphertext, nonce, wrapped_key, key_nonce, key_id, created_at, updated_at
+		FROM environment_secrets
+		WHERE environment_id = $1
+	`
+	return r.query(ctx, query, environmentID)
+}
+
+// ListSecrets retrieves every stored secret
+func (r *SecretRepository) ListSecrets(ctx context.Context) ([]*entities.EncryptedSecret, error) {
+	query := `
+		SELECT environment_id, name, ciphertext, nonce, wrapped_key, key_nonce, key_id, created_at, updated_at
+		FROM environment_secrets
+	`
+	return r.query(ctx, query)
+}
+
+// UpdateWrappedKey stores a re-wrapped data key after rotation
+func (r *SecretRepository) UpdateWrappedKey(ctx context.Context, secret *entities.EncryptedSecret) error {
+	query := `
+		UPDATE environment_secrets
+		SET wrapped_key = $3, key_nonce = $4, key_id = $5, updated_at = $6
+		WHERE environment_id = $1 AND name = $2
+	`
+	_, err := r.pool.Exec(ctx, query,
+		secret.EnvironmentID,
+		secret.Name,
+		secret.WrappedKey,
+		secret.KeyNonce,
+		secret.KeyID,
+		time.Now(),
+	)
+	return err
+}
+
+func (r *SecretRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entities.EncryptedSecret, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var secrets []*entities.EncryptedSecret
+	for rows.Next() {
+		var secret entities.EncryptedSecret
+		err := rows.Scan(
+			&secret.EnvironmentID,
+			&secret.Name,
+			&secret.Ciphertext,
+			&secret.Nonce,
+			&secret.WrappedKey,
+			&secret.KeyNonce,
+			&secret.KeyID,
+			&secret.CreatedAt,
+			&secret.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, &secret)
+	}
+
+	return secrets, rows.Err()
+}
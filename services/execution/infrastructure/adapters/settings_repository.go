@@ -0,0 +1,98 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// SettingsRepository implements execution settings storage on system_config
+type SettingsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(pool *pgxpool.Pool) *SettingsRepository {
+	return &SettingsRepository{
+		pool: pool,
+	}
+}
+
+// FindExecutionLimits retrieves the stored limits, or nil if none are stored
+func (r *SettingsRepository) FindExecutionLimits(ctx context.Context) (*entities.ExecutionLimits, error) {
+	var raw []byte
+	err := r.pool.QueryRow(ctx,
+		"SELECT value FROM system_config WHERE key = $1",
+		entities.ExecutionLimitsKey,
+	).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var limits entities.ExecutionLimits
+	if err := json.Unmarshal(raw, &limits); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", entities.ExecutionLimitsKey, err)
+	}
+	return &limits, nil
+}
+
+// SaveExecutionLimits creates or replaces the stored limits
+func (r *SettingsRepository) SaveExecutionLimits(ctx context.Context, limits *entities.ExecutionLimits) error {
+	value, err := json.Marshal(limits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+
+	query := `
+		INSERT INTO system_config (key, value, description)
+		VALUES ($1, $2, 'Execution timeout and response size limits')
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
+	`
+	_, err = r.pool.Exec(ctx, query, entities.ExecutionLimitsKey, value)
+	return err
+}
+
+// FindEgressPolicy retrieves the stored egress policy, or nil if none is stored
+func (r *SettingsRepository) FindEgressPolicy(ctx context.Context) (*entities.EgressPolicy, error) {
+	var raw []byte
+	err := r.pool.QueryRow(ctx,
+		"SELECT value FROM system_config WHERE key = $1",
+		entities.EgressPolicyKey,
+	).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var policy entities.EgressPolicy
+	if err := json.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", entities.EgressPolicyKey, err)
+	}
+	return &policy, nil
+}
+
+// SaveEgressPolicy creates or replaces the stored egress policy
+func (r *SettingsRepository) SaveEgressPolicy(ctx context.Context, policy *entities.EgressPolicy) error {
+	value, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to marshal egress policy: %w", err)
+	}
+
+	query := `
+		INSERT INTO system_config (key, value, description)
+		VALUES ($1, $2, 'Hosts and address ranges API calls may reach')
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
+	`
+	_, err = r.pool.Exec(ctx, query, entities.EgressPolicyKey, value)
+	return err
+}
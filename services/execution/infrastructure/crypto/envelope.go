@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	sharedcrypto "github.com/testpilot-ai/shared/crypto"
+)
+
+// SecretKeyring envelope-encrypts environment secrets with the shared
+// keyring. Each value is bound to its environment and secret name.
+type SecretKeyring struct {
+	keyring *sharedcrypto.Keyring
+}
+
+// NewSecretKeyring creates a secret keyring on top of keyring
+func NewSecretKeyring(keyring *sharedcrypto.Keyring) *SecretKeyring {
+	return &SecretKeyring{keyring: keyring}
+}
+
+// ActiveKeyID returns the ID of the key used for new secrets
+func (k *SecretKeyring) ActiveKeyID() string {
+	return k.keyring.ActiveKeyID()
+}
+
+// Seal encrypts a plaintext secret value with a fresh data key and wraps
+// that key with the active master key
+func (k *SecretKeyring) Seal(environmentID uuid.UUID, name string, plaintext []byte) (*entities.EncryptedSecret, error) {
+	envelope, err := k.keyring.Seal(valueAAD(environmentID, name), plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entities.EncryptedSecret{
+		EnvironmentID: environmentID,
+		Name:          name,
+		Ciphertext:    envelope.Ciphertext,
+		Nonce:         envelope.Nonce,
+		WrappedKey:    envelope.WrappedKey,
+		KeyNonce:      envelope.KeyNonce,
+		KeyID:         envelope.KeyID,
+	}, nil
+}
+
+// Open decrypts a secret value
+func (k *SecretKeyring) Open(secret *entities.EncryptedSecret) ([]byte, error) {
+	plaintext, err := k.keyring.Open(toEnvelope(secret), valueAAD(secret.EnvironmentID, secret.Name))
+	if err != nil {
+		return nil, secretError(secret, err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap re-encrypts a secret's data key with the active master key.
+// The secret value itself is untouched. It reports whether anything changed.
+func (k *SecretKeyring) Rewrap(secret *entities.EncryptedSecret) (bool, error) {
+	envelope := toEnvelope(secret)
+	changed, err := k.keyring.Rewrap(envelope)
+	if err != nil {
+		return false, secretError(secret, err)
+	}
+	secret.WrappedKey = envelope.WrappedKey
+	secret.KeyNonce = envelope.KeyNonce
+	secret.KeyID = envelope.KeyID
+	return changed, nil
+}
+
+func toEnvelope(secret *entities.EncryptedSecret) *sharedcrypto.Envelope {
+	return &sharedcrypto.Envelope{
+		Ciphertext: secret.Ciphertext,
+		Nonce:      secret.Nonce,
+		WrappedKey: secret.WrappedKey,
+		KeyNonce:   secret.KeyNonce,
+		KeyID:      secret.KeyID,
+	}
+}
+
+func secretError(secret *entities.EncryptedSecret, err error) error {
+	if errors.Is(err, sharedcrypto.ErrUnknownKey) {
+		return fmt.Errorf("%w: %s", entities.ErrUnknownSecretKey, secret.KeyID)
+	}
+	return fmt.Errorf("secret %q: %w", secret.Name, err)
+}
+
+// valueAAD binds a ciphertext to its environment and secret name so that
+// values cannot be swapped between rows
+func valueAAD(environmentID uuid.UUID, name string) string {
+	return environmentID.String() + ":" + name
+}
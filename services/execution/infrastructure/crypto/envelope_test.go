@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/execution/domain/entities"
+	sharedcrypto "github.com/testpilot-ai/shared/crypto"
+)
+
+func testKeyring(t *testing.T, keys map[string]byte, activeID string) *SecretKeyring {
+	t.Helper()
+	masterKeys := make(map[string][]byte)
+	for id, b := range keys {
+		masterKeys[id] = bytes.Repeat([]byte{b}, 32)
+	}
+	keyring, err := sharedcrypto.NewKeyring(masterKeys, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSecretKeyring(keyring)
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	keyring := testKeyring(t, map[string]byte{"k1": 1}, "k1")
+	envID := uuid.New()
+
+	sealed, err := keyring.Seal(envID, "api_key", []byte("sk-live-123"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if sealed.EnvironmentID != envID || sealed.Name != "api_key" || sealed.KeyID != "k1" {
+		t.Errorf("sealed secret = %+v", sealed)
+	}
+	got, err := keyring.Open(sealed)
+	if err != nil || string(got) != "sk-live-123" {
+		t.Fatalf("Open = %q, %v", got, err)
+	}
+}
+
+func TestOpenRejectsMovedSecrets(t *testing.T) {
+	keyring := testKeyring(t, map[string]byte{"k1": 1}, "k1")
+
+	tests := []struct {
+		name   string
+		tamper func(s *entities.EncryptedSecret)
+	}{
+		{"other environment", func(s *entities.EncryptedSecret) { s.EnvironmentID = uuid.New() }},
+		{"other name", func(s *entities.EncryptedSecret) { s.Name = "password" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := keyring.Seal(uuid.New(), "token", []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(sealed)
+			if _, err := keyring.Open(sealed); err == nil {
+				t.Error("Open succeeded on a secret moved to another row")
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	envID := uuid.New()
+	old := testKeyring(t, map[string]byte{"old": 1}, "old")
+	sealed, err := old.Seal(envID, "token", []byte("rotate me"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalCiphertext := append([]byte(nil), sealed.Ciphertext...)
+
+	rotated := testKeyring(t, map[string]byte{"old": 1, "new": 2}, "new")
+	changed, err := rotated.Rewrap(sealed)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v; want true, nil", changed, err)
+	}
+	if sealed.KeyID != "new" {
+		t.Errorf("KeyID after Rewrap = %q, want new", sealed.KeyID)
+	}
+	if !bytes.Equal(sealed.Ciphertext, originalCiphertext) {
+		t.Error("Rewrap changed the value ciphertext")
+	}
+
+	retired := testKeyring(t, map[string]byte{"new": 2}, "new")
+	if got, err := retired.Open(sealed); err != nil || string(got) != "rotate me" {
+		t.Fatalf("Open after retiring the old key = %q, %v", got, err)
+	}
+
+	// A secret still on a retired key cannot be read
+	stale, _ := old.Seal(envID, "token", []byte("stale"))
+	if _, err := retired.Open(stale); !errors.Is(err, entities.ErrUnknownSecretKey) {
+		t.Errorf("Open with an unknown key = %v, want ErrUnknownSecretKey", err)
+	}
+	if _, err := retired.Rewrap(stale); !errors.Is(err, entities.ErrUnknownSecretKey) {
+		t.Errorf("Rewrap with an unknown key = %v, want ErrUnknownSecretKey", err)
+	}
+}
@@ -0,0 +1,149 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// maxTokenResponseBytes bounds how much of a token endpoint response is read
+const maxTokenResponseBytes = 1 << 20
+
+// Client requests access tokens from OAuth2 token endpoints
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a token client with the given request timeout. Token
+// requests go through transport, so they follow the same egress rules as
+// API calls.
+func NewClient(timeout time.Duration, transport http.RoundTripper) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// FetchToken performs the configured grant and returns a new token
+func (c *Client) FetchToken(ctx context.Context, cfg *entities.OAuth2Config) (*entities.OAuth2Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", cfg.GrantType)
+	if cfg.GrantType == entities.GrantTypePassword {
+		form.Set("username", cfg.Username)
+		form.Set("password", cfg.Password)
+	}
+	if cfg.Scope != "" {
+		form.Set("scope", cfg.Scope)
+	}
+	if cfg.Audience != "" {
+		form.Set("audience", cfg.Audience)
+	}
+	return c.requestToken(ctx, cfg, form)
+}
+
+// RefreshToken exchanges a refresh token for a new token
+func (c *Client) RefreshToken(ctx context.Context, cfg *entities.OAuth2Config, refreshToken string) (*entities.OAuth2Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", entities.GrantTypeRefreshToken)
+	form.Set("refresh_token", refreshToken)
+	if cfg.Scope != "" {
+		form.Set("scope", cfg.Scope)
+	}
+
+	token, err := c.requestToken(ctx, cfg, form)
+	if err != nil {
+		return nil, err
+	}
+	// Servers may omit the refresh token when it is not rotated
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// tokenResponse is the RFC 6749 section 5.1 / 5.2 response body
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	RefreshToken     string      `json:"refresh_token"`
+	Scope            string      `json:"scope"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+func (c *Client) requestToken(ctx context.Context, cfg *entities.OAuth2Config, form url.Values) (*entities.OAuth2Token, error) {
+	if cfg.ClientAuth == entities.ClientAuthBody {
+		form.Set("client_id", cfg.ClientID)
+		if cfg.ClientSecret != "" {
+			form.Set("client_secret", cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if cfg.ClientAuth == entities.ClientAuthBasic {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before basic auth
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	obtainedAt := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var parsed tokenResponse
+	jsonErr := json.Unmarshal(body, &parsed)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if jsonErr == nil && parsed.Error != "" {
+			if parsed.ErrorDescription != "" {
+				return nil, fmt.Errorf("token endpoint returned %d: %s: %s", resp.StatusCode, parsed.Error, parsed.ErrorDescription)
+			}
+			return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, parsed.Error)
+		}
+		return nil, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("invalid token response: %w", jsonErr)
+	}
+	if parsed.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	token := &entities.OAuth2Token{
+		AccessToken:  parsed.AccessToken,
+		RefreshToken: parsed.RefreshToken,
+		TokenType:    parsed.TokenType,
+		Scope:        parsed.Scope,
+		ObtainedAt:   obtainedAt,
+	}
+	if parsed.ExpiresIn != "" {
+		seconds, err := strconv.ParseInt(parsed.ExpiresIn.String(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_in %q", parsed.ExpiresIn)
+		}
+		if seconds > 0 {
+			token.ExpiresAt = obtainedAt.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return token, nil
+}
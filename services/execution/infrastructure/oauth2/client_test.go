@@ -0,0 +1,206 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testpilot-ai/execution/domain/entities"
+)
+
+// tokenServer is a local token endpoint that records the last request it
+// received and answers with a fixed status and body
+type tokenServer struct {
+	*httptest.Server
+	form     url.Values
+	user     string
+	password string
+	hasBasic bool
+}
+
+func newTokenServer(t *testing.T, status int, body interface{}) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("token request method = %s, want POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("token request Content-Type = %q", ct)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		ts.form = r.PostForm
+		ts.user, ts.password, ts.hasBasic = r.BasicAuth()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestFetchToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       entities.OAuth2Config
+		status    int
+		response  interface{}
+		wantForm  map[string]string
+		wantBasic []string // client id and secret, when sent with basic auth
+		wantToken string
+		wantTTL   time.Duration
+		wantErr   string
+	}{
+		{
+			name: "client credentials with basic auth",
+			cfg: entities.OAuth2Config{
+				GrantType: entities.GrantTypeClientCredentials, ClientID: "my client", ClientSecret: "s3cr:t",
+				Scope: "read write", ClientAuth: entities.ClientAuthBasic,
+			},
+			status:    http.StatusOK,
+			response:  map[string]interface{}{"access_token": "at-1", "token_type": "bearer", "expires_in": 3600},
+			wantForm:  map[string]string{"grant_type": "client_credentials", "scope": "read write", "client_secret": ""},
+			wantBasic: []string{"my+client", "s3cr%3At"},
+			wantToken: "at-1",
+			wantTTL:   time.Hour,
+		},
+		{
+			name: "client credentials in the body with audience",
+			cfg: entities.OAuth2Config{
+				GrantType: entities.GrantTypeClientCredentials, ClientID: "cid", ClientSecret: "cs",
+				Audience: "https://api.example.com", ClientAuth: entities.ClientAuthBody,
+			},
+			status:    http.StatusOK,
+			response:  map[string]interface{}{"access_token": "at-2", "expires_in": "60"},
+			wantForm:  map[string]string{"grant_type": "client_credentials", "client_id": "cid", "client_secret": "cs", "audience": "https://api.example.com"},
+			wantToken: "at-2",
+			wantTTL:   time.Minute,
+		},
+		{
+			name: "password grant",
+			cfg: entities.OAuth2Config{
+				GrantType: entities.GrantTypePassword, ClientID: "cid", Username: "alice", Password: "pw",
+				ClientAuth: entities.ClientAuthBody,
+			},
+			status:    http.StatusOK,
+			response:  map[string]interface{}{"access_token": "at-3", "refresh_token": "rt-3"},
+			wantForm:  map[string]string{"grant_type": "password", "username": "alice", "password": "pw", "client_id": "cid"},
+			wantToken: "at-3",
+		},
+		{
+			name:     "oauth error response",
+			cfg:      entities.OAuth2Config{GrantType: entities.GrantTypeClientCredentials, ClientID: "cid", ClientAuth: entities.ClientAuthBasic},
+			status:   http.StatusUnauthorized,
+			response: map[string]interface{}{"error": "invalid_client", "error_description": "bad secret"},
+			wantErr:  "token endpoint returned 401: invalid_client: bad secret",
+		},
+		{
+			name:     "error without body",
+			cfg:      entities.OAuth2Config{GrantType: entities.GrantTypeClientCredentials, ClientID: "cid", ClientAuth: entities.ClientAuthBasic},
+			status:   http.StatusBadGateway,
+			response: "upstream down",
+			wantErr:  "token endpoint returned 502",
+		},
+		{
+			name:     "missing access token",
+			cfg:      entities.OAuth2Config{GrantType: entities.GrantTypeClientCredentials, ClientID: "cid", ClientAuth: entities.ClientAuthBasic},
+			status:   http.StatusOK,
+			response: map[string]interface{}{"token_type": "bearer"},
+			wantErr:  "no access_token",
+		},
+		{
+			name:     "invalid expires_in",
+			cfg:      entities.OAuth2Config{GrantType: entities.GrantTypeClientCredentials, ClientID: "cid", ClientAuth: entities.ClientAuthBasic},
+			status:   http.StatusOK,
+			response: map[string]interface{}{"access_token": "at", "expires_in": 1.5},
+			wantErr:  "invalid expires_in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTokenServer(t, tt.status, tt.response)
+			cfg := tt.cfg
+			cfg.TokenURL = server.URL + "/oauth/token"
+
+			before := time.Now()
+			token, err := NewClient(5*time.Second, http.DefaultTransport).FetchToken(context.Background(), &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchToken error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchToken: %v", err)
+			}
+
+			for key, want := range tt.wantForm {
+				if got := server.form.Get(key); got != want {
+					t.Errorf("form %s = %q, want %q", key, got, want)
+				}
+			}
+			if tt.wantBasic != nil {
+				if !server.hasBasic || server.user != tt.wantBasic[0] || server.password != tt.wantBasic[1] {
+					t.Errorf("basic auth = %q:%q (%v), want %q:%q", server.user, server.password, server.hasBasic, tt.wantBasic[0], tt.wantBasic[1])
+				}
+			} else if server.hasBasic {
+				t.Error("unexpected basic auth")
+			}
+
+			if token.AccessToken != tt.wantToken {
+				t.Errorf("AccessToken = %q, want %q", token.AccessToken, tt.wantToken)
+			}
+			if tt.wantTTL == 0 {
+				if !token.ExpiresAt.IsZero() {
+					t.Errorf("ExpiresAt = %v, want none", token.ExpiresAt)
+				}
+			} else if ttl := token.ExpiresAt.Sub(before); ttl < tt.wantTTL || ttl > tt.wantTTL+time.Minute {
+				t.Errorf("token lifetime = %v, want about %v", ttl, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    map[string]interface{}
+		wantRefresh string
+	}{
+		{"rotated refresh token", map[string]interface{}{"access_token": "at-new", "refresh_token": "rt-new"}, "rt-new"},
+		{"refresh token kept when omitted", map[string]interface{}{"access_token": "at-new"}, "rt-old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTokenServer(t, http.StatusOK, tt.response)
+			cfg := &entities.OAuth2Config{
+				TokenURL: server.URL, ClientID: "cid", ClientSecret: "cs",
+				Scope: "read", ClientAuth: entities.ClientAuthBasic,
+			}
+
+			token, err := NewClient(5*time.Second, http.DefaultTransport).RefreshToken(context.Background(), cfg, "rt-old")
+			if err != nil {
+				t.Fatalf("RefreshToken: %v", err)
+			}
+			if got := server.form.Get("grant_type"); got != "refresh_token" {
+				t.Errorf("grant_type = %q, want refresh_token", got)
+			}
+			if got := server.form.Get("refresh_token"); got != "rt-old" {
+				t.Errorf("refresh_token = %q, want rt-old", got)
+			}
+			if token.AccessToken != "at-new" || token.RefreshToken != tt.wantRefresh {
+				t.Errorf("token = %q/%q, want at-new/%q", token.AccessToken, token.RefreshToken, tt.wantRefresh)
+			}
+		})
+	}
+}
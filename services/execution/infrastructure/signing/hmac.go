@@ -0,0 +1,281 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Canonical string components for HMAC signing. "header:<name>" adds the
+// value of a request header.
+const (
+	ComponentMethod     = "method"
+	ComponentPath       = "path"
+	ComponentQuery      = "query"
+	ComponentHost       = "host"
+	ComponentTimestamp  = "timestamp"
+	ComponentNonce      = "nonce"
+	ComponentBody       = "body"
+	ComponentBodySHA256 = "body_sha256"
+	componentHeader     = "header:"
+)
+
+// defaultHMACComponents is the canonical string used when none is configured
+var defaultHMACComponents = []string{
+	ComponentMethod, ComponentPath, ComponentQuery, ComponentTimestamp, ComponentNonce, ComponentBodySHA256,
+}
+
+// HMACSigner signs requests with HMAC-SHA256 over a configurable canonical
+// string, adding timestamp and nonce headers for replay protection.
+//
+// Auth config keys:
+//
+//	secret             shared secret (required)
+//	secret_encoding    raw (default), hex or base64
+//	key_id             optional key identifier, sent in key_id_header
+//	key_id_header      default X-Key-Id
+//	signature_header   default X-Signature
+//	signature_prefix   prepended to the signature, e.g. "sha256="
+//	timestamp_header   default X-Timestamp
+//	timestamp_format   unix (default), unix_ms or rfc3339
+//	nonce_header       default X-Nonce; "-" disables the nonce
+//	body_hash_header   optional header carrying the hex body SHA-256
+//	components         canonical string parts in order (default: method, path,
+//	                   query, timestamp, nonce, body_sha256)
+//	separator          joins the components, default "\n"
+//	encoding           signature encoding: hex (default) or base64
+type HMACSigner struct {
+	secret          []byte
+	keyID           string
+	keyIDHeader     string
+	signatureHeader string
+	signaturePrefix string
+	timestampHeader string
+	timestampFormat string
+	nonceHeader     string
+	bodyHashHeader  string
+	components      []string
+	separator       string
+	encoding        string
+}
+
+// NewHMACSigner creates an HMAC signer from an auth config
+func NewHMACSigner(cfg map[string]interface{}) (*HMACSigner, error) {
+	secret, err := decodeHMACSecret(configString(cfg, "secret"), strings.ToLower(configString(cfg, "secret_encoding")))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &HMACSigner{
+		secret:          secret,
+		keyID:           configString(cfg, "key_id"),
+		keyIDHeader:     stringOr(configString(cfg, "key_id_header"), "X-Key-Id"),
+		signatureHeader: stringOr(configString(cfg, "signature_header"), "X-Signature"),
+		signaturePrefix: configString(cfg, "signature_prefix"),
+		timestampHeader: stringOr(configString(cfg, "timestamp_header"), "X-Timestamp"),
+		timestampFormat: stringOr(strings.ToLower(configString(cfg, "timestamp_format")), "unix"),
+		nonceHeader:     stringOr(configString(cfg, "nonce_header"), "X-Nonce"),
+		bodyHashHeader:  configString(cfg, "body_hash_header"),
+		components:      configStrings(cfg, "components"),
+		separator:       "\n",
+		encoding:        stringOr(strings.ToLower(configString(cfg, "encoding")), "hex"),
+	}
+	// The separator may legitimately be whitespace, so it is not trimmed
+	if sep, ok := cfg["separator"].(string); ok {
+		s.separator = sep
+	}
+	if len(s.components) == 0 {
+		s.components = defaultHMACComponents
+	}
+
+	switch s.timestampFormat {
+	case "unix", "unix_ms", "rfc3339":
+	default:
+		return nil, fmt.Errorf("hmac: unsupported timestamp_format %q", s.timestampFormat)
+	}
+	switch s.encoding {
+	case "hex", "base64":
+	default:
+		return nil, fmt.Errorf("hmac: unsupported encoding %q", s.encoding)
+	}
+	for _, component := range s.components {
+		if !validHMACComponent(component) {
+			return nil, fmt.Errorf("hmac: unknown canonical component %q", component)
+		}
+		if component == ComponentNonce && s.nonceHeader == "-" {
+			return nil, fmt.Errorf("hmac: nonce component requires a nonce_header")
+		}
+	}
+	return s, nil
+}
+
+// Sign implements Signer
+func (s *HMACSigner) Sign(req *http.Request, body []byte, now time.Time) error {
+	timestamp := s.formatTimestamp(now)
+	req.Header.Set(s.timestampHeader, timestamp)
+
+	var nonce string
+	if s.nonceHeader != "-" {
+		var err error
+		if nonce, err = newNonce(); err != nil {
+			return err
+		}
+		req.Header.Set(s.nonceHeader, nonce)
+	}
+
+	bodyHash := sha256.Sum256(body)
+	bodyHashHex := hex.EncodeToString(bodyHash[:])
+	if s.bodyHashHeader != "" {
+		req.Header.Set(s.bodyHashHeader, bodyHashHex)
+	}
+	if s.keyID != "" {
+		req.Header.Set(s.keyIDHeader, s.keyID)
+	}
+
+	parts := make([]string, 0, len(s.components))
+	for _, component := range s.components {
+		switch {
+		case component == ComponentMethod:
+			parts = append(parts, strings.ToUpper(req.Method))
+		case component == ComponentPath:
+			parts = append(parts, stringOr(req.URL.EscapedPath(), "/"))
+		case component == ComponentQuery:
+			parts = append(parts, canonicalQuery(req.URL.Query()))
+		case component == ComponentHost:
+			parts = append(parts, requestHost(req))
+		case component == ComponentTimestamp:
+			parts = append(parts, timestamp)
+		case component == ComponentNonce:
+			parts = append(parts, nonce)
+		case component == ComponentBody:
+			parts = append(parts, string(body))
+		case component == ComponentBodySHA256:
+			parts = append(parts, bodyHashHex)
+		case strings.HasPrefix(component, componentHeader):
+			parts = append(parts, strings.TrimSpace(req.Header.Get(strings.TrimPrefix(component, componentHeader))))
+		}
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join(parts, s.separator)))
+	sum := mac.Sum(nil)
+
+	var signature string
+	if s.encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	} else {
+		signature = hex.EncodeToString(sum)
+	}
+	req.Header.Set(s.signatureHeader, s.signaturePrefix+signature)
+	return nil
+}
+
+func (s *HMACSigner) formatTimestamp(now time.Time) string {
+	switch s.timestampFormat {
+	case "unix_ms":
+		return strconv.FormatInt(now.UnixMilli(), 10)
+	case "rfc3339":
+		return now.UTC().Format(time.RFC3339)
+	default:
+		return strconv.FormatInt(now.Unix(), 10)
+	}
+}
+
+func decodeHMACSecret(secret, encoding string) ([]byte, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("hmac: secret is required")
+	}
+	switch encoding {
+	case "", "raw":
+		return []byte(secret), nil
+	case "hex":
+		key, err := hex.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("hmac: secret is not valid hex")
+		}
+		return key, nil
+	case "base64":
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("hmac: secret is not valid base64")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("hmac: unsupported secret_encoding %q", encoding)
+	}
+}
+
+func validHMACComponent(component string) bool {
+	switch component {
+	case ComponentMethod, ComponentPath, ComponentQuery, ComponentHost,
+		ComponentTimestamp, ComponentNonce, ComponentBody, ComponentBodySHA256:
+		return true
+	}
+	return strings.HasPrefix(component, componentHeader) && len(component) > len(componentHeader)
+}
+
+// canonicalQuery encodes query parameters sorted by name, then value
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(values))
+	for _, key := range keys {
+		vals := append([]string(nil), values[key]...)
+		sort.Strings(vals)
+		for _, val := range vals {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(val, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything except RFC 3986 unreserved characters
+// (and '/' unless encodeSlash is set)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func newNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func stringOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
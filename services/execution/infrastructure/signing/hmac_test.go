@@ -0,0 +1,204 @@
+package signing
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHMACSignerKnownSignatures(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           map[string]interface{}
+		body          string
+		wantSignature string
+	}{
+		{
+			// RFC 4231 test case 2
+			name: "rfc 4231 hex",
+			cfg: map[string]interface{}{
+				"secret": "Jefe", "components": "body", "nonce_header": "-",
+			},
+			body:          "what do ya want for nothing?",
+			wantSignature: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name: "rfc 4231 base64 with hex secret",
+			cfg: map[string]interface{}{
+				"secret": "4a656665", "secret_encoding": "hex", "components": "body",
+				"nonce_header": "-", "encoding": "base64",
+			},
+			body:          "what do ya want for nothing?",
+			wantSignature: "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=",
+		},
+		{
+			// GitHub webhook documentation example
+			name: "github webhook",
+			cfg: map[string]interface{}{
+				"secret": "It's a Secret to Everybody", "components": []interface{}{"body"},
+				"nonce_header": "-", "signature_header": "X-Hub-Signature-256", "signature_prefix": "sha256=",
+			},
+			body:          "Hello, World!",
+			wantSignature: "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewHMACSigner(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/hook", nil)
+			if err := signer.Sign(req, []byte(tt.body), time.Unix(1700000000, 0)); err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			header := stringOr(configString(tt.cfg, "signature_header"), "X-Signature")
+			if got := req.Header.Get(header); got != tt.wantSignature {
+				t.Errorf("%s = %q, want %q", header, got, tt.wantSignature)
+			}
+			if got := req.Header.Get("X-Nonce"); got != "" {
+				t.Errorf("X-Nonce = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestHMACSignerCanonicalString(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sign := func(cfg map[string]interface{}, method, target string, body []byte) *http.Request {
+		t.Helper()
+		signer, err := NewHMACSigner(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(method, target, nil)
+		req.Header.Set("X-Tenant", "acme")
+		if err := signer.Sign(req, body, now); err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	// Signing the canonical string with the body component must match the
+	// signature over the whole request, proving the parts and their order
+	canonical := "GET|/v1/items|a=1&b=2|api.example.com|1700000000|acme|" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	want := sign(map[string]interface{}{"secret": "k", "nonce_header": "-", "components": "body"},
+		http.MethodPost, "https://other.example.com/", []byte(canonical)).Header.Get("X-Signature")
+
+	req := sign(map[string]interface{}{
+		"secret": "k", "nonce_header": "-", "separator": "|",
+		"components": "method,path,query,host,timestamp,header:X-Tenant,body_sha256",
+	}, http.MethodGet, "https://api.example.com/v1/items?b=2&a=1", nil)
+	if got := req.Header.Get("X-Signature"); got != want {
+		t.Errorf("X-Signature = %q, want %q", got, want)
+	}
+	if got := req.Header.Get("X-Timestamp"); got != "1700000000" {
+		t.Errorf("X-Timestamp = %q", got)
+	}
+}
+
+func TestHMACSignerHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    map[string]interface{}
+		header string
+		want   string
+	}{
+		{"unix timestamp", map[string]interface{}{"secret": "k"}, "X-Timestamp", "1700000000"},
+		{"unix_ms timestamp", map[string]interface{}{"secret": "k", "timestamp_format": "unix_ms"}, "X-Timestamp", "1700000000123"},
+		{"rfc3339 timestamp", map[string]interface{}{"secret": "k", "timestamp_format": "rfc3339", "timestamp_header": "Date"}, "Date", "2023-11-14T22:13:20Z"},
+		{"key id", map[string]interface{}{"secret": "k", "key_id": "key-1"}, "X-Key-Id", "key-1"},
+		{"body hash", map[string]interface{}{"secret": "k", "body_hash_header": "X-Content-SHA256"}, "X-Content-SHA256",
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewHMACSigner(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+			if err := signer.Sign(req, nil, time.UnixMilli(1700000000123)); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get(tt.header); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHMACSignerNonce(t *testing.T) {
+	signer, err := NewHMACSigner(map[string]interface{}{"secret": "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	b, _ := http.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+	signer.Sign(a, nil, now)
+	signer.Sign(b, nil, now)
+
+	if a.Header.Get("X-Nonce") == "" || a.Header.Get("X-Nonce") == b.Header.Get("X-Nonce") {
+		t.Errorf("nonces = %q, %q; want distinct values", a.Header.Get("X-Nonce"), b.Header.Get("X-Nonce"))
+	}
+	if a.Header.Get("X-Signature") == b.Header.Get("X-Signature") {
+		t.Error("requests with different nonces have the same signature")
+	}
+}
+
+func TestNewHMACSignerRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  map[string]interface{}
+	}{
+		{"no secret", map[string]interface{}{}},
+		{"bad hex secret", map[string]interface{}{"secret": "zz", "secret_encoding": "hex"}},
+		{"bad base64 secret", map[string]interface{}{"secret": "!!", "secret_encoding": "base64"}},
+		{"unknown secret encoding", map[string]interface{}{"secret": "k", "secret_encoding": "rot13"}},
+		{"unknown timestamp format", map[string]interface{}{"secret": "k", "timestamp_format": "iso"}},
+		{"unknown encoding", map[string]interface{}{"secret": "k", "encoding": "base32"}},
+		{"unknown component", map[string]interface{}{"secret": "k", "components": "method,cookie"}},
+		{"empty header component", map[string]interface{}{"secret": "k", "components": "header:"}},
+		{"nonce without header", map[string]interface{}{"secret": "k", "components": "nonce", "nonce_header": "-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHMACSigner(tt.cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestRegistrySign(t *testing.T) {
+	registry := NewRegistry()
+	cfg := map[string]interface{}{"secret": "Jefe", "components": "body", "nonce_header": "-"}
+
+	body := []byte("what do ya want for nothing?")
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/", bytes.NewReader(body))
+	if err := registry.Sign("HMAC", cfg, req); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if got := req.Header.Get("X-Signature"); got != "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		t.Errorf("X-Signature = %q", got)
+	}
+
+	// The body is still there to be sent
+	sent := new(bytes.Buffer)
+	sent.ReadFrom(req.Body)
+	if !bytes.Equal(sent.Bytes(), body) {
+		t.Errorf("body after signing = %q", sent.Bytes())
+	}
+
+	if !registry.Supports("aws_sigv4") || registry.Supports("bearer") {
+		t.Error("Supports reports the wrong signing schemes")
+	}
+	if err := registry.Sign("bearer", cfg, req); err == nil {
+		t.Error("Sign with an unregistered type succeeded")
+	}
+}
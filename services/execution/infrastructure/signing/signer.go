@@ -0,0 +1,128 @@
+package signing
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth config types handled by the built-in signers
+const (
+	TypeHMAC  = "hmac"
+	TypeSigV4 = "aws_sigv4"
+)
+
+// Signer signs a fully built request. body is the exact payload that will be
+// sent (nil for requests without a body).
+type Signer interface {
+	Sign(req *http.Request, body []byte, now time.Time) error
+}
+
+// Factory builds a signer from an environment's auth config
+type Factory func(authConfig map[string]interface{}) (Signer, error)
+
+// Registry maps auth config types to signer factories
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry with the HMAC and AWS SigV4 signers registered
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	r.Register(TypeHMAC, func(cfg map[string]interface{}) (Signer, error) {
+		return NewHMACSigner(cfg)
+	})
+	r.Register(TypeSigV4, func(cfg map[string]interface{}) (Signer, error) {
+		return NewSigV4Signer(cfg)
+	})
+	return r
+}
+
+// Register adds or replaces the factory for an auth config type
+func (r *Registry) Register(authType string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[strings.ToLower(authType)] = factory
+}
+
+// Supports reports whether authType is a signing scheme
+func (r *Registry) Supports(authType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.factories[strings.ToLower(authType)]
+	return ok
+}
+
+// Sign signs req with the signer for authType, configured from authConfig
+func (r *Registry) Sign(authType string, authConfig map[string]interface{}, req *http.Request) error {
+	r.mu.RLock()
+	factory, ok := r.factories[strings.ToLower(authType)]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no signer registered for auth type %q", authType)
+	}
+
+	signer, err := factory(authConfig)
+	if err != nil {
+		return err
+	}
+
+	body, err := requestBody(req)
+	if err != nil {
+		return err
+	}
+	return signer.Sign(req, body, time.Now().UTC())
+}
+
+// requestBody returns a copy of the request payload without consuming it
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body cannot be read for signing")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body for signing: %w", err)
+	}
+	defer body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, fmt.Errorf("failed to read body for signing: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// configString reads a trimmed string value from an auth config
+func configString(cfg map[string]interface{}, key string) string {
+	if value, ok := cfg[key].(string); ok {
+		return strings.TrimSpace(value)
+	}
+	return ""
+}
+
+// configStrings reads a list of strings, accepting a comma-separated string too
+func configStrings(cfg map[string]interface{}, key string) []string {
+	var values []string
+	switch v := cfg[key].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	}
+	return values
+}
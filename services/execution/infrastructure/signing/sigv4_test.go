@@ -0,0 +1,153 @@
+package signing
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credentials and time of the AWS Signature Version 4 test suite
+const (
+	sigV4TestAccessKey = "AKIDEXAMPLE"
+	sigV4TestSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+var sigV4TestTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestSigV4TestVectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		service       string
+		headers       map[string]string
+		body          string
+		wantSigned    string
+		wantSignature string
+	}{
+		{
+			// aws-sig-v4-test-suite: get-vanilla
+			name:          "get vanilla",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/",
+			service:       "service",
+			wantSigned:    "host;x-amz-date",
+			wantSignature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			// aws-sig-v4-test-suite: get-vanilla-query-order-key-case
+			name:          "query parameters are sorted",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			service:       "service",
+			wantSigned:    "host;x-amz-date",
+			wantSignature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			// aws-sig-v4-test-suite: post-vanilla
+			name:          "post vanilla",
+			method:        http.MethodPost,
+			url:           "https://example.amazonaws.com/",
+			service:       "service",
+			wantSigned:    "host;x-amz-date",
+			wantSignature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			// The IAM ListUsers example of the AWS General Reference
+			name:          "iam list users",
+			method:        http.MethodGet,
+			url:           "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			service:       "iam",
+			headers:       map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			wantSigned:    "content-type;host;x-amz-date",
+			wantSignature: "5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigV4Signer(map[string]interface{}{
+				"access_key_id":     sigV4TestAccessKey,
+				"secret_access_key": sigV4TestSecretKey,
+				"region":            "us-east-1",
+				"service":           tt.service,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			var body []byte
+			if tt.body != "" {
+				body = []byte(tt.body)
+			}
+			if err := signer.Sign(req, body, sigV4TestTime); err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/" + tt.service + "/aws4_request, " +
+				"SignedHeaders=" + tt.wantSigned + ", Signature=" + tt.wantSignature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization =\n  %s\nwant\n  %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSigV4SessionTokenAndS3(t *testing.T) {
+	signer, err := NewSigV4Signer(map[string]interface{}{
+		"access_key_id":     sigV4TestAccessKey,
+		"secret_access_key": sigV4TestSecretKey,
+		"session_token":     "session",
+		"region":            "us-east-1",
+		"service":           "s3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, "https://bucket.s3.amazonaws.com/key", nil)
+	if err := signer.Sign(req, []byte("payload"), sigV4TestTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session" {
+		t.Errorf("X-Amz-Security-Token = %q", got)
+	}
+	// sha256("payload")
+	if got := req.Header.Get("X-Amz-Content-Sha256"); got != "239f59ed55e737c77147cf55ad0c1b030b6d7ee748a7426952f9b852d5a935e5" {
+		t.Errorf("X-Amz-Content-Sha256 = %q", got)
+	}
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization does not sign the session token and payload hash: %s", got)
+	}
+}
+
+func TestNewSigV4SignerRequiresConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  map[string]interface{}
+	}{
+		{"no credentials", map[string]interface{}{"region": "us-east-1", "service": "s3"}},
+		{"no secret", map[string]interface{}{"access_key_id": "a", "region": "us-east-1", "service": "s3"}},
+		{"no region", map[string]interface{}{"access_key_id": "a", "secret_access_key": "s", "service": "s3"}},
+		{"no service", map[string]interface{}{"access_key_id": "a", "secret_access_key": "s", "region": "us-east-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSigV4Signer(tt.cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,8 +15,14 @@ import (
 	"github.com/testpilot-ai/execution/api"
 	"github.com/testpilot-ai/execution/api/handlers"
 	"github.com/testpilot-ai/execution/application/usecases"
+	"github.com/testpilot-ai/execution/domain/entities"
 	"github.com/testpilot-ai/execution/infrastructure/adapters"
 	"github.com/testpilot-ai/execution/infrastructure/config"
+	"github.com/testpilot-ai/execution/infrastructure/crypto"
+	"github.com/testpilot-ai/execution/infrastructure/oauth2"
+	"github.com/testpilot-ai/execution/infrastructure/signing"
+	"github.com/testpilot-ai/shared/audit"
+	sharedcrypto "github.com/testpilot-ai/shared/crypto"
 	"github.com/testpilot-ai/shared/logger"
 )
 
@@ -40,13 +47,82 @@ func main() {
 	// Initialize repositories
 	executionRepo := adapters.NewPostgresRepository(pool)
 	envRepo := adapters.NewEnvironmentRepository(pool)
+	secretRepo := adapters.NewSecretRepository(pool)
+	settingsRepo := adapters.NewSettingsRepository(pool)
+	idempotencyRepo := adapters.NewIdempotencyRepository(pool)
+	cassetteRepo := adapters.NewCassetteRepository(pool)
+	callbackRepo := adapters.NewCallbackRepository(pool)
+	loadTestRepo := adapters.NewLoadTestRepository(pool)
+	grpcDescriptorRepo := adapters.NewGRPCDescriptorRepository(pool)
+
+	// Initialize secrets keyring
+	keyring, err := loadKeyring(cfg)
+	if err != nil {
+		logger.Err(err).Msg("Invalid secrets master key configuration")
+		os.Exit(1)
+	}
 
 	// Initialize use cases
-	executeUseCase := usecases.NewExecuteAPICallUseCase(executionRepo)
-	envUseCase := usecases.NewManageEnvironmentsUseCase(envRepo)
+	envUseCase := usecases.NewManageEnvironmentsUseCase(envRepo, secretRepo, crypto.NewSecretKeyring(keyring))
+	limitsUseCase := usecases.NewExecutionLimitsUseCase(settingsRepo, entities.ExecutionLimits{
+		DefaultTimeoutSeconds: cfg.DefaultTimeout,
+		MaxTimeoutSeconds:     cfg.MaxTimeout,
+		MaxResponseBytes:      int64(cfg.MaxResponseBytes),
+	})
+	egressDefaults := entities.EgressPolicy{
+		AllowedHosts: splitList(cfg.EgressAllowedHosts),
+		AllowedCIDRs: splitList(cfg.EgressAllowedCIDRs),
+	}
+	if err := egressDefaults.Validate(); err != nil {
+		logger.Err(err).Msg("Invalid egress policy configuration")
+		os.Exit(1)
+	}
+	egressUseCase := usecases.NewEgressPolicyUseCase(settingsRepo, egressDefaults, splitList(cfg.EgressInternalHosts))
+	transports := usecases.NewTransportPool(usecases.TransportSettings{
+		ConnectTimeout:        time.Duration(cfg.ConnectTimeout) * time.Second,
+		TLSHandshakeTimeout:   time.Duration(cfg.TLSHandshakeTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(cfg.ResponseHeaderTimeout) * time.Second,
+		IdleConnTimeout:       time.Duration(cfg.IdleConnTimeout) * time.Second,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+	}, egressUseCase)
+	tokenProvider := usecases.NewOAuth2TokenProvider(
+		envUseCase,
+		oauth2.NewClient(time.Duration(cfg.OAuth2TokenTimeout)*time.Second, transports.Shared()),
+		time.Duration(cfg.OAuth2ExpiryLeeway)*time.Second,
+	)
+	idempotencyGuard := usecases.NewIdempotencyGuard(idempotencyRepo, executionRepo, cfg.DuplicateWindow)
+	cassetteUseCase := usecases.NewCassetteUseCase(cassetteRepo)
+	callbackUseCase := usecases.NewCallbackUseCase(callbackRepo, envUseCase, cfg.CallbackBaseURL)
+	executeUseCase := usecases.NewExecuteAPICallUseCase(
+		executionRepo,
+		envUseCase,
+		tokenProvider,
+		signing.NewRegistry(),
+		transports,
+		limitsUseCase,
+		idempotencyGuard,
+		egressUseCase,
+		cassetteUseCase,
+		callbackUseCase,
+		usecases.NewGRPCDescriptorCache(grpcDescriptorRepo),
+	)
+
+	loadTestUseCase := usecases.NewLoadTestUseCase(loadTestRepo, executeUseCase, envUseCase, limitsUseCase)
+
+	// Runs cut short by a restart cannot resume
+	if interrupted, err := loadTestUseCase.Recover(context.Background()); err != nil {
+		logger.Err(err).Msg("Failed to mark interrupted load tests")
+	} else if interrupted > 0 {
+		logger.Infof("Marked %d load tests interrupted by the restart", interrupted)
+	}
+
+	// Move any credentials still stored in plaintext into the secrets store
+	if err := envUseCase.MigratePlaintextSecrets(context.Background()); err != nil {
+		logger.Err(err).Msg("Failed to migrate plaintext environment secrets")
+	}
 
 	// Initialize handlers
-	handler := handlers.NewExecutionHandler(executeUseCase, envUseCase)
+	handler := handlers.NewExecutionHandler(executeUseCase, envUseCase, tokenProvider, limitsUseCase, idempotencyGuard, egressUseCase, cassetteUseCase, callbackUseCase, loadTestUseCase, audit.NewRecorder(pool, "execution"))
 
 	// Setup router
 	router := api.SetupRouter(handler)
@@ -70,6 +146,26 @@ func main() {
 	logger.Info("Shutting down server...")
 }
 
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func loadKeyring(cfg *config.Config) (*sharedcrypto.Keyring, error) {
+	if cfg.SecretsMasterKeys == "" {
+		// Default for development only - should be set in production
+		logger.Warn("SECRETS_MASTER_KEYS not set - using development key for environment secrets")
+		return sharedcrypto.DevKeyring(), nil
+	}
+	return sharedcrypto.ParseKeyring(cfg.SecretsMasterKeys, cfg.SecretsActiveKeyID)
+}
+
 func initDatabase(databaseURL string) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
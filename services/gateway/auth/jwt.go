@@ -23,14 +23,17 @@ func init() {
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Role   string    `json:"role"`
+	// MustChangePassword restricts the token to the change-password flow
+	MustChangePassword bool `json:"must_change_password,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token
-func GenerateToken(userID uuid.UUID, role string) (string, error) {
+func GenerateToken(userID uuid.UUID, role string, mustChangePassword bool) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Role:   role,
+		UserID:             userID,
+		Role:               role,
+		MustChangePassword: mustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -1,6 +1,11 @@
 package auth
 
 import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -19,3 +24,74 @@ func CheckPasswordHash(password, hash string) bool {
 
 
 
+
+// Character classes of generated passwords; look-alike characters are left out
+const (
+	upperChars  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	lowerChars  = "abcdefghijkmnopqrstuvwxyz"
+	digitChars  = "23456789"
+	symbolChars = "!#$%&*+-=?@^_"
+)
+
+// minTemporaryPasswordLength is the length of generated passwords when the
+// policy asks for less
+const minTemporaryPasswordLength = 16
+
+// GenerateTemporaryPassword returns a random password that satisfies the
+// policy for the given user, for admin resets where no password is supplied.
+// It holds every character class, so any class the policy requires is met.
+func GenerateTemporaryPassword(policy PasswordPolicy, username string) (string, error) {
+	length := policy.MinLength
+	if length < minTemporaryPasswordLength {
+		length = minTemporaryPasswordLength
+	}
+
+	// A random password rarely contains the username; retry when it does
+	for attempt := 0; attempt < 10; attempt++ {
+		password, err := randomPassword(length)
+		if err != nil {
+			return "", err
+		}
+		if policy.Validate(password, username) == nil {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate a password satisfying the policy")
+}
+
+// randomPassword returns a password of the given length with at least one
+// character of each class, in random positions
+func randomPassword(length int) (string, error) {
+	classes := []string{upperChars, lowerChars, digitChars, symbolChars}
+	all := strings.Join(classes, "")
+
+	password := make([]byte, length)
+	for i := range password {
+		set := all
+		if i < len(classes) {
+			set = classes[i]
+		}
+		n, err := randomInt(len(set))
+		if err != nil {
+			return "", err
+		}
+		password[i] = set[n]
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password), nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PasswordPolicy describes the rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength        int  `json:"min_length"`
+	RequireUpper     bool `json:"require_upper"`
+	RequireLower     bool `json:"require_lower"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DisallowUsername bool `json:"disallow_username"`
+}
+
+// LockoutPolicy controls account lockout after repeated failed logins.
+// Once Threshold consecutive failures are reached the account is locked for
+// BaseDuration, doubling with every further failure up to MaxDuration.
+type LockoutPolicy struct {
+	Threshold    int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+}
+
+// LoadPasswordPolicy reads the password policy from the environment
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:     getEnvBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:     getEnvBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:     getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		DisallowUsername: getEnvBool("PASSWORD_DISALLOW_USERNAME", true),
+	}
+}
+
+// LoadLockoutPolicy reads the lockout policy from the environment
+func LoadLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		Threshold:    getEnvInt("LOCKOUT_THRESHOLD", 5),
+		BaseDuration: time.Duration(getEnvInt("LOCKOUT_BASE_SECONDS", 60)) * time.Second,
+		MaxDuration:  time.Duration(getEnvInt("LOCKOUT_MAX_SECONDS", 3600)) * time.Second,
+	}
+}
+
+// Validate checks a candidate password against the policy
+func (p PasswordPolicy) Validate(password, username string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if p.RequireUpper && !hasUpper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("password must contain %s", strings.Join(missing, ", "))
+	}
+
+	if p.DisallowUsername && username != "" &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("password must not contain the username")
+	}
+
+	return nil
+}
+
+// LockDuration returns how long to lock an account after the given number
+// of consecutive failed attempts, or zero if it should not be locked
+func (p LockoutPolicy) LockDuration(failedAttempts int) time.Duration {
+	if p.Threshold <= 0 || failedAttempts < p.Threshold {
+		return 0
+	}
+
+	duration := p.BaseDuration
+	for i := p.Threshold; i < failedAttempts && duration < p.MaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > p.MaxDuration {
+		duration = p.MaxDuration
+	}
+	return duration
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
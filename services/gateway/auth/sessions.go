@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionStore checks that a valid token still belongs to a live session:
+// its user exists and has not changed or been reset their password since
+// the token was issued
+type SessionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionStore creates a session store backed by the users table
+func NewSessionStore(db *pgxpool.Pool) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+// Valid reports whether the session of a token is still valid
+func (s *SessionStore) Valid(ctx context.Context, claims *Claims) (bool, error) {
+	var changedAt *time.Time
+	err := s.db.QueryRow(ctx, "SELECT password_changed_at FROM users WHERE id = $1", claims.UserID).Scan(&changedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up user: %w", err)
+	}
+	return IssuedSince(claims, changedAt), nil
+}
+
+// IssuedSince reports whether a token was issued at or after a password
+// change. Token times have whole seconds, so the change is compared at
+// the same precision; a token issued right after a change stays valid.
+func IssuedSince(claims *Claims, changedAt *time.Time) bool {
+	if changedAt == nil {
+		return true
+	}
+	if claims.IssuedAt == nil {
+		return false
+	}
+	return !claims.IssuedAt.Time.Before(changedAt.Truncate(time.Second))
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// recordAudit fills in request metadata and the authenticated actor (when
+// not already set) and writes the event. Failures are logged, not returned.
+func recordAudit(c *gin.Context, recorder *audit.Recorder, event audit.Event) {
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	event.RequestID = requestIDStr
+	event.IPAddress = c.ClientIP()
+
+	if event.ActorID == nil {
+		if userID, ok := c.Get("user_id"); ok {
+			if uid, ok := userID.(uuid.UUID); ok {
+				event.ActorID = &uid
+			}
+		}
+	}
+	if event.ActorRole == "" {
+		if role, ok := c.Get("role"); ok {
+			event.ActorRole, _ = role.(string)
+		}
+	}
+
+	if err := recorder.Record(c.Request.Context(), event); err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).
+			Str("action", event.Action).
+			Msg("Failed to write audit entry")
+	}
+}
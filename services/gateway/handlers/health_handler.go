@@ -4,25 +4,33 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/testpilot-ai/gateway/proxy"
 )
 
+// healthCheckTimeout bounds each backend health probe
+const healthCheckTimeout = 5 * time.Second
+
 // HealthHandler handles health check aggregation
 type HealthHandler struct {
-	services map[string]string
+	serviceProxy *proxy.ServiceProxy
+	services     map[string]string
+	client       *http.Client
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler() *HealthHandler {
+// NewHealthHandler creates a new health handler using the proxy's service registry
+func NewHealthHandler(serviceProxy *proxy.ServiceProxy) *HealthHandler {
+	services := make(map[string]string)
+	for name, baseURL := range serviceProxy.Services() {
+		services[name] = baseURL + "/health"
+	}
 	return &HealthHandler{
-		services: map[string]string{
-			"ingestion":  "http://ingestion:8001/health",
-			"llm":        "http://llm:8002/health",
-			"execution":  "http://execution:8003/health",
-			"validation": "http://validation:8004/health",
-			"query":      "http://query:8005/health",
-		},
+		serviceProxy: serviceProxy,
+		services:     services,
+		client:       &http.Client{Timeout: healthCheckTimeout},
 	}
 }
 
@@ -34,24 +42,23 @@ func (h *HealthHandler) GatewayHealth(c *gin.Context) {
 	})
 }
 
-// AllServicesHealth checks health of all services
+// AllServicesHealth checks health of all services and reports circuit breaker state
 func (h *HealthHandler) AllServicesHealth(c *gin.Context) {
 	statuses := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
 
 	for name, url := range h.services {
-		resp, err := http.Get(url)
-		if err != nil {
-			statuses[name] = "unhealthy"
-			continue
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode == 200 {
-			statuses[name] = "healthy"
-		} else {
-			statuses[name] = fmt.Sprintf("unhealthy (status: %d)", resp.StatusCode)
-		}
+		wg.Add(1)
+		go func(name, url string) {
+			defer wg.Done()
+			status := h.checkService(url)
+			mu.Lock()
+			statuses[name] = status
+			mu.Unlock()
+		}(name, url)
 	}
+	wg.Wait()
 
 	// Determine overall status
 	overallHealthy := true
@@ -62,6 +69,13 @@ func (h *HealthHandler) AllServicesHealth(c *gin.Context) {
 		}
 	}
 
+	breakers := h.serviceProxy.BreakerStates()
+	for _, breaker := range breakers {
+		if breaker.State != proxy.StateClosed {
+			overallHealthy = false
+		}
+	}
+
 	statusCode := http.StatusOK
 	if !overallHealthy {
 		statusCode = http.StatusServiceUnavailable
@@ -70,9 +84,24 @@ func (h *HealthHandler) AllServicesHealth(c *gin.Context) {
 	c.JSON(statusCode, gin.H{
 		"status":   map[bool]string{true: "healthy", false: "degraded"}[overallHealthy],
 		"services": statuses,
+		"breakers": breakers,
 	})
 }
 
+// checkService probes a single health endpoint
+func (h *HealthHandler) checkService(url string) string {
+	resp, err := h.client.Get(url)
+	if err != nil {
+		return "unhealthy"
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return "healthy"
+	}
+	return fmt.Sprintf("unhealthy (status: %d)", resp.StatusCode)
+}
+
 // ProxyHealth proxies health check to a specific service
 func (h *HealthHandler) ProxyHealth(c *gin.Context, serviceName string) {
 	url, ok := h.services[serviceName]
@@ -81,7 +110,7 @@ func (h *HealthHandler) ProxyHealth(c *gin.Context, serviceName string) {
 		return
 	}
 
-	resp, err := http.Get(url)
+	resp, err := h.client.Get(url)
 	if err != nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"status":  "unhealthy",
@@ -95,7 +124,3 @@ func (h *HealthHandler) ProxyHealth(c *gin.Context, serviceName string) {
 	body, _ := io.ReadAll(resp.Body)
 	c.Data(resp.StatusCode, "application/json", body)
 }
-
-
-
-
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/gateway/ratelimit"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// RateLimitHandler handles rate limit administration
+type RateLimitHandler struct {
+	store    *ratelimit.Store
+	recorder *audit.Recorder
+}
+
+// NewRateLimitHandler creates a new rate limit handler
+func NewRateLimitHandler(store *ratelimit.Store, recorder *audit.Recorder) *RateLimitHandler {
+	return &RateLimitHandler{store: store, recorder: recorder}
+}
+
+// GetRateLimits returns the current rate limit settings (admin only)
+func (h *RateLimitHandler) GetRateLimits(c *gin.Context) {
+	role := c.MustGet("role").(string)
+	if role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.store.Limits(c.Request.Context()))
+}
+
+// UpdateRateLimits replaces the rate limit settings (admin only)
+func (h *RateLimitHandler) UpdateRateLimits(c *gin.Context) {
+	role := c.MustGet("role").(string)
+	if role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var limits ratelimit.Limits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := limits.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestID, _ := c.Get("request_id")
+	requestIDStr, _ := requestID.(string)
+
+	before := h.store.Limits(c.Request.Context())
+	if err := h.store.Save(c.Request.Context(), limits); err != nil {
+		logger.WithRequestID(requestIDStr).Err(err).Msg("Failed to save rate limits")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save rate limits"})
+		return
+	}
+
+	logger.WithRequestID(requestIDStr).Info().
+		Str("updated_by", c.MustGet("user_id").(uuid.UUID).String()).
+		Int("llm_per_minute", limits.LLMPerMinute).
+		Int("execution_per_minute", limits.ExecutionPerMinute).
+		Int("default_per_minute", limits.DefaultPerMinute).
+		Int("llm_daily_quota", limits.LLMDailyQuota).
+		Msg("Rate limits updated by admin")
+
+	recordAudit(c, h.recorder, audit.Event{
+		Action:     "config.update",
+		TargetType: "system_config",
+		TargetID:   ratelimit.ConfigKey,
+		Before:     before,
+		After:      limits,
+	})
+
+	c.JSON(http.StatusOK, limits)
+}
+
+// MyUsage returns the caller's LLM usage against the daily quota
+func (h *RateLimitHandler) MyUsage(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	limits := h.store.Limits(c.Request.Context())
+
+	used, err := h.store.LLMUsageToday(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"llm_calls_today": used,
+		"llm_daily_quota": limits.LLMDailyQuota,
+		"limits":          limits,
+	})
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 
+	"github.com/testpilot-ai/gateway/auth"
 	"github.com/testpilot-ai/gateway/handlers"
 	"github.com/testpilot-ai/gateway/middleware"
 	"github.com/testpilot-ai/gateway/proxy"
+	"github.com/testpilot-ai/gateway/ratelimit"
+	"github.com/testpilot-ai/shared/audit"
 	"github.com/testpilot-ai/shared/logger"
 )
 
@@ -35,18 +40,45 @@ func main() {
 	}
 	defer pool.Close()
 
+	// Initialize audit recorder
+	auditRecorder := audit.NewRecorder(pool, "gateway")
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(pool)
-	healthHandler := handlers.NewHealthHandler()
-	serviceProxy := proxy.NewServiceProxy()
+	authHandler := handlers.NewAuthHandler(pool, auditRecorder, auth.LoadPasswordPolicy(), auth.LoadLockoutPolicy())
+	serviceProxy := proxy.NewServiceProxy(proxy.LoadServiceConfigs(), proxy.LoadSettings())
+	healthHandler := handlers.NewHealthHandler(serviceProxy)
+
+	// Initialize rate limiting (limits are admin-configurable via system_config)
+	defaultPerMinute := 60
+	if v, err := strconv.Atoi(os.Getenv("RATE_LIMIT_PER_MINUTE")); err == nil {
+		defaultPerMinute = v
+	}
+	rateLimitStore := ratelimit.NewStore(pool, ratelimit.DefaultLimits(defaultPerMinute))
+	limiter := ratelimit.NewLimiter()
+	rateLimitHandler := handlers.NewRateLimitHandler(rateLimitStore, auditRecorder)
+
+	llmLimit := middleware.RateLimitMiddleware(limiter, rateLimitStore, ratelimit.GroupLLM)
+	executionLimit := middleware.RateLimitMiddleware(limiter, rateLimitStore, ratelimit.GroupExecution)
+	defaultLimit := middleware.RateLimitMiddleware(limiter, rateLimitStore, ratelimit.GroupDefault)
+
+	// Tokens issued before a password change or reset are refused
+	requireAuth := middleware.AuthMiddleware(auth.NewSessionStore(pool))
 
 	// Setup router (use gin.New() to avoid default logger noise)
 	router := gin.New()
 	router.Use(gin.Recovery())
 
+	// Only trusted proxies may set the client IP through X-Forwarded-For;
+	// without any, the IP is that of the connection
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		logger.Err(err).Msg("Invalid TRUSTED_PROXIES")
+		os.Exit(1)
+	}
+
 	// Apply global middleware
 	router.Use(middleware.CORSMiddleware())
 	router.Use(middleware.LoggingMiddleware())
+	router.Use(middleware.AuditMiddleware(auditRecorder))
 
 	// Health routes (no auth required)
 	router.GET("/health", healthHandler.GatewayHealth)
@@ -61,82 +93,105 @@ func main() {
 
 	// Protected auth routes
 	authProtected := router.Group("/api/v1/auth")
-	authProtected.Use(middleware.AuthMiddleware())
+	authProtected.Use(requireAuth)
 	{
 		authProtected.GET("/me", authHandler.Me)
+		authProtected.GET("/usage", rateLimitHandler.MyUsage)
+		authProtected.POST("/change-password", authHandler.ChangePassword)
 	}
 
 	// User management routes (admin only)
 	users := router.Group("/api/v1/users")
-	users.Use(middleware.AuthMiddleware())
+	users.Use(requireAuth)
 	{
 		users.GET("", authHandler.ListUsers)
 		users.POST("", authHandler.CreateUser)
 		users.DELETE("/:id", authHandler.DeleteUser)
+		users.POST("/:id/reset-password", authHandler.ResetPassword)
+		users.POST("/:id/unlock", authHandler.UnlockUser)
+	}
+
+	// Admin configuration routes (admin only)
+	admin := router.Group("/api/v1/admin")
+	admin.Use(requireAuth)
+	{
+		admin.GET("/rate-limits", rateLimitHandler.GetRateLimits)
+		admin.PUT("/rate-limits", rateLimitHandler.UpdateRateLimits)
 	}
 
 	// Protected service proxy routes
 	// Ingestion service
-	router.Any("/api/v1/ingest/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/ingest/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/apis", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/apis", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/apis/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/apis/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
 
 	// LLM service
-	router.Any("/api/v1/llm/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/llm/*path", requireAuth, llmLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/parse", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/parse", requireAuth, llmLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/construct", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/construct", requireAuth, llmLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
 
 	// Execution service
-	router.Any("/api/v1/execute", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/execute", requireAuth, executionLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/execute/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/execute/*path", requireAuth, executionLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/environments", middleware.AuthMiddleware(), func(c *gin.Context) {
+	// Callbacks from the APIs under test carry no credentials; the token in
+	// the URL identifies the receiver and each receiver caps what it stores
+	router.Any("/callbacks/:token", func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/environments/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/environments", requireAuth, defaultLimit, func(c *gin.Context) {
+		serviceProxy.RouteToService(c)
+	})
+	router.Any("/api/v1/environments/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
 
 	// Validation service
-	router.Any("/api/v1/validate", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/validate", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/validate/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/validate/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/rules", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/rules", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/rules/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/rules/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
 
 	// Query service
-	router.Any("/api/v1/history", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/history", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/history/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/history/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/analytics", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/analytics", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
-	router.Any("/api/v1/analytics/*path", middleware.AuthMiddleware(), func(c *gin.Context) {
+	router.Any("/api/v1/analytics/*path", requireAuth, defaultLimit, func(c *gin.Context) {
+		serviceProxy.RouteToService(c)
+	})
+	router.Any("/api/v1/audit", requireAuth, defaultLimit, func(c *gin.Context) {
+		serviceProxy.RouteToService(c)
+	})
+	router.Any("/api/v1/audit/*path", requireAuth, defaultLimit, func(c *gin.Context) {
 		serviceProxy.RouteToService(c)
 	})
 
@@ -153,6 +208,18 @@ func main() {
 	}
 }
 
+// trustedProxies returns the IPs and CIDRs of TRUSTED_PROXIES, a
+// comma-separated list, or nil to trust none
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func initDatabase(databaseURL string) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// AuditMiddleware records every mutating request (POST, PUT, PATCH, DELETE)
+// in the audit log once it has been handled. Auth routes are skipped because
+// the auth handler records its own, more specific events.
+func AuditMiddleware(recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			return
+		}
+
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/v1/") || strings.HasPrefix(path, "/api/v1/auth/") {
+			return
+		}
+
+		requestID, _ := c.Get("request_id")
+		requestIDStr, _ := requestID.(string)
+
+		targetType, targetID := splitTarget(path)
+		event := audit.Event{
+			Action:     "http." + strings.ToLower(method),
+			TargetType: targetType,
+			TargetID:   targetID,
+			RequestID:  requestIDStr,
+			IPAddress:  c.ClientIP(),
+			Metadata: map[string]interface{}{
+				"path":   path,
+				"status": c.Writer.Status(),
+			},
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			if uid, ok := userID.(uuid.UUID); ok {
+				event.ActorID = &uid
+			}
+		}
+		if role, ok := c.Get("role"); ok {
+			event.ActorRole, _ = role.(string)
+		}
+
+		if err := recorder.Record(c.Request.Context(), event); err != nil {
+			logger.WithRequestID(requestIDStr).Err(err).
+				Str("path", path).
+				Msg("Failed to write audit entry")
+		}
+	}
+}
+
+// splitTarget derives the target type and ID from an /api/v1/<type>/<id...> path
+func splitTarget(path string) (string, string) {
+	rest := strings.TrimPrefix(path, "/api/v1/")
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
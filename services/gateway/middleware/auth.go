@@ -1,15 +1,24 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/testpilot-ai/gateway/auth"
+	"github.com/testpilot-ai/shared/logger"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware() gin.HandlerFunc {
+// SessionValidator tells whether a well-formed token's session is still
+// valid, e.g. that no password change has happened since it was issued
+type SessionValidator interface {
+	Valid(ctx context.Context, claims *auth.Claims) (bool, error)
+}
+
+// AuthMiddleware validates JWT tokens and rejects those whose session has
+// ended, like tokens issued before a password change or reset
+func AuthMiddleware(sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -34,6 +43,29 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		valid, err := sessions.Valid(c.Request.Context(), claims)
+		if err != nil {
+			logger.Err(err).Str("user_id", claims.UserID.String()).Msg("Failed to check session")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to verify session"})
+			c.Abort()
+			return
+		}
+		if !valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session is no longer valid, please log in again"})
+			c.Abort()
+			return
+		}
+
+		// Accounts flagged for rotation may only see who they are and change the password
+		if claims.MustChangePassword && !passwordChangeAllowed(c.Request.URL.Path) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Password change required",
+				"code":  "password_change_required",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set claims in context
 		c.Set("user_id", claims.UserID)
 		c.Set("role", claims.Role)
@@ -41,3 +73,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// passwordChangeAllowed lists the routes usable before a required password change
+func passwordChangeAllowed(path string) bool {
+	return path == "/api/v1/auth/me" || path == "/api/v1/auth/change-password"
+}
@@ -13,7 +13,12 @@ import (
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		requestID := uuid.New().String()
+
+		// Reuse an incoming request ID so logs correlate across services
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
 
 		c.Set("request_id", requestID)
 		c.Header("X-Request-ID", requestID)
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/gateway/ratelimit"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// RateLimitMiddleware enforces per-user token-bucket limits for a route group.
+// For the LLM group it also enforces the daily per-user quota.
+// Must run after AuthMiddleware so the user id is available.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, store *ratelimit.Store, group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("user_id")
+		userID, ok := value.(uuid.UUID)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		requestID, _ := c.Get("request_id")
+		requestIDStr, _ := requestID.(string)
+
+		limits := store.Limits(c.Request.Context())
+
+		decision := limiter.Allow(userID.String()+":"+group, limits.PerMinute(group))
+		if decision.Limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		}
+
+		if !decision.Allowed {
+			logger.WithRequestID(requestIDStr).Warn().
+				Str("user_id", userID.String()).
+				Str("group", group).
+				Int("limit_per_minute", decision.Limit).
+				Msg("Rate limit exceeded")
+			abortTooManyRequests(c, decision.RetryAfter, "Rate limit exceeded, please slow down")
+			return
+		}
+
+		if group == ratelimit.GroupLLM && limits.LLMDailyQuota > 0 {
+			used, allowed, err := store.ConsumeLLMQuota(c.Request.Context(), userID, limits.LLMDailyQuota)
+			if err != nil {
+				// Fail open: a quota bookkeeping error should not block testing
+				logger.WithRequestID(requestIDStr).Err(err).
+					Str("user_id", userID.String()).
+					Msg("Failed to check daily LLM quota")
+				c.Next()
+				return
+			}
+
+			c.Header("X-Quota-Limit", strconv.Itoa(limits.LLMDailyQuota))
+			c.Header("X-Quota-Remaining", strconv.Itoa(max(limits.LLMDailyQuota-used, 0)))
+
+			if !allowed {
+				logger.WithRequestID(requestIDStr).Warn().
+					Str("user_id", userID.String()).
+					Int("daily_quota", limits.LLMDailyQuota).
+					Msg("Daily LLM quota exhausted")
+				abortTooManyRequests(c, ratelimit.UntilQuotaReset(time.Now()), "Daily LLM quota exhausted")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// abortTooManyRequests responds with 429 and a Retry-After header in whole seconds
+func abortTooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       message,
+		"retry_after": seconds,
+	})
+	c.Abort()
+}
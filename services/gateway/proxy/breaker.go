@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// BreakerSnapshot is a point-in-time view of a circuit breaker
+type BreakerSnapshot struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
+// CircuitBreaker trips after a run of consecutive failures and rejects calls
+// until the open period elapses. It then lets a single probe through
+// (half-open); the probe's outcome closes or re-opens the breaker.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+
+	state         string
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+	now           func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		state:            StateClosed,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed. When it returns false, the
+// second value is how long until the breaker will admit a probe.
+func (b *CircuitBreaker) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		wait := b.openDuration - b.now().Sub(b.openedAt)
+		if wait > 0 {
+			return false, wait
+		}
+		b.state = StateHalfOpen
+		b.probeInFlight = true
+		return true, 0
+	case StateHalfOpen:
+		if b.probeInFlight {
+			return false, time.Second
+		}
+		b.probeInFlight = true
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// Success records a successful call and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probeInFlight = false
+}
+
+// Failure records a failed call, opening the breaker when the threshold is
+// reached or when a half-open probe fails
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probeInFlight = false
+	if b.state == StateHalfOpen || b.failures >= b.failureThreshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Abandon releases a half-open probe whose outcome is unknown (e.g. the
+// client disconnected) without counting it as a success or failure
+func (b *CircuitBreaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// Snapshot returns the current breaker state
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := BreakerSnapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(b.openDuration)
+		snapshot.OpenedAt = &openedAt
+		snapshot.RetryAt = &retryAt
+	}
+	return snapshot
+}
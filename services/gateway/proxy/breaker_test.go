@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker on a manually advanced clock
+func newTestBreaker(threshold int, openDuration time.Duration) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(threshold, openDuration)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b, now := newTestBreaker(3, 30*time.Second)
+	opened := *now
+
+	// Closed: failures below the threshold still let calls through
+	for i := 0; i < 2; i++ {
+		if allowed, _ := b.Allow(); !allowed {
+			t.Fatalf("closed breaker rejected call %d", i)
+		}
+		b.Failure()
+	}
+	if s := b.Snapshot(); s.State != StateClosed || s.ConsecutiveFailures != 2 || s.OpenedAt != nil {
+		t.Fatalf("after 2 failures = %+v, want closed", s)
+	}
+
+	// Open: the third consecutive failure trips the breaker
+	b.Failure()
+	s := b.Snapshot()
+	if s.State != StateOpen || !s.OpenedAt.Equal(opened) || !s.RetryAt.Equal(opened.Add(30*time.Second)) {
+		t.Fatalf("after 3 failures = %+v, want open", s)
+	}
+	*now = now.Add(10 * time.Second)
+	if allowed, wait := b.Allow(); allowed || wait != 20*time.Second {
+		t.Fatalf("open Allow = %v, %v; want rejected for 20s", allowed, wait)
+	}
+
+	// Half-open: once the open period ends a single probe is admitted
+	*now = now.Add(20 * time.Second)
+	if allowed, _ := b.Allow(); !allowed {
+		t.Fatal("probe was rejected after the open period")
+	}
+	if b.Snapshot().State != StateHalfOpen {
+		t.Fatalf("state = %s, want half-open", b.Snapshot().State)
+	}
+	if allowed, wait := b.Allow(); allowed || wait != time.Second {
+		t.Fatalf("second call while probing = %v, %v; want rejected", allowed, wait)
+	}
+
+	// Closed: a successful probe resets the breaker
+	b.Success()
+	if s := b.Snapshot(); s.State != StateClosed || s.ConsecutiveFailures != 0 || s.OpenedAt != nil {
+		t.Fatalf("after a successful probe = %+v, want closed", s)
+	}
+	if allowed, _ := b.Allow(); !allowed {
+		t.Error("closed breaker rejected a call")
+	}
+}
+
+func TestCircuitBreakerFailedProbe(t *testing.T) {
+	b, now := newTestBreaker(1, time.Minute)
+	b.Failure()
+	*now = now.Add(time.Minute)
+	if allowed, _ := b.Allow(); !allowed {
+		t.Fatal("probe was rejected")
+	}
+
+	// A failed probe re-opens the breaker for a full period
+	b.Failure()
+	if s := b.Snapshot(); s.State != StateOpen || !s.OpenedAt.Equal(*now) {
+		t.Fatalf("after a failed probe = %+v, want open from now", s)
+	}
+	if allowed, wait := b.Allow(); allowed || wait != time.Minute {
+		t.Errorf("Allow = %v, %v; want rejected for 1m", allowed, wait)
+	}
+}
+
+func TestCircuitBreakerAbandonedProbe(t *testing.T) {
+	b, now := newTestBreaker(1, time.Minute)
+	b.Failure()
+	*now = now.Add(time.Minute)
+	b.Allow()
+
+	// An abandoned probe frees the slot without changing the state
+	b.Abandon()
+	if s := b.Snapshot(); s.State != StateHalfOpen || s.ConsecutiveFailures != 1 {
+		t.Fatalf("after abandon = %+v, want half-open", s)
+	}
+	if allowed, _ := b.Allow(); !allowed {
+		t.Error("next probe was rejected after an abandoned one")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Minute)
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if s := b.Snapshot(); s.State != StateClosed || s.ConsecutiveFailures != 1 {
+		t.Errorf("failures separated by a success = %+v, want closed", s)
+	}
+}
+
+func TestNewCircuitBreakerMinimumThreshold(t *testing.T) {
+	b, _ := newTestBreaker(0, time.Minute)
+	b.Failure()
+	if s := b.Snapshot(); s.State != StateOpen {
+		t.Errorf("threshold 0 after one failure = %s, want open", s.State)
+	}
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceConfig describes a backend service the gateway proxies to
+type ServiceConfig struct {
+	Name    string
+	BaseURL string
+	// Timeout bounds how long the gateway waits for response headers.
+	// Streaming bodies are not cut off once headers have arrived.
+	Timeout time.Duration
+}
+
+// Settings holds proxy-wide resilience settings
+type Settings struct {
+	MaxRetries       int
+	RetryBackoff     time.Duration
+	FailureThreshold int
+	OpenDuration     time.Duration
+}
+
+// defaultServices lists the backend services with their docker-compose
+// defaults. The LLM service gets a longer timeout because generation is slow.
+var defaultServices = []ServiceConfig{
+	{Name: "ingestion", BaseURL: "http://ingestion:8001", Timeout: 60 * time.Second},
+	{Name: "llm", BaseURL: "http://llm:8002", Timeout: 120 * time.Second},
+	{Name: "execution", BaseURL: "http://execution:8003", Timeout: 90 * time.Second},
+	{Name: "validation", BaseURL: "http://validation:8004", Timeout: 30 * time.Second},
+	{Name: "query", BaseURL: "http://query:8005", Timeout: 30 * time.Second},
+}
+
+// LoadServiceConfigs builds the service registry from the environment.
+// Each service reads <NAME>_SERVICE_URL and <NAME>_SERVICE_TIMEOUT (seconds).
+func LoadServiceConfigs() []ServiceConfig {
+	configs := make([]ServiceConfig, 0, len(defaultServices))
+	for _, svc := range defaultServices {
+		prefix := strings.ToUpper(svc.Name) + "_SERVICE_"
+		if url := os.Getenv(prefix + "URL"); url != "" {
+			svc.BaseURL = strings.TrimRight(url, "/")
+		}
+		if seconds := getEnvInt(prefix+"TIMEOUT", 0); seconds > 0 {
+			svc.Timeout = time.Duration(seconds) * time.Second
+		}
+		configs = append(configs, svc)
+	}
+	return configs
+}
+
+// LoadSettings reads proxy resilience settings from the environment
+func LoadSettings() Settings {
+	return Settings{
+		MaxRetries:       getEnvInt("PROXY_MAX_RETRIES", 2),
+		RetryBackoff:     time.Duration(getEnvInt("PROXY_RETRY_BACKOFF_MS", 200)) * time.Millisecond,
+		FailureThreshold: getEnvInt("BREAKER_FAILURE_THRESHOLD", 5),
+		OpenDuration:     time.Duration(getEnvInt("BREAKER_OPEN_SECONDS", 30)) * time.Second,
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -1,39 +1,96 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/testpilot-ai/shared/logger"
 )
 
+// hopByHopHeaders must not be forwarded by proxies (RFC 7230 section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// backend is a registered service with its own pooled client and breaker
+type backend struct {
+	config  ServiceConfig
+	client  *http.Client
+	breaker *CircuitBreaker
+}
+
 // ServiceProxy handles proxying requests to backend services
 type ServiceProxy struct {
-	services map[string]string
+	services map[string]*backend
+	settings Settings
+}
+
+// NewServiceProxy creates a new service proxy from the service registry
+func NewServiceProxy(configs []ServiceConfig, settings Settings) *ServiceProxy {
+	services := make(map[string]*backend, len(configs))
+	for _, cfg := range configs {
+		transport := &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   20,
+			IdleConnTimeout:       90 * time.Second,
+			ResponseHeaderTimeout: cfg.Timeout,
+		}
+		services[cfg.Name] = &backend{
+			config: cfg,
+			// No client-level timeout: it would cut off streamed responses.
+			client:  &http.Client{Transport: transport},
+			breaker: NewCircuitBreaker(settings.FailureThreshold, settings.OpenDuration),
+		}
+	}
+	return &ServiceProxy{services: services, settings: settings}
+}
+
+// Services returns the registered service base URLs keyed by name
+func (sp *ServiceProxy) Services() map[string]string {
+	urls := make(map[string]string, len(sp.services))
+	for name, b := range sp.services {
+		urls[name] = b.config.BaseURL
+	}
+	return urls
 }
 
-// NewServiceProxy creates a new service proxy
-func NewServiceProxy() *ServiceProxy {
-	return &ServiceProxy{
-		services: map[string]string{
-			"ingestion": "http://ingestion:8001",
-			"llm":       "http://llm:8002",
-			"execution": "http://execution:8003",
-			"validation": "http://validation:8004",
-			"query":     "http://query:8005",
-		},
+// BreakerStates returns a snapshot of every service's circuit breaker
+func (sp *ServiceProxy) BreakerStates() map[string]BreakerSnapshot {
+	states := make(map[string]BreakerSnapshot, len(sp.services))
+	for name, b := range sp.services {
+		states[name] = b.breaker.Snapshot()
 	}
+	return states
 }
 
 // ProxyRequest forwards a request to a backend service
 func (sp *ServiceProxy) ProxyRequest(c *gin.Context, serviceName, path string) {
 	requestID, _ := c.Get("request_id")
 	requestIDStr, _ := requestID.(string)
-	
-	baseURL, ok := sp.services[serviceName]
+
+	svc, ok := sp.services[serviceName]
 	if !ok {
 		logger.WithRequestID(requestIDStr).Error().
 			Str("service", serviceName).
@@ -42,45 +99,111 @@ func (sp *ServiceProxy) ProxyRequest(c *gin.Context, serviceName, path string) {
 		return
 	}
 
+	if allowed, wait := svc.breaker.Allow(); !allowed {
+		logger.WithRequestID(requestIDStr).Warn().
+			Str("service", serviceName).
+			Msg("Circuit breaker open, rejecting request")
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Service temporarily unavailable"})
+		return
+	}
+
 	// Build target URL
-	targetURL := baseURL + path
+	targetURL := svc.config.BaseURL + path
 	if c.Request.URL.RawQuery != "" {
 		targetURL += "?" + c.Request.URL.RawQuery
 	}
 
-	// Create request
-	req, err := http.NewRequest(c.Request.Method, targetURL, c.Request.Body)
-	if err != nil {
-		logger.WithRequestID(requestIDStr).Err(err).
-			Str("service", serviceName).
-			Str("url", targetURL).
-			Msg("Failed to create proxy request")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
-		return
+	// Idempotent requests are buffered so they can be replayed on retry;
+	// everything else is streamed through once.
+	retryable := isIdempotent(c.Request.Method)
+	var body []byte
+	if retryable && c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(c.Request.Body)
+		if err != nil {
+			svc.breaker.Abandon()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
 	}
 
-	// Copy headers
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			req.Header.Add(key, value)
-		}
+	maxAttempts := 1
+	if retryable {
+		maxAttempts += sp.settings.MaxRetries
 	}
 
-	// Add user_id header from context (set by auth middleware)
-	if userID, exists := c.Get("user_id"); exists {
-		if uid, ok := userID.(uuid.UUID); ok {
-			req.Header.Set("X-User-ID", uid.String())
+	var resp *http.Response
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			if allowed, _ := svc.breaker.Allow(); !allowed {
+				break
+			}
+			backoff := sp.settings.RetryBackoff * time.Duration(1<<(attempt-2))
+			select {
+			case <-time.After(backoff):
+			case <-c.Request.Context().Done():
+			}
+		}
+
+		var reqBody io.Reader = c.Request.Body
+		if retryable {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, buildErr := sp.buildRequest(c, targetURL, reqBody, requestIDStr)
+		if buildErr != nil {
+			svc.breaker.Abandon()
+			logger.WithRequestID(requestIDStr).Err(buildErr).
+				Str("service", serviceName).
+				Str("url", targetURL).
+				Msg("Failed to create proxy request")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+			return
+		}
+
+		resp, err = svc.client.Do(req)
+
+		// The caller went away: neither a backend success nor failure
+		if c.Request.Context().Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			svc.breaker.Abandon()
+			return
+		}
+
+		if err == nil && !isBackendFailure(resp.StatusCode) {
+			svc.breaker.Success()
+			break
+		}
+
+		svc.breaker.Failure()
+		if attempt < maxAttempts && retryable {
+			if resp != nil {
+				resp.Body.Close()
+				resp = nil
+			}
+			logger.WithRequestID(requestIDStr).Warn().
+				Str("service", serviceName).
+				Int("attempt", attempt).
+				Msg("Retrying request to backend service")
 		}
 	}
 
-	// Make request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	if resp == nil {
+		if err == nil {
+			err = errors.New("circuit breaker opened during retries")
+		}
 		logger.WithRequestID(requestIDStr).Err(err).
 			Str("service", serviceName).
 			Str("url", targetURL).
 			Msg("Failed to contact backend service")
+		if isTimeout(err) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Service timed out"})
+			return
+		}
 		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to contact service"})
 		return
 	}
@@ -93,15 +216,120 @@ func (sp *ServiceProxy) ProxyRequest(c *gin.Context, serviceName, path string) {
 		Msg("Proxied request to backend service")
 
 	// Copy response headers
+	header := c.Writer.Header()
 	for key, values := range resp.Header {
+		header.Del(key)
 		for _, value := range values {
-			c.Header(key, value)
+			header.Add(key, value)
 		}
 	}
+	removeHopByHop(header)
+	header.Set("X-Request-ID", requestIDStr)
 
-	// Copy response body
 	c.Status(resp.StatusCode)
-	io.Copy(c.Writer, resp.Body)
+	streamBody(c.Writer, resp)
+}
+
+// buildRequest creates the outbound request with forwarded and identity headers
+func (sp *ServiceProxy) buildRequest(c *gin.Context, targetURL string, body io.Reader, requestID string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, targetURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy headers
+	for key, values := range c.Request.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	removeHopByHop(req.Header)
+
+	// Add user_id and role headers from context (set by auth middleware).
+	// Always overwrite so clients cannot spoof identity headers.
+	req.Header.Del("X-User-ID")
+	req.Header.Del("X-User-Role")
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uuid.UUID); ok {
+			req.Header.Set("X-User-ID", uid.String())
+		}
+	}
+	if role, exists := c.Get("role"); exists {
+		if r, ok := role.(string); ok {
+			req.Header.Set("X-User-Role", r)
+		}
+	}
+
+	// Forward the original client IP for audit logging. ClientIP only
+	// honours X-Forwarded-For from trusted proxies, and the client's own
+	// X-Real-IP is dropped so backends see no other IP.
+	req.Header.Set("X-Forwarded-For", c.ClientIP())
+	req.Header.Del("X-Real-IP")
+
+	// Propagate the request ID so backend logs can be correlated
+	if requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+
+	return req, nil
+}
+
+// streamBody copies the response body, flushing as data arrives for
+// streamed responses (SSE, chunked) so clients see events immediately
+func streamBody(w gin.ResponseWriter, resp *http.Response) {
+	streaming := resp.ContentLength < 0 ||
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	if !streaming {
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return
+			}
+			w.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isIdempotent reports whether a request with this method may be retried
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isBackendFailure reports whether a status means the backend itself is
+// unhealthy. Plain 500s are not counted: services use them for request-level
+// errors (e.g. a failed test execution).
+func isBackendFailure(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+// isTimeout reports whether err is a network or context timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func removeHopByHop(header http.Header) {
+	for _, h := range hopByHopHeaders {
+		header.Del(h)
+	}
 }
 
 // RouteToService determines which service to route to based on path
@@ -128,6 +356,8 @@ func (sp *ServiceProxy) RouteToService(c *gin.Context) {
 		sp.ProxyRequest(c, "execution", path)
 	case strings.HasPrefix(path, "/api/v1/environments"):
 		sp.ProxyRequest(c, "execution", path)
+	case strings.HasPrefix(path, "/callbacks/"):
+		sp.ProxyRequest(c, "execution", path)
 
 	// Validation service routes
 	case strings.HasPrefix(path, "/api/v1/validate"):
@@ -140,6 +370,8 @@ func (sp *ServiceProxy) RouteToService(c *gin.Context) {
 		sp.ProxyRequest(c, "query", path)
 	case strings.HasPrefix(path, "/api/v1/analytics"):
 		sp.ProxyRequest(c, "query", path)
+	case strings.HasPrefix(path, "/api/v1/audit"):
+		sp.ProxyRequest(c, "query", path)
 
 	default:
 		requestID, _ := c.Get("request_id")
@@ -150,7 +382,3 @@ func (sp *ServiceProxy) RouteToService(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
 	}
 }
-
-
-
-
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// flakyBackend answers the first failures requests with failStatus and
+// then 200, recording the bodies it received
+type flakyBackend struct {
+	mu         sync.Mutex
+	failures   int
+	failStatus int
+	bodies     []string
+}
+
+func (f *flakyBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.bodies = append(f.bodies, string(body))
+	fail := len(f.bodies) <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		w.WriteHeader(f.failStatus)
+		io.WriteString(w, "failing")
+		return
+	}
+	io.WriteString(w, "ok")
+}
+
+func (f *flakyBackend) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.bodies)
+}
+
+// proxyTo sends one request through a proxy for the backend
+func proxyTo(sp *ServiceProxy, method, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Any("/*path", func(c *gin.Context) {
+		sp.ProxyRequest(c, "backend", c.Request.URL.Path)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/things", strings.NewReader(body)))
+	return w
+}
+
+func TestProxyRequestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		failures   int
+		failStatus int
+		threshold  int
+		wantStatus int
+		wantBody   string
+		wantCalls  int
+		wantState  string
+	}{
+		{
+			name: "recovers within the retries", method: http.MethodGet, failures: 2, failStatus: http.StatusServiceUnavailable,
+			wantStatus: http.StatusOK, wantBody: "ok", wantCalls: 3, wantState: StateClosed,
+		},
+		{
+			name: "last failure is passed through", method: http.MethodGet, failures: 3, failStatus: http.StatusBadGateway,
+			wantStatus: http.StatusBadGateway, wantBody: "failing", wantCalls: 3, wantState: StateClosed,
+		},
+		{
+			name: "body is replayed on retry", method: http.MethodPut, failures: 1, failStatus: http.StatusGatewayTimeout,
+			wantStatus: http.StatusOK, wantBody: "ok", wantCalls: 2, wantState: StateClosed,
+		},
+		{
+			name: "non-idempotent requests are not retried", method: http.MethodPost, failures: 1, failStatus: http.StatusServiceUnavailable,
+			wantStatus: http.StatusServiceUnavailable, wantBody: "failing", wantCalls: 1, wantState: StateClosed,
+		},
+		{
+			name: "plain 500 is not retried", method: http.MethodGet, failures: 1, failStatus: http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError, wantBody: "failing", wantCalls: 1, wantState: StateClosed,
+		},
+		{
+			name: "breaker opening stops the retries", method: http.MethodGet, failures: 5, failStatus: http.StatusServiceUnavailable,
+			threshold: 2, wantStatus: http.StatusBadGateway, wantCalls: 2, wantState: StateOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &flakyBackend{failures: tt.failures, failStatus: tt.failStatus}
+			server := httptest.NewServer(backend)
+			defer server.Close()
+
+			threshold := tt.threshold
+			if threshold == 0 {
+				threshold = 10
+			}
+			sp := NewServiceProxy(
+				[]ServiceConfig{{Name: "backend", BaseURL: server.URL, Timeout: 5 * time.Second}},
+				Settings{MaxRetries: 2, RetryBackoff: time.Millisecond, FailureThreshold: threshold, OpenDuration: time.Minute},
+			)
+
+			w := proxyTo(sp, tt.method, `{"name":"widget"}`)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if got := backend.calls(); got != tt.wantCalls {
+				t.Errorf("backend calls = %d, want %d", got, tt.wantCalls)
+			}
+			for i, body := range backend.bodies {
+				if body != `{"name":"widget"}` {
+					t.Errorf("attempt %d body = %q", i+1, body)
+				}
+			}
+			if state := sp.BreakerStates()["backend"].State; state != tt.wantState {
+				t.Errorf("breaker state = %s, want %s", state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestProxyRequestOpenBreaker(t *testing.T) {
+	backend := &flakyBackend{}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+
+	sp := NewServiceProxy(
+		[]ServiceConfig{{Name: "backend", BaseURL: server.URL, Timeout: 5 * time.Second}},
+		Settings{FailureThreshold: 1, OpenDuration: 90 * time.Second},
+	)
+	sp.services["backend"].breaker.Failure()
+
+	w := proxyTo(sp, http.MethodGet, "")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "90" {
+		t.Errorf("open breaker = %d, Retry-After %q; want 503 after 90s", w.Code, w.Header().Get("Retry-After"))
+	}
+	if backend.calls() != 0 {
+		t.Errorf("backend was called %d times through an open breaker", backend.calls())
+	}
+}
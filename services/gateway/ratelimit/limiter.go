@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Route groups with separate budgets
+const (
+	GroupLLM       = "llm"
+	GroupExecution = "execution"
+	GroupDefault   = "default"
+)
+
+// bucketIdleTTL is how long an unused bucket is kept before being evicted
+const bucketIdleTTL = 10 * time.Minute
+
+// Decision is the outcome of a rate limit check
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// bucket is a single token bucket
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// Limiter is an in-memory token-bucket limiter keyed by arbitrary strings
+// (typically user id + route group). Each bucket holds up to perMinute tokens
+// and refills continuously at perMinute/60 tokens per second.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewLimiter creates a new limiter and starts evicting idle buckets
+func NewLimiter() *Limiter {
+	l := &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	go l.evictIdle()
+	return l
+}
+
+// Allow takes one token from the bucket identified by key.
+// A perMinute value <= 0 disables limiting for that key.
+func (l *Limiter) Allow(key string, perMinute int) Decision {
+	if perMinute <= 0 {
+		return Decision{Allowed: true, Limit: 0, Remaining: -1}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(perMinute)
+	ratePerSec := capacity / 60
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, lastSeen: now}
+		l.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.lastSeen).Seconds()
+		b.tokens = math.Min(capacity, b.tokens+elapsed*ratePerSec)
+		b.lastSeen = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Decision{
+			Allowed:   true,
+			Limit:     perMinute,
+			Remaining: int(b.tokens),
+		}
+	}
+
+	// Time until one full token is available again
+	wait := time.Duration((1 - b.tokens) / ratePerSec * float64(time.Second))
+	return Decision{
+		Allowed:    false,
+		Limit:      perMinute,
+		Remaining:  0,
+		RetryAfter: wait,
+	}
+}
+
+// evictIdle periodically drops buckets that have not been used recently
+func (l *Limiter) evictIdle() {
+	ticker := time.NewTicker(bucketIdleTTL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.evict()
+	}
+}
+
+// evict drops the buckets unused for bucketIdleTTL
+func (l *Limiter) evict() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := l.now().Add(-bucketIdleTTL)
+	for key, b := range l.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
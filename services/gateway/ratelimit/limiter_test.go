@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced time source
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestLimiter returns a limiter on a fake clock, without the eviction loop
+func newTestLimiter() (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	return &Limiter{buckets: make(map[string]*bucket), now: clock.Now}, clock
+}
+
+func TestLimiterBurst(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	// A fresh bucket allows a burst of perMinute requests
+	for i := 0; i < 5; i++ {
+		d := l.Allow("user-1:default", 5)
+		if !d.Allowed || d.Limit != 5 || d.Remaining != 4-i {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i, d, 4-i)
+		}
+	}
+
+	d := l.Allow("user-1:default", 5)
+	if d.Allowed || d.Remaining != 0 {
+		t.Fatalf("request over the burst = %+v, want denied", d)
+	}
+	// 5 per minute refills one token every 12s
+	if d.RetryAfter != 12*time.Second {
+		t.Errorf("RetryAfter = %v, want 12s", d.RetryAfter)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l, clock := newTestLimiter()
+	for i := 0; i < 60; i++ {
+		l.Allow("k", 60)
+	}
+
+	// 60 per minute refills one token per second
+	clock.Advance(400 * time.Millisecond)
+	d := l.Allow("k", 60)
+	if d.Allowed || d.RetryAfter != 600*time.Millisecond {
+		t.Fatalf("after 400ms = %+v, want denied for 600ms", d)
+	}
+
+	clock.Advance(600 * time.Millisecond)
+	if d = l.Allow("k", 60); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("after 1s = %+v, want one token", d)
+	}
+
+	clock.Advance(10 * time.Second)
+	if d = l.Allow("k", 60); !d.Allowed || d.Remaining != 9 {
+		t.Fatalf("after 10s = %+v, want 9 remaining", d)
+	}
+
+	// Refill stops at capacity
+	clock.Advance(time.Hour)
+	if d = l.Allow("k", 60); !d.Allowed || d.Remaining != 59 {
+		t.Fatalf("after an hour = %+v, want 59 remaining", d)
+	}
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	l, _ := newTestLimiter()
+	l.Allow("user-1:llm", 1)
+	if d := l.Allow("user-1:llm", 1); d.Allowed {
+		t.Fatalf("second request = %+v, want denied", d)
+	}
+	for _, key := range []string{"user-2:llm", "user-1:execution"} {
+		if d := l.Allow(key, 1); !d.Allowed {
+			t.Errorf("%s = %+v, want allowed", key, d)
+		}
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l, _ := newTestLimiter()
+	for _, perMinute := range []int{0, -1} {
+		for i := 0; i < 100; i++ {
+			if d := l.Allow("k", perMinute); !d.Allowed || d.Limit != 0 || d.Remaining != -1 {
+				t.Fatalf("perMinute %d = %+v, want unlimited", perMinute, d)
+			}
+		}
+	}
+	if len(l.buckets) != 0 {
+		t.Errorf("disabled limits created %d buckets", len(l.buckets))
+	}
+}
+
+func TestLimiterEvict(t *testing.T) {
+	l, clock := newTestLimiter()
+	l.Allow("idle", 1)
+	clock.Advance(bucketIdleTTL / 2)
+	l.Allow("active", 1)
+	clock.Advance(bucketIdleTTL/2 + time.Second)
+
+	l.evict()
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was kept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket was evicted")
+	}
+
+	// An evicted key starts again with a full bucket
+	if d := l.Allow("idle", 1); !d.Allowed {
+		t.Errorf("evicted key = %+v, want allowed", d)
+	}
+}
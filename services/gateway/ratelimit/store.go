@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConfigKey is the system_config key holding the rate limit settings
+const ConfigKey = "rate_limits"
+
+// refreshInterval controls how often limits are re-read from system_config
+const refreshInterval = 30 * time.Second
+
+// Limits holds the admin-configurable rate limit settings.
+// Per-minute values <= 0 disable limiting for that group, and a
+// LLMDailyQuota <= 0 disables the daily LLM quota.
+type Limits struct {
+	LLMPerMinute       int `json:"llm_per_minute"`
+	ExecutionPerMinute int `json:"execution_per_minute"`
+	DefaultPerMinute   int `json:"default_per_minute"`
+	LLMDailyQuota      int `json:"llm_daily_quota"`
+}
+
+// PerMinute returns the per-minute budget for a route group
+func (l Limits) PerMinute(group string) int {
+	switch group {
+	case GroupLLM:
+		return l.LLMPerMinute
+	case GroupExecution:
+		return l.ExecutionPerMinute
+	default:
+		return l.DefaultPerMinute
+	}
+}
+
+// Validate checks that the limits are usable
+func (l Limits) Validate() error {
+	if l.LLMPerMinute < 0 || l.ExecutionPerMinute < 0 || l.DefaultPerMinute < 0 || l.LLMDailyQuota < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// DefaultLimits returns the limits used when system_config has no entry
+func DefaultLimits(perMinute int) Limits {
+	return Limits{
+		LLMPerMinute:       10,
+		ExecutionPerMinute: 30,
+		DefaultPerMinute:   perMinute,
+		LLMDailyQuota:      500,
+	}
+}
+
+// Store loads and persists rate limit settings and tracks daily LLM usage
+type Store struct {
+	db       *pgxpool.Pool
+	defaults Limits
+
+	mu         sync.RWMutex
+	current    Limits
+	lastLoaded time.Time
+	now        func() time.Time
+}
+
+// NewStore creates a new rate limit store
+func NewStore(db *pgxpool.Pool, defaults Limits) *Store {
+	return &Store{
+		db:       db,
+		defaults: defaults,
+		current:  defaults,
+		now:      time.Now,
+	}
+}
+
+// Limits returns the cached limits, refreshing them from system_config
+// when the cache is stale. Database errors keep the previous values.
+func (s *Store) Limits(ctx context.Context) Limits {
+	s.mu.RLock()
+	current, fresh := s.current, s.now().Sub(s.lastLoaded) < refreshInterval
+	s.mu.RUnlock()
+
+	if fresh {
+		return current
+	}
+
+	limits, err := s.load(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastLoaded = s.now()
+	if err == nil {
+		s.current = limits
+	}
+	return s.current
+}
+
+// Save persists new limits to system_config and updates the cache
+func (s *Store) Save(ctx context.Context, limits Limits) error {
+	value, err := json.Marshal(limits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limits: %w", err)
+	}
+
+	query := `
+		INSERT INTO system_config (key, value, description)
+		VALUES ($1, $2, 'Gateway rate limits per user and route group')
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value
+	`
+	if _, err := s.db.Exec(ctx, query, ConfigKey, value); err != nil {
+		return fmt.Errorf("failed to save limits: %w", err)
+	}
+
+	s.mu.Lock()
+	s.current = limits
+	s.lastLoaded = s.now()
+	s.mu.Unlock()
+	return nil
+}
+
+// load reads limits from system_config, falling back to defaults for missing fields
+func (s *Store) load(ctx context.Context) (Limits, error) {
+	var raw []byte
+	err := s.db.QueryRow(ctx, "SELECT value FROM system_config WHERE key = $1", ConfigKey).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.defaults, nil
+	}
+	if err != nil {
+		return Limits{}, err
+	}
+
+	limits := s.defaults
+	if err := json.Unmarshal(raw, &limits); err != nil {
+		return Limits{}, fmt.Errorf("invalid %s config: %w", ConfigKey, err)
+	}
+	return limits, nil
+}
+
+// ConsumeLLMQuota records one LLM call for the user today (UTC) if the
+// user is still under quota. It returns the number of calls made today
+// and whether this call was allowed.
+func (s *Store) ConsumeLLMQuota(ctx context.Context, userID uuid.UUID, quota int) (int, bool, error) {
+	query := `
+		INSERT INTO llm_usage_daily (user_id, usage_date, request_count)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, 1)
+		ON CONFLICT (user_id, usage_date)
+		DO UPDATE SET request_count = llm_usage_daily.request_count + 1
+		WHERE llm_usage_daily.request_count < $2
+		RETURNING request_count
+	`
+
+	var count int
+	err := s.db.QueryRow(ctx, query, userID, quota).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quota, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+	return count, true, nil
+}
+
+// LLMUsageToday returns how many LLM calls the user has made today (UTC)
+func (s *Store) LLMUsageToday(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+		SELECT request_count FROM llm_usage_daily
+		WHERE user_id = $1 AND usage_date = (NOW() AT TIME ZONE 'UTC')::date
+	`
+
+	var count int
+	err := s.db.QueryRow(ctx, query, userID).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// UntilQuotaReset returns the time remaining until the daily quota resets (UTC midnight)
+func UntilQuotaReset(now time.Time) time.Duration {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return midnight.Sub(now)
+}
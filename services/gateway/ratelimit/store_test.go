@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLimitsPerMinute(t *testing.T) {
+	limits := Limits{LLMPerMinute: 1, ExecutionPerMinute: 2, DefaultPerMinute: 3}
+	tests := map[string]int{GroupLLM: 1, GroupExecution: 2, GroupDefault: 3, "unknown": 3}
+	for group, want := range tests {
+		if got := limits.PerMinute(group); got != want {
+			t.Errorf("PerMinute(%q) = %d, want %d", group, got, want)
+		}
+	}
+}
+
+func TestLimitsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		wantErr bool
+	}{
+		{name: "defaults", limits: DefaultLimits(60)},
+		{name: "zero disables", limits: Limits{}},
+		{name: "negative llm", limits: Limits{LLMPerMinute: -1}, wantErr: true},
+		{name: "negative execution", limits: Limits{ExecutionPerMinute: -1}, wantErr: true},
+		{name: "negative default", limits: Limits{DefaultPerMinute: -1}, wantErr: true},
+		{name: "negative quota", limits: Limits{LLMDailyQuota: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limits.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUntilQuotaReset(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want time.Duration
+	}{
+		{now: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), want: 24 * time.Hour},
+		{now: time.Date(2024, 3, 1, 23, 59, 30, 0, time.UTC), want: 30 * time.Second},
+		{now: time.Date(2024, 2, 29, 18, 0, 0, 0, time.UTC), want: 6 * time.Hour},
+		// Reset is at UTC midnight whatever the caller's zone
+		{now: time.Date(2024, 3, 1, 20, 0, 0, 0, time.FixedZone("UTC-5", -5*3600)), want: 23 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := UntilQuotaReset(tt.now); got != tt.want {
+			t.Errorf("UntilQuotaReset(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestStoreLimitsCache(t *testing.T) {
+	// Nothing listens on port 1, so every load fails
+	pool, err := pgxpool.New(context.Background(), "postgres://gateway@127.0.0.1:1/gateway?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewStore(pool, DefaultLimits(60))
+	store.now = clock.Now
+
+	saved := Limits{LLMPerMinute: 5, ExecutionPerMinute: 6, DefaultPerMinute: 7, LLMDailyQuota: 8}
+	store.current, store.lastLoaded = saved, clock.now
+
+	// Fresh limits are served from the cache without a load
+	loadedAt := clock.now
+	clock.Advance(refreshInterval - time.Second)
+	if got := store.Limits(context.Background()); got != saved || !store.lastLoaded.Equal(loadedAt) {
+		t.Fatalf("cached limits = %+v loaded at %v, want %+v loaded at %v", got, store.lastLoaded, saved, loadedAt)
+	}
+
+	// A failed refresh keeps the previous limits and is not retried at once
+	clock.Advance(time.Second)
+	if got := store.Limits(context.Background()); got != saved {
+		t.Fatalf("limits after a failed refresh = %+v, want %+v", got, saved)
+	}
+	if !store.lastLoaded.Equal(clock.now) {
+		t.Errorf("lastLoaded = %v, want %v", store.lastLoaded, clock.now)
+	}
+}
@@ -28,6 +28,12 @@ func (p *FileParser) ParseFile(filePath string) (*entities.APIConfig, string, er
 		return nil, "", fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return p.ParseFileData(filePath, data)
+}
+
+// ParseFileData parses a YAML or JSON configuration from bytes; the file
+// name tells which format it is
+func (p *FileParser) ParseFileData(filePath string, data []byte) (*entities.APIConfig, string, error) {
 	// Calculate content hash
 	hash := sha256.Sum256(data)
 	contentHash := hex.EncodeToString(hash[:])
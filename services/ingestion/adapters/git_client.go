@@ -0,0 +1,264 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/testpilot-ai/shared/egress"
+)
+
+// GitClient reads spec files from git repositories with the git command
+// line tool, which must be installed
+type GitClient struct {
+	workDir    string
+	localRoots []string
+	dialer     *egress.Dialer
+}
+
+// NewGitClient creates a git client that clones into workDir, the system
+// temp directory when empty. Local repositories are only cloned from under
+// localRoots; none are when it is empty. Like URL fetches, http(s) and ssh
+// clones only reach public addresses or those in allowed.
+func NewGitClient(workDir string, localRoots []string, allowed []netip.Prefix) *GitClient {
+	roots := make([]string, 0, len(localRoots))
+	for _, root := range localRoots {
+		roots = append(roots, resolvePath(root))
+	}
+	return &GitClient{
+		workDir:    workDir,
+		localRoots: roots,
+		dialer:     newEgressDialer(allowed),
+	}
+}
+
+// GitCheckout is a bare clone of a repository. Files are read from commits
+// directly, so nothing is checked out.
+type GitCheckout struct {
+	dir string
+}
+
+// ValidateGitRepository checks that a repository is a local path or a
+// file, http(s) or ssh URL. Other git transports, like ext::, can run
+// commands and are refused.
+func ValidateGitRepository(repository string) error {
+	if repository == "" {
+		return fmt.Errorf("repository is required")
+	}
+	if strings.HasPrefix(repository, "-") {
+		return fmt.Errorf("invalid repository %q", repository)
+	}
+	if filepath.IsAbs(repository) {
+		return nil
+	}
+	parsed, err := url.Parse(repository)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "file", "http", "https", "ssh":
+		return nil
+	}
+	return fmt.Errorf("repository must be an absolute path or a file://, http(s):// or ssh:// URL")
+}
+
+// Clone makes a bare clone of the repository; Close removes it
+func (g *GitClient) Clone(ctx context.Context, repository string) (*GitCheckout, error) {
+	if err := g.checkRepository(ctx, repository); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(g.workDir, "git-ingest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	checkout := &GitCheckout{dir: dir}
+	// Redirects are not followed: they would lead git to hosts never checked
+	_, err = runGit(ctx, "", "-c", "http.followRedirects=false", "clone", "--bare", "--quiet", "--", repository, dir)
+	if err != nil {
+		checkout.Close()
+		return nil, fmt.Errorf("failed to clone %s: %w", repository, err)
+	}
+	return checkout, nil
+}
+
+// checkRepository refuses local repositories outside the local roots, and
+// remote ones on hosts the egress rules refuse. git resolves the host again,
+// so this cannot rule out DNS rebinding.
+func (g *GitClient) checkRepository(ctx context.Context, repository string) error {
+	if err := ValidateGitRepository(repository); err != nil {
+		return err
+	}
+	if filepath.IsAbs(repository) {
+		return g.checkLocal(repository)
+	}
+	parsed, _ := url.Parse(repository)
+	if parsed.Scheme == "file" {
+		if parsed.Host != "" && parsed.Host != "localhost" {
+			return fmt.Errorf("file:// repositories must be on this host")
+		}
+		return g.checkLocal(parsed.Path)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("repository URL has no host")
+	}
+	return g.dialer.Check(ctx, parsed.Hostname())
+}
+
+// checkLocal refuses local repositories outside the local roots
+func (g *GitClient) checkLocal(dir string) error {
+	if len(g.localRoots) == 0 {
+		return fmt.Errorf("local repositories are disabled; list the directories they may be cloned from in GIT_LOCAL_ROOTS")
+	}
+	dir = resolvePath(dir)
+	for _, root := range g.localRoots {
+		if rel, err := filepath.Rel(root, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not under GIT_LOCAL_ROOTS", dir)
+}
+
+// resolvePath cleans a path and resolves its symlinks, so a link cannot lead
+// out of a local root
+func resolvePath(p string) string {
+	p = filepath.Clean(p)
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	return p
+}
+
+// Close removes the clone
+func (r *GitCheckout) Close() {
+	os.RemoveAll(r.dir)
+}
+
+// ResolveRef returns the commit SHA of a branch, tag or commit; an empty
+// ref is the repository's default branch
+func (r *GitCheckout) ResolveRef(ctx context.Context, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	out, err := runGit(ctx, r.dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("ref %q not found", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// HasCommit reports whether the repository contains a commit, e.g. one
+// recorded by an earlier ingestion that a force push may have dropped
+func (r *GitCheckout) HasCommit(ctx context.Context, sha string) bool {
+	if sha == "" || strings.HasPrefix(sha, "-") {
+		return false
+	}
+	_, err := runGit(ctx, r.dir, "cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
+
+// ListFiles returns the paths of the files in a commit
+func (r *GitCheckout) ListFiles(ctx context.Context, commit string) ([]string, error) {
+	out, err := runGit(ctx, r.dir, "ls-tree", "-r", "-z", "--name-only", commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", commit, err)
+	}
+	return splitNUL(out), nil
+}
+
+// ChangedFiles returns the paths of the files added, modified or deleted
+// between two commits. Renames are listed as a deletion and an addition.
+func (r *GitCheckout) ChangedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := runGit(ctx, r.dir, "diff", "--name-only", "--no-renames", "-z", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s..%s: %w", from, to, err)
+	}
+	return splitNUL(out), nil
+}
+
+// ReadFile returns the content of a file in a commit
+func (r *GitCheckout) ReadFile(ctx context.Context, commit, filePath string) ([]byte, error) {
+	out, err := runGit(ctx, r.dir, "cat-file", "blob", commit+":"+filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return out, nil
+}
+
+// MatchGlob reports whether a slash-separated path matches a glob. Patterns
+// are those of path.Match per segment, and ** matches any number of
+// directories.
+func MatchGlob(pattern, filePath string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(filePath, "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// ValidateGlob checks the syntax of a glob
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// runGit runs a git command without prompting for credentials or allowing
+// transports other than file, http(s) and ssh
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ALLOW_PROTOCOL=file:http:https:ssh",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func splitNUL(out []byte) []string {
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
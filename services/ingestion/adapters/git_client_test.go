@@ -0,0 +1,312 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/testpilot-ai/shared/egress"
+)
+
+func TestGitClientCheckRepository(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "specs.git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		localRoots []string
+		repository string
+		wantErr    string
+	}{
+		{"path under a local root", []string{root}, filepath.Join(root, "specs.git"), ""},
+		{"file url under a local root", []string{root}, "file://" + filepath.Join(root, "specs.git"), ""},
+		{"the local root itself", []string{root}, root, ""},
+		{"local repositories disabled", nil, filepath.Join(root, "specs.git"), "local repositories are disabled"},
+		{"path outside the local roots", []string{root}, outside, "not under GIT_LOCAL_ROOTS"},
+		{"dot-dot out of a local root", []string{root}, root + "/../" + filepath.Base(outside), "not under GIT_LOCAL_ROOTS"},
+		{"symlink out of a local root", []string{root}, filepath.Join(root, "link"), "not under GIT_LOCAL_ROOTS"},
+		{"file url on another host", []string{root}, "file://server" + root, "must be on this host"},
+		{"loopback http", nil, "http://127.0.0.1/specs.git", "egress blocked"},
+		{"metadata address", nil, "https://169.254.169.254/specs.git", "egress blocked"},
+		{"host name resolving to loopback", nil, "https://localhost/specs.git", "egress blocked"},
+		{"ssh to a private address", nil, "ssh://git@10.0.0.5/specs.git", "egress blocked"},
+		{"ext transport", []string{root}, "ext::sh -c touch% /tmp/pwned", "must be an absolute path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewGitClient(t.TempDir(), tt.localRoots, nil)
+			err := client.checkRepository(context.Background(), tt.repository)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkRepository(%s) = %v", tt.repository, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("checkRepository(%s) = %v, want %q", tt.repository, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGitClientCloneRefusesBlockedHosts(t *testing.T) {
+	client := NewGitClient(t.TempDir(), nil, nil)
+	_, err := client.Clone(context.Background(), "http://127.0.0.1:1/specs.git")
+	if !errors.Is(err, egress.ErrBlocked) {
+		t.Fatalf("Clone error = %v, want ErrBlocked", err)
+	}
+
+	allowed, _ := egress.ParseCIDRs([]string{"127.0.0.0/8"})
+	client = NewGitClient(t.TempDir(), nil, allowed)
+	_, err = client.Clone(context.Background(), "http://127.0.0.1:1/specs.git")
+	if err == nil || errors.Is(err, egress.ErrBlocked) {
+		t.Fatalf("Clone error = %v, want a connection failure", err)
+	}
+}
+
+// testRepo is a work tree under a local root, committed to with git
+type testRepo struct {
+	t    *testing.T
+	root string
+	dir  string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	root := t.TempDir()
+	repo := &testRepo{t: t, root: root, dir: filepath.Join(root, "specs")}
+	if err := os.Mkdir(repo.dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo.git("init", "--quiet", "--initial-branch=main")
+	return repo
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	out, err := runGit(context.Background(), r.dir, args...)
+	if err != nil {
+		r.t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes files, removing those with empty content, and commits them
+func (r *testRepo) commit(files map[string]string) string {
+	r.t.Helper()
+	for name, content := range files {
+		p := filepath.Join(r.dir, filepath.FromSlash(name))
+		if content == "" {
+			if err := os.Remove(p); err != nil {
+				r.t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git("add", "--all")
+	r.git("commit", "--quiet", "--message", "update specs")
+	return r.git("rev-parse", "HEAD")
+}
+
+func (r *testRepo) clone() *GitCheckout {
+	r.t.Helper()
+	checkout, err := NewGitClient(r.t.TempDir(), []string{r.root}, nil).Clone(context.Background(), r.dir)
+	if err != nil {
+		r.t.Fatalf("Clone: %v", err)
+	}
+	r.t.Cleanup(checkout.Close)
+	return checkout
+}
+
+func TestGitCheckoutResolveRef(t *testing.T) {
+	repo := newTestRepo(t)
+	first := repo.commit(map[string]string{"apis/orders.yaml": "name: orders\n"})
+	repo.git("tag", "v1")
+	second := repo.commit(map[string]string{"apis/orders.yaml": "name: orders\nversion: 2\n"})
+	checkout := repo.clone()
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"", second, false},
+		{"main", second, false},
+		{"v1", first, false},
+		{first, first, false},
+		{first[:10], first, false},
+		{"missing", "", true},
+		{"--all", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := checkout.ResolveRef(context.Background(), tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ResolveRef(%q) = %s, want an error", tt.ref, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ResolveRef(%q) = %s, %v; want %s", tt.ref, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitCheckoutFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	first := repo.commit(map[string]string{
+		"README.md":           "# specs\n",
+		"apis/orders.yaml":    "name: orders\n",
+		"apis/payments.yaml":  "name: payments\n",
+		"apis/v1/users.yaml":  "name: users\n",
+		"apis/v1/old.json":    "{}",
+		"apis/v1/unused.yaml": "name: unused\n",
+	})
+	second := repo.commit(map[string]string{
+		"README.md":           "",
+		"apis/orders.yaml":    "name: orders\nversion: 2\n",
+		"apis/v1/unused.yaml": "",
+		"apis/v2/users.yaml":  "name: users\nversion: 2\n",
+	})
+	checkout := repo.clone()
+	ctx := context.Background()
+
+	changed, err := checkout.ChangedFiles(ctx, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"README.md", "apis/orders.yaml", "apis/v1/unused.yaml", "apis/v2/users.yaml"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("ChangedFiles = %v, want %v", changed, want)
+	}
+
+	files, err := checkout.ListFiles(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"apis/orders.yaml", "apis/payments.yaml", "apis/v1/old.json", "apis/v1/users.yaml", "apis/v2/users.yaml"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ListFiles = %v, want %v", files, want)
+	}
+
+	data, err := checkout.ReadFile(ctx, first, "apis/orders.yaml")
+	if err != nil || string(data) != "name: orders\n" {
+		t.Errorf("ReadFile at the first commit = %q, %v", data, err)
+	}
+	data, err = checkout.ReadFile(ctx, second, "apis/orders.yaml")
+	if err != nil || string(data) != "name: orders\nversion: 2\n" {
+		t.Errorf("ReadFile at the second commit = %q, %v", data, err)
+	}
+	if _, err := checkout.ReadFile(ctx, second, "README.md"); err == nil {
+		t.Error("ReadFile of a deleted file succeeded")
+	}
+}
+
+func TestGitCheckoutHasCommitAfterForcePush(t *testing.T) {
+	repo := newTestRepo(t)
+	first := repo.commit(map[string]string{"apis/orders.yaml": "name: orders\n"})
+	dropped := repo.commit(map[string]string{"apis/orders.yaml": "name: orders\nversion: 2\n"})
+	if !repo.clone().HasCommit(context.Background(), dropped) {
+		t.Fatal("HasCommit is false for the branch head")
+	}
+
+	// Rewrite the branch and drop the old head, as a force push would
+	repo.git("reset", "--quiet", "--hard", first)
+	rewritten := repo.commit(map[string]string{"apis/orders.yaml": "name: orders\nversion: 3\n"})
+	repo.git("reflog", "expire", "--expire=now", "--all")
+	repo.git("gc", "--quiet", "--prune=now")
+
+	checkout := repo.clone()
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		sha  string
+		want bool
+	}{
+		{"kept commit", first, true},
+		{"new head", rewritten, true},
+		{"dropped commit", dropped, false},
+		{"empty", "", false},
+		{"option", "--all", false},
+	}
+	for _, tt := range tests {
+		if got := checkout.HasCommit(ctx, tt.sha); got != tt.want {
+			t.Errorf("HasCommit(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"apis/*.yaml", "apis/orders.yaml", true},
+		{"apis/*.yaml", "apis/v1/orders.yaml", false},
+		{"apis/**/*.yaml", "apis/orders.yaml", true},
+		{"apis/**/*.yaml", "apis/v1/beta/orders.yaml", true},
+		{"apis/**/*.yaml", "apis/v1/orders.json", false},
+		{"**/*.yaml", "orders.yaml", true},
+		{"**/*.yaml", "a/b/c/orders.yaml", true},
+		{"**", "a/b/c.json", true},
+		{"apis/**", "apis/v1/orders.yaml", true},
+		{"apis/**", "specs/orders.yaml", false},
+		{"apis/**/openapi.json", "apis/orders/v1/openapi.json", true},
+		{"apis/**/openapi.json", "apis/orders/v1/openapi.yaml", false},
+		{"apis/orders.yaml", "apis/orders.yaml", true},
+		{"apis/orders.yaml", "other/apis/orders.yaml", false},
+		{"*.yaml", "apis/orders.yaml", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestValidateGitRepository(t *testing.T) {
+	tests := []struct {
+		repository string
+		wantErr    bool
+	}{
+		{"/srv/specs.git", false},
+		{"file:///srv/specs.git", false},
+		{"https://github.com/acme/specs.git", false},
+		{"http://git.example.com/specs.git", false},
+		{"ssh://git@github.com/acme/specs.git", false},
+		{"", true},
+		{"-uhelp", true},
+		{"--upload-pack=touch /tmp/pwned", true},
+		{"ext::sh -c touch% /tmp/pwned", true},
+		{"fd::17", true},
+		{"git://github.com/acme/specs.git", true},
+		{"git@github.com:acme/specs.git", true},
+		{"relative/specs", true},
+	}
+	for _, tt := range tests {
+		err := ValidateGitRepository(tt.repository)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("ValidateGitRepository(%q) = %v, want error %v", tt.repository, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+// DefaultGraphQLPath is the endpoint path of GraphQL APIs that set none
+const DefaultGraphQLPath = "/graphql"
+
+// graphQLSelectionDepth is how many levels of object fields the generated
+// operation documents select below the root field
+const graphQLSelectionDepth = 2
+
+// GraphQLParser handles parsing of GraphQL schemas, as SDL or as the JSON
+// result of an introspection query
+type GraphQLParser struct{}
+
+// NewGraphQLParser creates a new GraphQL parser
+func NewGraphQLParser() *GraphQLParser {
+	return &GraphQLParser{}
+}
+
+// GraphQLSource describes the API a schema belongs to, which the schema
+// itself does not say
+type GraphQLSource struct {
+	Name        string
+	Version     string
+	Description string
+	BaseURL     string
+	Path        string
+}
+
+// ParseSchemaData parses a GraphQL schema into an API configuration with
+// one POST endpoint per query and mutation. Subscriptions are skipped.
+func (p *GraphQLParser) ParseSchemaData(data []byte, source GraphQLSource) (*entities.APIConfig, string, error) {
+	var schema *gqlSchema
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		schema, err = parseIntrospection(trimmed)
+	} else {
+		schema, err = parseSDL(string(data))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+
+	path := source.Path
+	if path == "" {
+		path = DefaultGraphQLPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	version := source.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	endpoints := schema.endpoints(path)
+	if len(endpoints) == 0 {
+		return nil, "", fmt.Errorf("GraphQL schema defines no queries or mutations")
+	}
+
+	config := &entities.APIConfig{
+		Name:        source.Name,
+		Version:     version,
+		Description: source.Description,
+		BaseURL:     source.BaseURL,
+		Protocol:    entities.ProtocolGraphQL,
+		Endpoints:   endpoints,
+	}
+
+	return config, NewFileParser().CalculateHash(data), nil
+}
+
+// introspectionTypeRef is a type reference in an introspection result
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   string                `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+// String renders the reference in SDL syntax, e.g. [ID!]!
+func (r *introspectionTypeRef) String() string {
+	if r == nil {
+		return ""
+	}
+	switch r.Kind {
+	case "NON_NULL":
+		return r.OfType.String() + "!"
+	case "LIST":
+		return "[" + r.OfType.String() + "]"
+	}
+	return r.Name
+}
+
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionSchema struct {
+	QueryType    *struct{ Name string } `json:"queryType"`
+	MutationType *struct{ Name string } `json:"mutationType"`
+	Types        []struct {
+		Kind        string `json:"kind"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Fields      []struct {
+			Name         string                    `json:"name"`
+			Description  string                    `json:"description"`
+			Args         []introspectionInputValue `json:"args"`
+			Type         introspectionTypeRef      `json:"type"`
+			IsDeprecated bool                      `json:"isDeprecated"`
+		} `json:"fields"`
+		InputFields []introspectionInputValue `json:"inputFields"`
+		EnumValues  []struct {
+			Name string `json:"name"`
+		} `json:"enumValues"`
+	} `json:"types"`
+}
+
+// parseIntrospection reads the result of an introspection query, with or
+// without its {"data": ...} envelope
+func parseIntrospection(data []byte) (*gqlSchema, error) {
+	var envelope struct {
+		Data *struct {
+			Schema *introspectionSchema `json:"__schema"`
+		} `json:"data"`
+		Schema *introspectionSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid introspection result: %w", err)
+	}
+	result := envelope.Schema
+	if envelope.Data != nil && envelope.Data.Schema != nil {
+		result = envelope.Data.Schema
+	}
+	if result == nil {
+		return nil, fmt.Errorf("introspection result has no __schema")
+	}
+
+	schema := &gqlSchema{types: make(map[string]*gqlType)}
+	if result.QueryType != nil {
+		schema.queryType = result.QueryType.Name
+	}
+	if result.MutationType != nil {
+		schema.mutationType = result.MutationType.Name
+	}
+	inputValues := func(values []introspectionInputValue) []gqlInputValue {
+		converted := make([]gqlInputValue, 0, len(values))
+		for _, v := range values {
+			value := gqlInputValue{name: v.Name, description: v.Description, typ: v.Type.String()}
+			if v.DefaultValue != nil {
+				value.defaultValue = *v.DefaultValue
+			}
+			converted = append(converted, value)
+		}
+		return converted
+	}
+	for _, t := range result.Types {
+		converted := &gqlType{
+			name:        t.Name,
+			kind:        t.Kind,
+			description: t.Description,
+			inputFields: inputValues(t.InputFields),
+		}
+		for _, f := range t.Fields {
+			converted.fields = append(converted.fields, gqlField{
+				name:        f.Name,
+				description: f.Description,
+				typ:         f.Type.String(),
+				args:        inputValues(f.Args),
+				deprecated:  f.IsDeprecated,
+			})
+		}
+		for _, v := range t.EnumValues {
+			converted.enumValues = append(converted.enumValues, v.Name)
+		}
+		schema.types[t.Name] = converted
+	}
+	return schema, nil
+}
+
+// namedType strips the list and non-null wrappers off a type reference
+func namedType(ref string) string {
+	return strings.Trim(ref, "[]!")
+}
+
+// isLeaf reports whether a type has no fields to select: scalars, enums,
+// and types the schema does not define, like the built-in scalars
+func (s *gqlSchema) isLeaf(name string) bool {
+	t, ok := s.types[name]
+	return !ok || t.kind == gqlKindScalar || t.kind == gqlKindEnum
+}
+
+// endpoints returns an endpoint per query and mutation, all posting to path
+func (s *gqlSchema) endpoints(path string) []entities.APIEndpoint {
+	var endpoints []entities.APIEndpoint
+	roots := []struct{ operation, typeName string }{
+		{entities.GraphQLQuery, s.queryType},
+		{entities.GraphQLMutation, s.mutationType},
+	}
+	for _, root := range roots {
+		t, ok := s.types[root.typeName]
+		if !ok || root.typeName == "" {
+			continue
+		}
+		for _, field := range t.fields {
+			endpoints = append(endpoints, s.endpoint(root.operation, field, path))
+		}
+	}
+	return endpoints
+}
+
+func (s *gqlSchema) endpoint(operation string, field gqlField, path string) entities.APIEndpoint {
+	op := &entities.GraphQLOperation{
+		Type:       operation,
+		Field:      field.name,
+		ReturnType: field.typ,
+		Document:   s.document(operation, field),
+		Deprecated: field.deprecated,
+	}
+
+	var params []entities.Parameter
+	variables := make(map[string]interface{})
+	for _, arg := range field.args {
+		op.Arguments = append(op.Arguments, entities.GraphQLArgument{
+			Name:         arg.name,
+			Type:         arg.typ,
+			Description:  arg.description,
+			DefaultValue: arg.defaultValue,
+		})
+		required := strings.HasSuffix(arg.typ, "!") && arg.defaultValue == ""
+		params = append(params, entities.Parameter{
+			Name:        arg.name,
+			Type:        arg.typ,
+			In:          "variables",
+			Required:    required,
+			Description: arg.description,
+			Default:     arg.defaultValue,
+		})
+		if required {
+			variables[arg.name] = s.exampleValue(arg.typ, 0)
+		}
+	}
+
+	description := field.description
+	if description == "" {
+		description = fmt.Sprintf("GraphQL %s %s returning %s", operation, field.name, field.typ)
+	}
+
+	return entities.APIEndpoint{
+		Name:        field.name,
+		Path:        path,
+		Method:      "POST",
+		Description: description,
+		Parameters:  params,
+		Examples: []entities.Example{{
+			Name: field.name,
+			Request: map[string]interface{}{
+				"query":         op.Document,
+				"variables":     variables,
+				"operationName": operationName(field.name),
+			},
+		}},
+		GraphQL: op,
+	}
+}
+
+// operationName names the generated operation of a field: user -> User
+func operationName(field string) string {
+	return strings.ToUpper(field[:1]) + field[1:]
+}
+
+// document builds an operation calling the field with every argument
+// passed as a variable, e.g.
+//
+//	query User($id: ID!) {
+//	  user(id: $id) {
+//	    id
+//	    name
+//	  }
+//	}
+func (s *gqlSchema) document(operation string, field gqlField) string {
+	var b strings.Builder
+	b.WriteString(operation + " " + operationName(field.name))
+	if len(field.args) > 0 {
+		var defs, args []string
+		for _, arg := range field.args {
+			def := "$" + arg.name + ": " + arg.typ
+			if arg.defaultValue != "" {
+				def += " = " + arg.defaultValue
+			}
+			defs = append(defs, def)
+			args = append(args, arg.name+": $"+arg.name)
+		}
+		b.WriteString("(" + strings.Join(defs, ", ") + ")")
+		b.WriteString(" {\n  " + field.name + "(" + strings.Join(args, ", ") + ")")
+	} else {
+		b.WriteString(" {\n  " + field.name)
+	}
+	s.writeSelection(&b, namedType(field.typ), 1, map[string]bool{})
+	b.WriteString("\n}")
+	return b.String()
+}
+
+// writeSelection writes the selection set of an object type: its scalar
+// fields, and its object fields down to graphQLSelectionDepth. Fields with
+// required arguments and deprecated fields are left out; visiting guards
+// against recursive types.
+func (s *gqlSchema) writeSelection(b *strings.Builder, typeName string, depth int, visiting map[string]bool) {
+	if s.isLeaf(typeName) {
+		return
+	}
+	indent := strings.Repeat("  ", depth)
+	t := s.types[typeName]
+	if t.kind == gqlKindUnion || len(t.fields) == 0 {
+		b.WriteString(" {\n" + indent + "  __typename\n" + indent + "}")
+		return
+	}
+
+	visiting[typeName] = true
+	defer delete(visiting, typeName)
+
+	var lines []string
+	for _, field := range t.fields {
+		if field.deprecated || hasRequiredArgs(field) {
+			continue
+		}
+		child := namedType(field.typ)
+		if s.isLeaf(child) {
+			lines = append(lines, indent+"  "+field.name)
+			continue
+		}
+		if depth >= graphQLSelectionDepth || visiting[child] {
+			continue
+		}
+		var nested strings.Builder
+		nested.WriteString(indent + "  " + field.name)
+		s.writeSelection(&nested, child, depth+1, visiting)
+		lines = append(lines, nested.String())
+	}
+	if len(lines) == 0 {
+		lines = append(lines, indent+"  __typename")
+	}
+	b.WriteString(" {\n" + strings.Join(lines, "\n") + "\n" + indent + "}")
+}
+
+func hasRequiredArgs(field gqlField) bool {
+	for _, arg := range field.args {
+		if strings.HasSuffix(arg.typ, "!") && arg.defaultValue == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// exampleValue returns a placeholder value of the referenced type for the
+// example variables. Input objects get their required fields.
+func (s *gqlSchema) exampleValue(ref string, depth int) interface{} {
+	ref = strings.TrimSuffix(ref, "!")
+	if strings.HasPrefix(ref, "[") {
+		return []interface{}{s.exampleValue(strings.TrimSuffix(strings.TrimPrefix(ref, "["), "]"), depth)}
+	}
+
+	switch ref {
+	case "Int":
+		return 1
+	case "Float":
+		return 1.5
+	case "Boolean":
+		return true
+	case "ID":
+		return "1"
+	}
+	t, ok := s.types[ref]
+	if !ok {
+		return "example"
+	}
+	switch t.kind {
+	case gqlKindEnum:
+		if len(t.enumValues) > 0 {
+			return t.enumValues[0]
+		}
+	case gqlKindInputObject:
+		value := make(map[string]interface{})
+		if depth >= 3 {
+			return value
+		}
+		for _, field := range t.inputFields {
+			if strings.HasSuffix(field.typ, "!") && field.defaultValue == "" {
+				value[field.name] = s.exampleValue(field.typ, depth+1)
+			}
+		}
+		return value
+	}
+	return "example"
+}
@@ -0,0 +1,651 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GraphQL type kinds, as named by introspection
+const (
+	gqlKindScalar      = "SCALAR"
+	gqlKindObject      = "OBJECT"
+	gqlKindInterface   = "INTERFACE"
+	gqlKindUnion       = "UNION"
+	gqlKindEnum        = "ENUM"
+	gqlKindInputObject = "INPUT_OBJECT"
+)
+
+// gqlSchema is the part of a GraphQL schema needed to describe its
+// operations, whether it came from SDL or from introspection
+type gqlSchema struct {
+	queryType    string
+	mutationType string
+	types        map[string]*gqlType
+}
+
+type gqlType struct {
+	name        string
+	kind        string
+	description string
+	fields      []gqlField
+	inputFields []gqlInputValue
+	enumValues  []string
+}
+
+type gqlField struct {
+	name        string
+	description string
+	typ         string // type reference, e.g. [User!]!
+	args        []gqlInputValue
+	deprecated  bool
+}
+
+type gqlInputValue struct {
+	name         string
+	description  string
+	typ          string
+	defaultValue string
+}
+
+// sdlToken kinds
+const (
+	sdlEOF = iota
+	sdlName
+	sdlPunct
+	sdlString
+	sdlNumber
+)
+
+type sdlToken struct {
+	kind  int
+	value string
+	line  int
+}
+
+// lexSDL splits GraphQL SDL into tokens. Commas, whitespace and comments
+// are insignificant and dropped.
+func lexSDL(src string) ([]sdlToken, error) {
+	var tokens []sdlToken
+	line := 1
+	i := 0
+	for i < len(src) {
+		ch := src[i]
+		switch {
+		case ch == '\n':
+			line++
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, sdlToken{kind: sdlPunct, value: "...", line: line})
+			i += 3
+		case strings.ContainsRune("!$&()[]{}:=@|", rune(ch)):
+			tokens = append(tokens, sdlToken{kind: sdlPunct, value: string(ch), line: line})
+			i++
+		case strings.HasPrefix(src[i:], `"""`):
+			end := i + 3
+			for end < len(src) && !strings.HasPrefix(src[end:], `"""`) {
+				if strings.HasPrefix(src[end:], `\"""`) {
+					end += 4
+					continue
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated block string", line)
+			}
+			raw := src[i+3 : end]
+			tokens = append(tokens, sdlToken{kind: sdlString, value: blockStringValue(raw), line: line})
+			line += strings.Count(raw, "\n")
+			i = end + 3
+		case ch == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) || src[end] != '"' {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			// GraphQL string escapes are those of JSON
+			var value string
+			if err := json.Unmarshal([]byte(src[i:end+1]), &value); err != nil {
+				return nil, fmt.Errorf("line %d: invalid string: %w", line, err)
+			}
+			tokens = append(tokens, sdlToken{kind: sdlString, value: value, line: line})
+			i = end + 1
+		case isNameStart(ch):
+			end := i + 1
+			for end < len(src) && (isNameStart(src[end]) || isDigit(src[end])) {
+				end++
+			}
+			tokens = append(tokens, sdlToken{kind: sdlName, value: src[i:end], line: line})
+			i = end
+		case ch == '-' || isDigit(ch):
+			end := i + 1
+			for end < len(src) && (isDigit(src[end]) || strings.ContainsRune(".eE+-", rune(src[end]))) {
+				end++
+			}
+			tokens = append(tokens, sdlToken{kind: sdlNumber, value: src[i:end], line: line})
+			i = end
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, ch)
+		}
+	}
+	return append(tokens, sdlToken{kind: sdlEOF, line: line}), nil
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// blockStringValue removes the common indentation and the leading and
+// trailing blank lines of a block string
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, `\"""`, `"""`), "\n")
+	indent := -1
+	for _, l := range lines[1:] {
+		trimmed := strings.TrimLeft(l, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(l) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = strings.TrimLeft(lines[i], " \t")
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sdlParser reads the type system definitions of a GraphQL SDL document
+type sdlParser struct {
+	tokens []sdlToken
+	pos    int
+	schema *gqlSchema
+}
+
+// parseSDL parses GraphQL SDL. Directives are read but ignored, except
+// @deprecated on fields. Without a schema definition the root types are
+// Query and Mutation.
+func parseSDL(src string) (*gqlSchema, error) {
+	tokens, err := lexSDL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &sdlParser{tokens: tokens, schema: &gqlSchema{types: make(map[string]*gqlType)}}
+	for p.peek().kind != sdlEOF {
+		if err := p.definition(); err != nil {
+			return nil, err
+		}
+	}
+	if p.schema.queryType == "" {
+		p.schema.queryType = "Query"
+	}
+	if p.schema.mutationType == "" {
+		p.schema.mutationType = "Mutation"
+	}
+	return p.schema, nil
+}
+
+func (p *sdlParser) peek() sdlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *sdlParser) next() sdlToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != sdlEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *sdlParser) peekPunct(value string) bool {
+	tok := p.peek()
+	return tok.kind == sdlPunct && tok.value == value
+}
+
+// skipPunct consumes the punctuator if it is next
+func (p *sdlParser) skipPunct(value string) bool {
+	if p.peekPunct(value) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *sdlParser) expectPunct(value string) error {
+	if tok := p.next(); tok.kind != sdlPunct || tok.value != value {
+		return p.unexpected(tok, fmt.Sprintf("%q", value))
+	}
+	return nil
+}
+
+func (p *sdlParser) expectName() (string, error) {
+	tok := p.next()
+	if tok.kind != sdlName {
+		return "", p.unexpected(tok, "a name")
+	}
+	return tok.value, nil
+}
+
+func (p *sdlParser) unexpected(tok sdlToken, want string) error {
+	if tok.kind == sdlEOF {
+		return fmt.Errorf("line %d: unexpected end of schema, expected %s", tok.line, want)
+	}
+	return fmt.Errorf("line %d: unexpected %q, expected %s", tok.line, tok.value, want)
+}
+
+// description consumes an optional description string
+func (p *sdlParser) description() string {
+	if p.peek().kind == sdlString {
+		return p.next().value
+	}
+	return ""
+}
+
+// typeOf returns the named type, creating it on first use, so extensions
+// may come before or after the definition they extend
+func (p *sdlParser) typeOf(name, kind string) *gqlType {
+	t, ok := p.schema.types[name]
+	if !ok {
+		t = &gqlType{name: name}
+		p.schema.types[name] = t
+	}
+	t.kind = kind
+	return t
+}
+
+func (p *sdlParser) definition() error {
+	description := p.description()
+	tok := p.next()
+	if tok.kind != sdlName {
+		return p.unexpected(tok, "a definition")
+	}
+	keyword := tok.value
+	if keyword == "extend" {
+		extended, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		keyword = extended
+	}
+
+	switch keyword {
+	case "schema":
+		if _, err := p.directives(); err != nil {
+			return err
+		}
+		if !p.skipPunct("{") {
+			return nil
+		}
+		for !p.skipPunct("}") {
+			operation, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			switch operation {
+			case "query":
+				p.schema.queryType = name
+			case "mutation":
+				p.schema.mutationType = name
+			}
+		}
+		return nil
+
+	case "type", "interface":
+		kind := gqlKindObject
+		if keyword == "interface" {
+			kind = gqlKindInterface
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		t := p.typeOf(name, kind)
+		if description != "" {
+			t.description = description
+		}
+		if p.peek().kind == sdlName && p.peek().value == "implements" {
+			p.next()
+			p.skipPunct("&")
+			for {
+				if _, err := p.expectName(); err != nil {
+					return err
+				}
+				if !p.skipPunct("&") {
+					break
+				}
+			}
+		}
+		if _, err := p.directives(); err != nil {
+			return err
+		}
+		if !p.skipPunct("{") {
+			return nil
+		}
+		for !p.skipPunct("}") {
+			field, err := p.field()
+			if err != nil {
+				return err
+			}
+			t.fields = append(t.fields, field)
+		}
+		return nil
+
+	case "input":
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		t := p.typeOf(name, gqlKindInputObject)
+		if description != "" {
+			t.description = description
+		}
+		if _, err := p.directives(); err != nil {
+			return err
+		}
+		if !p.skipPunct("{") {
+			return nil
+		}
+		for !p.skipPunct("}") {
+			value, err := p.inputValue()
+			if err != nil {
+				return err
+			}
+			t.inputFields = append(t.inputFields, value)
+		}
+		return nil
+
+	case "enum":
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		t := p.typeOf(name, gqlKindEnum)
+		if description != "" {
+			t.description = description
+		}
+		if _, err := p.directives(); err != nil {
+			return err
+		}
+		if !p.skipPunct("{") {
+			return nil
+		}
+		for !p.skipPunct("}") {
+			p.description()
+			value, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			if _, err := p.directives(); err != nil {
+				return err
+			}
+			t.enumValues = append(t.enumValues, value)
+		}
+		return nil
+
+	case "scalar":
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		t := p.typeOf(name, gqlKindScalar)
+		if description != "" {
+			t.description = description
+		}
+		_, err = p.directives()
+		return err
+
+	case "union":
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		t := p.typeOf(name, gqlKindUnion)
+		if description != "" {
+			t.description = description
+		}
+		if _, err := p.directives(); err != nil {
+			return err
+		}
+		if !p.skipPunct("=") {
+			return nil
+		}
+		p.skipPunct("|")
+		for {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if !p.skipPunct("|") {
+				return nil
+			}
+		}
+
+	case "directive":
+		if err := p.expectPunct("@"); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if _, err := p.arguments(); err != nil {
+			return err
+		}
+		if p.peek().kind == sdlName && p.peek().value == "repeatable" {
+			p.next()
+		}
+		if tok := p.next(); tok.kind != sdlName || tok.value != "on" {
+			return p.unexpected(tok, `"on"`)
+		}
+		p.skipPunct("|")
+		for {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if !p.skipPunct("|") {
+				return nil
+			}
+		}
+
+	case "query", "mutation", "subscription", "fragment":
+		return fmt.Errorf("line %d: %s definitions are operations, not part of a schema", tok.line, keyword)
+	}
+	return p.unexpected(tok, "a definition")
+}
+
+// field reads a field definition: description, name, arguments, type and
+// directives
+func (p *sdlParser) field() (gqlField, error) {
+	field := gqlField{description: p.description()}
+	name, err := p.expectName()
+	if err != nil {
+		return field, err
+	}
+	field.name = name
+	if field.args, err = p.arguments(); err != nil {
+		return field, err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return field, err
+	}
+	if field.typ, err = p.typeRef(); err != nil {
+		return field, err
+	}
+	directives, err := p.directives()
+	if err != nil {
+		return field, err
+	}
+	for _, directive := range directives {
+		if directive == "deprecated" {
+			field.deprecated = true
+		}
+	}
+	return field, nil
+}
+
+// arguments reads optional argument definitions in parentheses
+func (p *sdlParser) arguments() ([]gqlInputValue, error) {
+	if !p.skipPunct("(") {
+		return nil, nil
+	}
+	var args []gqlInputValue
+	for !p.skipPunct(")") {
+		arg, err := p.inputValue()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// inputValue reads an argument or input field definition
+func (p *sdlParser) inputValue() (gqlInputValue, error) {
+	value := gqlInputValue{description: p.description()}
+	name, err := p.expectName()
+	if err != nil {
+		return value, err
+	}
+	value.name = name
+	if err := p.expectPunct(":"); err != nil {
+		return value, err
+	}
+	if value.typ, err = p.typeRef(); err != nil {
+		return value, err
+	}
+	if p.skipPunct("=") {
+		if value.defaultValue, err = p.value(); err != nil {
+			return value, err
+		}
+	}
+	_, err = p.directives()
+	return value, err
+}
+
+// typeRef reads a type reference and returns it as written, e.g. [ID!]!
+func (p *sdlParser) typeRef() (string, error) {
+	var ref string
+	if p.skipPunct("[") {
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return "", err
+		}
+		ref = "[" + inner + "]"
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		ref = name
+	}
+	if p.skipPunct("!") {
+		ref += "!"
+	}
+	return ref, nil
+}
+
+// value reads a value literal and returns it in GraphQL syntax
+func (p *sdlParser) value() (string, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == sdlString:
+		quoted, _ := json.Marshal(tok.value)
+		return string(quoted), nil
+	case tok.kind == sdlName || tok.kind == sdlNumber:
+		return tok.value, nil
+	case tok.kind == sdlPunct && tok.value == "$":
+		name, err := p.expectName()
+		return "$" + name, err
+	case tok.kind == sdlPunct && tok.value == "[":
+		var items []string
+		for !p.skipPunct("]") {
+			item, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			items = append(items, item)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case tok.kind == sdlPunct && tok.value == "{":
+		var fields []string
+		for !p.skipPunct("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return "", err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return "", err
+			}
+			item, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, name+": "+item)
+		}
+		return "{" + strings.Join(fields, ", ") + "}", nil
+	}
+	return "", p.unexpected(tok, "a value")
+}
+
+// directives reads the directives applied at this point and returns
+// their names
+func (p *sdlParser) directives() ([]string, error) {
+	var names []string
+	for p.skipPunct("@") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.skipPunct("(") {
+			continue
+		}
+		for !p.skipPunct(")") {
+			if _, err := p.expectName(); err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if _, err := p.value(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,437 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/testpilot-ai/ingestion/domain/entities"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// grpcSchemaDepth is how many levels of nested messages the generated JSON
+// schemas and examples describe
+const grpcSchemaDepth = 4
+
+// GRPCParser handles parsing of gRPC services, from .proto sources or from
+// a compiled FileDescriptorSet (protoc --descriptor_set_out, buf build)
+type GRPCParser struct{}
+
+// NewGRPCParser creates a new gRPC parser
+func NewGRPCParser() *GRPCParser {
+	return &GRPCParser{}
+}
+
+// GRPCSource describes the API a set of services belongs to
+type GRPCSource struct {
+	Name        string
+	Version     string
+	Description string
+	BaseURL     string // e.g. grpc://orders.internal:50051
+}
+
+// ParseProtoFiles compiles .proto sources, keyed by file name, into an API
+// configuration. Imports resolve against the uploaded files, by path or by
+// base name, and the well-known google/protobuf types. It also returns the
+// serialized FileDescriptorSet the execution service calls the methods with.
+func (p *GRPCParser) ParseProtoFiles(ctx context.Context, sources map[string][]byte, source GRPCSource) (*entities.APIConfig, []byte, string, error) {
+	names := make([]string, 0, len(sources))
+	byBase := make(map[string][]string)
+	for name := range sources {
+		names = append(names, name)
+		byBase[path.Base(name)] = append(byBase[path.Base(name)], name)
+	}
+	sort.Strings(names)
+
+	accessor := func(name string) (io.ReadCloser, error) {
+		if data, ok := sources[name]; ok {
+			return io.NopCloser(strings.NewReader(string(data))), nil
+		}
+		if matches := byBase[path.Base(name)]; len(matches) == 1 {
+			return io.NopCloser(strings.NewReader(string(sources[matches[0]]))), nil
+		}
+		return nil, fmt.Errorf("file not found: %s", name)
+	}
+	compiler := protocompile.Compiler{
+		Resolver:       protocompile.WithStandardImports(&protocompile.SourceResolver{Accessor: accessor}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiled, err := compiler.Compile(ctx, names...)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to compile proto files: %w", err)
+	}
+
+	files := make([]protoreflect.FileDescriptor, len(compiled))
+	for i, file := range compiled {
+		files[i] = file
+	}
+	set, err := descriptorSet(files)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	var content []byte
+	for _, name := range names {
+		content = append(content, name...)
+		content = append(content, sources[name]...)
+	}
+	config, err := p.config(files, source)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return config, set, NewFileParser().CalculateHash(content), nil
+}
+
+// ParseDescriptorSet reads a serialized FileDescriptorSet into an API
+// configuration. The set must hold every file its files import.
+func (p *GRPCParser) ParseDescriptorSet(data []byte, source GRPCSource) (*entities.APIConfig, []byte, string, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, nil, "", fmt.Errorf("not a serialized FileDescriptorSet: %w", err)
+	}
+	registry, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("invalid descriptor set: %w", err)
+	}
+
+	var files []protoreflect.FileDescriptor
+	for _, file := range set.GetFile() {
+		found, err := registry.FindFileByPath(file.GetName())
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("invalid descriptor set: %w", err)
+		}
+		files = append(files, found)
+	}
+	config, err := p.config(files, source)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return config, data, NewFileParser().CalculateHash(data), nil
+}
+
+// config builds the API configuration: one endpoint per unary method of
+// the services in files. Streaming methods are skipped.
+func (p *GRPCParser) config(files []protoreflect.FileDescriptor, source GRPCSource) (*entities.APIConfig, error) {
+	version := source.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	var endpoints []entities.APIEndpoint
+	for _, file := range files {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				method := methods.Get(j)
+				if method.IsStreamingClient() || method.IsStreamingServer() {
+					continue
+				}
+				endpoints = append(endpoints, grpcEndpoint(method))
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("proto files define no unary service methods")
+	}
+
+	return &entities.APIConfig{
+		Name:        source.Name,
+		Version:     version,
+		Description: source.Description,
+		BaseURL:     source.BaseURL,
+		Protocol:    entities.ProtocolGRPC,
+		Endpoints:   endpoints,
+	}, nil
+}
+
+func grpcEndpoint(method protoreflect.MethodDescriptor) entities.APIEndpoint {
+	service := method.Parent().(protoreflect.ServiceDescriptor)
+	input, output := method.Input(), method.Output()
+	op := &entities.GRPCMethod{
+		Service:    string(service.FullName()),
+		Method:     string(method.Name()),
+		FullMethod: string(service.FullName()) + "/" + string(method.Name()),
+		InputType:  string(input.FullName()),
+		OutputType: string(output.FullName()),
+	}
+
+	// Top-level fields of the input message are the parameters; proto3
+	// has no required fields, so none are marked required
+	var params []entities.Parameter
+	fields := input.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		params = append(params, entities.Parameter{
+			Name:        field.JSONName(),
+			Type:        grpcFieldType(field),
+			In:          "body",
+			Description: protoComment(field),
+		})
+	}
+
+	description := protoComment(method)
+	if description == "" {
+		description = fmt.Sprintf("gRPC method %s taking %s and returning %s", op.FullMethod, op.InputType, op.OutputType)
+	}
+
+	return entities.APIEndpoint{
+		Name:           string(method.Name()),
+		Path:           "/" + op.FullMethod,
+		Method:         "POST",
+		Description:    description,
+		Parameters:     params,
+		RequestSchema:  messageSchema(input, 0, map[protoreflect.FullName]bool{}),
+		ResponseSchema: messageSchema(output, 0, map[protoreflect.FullName]bool{}),
+		Examples: []entities.Example{{
+			Name:    string(method.Name()),
+			Request: messageExample(input, 0),
+		}},
+		GRPC: op,
+	}
+}
+
+// protoComment returns the leading comment of a declaration, if the
+// descriptors carry source info
+func protoComment(d protoreflect.Descriptor) string {
+	return strings.TrimSpace(d.ParentFile().SourceLocations().ByDescriptor(d).LeadingComments)
+}
+
+// grpcFieldType names a field's type for parameter lists, e.g. string,
+// repeated orders.v1.Item or map<string, int32>
+func grpcFieldType(field protoreflect.FieldDescriptor) string {
+	if field.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", grpcFieldType(field.MapKey()), grpcFieldType(field.MapValue()))
+	}
+	name := field.Kind().String()
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		name = string(field.Message().FullName())
+	case protoreflect.EnumKind:
+		name = string(field.Enum().FullName())
+	}
+	if field.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
+
+// messageSchema describes a message's protobuf JSON form as a JSON schema.
+// Well-known types use their JSON mapping; visiting guards against
+// recursive messages.
+func messageSchema(message protoreflect.MessageDescriptor, depth int, visiting map[protoreflect.FullName]bool) map[string]interface{} {
+	if schema, ok := wellKnownSchema(message.FullName()); ok {
+		return schema
+	}
+	schema := map[string]interface{}{"type": "object"}
+	if description := protoComment(message); description != "" {
+		schema["description"] = description
+	}
+	if depth >= grpcSchemaDepth || visiting[message.FullName()] {
+		return schema
+	}
+	visiting[message.FullName()] = true
+	defer delete(visiting, message.FullName())
+
+	properties := make(map[string]interface{})
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		property := fieldSchema(field, depth, visiting)
+		description := protoComment(field)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			description = strings.TrimSpace(fmt.Sprintf("%s (one of %s)", description, oneof.Name()))
+		}
+		if description != "" {
+			property["description"] = description
+		}
+		properties[field.JSONName()] = property
+	}
+	schema["properties"] = properties
+	return schema
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, depth int, visiting map[protoreflect.FullName]bool) map[string]interface{} {
+	if field.IsMap() {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": singularSchema(field.MapValue(), depth, visiting),
+		}
+	}
+	if field.IsList() {
+		return map[string]interface{}{"type": "array", "items": singularSchema(field, depth, visiting)}
+	}
+	return singularSchema(field, depth, visiting)
+}
+
+// singularSchema describes one value of a field, as protojson writes it:
+// 64-bit integers as strings, bytes as base64, enums by name
+func singularSchema(field protoreflect.FieldDescriptor, depth int, visiting map[protoreflect.FullName]bool) map[string]interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": field.Kind().String()}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]interface{}{"type": "string", "format": field.Kind().String()}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": field.Kind().String()}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]interface{}, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return map[string]interface{}{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(field.Message(), depth+1, visiting)
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// wellKnownSchema returns the schema of the google.protobuf types that have
+// a JSON mapping of their own
+func wellKnownSchema(name protoreflect.FullName) (map[string]interface{}, bool) {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.Duration":
+		return map[string]interface{}{"type": "string", "format": "duration", "example": "1.5s"}, true
+	case "google.protobuf.FieldMask":
+		return map[string]interface{}{"type": "string", "description": "comma-separated field paths"}, true
+	case "google.protobuf.Struct":
+		return map[string]interface{}{"type": "object"}, true
+	case "google.protobuf.Value":
+		return map[string]interface{}{}, true
+	case "google.protobuf.ListValue":
+		return map[string]interface{}{"type": "array"}, true
+	case "google.protobuf.Empty":
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, true
+	case "google.protobuf.Any":
+		return map[string]interface{}{"type": "object", "required": []interface{}{"@type"}}, true
+	case "google.protobuf.BoolValue":
+		return map[string]interface{}{"type": "boolean"}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return map[string]interface{}{"type": "integer"}, true
+	case "google.protobuf.Int64Value", "google.protobuf.UInt64Value", "google.protobuf.StringValue":
+		return map[string]interface{}{"type": "string"}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]interface{}{"type": "number"}, true
+	case "google.protobuf.BytesValue":
+		return map[string]interface{}{"type": "string", "format": "byte"}, true
+	}
+	return nil, false
+}
+
+// messageExample returns a placeholder message in protobuf JSON, with a
+// value for every field outside of oneofs, down to grpcSchemaDepth
+func messageExample(message protoreflect.MessageDescriptor, depth int) map[string]interface{} {
+	example := make(map[string]interface{})
+	if depth >= grpcSchemaDepth {
+		return example
+	}
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			continue
+		}
+		if field.IsMap() {
+			example[field.JSONName()] = map[string]interface{}{}
+			continue
+		}
+		value := singularExample(field, depth)
+		if value == nil {
+			continue
+		}
+		if field.IsList() {
+			value = []interface{}{value}
+		}
+		example[field.JSONName()] = value
+	}
+	return example
+}
+
+func singularExample(field protoreflect.FieldDescriptor, depth int) interface{} {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return 1
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "1"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return 1.5
+	case protoreflect.BytesKind:
+		return "ZXhhbXBsZQ=="
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		if values.Len() > 1 {
+			// The first value is by convention UNSPECIFIED
+			return string(values.Get(1).Name())
+		}
+		return string(values.Get(0).Name())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch field.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return "2024-01-01T00:00:00Z"
+		case "google.protobuf.Duration":
+			return "1s"
+		case "google.protobuf.Any", "google.protobuf.Value", "google.protobuf.ListValue":
+			return nil
+		}
+		if schema, ok := wellKnownSchema(field.Message().FullName()); ok {
+			switch schema["type"] {
+			case "boolean":
+				return true
+			case "integer", "number":
+				return 1
+			case "object":
+				return map[string]interface{}{}
+			}
+			return "example"
+		}
+		return messageExample(field.Message(), depth+1)
+	}
+	return "example"
+}
+
+// descriptorSet serializes files and everything they import, dependencies
+// first, so the set can be loaded on its own
+func descriptorSet(files []protoreflect.FileDescriptor) ([]byte, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(file protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] {
+			return
+		}
+		seen[file.Path()] = true
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	for _, file := range files {
+		add(file)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize descriptors: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,387 @@
+package adapters
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operations of a path item, in the order endpoints
+// are listed
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+// openAPIServerVariable matches the {variables} of a server URL
+var openAPIServerVariable = regexp.MustCompile(`\{([^}]+)\}`)
+
+// OpenAPIParser handles parsing of OpenAPI 3 and Swagger 2 documents, in
+// YAML or JSON
+type OpenAPIParser struct{}
+
+// NewOpenAPIParser creates a new OpenAPI parser
+func NewOpenAPIParser() *OpenAPIParser {
+	return &OpenAPIParser{}
+}
+
+// IsOpenAPIDocument reports whether YAML or JSON data is an OpenAPI or
+// Swagger document rather than an API configuration
+func IsOpenAPIDocument(data []byte) bool {
+	var doc struct {
+		OpenAPI string `yaml:"openapi"`
+		Swagger string `yaml:"swagger"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.OpenAPI != "" || doc.Swagger != ""
+}
+
+// ParseSpecData parses an OpenAPI document into an API configuration with
+// one endpoint per operation. Local $refs are resolved; schemas that refer
+// to themselves keep the $ref at the point they recur.
+func (p *OpenAPIParser) ParseSpecData(data []byte) (*entities.APIConfig, string, error) {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, "", fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	doc := &openAPIDocument{root: root, swagger: stringField(root, "swagger") != ""}
+	if !doc.swagger && !strings.HasPrefix(stringField(root, "openapi"), "3.") {
+		return nil, "", fmt.Errorf("unsupported OpenAPI version %q", stringField(root, "openapi"))
+	}
+
+	info := mapField(root, "info")
+	config := &entities.APIConfig{
+		Name:        stringField(info, "title"),
+		Version:     stringField(info, "version"),
+		Description: stringField(info, "description"),
+		BaseURL:     doc.baseURL(),
+	}
+	if config.Name == "" {
+		return nil, "", fmt.Errorf("OpenAPI document has no info.title")
+	}
+	if config.Version == "" {
+		config.Version = "1.0.0"
+	}
+
+	paths := mapField(root, "paths")
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, path := range names {
+		item := doc.resolve(mapValue(paths[path]))
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			config.Endpoints = append(config.Endpoints, doc.endpoint(path, method, item, operation))
+		}
+	}
+	if len(config.Endpoints) == 0 {
+		return nil, "", fmt.Errorf("OpenAPI document defines no operations")
+	}
+
+	return config, NewFileParser().CalculateHash(data), nil
+}
+
+// openAPIDocument is a parsed OpenAPI or Swagger document
+type openAPIDocument struct {
+	root    map[string]interface{}
+	swagger bool
+}
+
+// baseURL returns the first server URL with its variables at their
+// defaults, or the Swagger scheme, host and base path
+func (d *openAPIDocument) baseURL() string {
+	if d.swagger {
+		host := stringField(d.root, "host")
+		if host == "" {
+			return stringField(d.root, "basePath")
+		}
+		scheme := "https"
+		if schemes, ok := d.root["schemes"].([]interface{}); ok && len(schemes) > 0 {
+			if s, ok := schemes[0].(string); ok {
+				scheme = s
+			}
+		}
+		return scheme + "://" + host + stringField(d.root, "basePath")
+	}
+
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+	server := mapValue(servers[0])
+	variables := mapField(server, "variables")
+	return openAPIServerVariable.ReplaceAllStringFunc(stringField(server, "url"), func(match string) string {
+		name := match[1 : len(match)-1]
+		if value := stringField(mapField(variables, name), "default"); value != "" {
+			return value
+		}
+		return match
+	})
+}
+
+func (d *openAPIDocument) endpoint(path, method string, item, operation map[string]interface{}) entities.APIEndpoint {
+	endpoint := entities.APIEndpoint{
+		Name:        stringField(operation, "operationId"),
+		Path:        path,
+		Method:      strings.ToUpper(method),
+		Description: stringField(operation, "summary"),
+	}
+	if endpoint.Name == "" {
+		endpoint.Name = strings.ToUpper(method) + " " + path
+	}
+	if description := stringField(operation, "description"); description != "" {
+		if endpoint.Description != "" {
+			endpoint.Description += ". "
+		}
+		endpoint.Description += description
+	}
+
+	// Operation parameters override the path item's of the same name and location
+	params := make(map[string]int)
+	for _, raw := range append(listField(item, "parameters"), listField(operation, "parameters")...) {
+		param := d.resolve(mapValue(raw))
+		name, in := stringField(param, "name"), stringField(param, "in")
+		if name == "" {
+			continue
+		}
+		if in == "body" {
+			endpoint.RequestSchema = d.schema(mapField(param, "schema"))
+			continue
+		}
+		parameter := d.parameter(param)
+		if i, ok := params[in+":"+name]; ok {
+			endpoint.Parameters[i] = parameter
+			continue
+		}
+		params[in+":"+name] = len(endpoint.Parameters)
+		endpoint.Parameters = append(endpoint.Parameters, parameter)
+	}
+
+	if body := d.resolve(mapField(operation, "requestBody")); body != nil {
+		if media := jsonMedia(mapField(body, "content")); media != nil {
+			endpoint.RequestSchema = d.schema(mapField(media, "schema"))
+			if example, ok := media["example"].(map[string]interface{}); ok {
+				endpoint.Examples = append(endpoint.Examples, entities.Example{Name: "example", Request: example})
+			}
+		}
+	}
+
+	responses := mapField(operation, "responses")
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response := d.resolve(mapValue(responses[code]))
+		status, err := strconv.Atoi(code)
+		if err != nil {
+			continue // default and 2XX ranges
+		}
+		endpoint.ExpectedStatusCodes = append(endpoint.ExpectedStatusCodes, map[string]interface{}{
+			"code":        status,
+			"description": stringField(response, "description"),
+		})
+		if endpoint.ResponseSchema == nil && status >= 200 && status < 300 {
+			if d.swagger {
+				endpoint.ResponseSchema = d.schema(mapField(response, "schema"))
+			} else if media := jsonMedia(mapField(response, "content")); media != nil {
+				endpoint.ResponseSchema = d.schema(mapField(media, "schema"))
+			}
+		}
+	}
+
+	endpoint.Authentication = d.authentication(operation)
+	return endpoint
+}
+
+func (d *openAPIDocument) parameter(param map[string]interface{}) entities.Parameter {
+	parameter := entities.Parameter{
+		Name:        stringField(param, "name"),
+		In:          stringField(param, "in"),
+		Description: stringField(param, "description"),
+		Style:       stringField(param, "style"),
+	}
+	if parameter.In == "formData" {
+		parameter.In = "form"
+	}
+	parameter.Required, _ = param["required"].(bool)
+	if explode, ok := param["explode"].(bool); ok {
+		parameter.Explode = &explode
+	}
+
+	// Swagger describes the type on the parameter itself
+	schema := param
+	if !d.swagger {
+		schema = d.resolve(mapField(param, "schema"))
+	}
+	parameter.Type = stringField(schema, "type")
+	parameter.Format = stringField(schema, "format")
+	if value, ok := schema["default"]; ok {
+		parameter.Default = fmt.Sprint(value)
+	}
+	if value, ok := param["example"]; ok {
+		parameter.Example = fmt.Sprint(value)
+	} else if value, ok := schema["example"]; ok {
+		parameter.Example = fmt.Sprint(value)
+	}
+	if d.swagger && parameter.Type == "array" && parameter.Style == "" {
+		switch stringField(param, "collectionFormat") {
+		case "multi":
+			parameter.Style = "form"
+		case "csv", "":
+			parameter.Style = "comma"
+		}
+	}
+	return parameter
+}
+
+// authentication returns the first security scheme of the operation, or
+// of the document when the operation sets none
+func (d *openAPIDocument) authentication(operation map[string]interface{}) *entities.AuthConfig {
+	requirements, ok := operation["security"].([]interface{})
+	if !ok {
+		requirements, _ = d.root["security"].([]interface{})
+	}
+	schemes := mapField(mapField(d.root, "components"), "securitySchemes")
+	if d.swagger {
+		schemes = mapField(d.root, "securityDefinitions")
+	}
+
+	for _, requirement := range requirements {
+		for name := range mapValue(requirement) {
+			scheme := mapField(schemes, name)
+			switch stringField(scheme, "type") {
+			case "apiKey":
+				auth := &entities.AuthConfig{Type: "api_key"}
+				if stringField(scheme, "in") == "header" {
+					auth.Header = stringField(scheme, "name")
+				}
+				return auth
+			case "http":
+				return &entities.AuthConfig{Type: strings.ToLower(stringField(scheme, "scheme"))}
+			case "basic":
+				return &entities.AuthConfig{Type: "basic"}
+			case "oauth2", "openIdConnect":
+				return &entities.AuthConfig{Type: "oauth2"}
+			}
+		}
+	}
+	return nil
+}
+
+// schema returns a schema with its local $refs resolved
+func (d *openAPIDocument) schema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	resolved, _ := d.inline(schema, map[string]bool{}).(map[string]interface{})
+	return resolved
+}
+
+// inline replaces the local $refs in a value by what they point to. seen
+// holds the refs being inlined, so a recursive schema stops at its $ref.
+func (d *openAPIDocument) inline(value interface{}, seen map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			if seen[ref] {
+				return map[string]interface{}{"$ref": ref}
+			}
+			target := d.lookup(ref)
+			if target == nil {
+				return v
+			}
+			seen[ref] = true
+			defer delete(seen, ref)
+			return d.inline(target, seen)
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = d.inline(item, seen)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = d.inline(item, seen)
+		}
+		return out
+	}
+	return value
+}
+
+// resolve follows a $ref to the object it points to, for parameters,
+// request bodies and responses
+func (d *openAPIDocument) resolve(value map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 10; i++ {
+		ref, ok := value["$ref"].(string)
+		if !ok {
+			return value
+		}
+		value = d.lookup(ref)
+	}
+	return value
+}
+
+// lookup returns the object at a local JSON pointer such as
+// #/components/schemas/Order
+func (d *openAPIDocument) lookup(ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	current := d.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		next, ok := current[token].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// jsonMedia returns the JSON media type of a content map, or the first one
+func jsonMedia(content map[string]interface{}) map[string]interface{} {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		if strings.Contains(mediaType, "json") {
+			return mapValue(content[mediaType])
+		}
+		types = append(types, mediaType)
+	}
+	if len(types) == 0 {
+		return nil
+	}
+	sort.Strings(types)
+	return mapValue(content[types[0]])
+}
+
+func mapValue(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func mapField(m map[string]interface{}, key string) map[string]interface{} {
+	return mapValue(m[key])
+}
+
+func listField(m map[string]interface{}, key string) []interface{} {
+	list, _ := m[key].([]interface{})
+	return list
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
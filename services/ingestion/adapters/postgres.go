@@ -2,22 +2,28 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testpilot-ai/ingestion/domain/entities"
+	"github.com/testpilot-ai/shared/crypto"
 )
 
 // PostgresRepository handles database operations
 type PostgresRepository struct {
-	pool *pgxpool.Pool
+	pool    *pgxpool.Pool
+	keyring *crypto.Keyring
 }
 
-// NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(pool *pgxpool.Pool) *PostgresRepository {
-	return &PostgresRepository{pool: pool}
+// NewPostgresRepository creates a new PostgreSQL repository. keyring
+// encrypts the secrets it stores, like URL source headers.
+func NewPostgresRepository(pool *pgxpool.Pool, keyring *crypto.Keyring) *PostgresRepository {
+	return &PostgresRepository{pool: pool, keyring: keyring}
 }
 
 // SaveAPISpecification saves or updates an API specification
@@ -83,6 +89,35 @@ func (r *PostgresRepository) GetAPISpecificationByHash(ctx context.Context, cont
 	return &spec, nil
 }
 
+// GetAPISpecificationByID retrieves an API specification by ID
+func (r *PostgresRepository) GetAPISpecificationByID(ctx context.Context, id uuid.UUID) (*entities.APISpecification, error) {
+	query := `
+		SELECT id, name, version, source_type, source_path, content_hash, metadata, created_at, updated_at, created_by
+		FROM api_specifications
+		WHERE id = $1
+	`
+
+	var spec entities.APISpecification
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&spec.ID,
+		&spec.Name,
+		&spec.Version,
+		&spec.SourceType,
+		&spec.SourcePath,
+		&spec.ContentHash,
+		&spec.Metadata,
+		&spec.CreatedAt,
+		&spec.UpdatedAt,
+		&spec.CreatedBy,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
 // GetAPISpecificationByNameVersion retrieves an API specification by name and version
 func (r *PostgresRepository) GetAPISpecificationByNameVersion(ctx context.Context, name, version string) (*entities.APISpecification, error) {
 	query := `
@@ -116,7 +151,7 @@ func (r *PostgresRepository) GetAPISpecificationByNameVersion(ctx context.Contex
 // UpdateAPISpecification updates an existing API specification
 func (r *PostgresRepository) UpdateAPISpecification(ctx context.Context, spec *entities.APISpecification) error {
 	query := `
-		UPDATE api_specifications 
+		UPDATE api_specifications
 		SET content_hash = $1, metadata = $2, updated_at = $3, source_path = $4
 		WHERE id = $5
 	`
@@ -136,12 +171,469 @@ func (r *PostgresRepository) UpdateAPISpecification(ctx context.Context, spec *e
 	return nil
 }
 
-// GetAllAPISpecifications retrieves all API specifications
+// SaveAPISpecRevision appends a revision to an API specification, numbered
+// one after its latest, and sets the revision's number. Two revisions
+// saved at once cannot share a number; the later one fails.
+func (r *PostgresRepository) SaveAPISpecRevision(ctx context.Context, rev *entities.APISpecRevision) error {
+	query := `
+		INSERT INTO api_spec_revisions (id, api_spec_id, revision, content_hash, source_type, source_path, config, created_at)
+		SELECT $1::uuid, $2::uuid, COALESCE(MAX(revision), 0) + 1, $3::varchar, $4::varchar, $5::text, $6::jsonb, $7::timestamptz
+		FROM api_spec_revisions
+		WHERE api_spec_id = $2::uuid
+		RETURNING revision
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		rev.ID,
+		rev.APISpecID,
+		rev.ContentHash,
+		rev.SourceType,
+		rev.SourcePath,
+		rev.Config,
+		rev.CreatedAt,
+	).Scan(&rev.Revision)
+
+	if err != nil {
+		return fmt.Errorf("failed to save API spec revision: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateAPISpecificationWithRevision updates an existing API specification
+// and appends rev as its next revision in one transaction, setting the
+// revision's number. The specification row is locked first, so concurrent
+// updates of the same API are numbered one after the other. When the API
+// has no revisions yet and previous is set, previous is saved as revision 1
+// first, so the content the API had before this update is kept.
+func (r *PostgresRepository) UpdateAPISpecificationWithRevision(ctx context.Context, spec *entities.APISpecification, rev, previous *entities.APISpecRevision) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT id FROM api_specifications WHERE id = $1 FOR UPDATE`, spec.ID); err != nil {
+		return fmt.Errorf("failed to lock API specification: %w", err)
+	}
+
+	var latest int
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(MAX(revision), 0)
+		FROM api_spec_revisions
+		WHERE api_spec_id = $1
+	`, spec.ID).Scan(&latest)
+	if err != nil {
+		return fmt.Errorf("failed to get latest API spec revision: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE api_specifications
+		SET content_hash = $1, metadata = $2, updated_at = $3, source_path = $4
+		WHERE id = $5
+	`,
+		spec.ContentHash,
+		spec.Metadata,
+		spec.UpdatedAt,
+		spec.SourcePath,
+		spec.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update API specification: %w", err)
+	}
+
+	insert := `
+		INSERT INTO api_spec_revisions (id, api_spec_id, revision, content_hash, source_type, source_path, config, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	for _, saved := range numberRevisions(latest, rev, previous) {
+		_, err = tx.Exec(ctx, insert,
+			saved.ID,
+			saved.APISpecID,
+			saved.Revision,
+			saved.ContentHash,
+			saved.SourceType,
+			saved.SourcePath,
+			saved.Config,
+			saved.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save API spec revision %d: %w", saved.Revision, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit API specification update: %w", err)
+	}
+
+	return nil
+}
+
+// numberRevisions numbers the revisions an update saves after the latest
+// one: previous as revision 1 when there are none yet, then rev
+func numberRevisions(latest int, rev, previous *entities.APISpecRevision) []*entities.APISpecRevision {
+	var revisions []*entities.APISpecRevision
+	if latest == 0 && previous != nil {
+		previous.Revision = 1
+		revisions = append(revisions, previous)
+		latest = 1
+	}
+	rev.Revision = latest + 1
+	return append(revisions, rev)
+}
+
+// HasAPISpecRevisions reports whether an API specification has any revisions
+func (r *PostgresRepository) HasAPISpecRevisions(ctx context.Context, apiSpecID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM api_spec_revisions WHERE api_spec_id = $1)`, apiSpecID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check API spec revisions: %w", err)
+	}
+	return exists, nil
+}
+
+// ListAPISpecRevisions retrieves the revisions of an API specification,
+// latest first, without their configurations
+func (r *PostgresRepository) ListAPISpecRevisions(ctx context.Context, apiSpecID uuid.UUID) ([]entities.APISpecRevision, error) {
+	query := `
+		SELECT id, api_spec_id, revision, content_hash, source_type, COALESCE(source_path, ''), created_at
+		FROM api_spec_revisions
+		WHERE api_spec_id = $1
+		ORDER BY revision DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, apiSpecID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API spec revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []entities.APISpecRevision{}
+	for rows.Next() {
+		var rev entities.APISpecRevision
+		err := rows.Scan(
+			&rev.ID,
+			&rev.APISpecID,
+			&rev.Revision,
+			&rev.ContentHash,
+			&rev.SourceType,
+			&rev.SourcePath,
+			&rev.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetAPISpecRevision retrieves a revision of an API specification with its
+// configuration, or nil if it does not exist
+func (r *PostgresRepository) GetAPISpecRevision(ctx context.Context, apiSpecID uuid.UUID, revision int) (*entities.APISpecRevision, error) {
+	query := `
+		SELECT id, api_spec_id, revision, content_hash, source_type, COALESCE(source_path, ''), config, created_at
+		FROM api_spec_revisions
+		WHERE api_spec_id = $1 AND revision = $2
+	`
+
+	var rev entities.APISpecRevision
+	err := r.pool.QueryRow(ctx, query, apiSpecID, revision).Scan(
+		&rev.ID,
+		&rev.APISpecID,
+		&rev.Revision,
+		&rev.ContentHash,
+		&rev.SourceType,
+		&rev.SourcePath,
+		&rev.Config,
+		&rev.CreatedAt,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API spec revision: %w", err)
+	}
+
+	return &rev, nil
+}
+
+// SaveGRPCDescriptorSet creates or replaces the descriptor set of a gRPC API
+// specification
+func (r *PostgresRepository) SaveGRPCDescriptorSet(ctx context.Context, apiSpecID uuid.UUID, descriptorSet []byte) error {
+	query := `
+		INSERT INTO grpc_descriptor_sets (api_spec_id, descriptor_set, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (api_spec_id) DO UPDATE
+		SET descriptor_set = EXCLUDED.descriptor_set, updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.pool.Exec(ctx, query, apiSpecID, descriptorSet, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save gRPC descriptor set: %w", err)
+	}
+
+	return nil
+}
+
+// GetGitSource retrieves a git source by its repository, ref and glob
+func (r *PostgresRepository) GetGitSource(ctx context.Context, repository, ref, glob string) (*entities.GitSource, error) {
+	query := `
+		SELECT id, repository, ref, glob, COALESCE(last_commit, ''), created_at, updated_at
+		FROM git_sources
+		WHERE repository = $1 AND ref = $2 AND glob = $3
+	`
+
+	var source entities.GitSource
+	err := r.pool.QueryRow(ctx, query, repository, ref, glob).Scan(
+		&source.ID,
+		&source.Repository,
+		&source.Ref,
+		&source.Glob,
+		&source.LastCommit,
+		&source.CreatedAt,
+		&source.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &source, nil
+}
+
+// SaveGitSource creates or updates a git source. The source's ID is set to
+// the stored one, which differs when another ingestion created it first.
+func (r *PostgresRepository) SaveGitSource(ctx context.Context, source *entities.GitSource) error {
+	query := `
+		INSERT INTO git_sources (id, repository, ref, glob, last_commit, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		ON CONFLICT (repository, ref, glob) DO UPDATE SET
+			last_commit = COALESCE(EXCLUDED.last_commit, git_sources.last_commit),
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		source.ID,
+		source.Repository,
+		source.Ref,
+		source.Glob,
+		source.LastCommit,
+		source.CreatedAt,
+		source.UpdatedAt,
+	).Scan(&source.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save git source: %w", err)
+	}
+
+	return nil
+}
+
+// SaveGitSpecFile records the file and commit an API specification was
+// ingested from
+func (r *PostgresRepository) SaveGitSpecFile(ctx context.Context, apiSpecID uuid.UUID, file *entities.GitSpecFile) error {
+	query := `
+		INSERT INTO git_spec_files (api_spec_id, git_source_id, file_path, commit_sha, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (api_spec_id) DO UPDATE SET
+			git_source_id = EXCLUDED.git_source_id,
+			file_path = EXCLUDED.file_path,
+			commit_sha = EXCLUDED.commit_sha,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.pool.Exec(ctx, query, apiSpecID, file.SourceID, file.Path, file.CommitSHA, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save git spec file: %w", err)
+	}
+
+	return nil
+}
+
+// urlSourceColumns are the columns scanned by scanURLSource
+const urlSourceColumns = `id, url, headers_ciphertext, headers_nonce, headers_wrapped_key, headers_key_nonce,
+	COALESCE(headers_key_id, ''), refresh_interval_seconds, api_spec_id, COALESCE(content_hash, ''),
+	last_fetched_at, COALESCE(last_error, ''), next_refresh_at, created_at, updated_at`
+
+func scanURLSource(row pgx.Row) (*entities.URLSource, *crypto.Envelope, error) {
+	var source entities.URLSource
+	var headers crypto.Envelope
+	err := row.Scan(
+		&source.ID,
+		&source.URL,
+		&headers.Ciphertext,
+		&headers.Nonce,
+		&headers.WrappedKey,
+		&headers.KeyNonce,
+		&headers.KeyID,
+		&source.RefreshIntervalSeconds,
+		&source.APISpecID,
+		&source.ContentHash,
+		&source.LastFetchedAt,
+		&source.LastError,
+		&source.NextRefreshAt,
+		&source.CreatedAt,
+		&source.UpdatedAt,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &source, &headers, nil
+}
+
+// openHeaders decrypts the headers of a URL source, if it has any
+func (r *PostgresRepository) openHeaders(source *entities.URLSource, headers *crypto.Envelope) error {
+	if headers.Ciphertext == nil {
+		return nil
+	}
+	plaintext, err := r.keyring.Open(headers, urlSourceHeadersAAD(source.URL))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt headers of %s: %w", source.URL, err)
+	}
+	if err := json.Unmarshal(plaintext, &source.Headers); err != nil {
+		return fmt.Errorf("failed to decode headers of %s: %w", source.URL, err)
+	}
+	return nil
+}
+
+// urlSourceHeadersAAD binds encrypted headers to their URL, the source's
+// stable key, so they cannot be moved to another source
+func urlSourceHeadersAAD(url string) string {
+	return "url_sources.headers:" + url
+}
+
+// GetURLSource retrieves a URL source by its URL
+func (r *PostgresRepository) GetURLSource(ctx context.Context, url string) (*entities.URLSource, error) {
+	query := `SELECT ` + urlSourceColumns + ` FROM url_sources WHERE url = $1`
+	source, headers, err := scanURLSource(r.pool.QueryRow(ctx, query, url))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.openHeaders(source, headers); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// SaveURLSource creates or updates a URL source. The source's ID is set to
+// the stored one, which differs when another ingestion created it first.
+// Headers are sealed again on every save, so they move to the active
+// master key as sources are refreshed.
+func (r *PostgresRepository) SaveURLSource(ctx context.Context, source *entities.URLSource) error {
+	headers := &crypto.Envelope{}
+	if len(source.Headers) > 0 {
+		plaintext, err := json.Marshal(source.Headers)
+		if err != nil {
+			return fmt.Errorf("failed to encode URL source headers: %w", err)
+		}
+		if headers, err = r.keyring.Seal(urlSourceHeadersAAD(source.URL), plaintext); err != nil {
+			return fmt.Errorf("failed to encrypt URL source headers: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO url_sources (id, url, headers_ciphertext, headers_nonce, headers_wrapped_key, headers_key_nonce,
+			headers_key_id, refresh_interval_seconds, api_spec_id, content_hash,
+			last_fetched_at, last_error, next_refresh_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13, $14, $15)
+		ON CONFLICT (url) DO UPDATE SET
+			headers_ciphertext = EXCLUDED.headers_ciphertext,
+			headers_nonce = EXCLUDED.headers_nonce,
+			headers_wrapped_key = EXCLUDED.headers_wrapped_key,
+			headers_key_nonce = EXCLUDED.headers_key_nonce,
+			headers_key_id = EXCLUDED.headers_key_id,
+			refresh_interval_seconds = EXCLUDED.refresh_interval_seconds,
+			api_spec_id = EXCLUDED.api_spec_id,
+			content_hash = EXCLUDED.content_hash,
+			last_fetched_at = EXCLUDED.last_fetched_at,
+			last_error = EXCLUDED.last_error,
+			next_refresh_at = EXCLUDED.next_refresh_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		source.ID,
+		source.URL,
+		headers.Ciphertext,
+		headers.Nonce,
+		headers.WrappedKey,
+		headers.KeyNonce,
+		headers.KeyID,
+		source.RefreshIntervalSeconds,
+		source.APISpecID,
+		source.ContentHash,
+		source.LastFetchedAt,
+		source.LastError,
+		source.NextRefreshAt,
+		source.CreatedAt,
+		source.UpdatedAt,
+	).Scan(&source.ID)
+
+	if err != nil {
+		return fmt.Errorf("failed to save URL source: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueURLSources returns up to limit URL sources due for a refresh and
+// moves their next refresh one interval ahead, so concurrent refreshers
+// never fetch the same source twice. Sources whose headers cannot be
+// decrypted are left out and reported in the error, next to the others.
+func (r *PostgresRepository) ClaimDueURLSources(ctx context.Context, now time.Time, limit int) ([]entities.URLSource, error) {
+	query := `
+		UPDATE url_sources
+		SET next_refresh_at = $1 + make_interval(secs => refresh_interval_seconds)
+		WHERE id IN (
+			SELECT id FROM url_sources
+			WHERE refresh_interval_seconds > 0 AND next_refresh_at <= $1
+			ORDER BY next_refresh_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + urlSourceColumns
+
+	rows, err := r.pool.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim URL sources: %w", err)
+	}
+	defer rows.Close()
+
+	var sources []entities.URLSource
+	var failed []error
+	for rows.Next() {
+		source, headers, err := scanURLSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		if err := r.openHeaders(source, headers); err != nil {
+			failed = append(failed, err)
+			continue
+		}
+		sources = append(sources, *source)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sources, errors.Join(failed...)
+}
+
+// GetAllAPISpecifications retrieves all API specifications, with the git
+// file and commit of those ingested from a repository
 func (r *PostgresRepository) GetAllAPISpecifications(ctx context.Context) ([]entities.APISpecification, error) {
 	query := `
-		SELECT id, name, version, source_type, source_path, content_hash, metadata, created_at, updated_at, created_by
-		FROM api_specifications
-		ORDER BY updated_at DESC
+		SELECT s.id, s.name, s.version, s.source_type, s.source_path, s.content_hash, s.metadata, s.created_at, s.updated_at, s.created_by,
+			f.git_source_id, g.repository, f.file_path, f.commit_sha
+		FROM api_specifications s
+		LEFT JOIN git_spec_files f ON f.api_spec_id = s.id
+		LEFT JOIN git_sources g ON g.id = f.git_source_id
+		ORDER BY s.updated_at DESC
 	`
 
 	rows, err := r.pool.Query(ctx, query)
@@ -153,6 +645,8 @@ func (r *PostgresRepository) GetAllAPISpecifications(ctx context.Context) ([]ent
 	var specs []entities.APISpecification
 	for rows.Next() {
 		var spec entities.APISpecification
+		var gitSourceID *uuid.UUID
+		var repository, filePath, commitSHA *string
 		err := rows.Scan(
 			&spec.ID,
 			&spec.Name,
@@ -164,10 +658,22 @@ func (r *PostgresRepository) GetAllAPISpecifications(ctx context.Context) ([]ent
 			&spec.CreatedAt,
 			&spec.UpdatedAt,
 			&spec.CreatedBy,
+			&gitSourceID,
+			&repository,
+			&filePath,
+			&commitSHA,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		if gitSourceID != nil {
+			spec.Git = &entities.GitSpecFile{
+				SourceID:   *gitSourceID,
+				Repository: *repository,
+				Path:       *filePath,
+				CommitSHA:  *commitSHA,
+			}
+		}
 		specs = append(specs, spec)
 	}
 
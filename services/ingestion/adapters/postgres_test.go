@@ -0,0 +1,48 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+func TestNumberRevisions(t *testing.T) {
+	tests := []struct {
+		name         string
+		latest       int
+		withPrevious bool
+		want         []int // revision numbers, previous content first
+	}{
+		{"first revision of a new spec", 0, false, []int{1}},
+		{"spec without revisions keeps its content as revision 1", 0, true, []int{1, 2}},
+		{"spec with revisions", 3, false, []int{4}},
+		{"previous content is not saved again", 3, true, []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiID := uuid.New()
+			rev := &entities.APISpecRevision{ID: uuid.New(), APISpecID: apiID, ContentHash: "new"}
+			var previous *entities.APISpecRevision
+			if tt.withPrevious {
+				previous = &entities.APISpecRevision{ID: uuid.New(), APISpecID: apiID, ContentHash: "old"}
+			}
+
+			revisions := numberRevisions(tt.latest, rev, previous)
+			if len(revisions) != len(tt.want) {
+				t.Fatalf("got %d revisions, want %d", len(revisions), len(tt.want))
+			}
+			for i, r := range revisions {
+				if r.Revision != tt.want[i] {
+					t.Errorf("revision %d is numbered %d, want %d", i, r.Revision, tt.want[i])
+				}
+			}
+			if last := revisions[len(revisions)-1]; last != rev {
+				t.Errorf("last revision is %q, want the new content", last.ContentHash)
+			}
+			if len(revisions) == 2 && revisions[0] != previous {
+				t.Errorf("first revision is %q, want the previous content", revisions[0].ContentHash)
+			}
+		})
+	}
+}
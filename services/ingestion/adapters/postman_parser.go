@@ -53,6 +53,16 @@ func (p *PostmanParser) ParseCollectionData(data []byte) (*entities.APIConfig, s
 	return config, contentHash, nil
 }
 
+// IsPostmanCollection reports whether JSON data is a Postman collection
+// rather than an API configuration
+func IsPostmanCollection(data []byte) bool {
+	var collection entities.PostmanCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return false
+	}
+	return strings.Contains(collection.Info.Schema, "schema.getpostman.com")
+}
+
 // extractBaseURL extracts the base URL from the first request in the collection
 func (p *PostmanParser) extractBaseURL(items []entities.PostmanItem) string {
 	for _, item := range items {
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/testpilot-ai/ingestion/domain/entities"
 )
 
 // QdrantAdapter handles vector database operations
@@ -202,3 +203,42 @@ func (a *QdrantAdapter) Delete(id uuid.UUID) error {
 	return nil
 }
 
+// GetConfig retrieves the API config stored with a point, or nil if the
+// point does not exist
+func (a *QdrantAdapter) GetConfig(id uuid.UUID) (*entities.APIConfig, error) {
+	resp, err := a.httpClient.Get(a.baseURL + "/collections/" + a.collection + "/points/" + id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get point (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Result struct {
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	raw, ok := result.Result.Payload["config"].(string)
+	if !ok {
+		return nil, fmt.Errorf("point %s has no stored config", id)
+	}
+
+	var config entities.APIConfig
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, fmt.Errorf("invalid stored config: %w", err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,121 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/testpilot-ai/shared/egress"
+)
+
+// Bounds of fetching a spec document
+const (
+	DefaultURLFetchTimeout  = 30 * time.Second
+	DefaultURLFetchMaxBytes = 10 << 20
+	maxURLFetchRedirects    = 10
+)
+
+// URLFetcher downloads spec documents over HTTP(S). Like the execution
+// service's calls, fetches never reach loopback, private, link-local or
+// other non-public addresses outside the allowed ranges.
+type URLFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewURLFetcher creates a fetcher whose requests take at most timeout and
+// whose documents are at most maxBytes long. allowed are the non-public
+// ranges it may fetch from, e.g. 10.20.0.0/16 for an internal spec server.
+func NewURLFetcher(timeout time.Duration, maxBytes int64, allowed []netip.Prefix) *URLFetcher {
+	dialer := newEgressDialer(allowed)
+	return &URLFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 dialer.Proxy,
+				DialContext:           dialer.DialContext,
+				ForceAttemptHTTP2:     true,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+// newEgressDialer returns a dialer that only connects to public addresses
+// or those in allowed
+func newEgressDialer(allowed []netip.Prefix) *egress.Dialer {
+	rules := &egress.Rules{Ranges: allowed, Hint: "allow its range in EGRESS_ALLOWED_CIDRS"}
+	return egress.NewDialer(
+		&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second},
+		func(context.Context) *egress.Rules { return rules },
+	)
+}
+
+// ValidateSpecURL checks that a spec URL is an absolute http(s) URL
+func ValidateSpecURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("URL must be an absolute http:// or https:// URL")
+	}
+	return nil
+}
+
+// Fetch downloads a document with the given headers, e.g. for
+// authentication, and returns it with its content type
+func (f *URLFetcher) Fetch(ctx context.Context, rawURL string, headers map[string]string) ([]byte, string, error) {
+	if err := ValidateSpecURL(rawURL); err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml, text/yaml, */*")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := *f.client
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		if len(via) >= maxURLFetchRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxURLFetchRedirects)
+		}
+		// Go forwards custom headers like X-API-Key to any host, so
+		// credentials only follow redirects within the same origin
+		if len(headers) > 0 && (next.URL.Scheme != via[0].URL.Scheme || next.URL.Host != via[0].URL.Host) {
+			return fmt.Errorf("refusing redirect to %s://%s: headers are only sent to %s://%s",
+				next.URL.Scheme, next.URL.Host, via[0].URL.Scheme, via[0].URL.Host)
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("fetching %s returned status %d", rawURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, "", fmt.Errorf("document at %s is larger than %d bytes", rawURL, f.maxBytes)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,114 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testpilot-ai/shared/egress"
+)
+
+func TestURLFetcherEgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte("openapi: 3.0.0\n"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	tests := []struct {
+		name    string
+		allowed []string
+		host    string
+		blocked bool
+	}{
+		{"loopback ip", nil, "127.0.0.1", true},
+		{"host name resolving to loopback", nil, "localhost", true},
+		{"other allowed range", []string{"10.0.0.0/8"}, "127.0.0.1", true},
+		{"allowed loopback range", []string{"127.0.0.0/8", "::1/128"}, "localhost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := egress.ParseCIDRs(tt.allowed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fetcher := NewURLFetcher(5*time.Second, DefaultURLFetchMaxBytes, allowed)
+
+			data, _, err := fetcher.Fetch(context.Background(), "http://"+net.JoinHostPort(tt.host, port)+"/openapi.yaml", nil)
+			if tt.blocked {
+				if !errors.Is(err, egress.ErrBlocked) {
+					t.Fatalf("Fetch error = %v, want ErrBlocked", err)
+				}
+				return
+			}
+			if err != nil || string(data) != "openapi: 3.0.0\n" {
+				t.Fatalf("Fetch = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestURLFetcherRedirects(t *testing.T) {
+	var gotKey string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-API-Key")
+		w.Write([]byte("{}"))
+	}))
+	defer target.Close()
+	// The same server on another host name is another origin
+	otherOrigin := strings.Replace(target.URL, "127.0.0.1", "localhost", 1)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/spec.json", http.StatusFound)
+		case "/cross":
+			http.Redirect(w, r, otherOrigin+"/spec.json", http.StatusFound)
+		default:
+			gotKey = r.Header.Get("X-API-Key")
+			w.Write([]byte("{}"))
+		}
+	}))
+	defer origin.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		wantErr string
+		wantKey string
+	}{
+		{"same origin keeps headers", "/same", map[string]string{"X-API-Key": "k"}, "", "k"},
+		{"cross origin with headers is refused", "/cross", map[string]string{"X-API-Key": "k"}, "refusing redirect", ""},
+		{"cross origin without headers", "/cross", nil, "", ""},
+	}
+	allowed, _ := egress.ParseCIDRs([]string{"127.0.0.0/8", "::1/128"})
+	fetcher := NewURLFetcher(5*time.Second, DefaultURLFetchMaxBytes, allowed)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey = "unset"
+			_, _, err := fetcher.Fetch(context.Background(), origin.URL+tt.path, tt.headers)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch error = %v, want %q", err, tt.wantErr)
+				}
+				if gotKey != "unset" {
+					t.Error("the redirect target was requested")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("X-API-Key at the target = %q, want %q", gotKey, tt.wantKey)
+			}
+		})
+	}
+}
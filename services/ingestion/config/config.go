@@ -17,6 +17,19 @@ type Config struct {
 	GeminiAPIKey   string
 	APIConfigsPath string
 	LogLevel       string
+	MockBaseURL    string
+	// GitWorkDir is where repositories are cloned for ingestion, the
+	// system temp directory when empty
+	GitWorkDir string
+	// Comma-separated directories local repositories may be cloned from;
+	// empty refuses local repositories
+	GitLocalRoots string
+	// Master keys encrypting URL source headers, shared with the execution
+	// service: comma-separated id:base64key pairs and the active key ID
+	SecretsMasterKeys  string
+	SecretsActiveKeyID string
+	// Comma-separated non-public ranges spec URLs may be fetched from
+	EgressAllowedCIDRs string
 }
 
 // Load loads configuration from environment variables
@@ -33,6 +46,13 @@ func Load() *Config {
 		GeminiAPIKey:   getEnv("GEMINI_API_KEY", ""),
 		APIConfigsPath: getEnv("API_CONFIGS_PATH", "./api_configs"),
 		LogLevel:       getEnv("LOG_LEVEL", "INFO"),
+		MockBaseURL:    getEnv("MOCK_BASE_URL", "http://ingestion:8001/mock"),
+		GitWorkDir:     getEnv("GIT_WORK_DIR", ""),
+		GitLocalRoots:  getEnv("GIT_LOCAL_ROOTS", ""),
+
+		SecretsMasterKeys:  getEnv("SECRETS_MASTER_KEYS", ""),
+		SecretsActiveKeyID: getEnv("SECRETS_ACTIVE_KEY_ID", ""),
+		EgressAllowedCIDRs: getEnv("EGRESS_ALLOWED_CIDRS", ""),
 	}
 }
 
@@ -18,6 +18,63 @@ type APISpecification struct {
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
 	CreatedBy   *uuid.UUID             `json:"created_by,omitempty"`
+	// Git is set on specifications ingested from a git repository
+	Git *GitSpecFile `json:"git,omitempty"`
+}
+
+// APISpecRevision is an immutable snapshot of an API specification, taken
+// each time it is ingested with new content. Revisions are numbered from 1.
+type APISpecRevision struct {
+	ID          uuid.UUID  `json:"id"`
+	APISpecID   uuid.UUID  `json:"api_spec_id"`
+	Revision    int        `json:"revision"`
+	ContentHash string     `json:"content_hash"`
+	SourceType  string     `json:"source_type"`
+	SourcePath  string     `json:"source_path,omitempty"`
+	Config      *APIConfig `json:"config,omitempty"` // omitted from listings
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// GitSource is a git repository location API specs are ingested from: the
+// spec files matching Glob at Ref. LastCommit is the commit last ingested
+// without failures; re-ingestion reads only the files changed since.
+type GitSource struct {
+	ID         uuid.UUID `json:"id"`
+	Repository string    `json:"repository"`
+	Ref        string    `json:"ref,omitempty"` // empty for the default branch
+	Glob       string    `json:"glob,omitempty"`
+	LastCommit string    `json:"last_commit,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GitSpecFile is the file and commit an API specification was last
+// ingested from
+type GitSpecFile struct {
+	SourceID   uuid.UUID `json:"source_id"`
+	Repository string    `json:"repository"`
+	Path       string    `json:"path"`
+	CommitSHA  string    `json:"commit_sha"`
+}
+
+// URLSource is a spec document ingested from a URL. With a refresh
+// interval it is re-fetched periodically and re-ingested when its content
+// hash changes.
+type URLSource struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+	// Headers are sent with every fetch, e.g. for authentication; only
+	// their names are returned
+	Headers                map[string]string `json:"-"`
+	HeaderNames            []string          `json:"header_names,omitempty"`
+	RefreshIntervalSeconds int               `json:"refresh_interval_seconds,omitempty"`
+	APISpecID              *uuid.UUID        `json:"api_spec_id,omitempty"`
+	ContentHash            string            `json:"content_hash,omitempty"`
+	LastFetchedAt          *time.Time        `json:"last_fetched_at,omitempty"`
+	LastError              string            `json:"last_error,omitempty"`
+	NextRefreshAt          *time.Time        `json:"next_refresh_at,omitempty"`
+	CreatedAt              time.Time         `json:"created_at"`
+	UpdatedAt              time.Time         `json:"updated_at"`
 }
 
 // IngestionResult represents the result of an ingestion operation
@@ -43,6 +100,48 @@ type APIEndpoint struct {
 	ResponseSchema      map[string]interface{}   `yaml:"response_schema" json:"response_schema,omitempty"`
 	ExpectedStatusCodes []map[string]interface{} `yaml:"expected_status_codes" json:"expected_status_codes,omitempty"`
 	Examples            []Example                `yaml:"examples" json:"examples,omitempty"`
+	// GraphQL is set on the endpoints of a GraphQL API, one per query or
+	// mutation; they all POST to the same path
+	GraphQL *GraphQLOperation `yaml:"graphql" json:"graphql,omitempty"`
+	// GRPC is set on the endpoints of a gRPC API, one per unary method.
+	// The request schema is the input message in protobuf JSON.
+	GRPC *GRPCMethod `yaml:"grpc" json:"grpc,omitempty"`
+}
+
+// GraphQL operation types
+const (
+	GraphQLQuery    = "query"
+	GraphQLMutation = "mutation"
+)
+
+// GraphQLOperation describes a root field of a GraphQL schema. Its
+// arguments are also listed as the endpoint's parameters, in "variables".
+type GraphQLOperation struct {
+	Type       string            `yaml:"type" json:"type"` // query or mutation
+	Field      string            `yaml:"field" json:"field"`
+	Arguments  []GraphQLArgument `yaml:"arguments" json:"arguments,omitempty"`
+	ReturnType string            `yaml:"return_type" json:"return_type"`
+	// Document is a ready-to-send operation taking every argument as a
+	// variable and selecting the scalar fields of the result
+	Document   string `yaml:"document" json:"document"`
+	Deprecated bool   `yaml:"deprecated" json:"deprecated,omitempty"`
+}
+
+// GraphQLArgument is an argument of a GraphQL field
+type GraphQLArgument struct {
+	Name         string `yaml:"name" json:"name"`
+	Type         string `yaml:"type" json:"type"` // GraphQL type reference, e.g. [ID!]!
+	Description  string `yaml:"description" json:"description,omitempty"`
+	DefaultValue string `yaml:"default_value" json:"default_value,omitempty"`
+}
+
+// GRPCMethod describes a unary method of a gRPC service
+type GRPCMethod struct {
+	Service    string `yaml:"service" json:"service"` // full name, e.g. orders.v1.OrderService
+	Method     string `yaml:"method" json:"method"`
+	FullMethod string `yaml:"full_method" json:"full_method"` // Service/Method, as the execution service takes it
+	InputType  string `yaml:"input_type" json:"input_type"`
+	OutputType string `yaml:"output_type" json:"output_type"`
 }
 
 // AuthConfig represents authentication configuration
@@ -62,6 +161,11 @@ type Parameter struct {
 	Default     string `yaml:"default" json:"default,omitempty"`
 	Format      string `yaml:"format" json:"format,omitempty"`
 	Example     string `yaml:"example" json:"example,omitempty"`
+	// Style is how an array query parameter is serialized: form (repeated
+	// key, the default), comma or brackets. Explode false with style form
+	// means comma, as in OpenAPI.
+	Style   string `yaml:"style" json:"style,omitempty"`
+	Explode *bool  `yaml:"explode" json:"explode,omitempty"`
 }
 
 // Example represents a request/response example
@@ -77,9 +181,17 @@ type APIConfig struct {
 	Version     string        `yaml:"version" json:"version"`
 	Description string        `yaml:"description" json:"description"`
 	BaseURL     string        `yaml:"base_url" json:"base_url"`
+	Protocol    string        `yaml:"protocol" json:"protocol,omitempty"` // rest (the default), graphql or grpc
 	Endpoints   []APIEndpoint `yaml:"endpoints" json:"endpoints"`
 }
 
+// API protocols
+const (
+	ProtocolREST    = "rest"
+	ProtocolGraphQL = "graphql"
+	ProtocolGRPC    = "grpc"
+)
+
 // PostmanCollection represents a Postman collection
 type PostmanCollection struct {
 	Info struct {
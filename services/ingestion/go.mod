@@ -3,16 +3,28 @@ module github.com/testpilot-ai/ingestion
 go 1.23
 
 require (
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/gin-gonic/gin v1.9.1
 	github.com/google/uuid v1.5.0
 	github.com/jackc/pgx/v5 v5.5.0
 	github.com/joho/godotenv v1.5.1
+	github.com/testpilot-ai/shared/audit v0.0.0-00010101000000-000000000000
+	github.com/testpilot-ai/shared/crypto v0.0.0-00010101000000-000000000000
+	github.com/testpilot-ai/shared/egress v0.0.0-00010101000000-000000000000
 	github.com/testpilot-ai/shared/logger v0.0.0-00010101000000-000000000000
+	github.com/xeipuuv/gojsonschema v1.2.0
+	google.golang.org/protobuf v1.34.2
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 replace github.com/testpilot-ai/shared/logger => ../../shared/logger
 
+replace github.com/testpilot-ai/shared/audit => ../../shared/audit
+
+replace github.com/testpilot-ai/shared/crypto => ../../shared/crypto
+
+replace github.com/testpilot-ai/shared/egress => ../../shared/egress
+
 require (
 	github.com/bytedance/sonic v1.9.1 // indirect
 	github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
@@ -38,11 +50,12 @@ require (
 	github.com/rs/zerolog v1.32.0 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.11 // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	golang.org/x/arch v0.3.0 // indirect
 	golang.org/x/crypto v0.9.0 // indirect
 	golang.org/x/net v0.10.0 // indirect
-	golang.org/x/sync v0.1.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
-	google.golang.org/protobuf v1.30.0 // indirect
 )
@@ -1,40 +1,70 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/testpilot-ai/ingestion/adapters"
 	"github.com/testpilot-ai/ingestion/domain/entities"
+	"github.com/testpilot-ai/ingestion/specdiff"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
 )
 
 // IngestionHandler handles ingestion-related HTTP requests
 type IngestionHandler struct {
 	fileParser     *adapters.FileParser
 	postmanParser  *adapters.PostmanParser
+	graphqlParser  *adapters.GraphQLParser
+	grpcParser     *adapters.GRPCParser
+	gitClient      *adapters.GitClient
+	openapiParser  *adapters.OpenAPIParser
+	urlFetcher     *adapters.URLFetcher
 	embeddingService *adapters.EmbeddingService
 	qdrantAdapter  *adapters.QdrantAdapter
 	postgresRepo   *adapters.PostgresRepository
+	auditRecorder  *audit.Recorder
 }
 
 // NewIngestionHandler creates a new ingestion handler
 func NewIngestionHandler(
 	fileParser *adapters.FileParser,
 	postmanParser *adapters.PostmanParser,
+	graphqlParser *adapters.GraphQLParser,
+	grpcParser *adapters.GRPCParser,
+	gitClient *adapters.GitClient,
+	openapiParser *adapters.OpenAPIParser,
+	urlFetcher *adapters.URLFetcher,
 	embeddingService *adapters.EmbeddingService,
 	qdrantAdapter *adapters.QdrantAdapter,
 	postgresRepo *adapters.PostgresRepository,
+	auditRecorder *audit.Recorder,
 ) *IngestionHandler {
 	return &IngestionHandler{
 		fileParser:     fileParser,
 		postmanParser:  postmanParser,
+		graphqlParser:  graphqlParser,
+		grpcParser:     grpcParser,
+		gitClient:      gitClient,
+		openapiParser:  openapiParser,
+		urlFetcher:     urlFetcher,
 		embeddingService: embeddingService,
 		qdrantAdapter:  qdrantAdapter,
 		postgresRepo:   postgresRepo,
+		auditRecorder:  auditRecorder,
 	}
 }
 
@@ -80,7 +110,7 @@ func (h *IngestionHandler) IngestFile(c *gin.Context) {
 	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(c.Request.Context(), config.Name, config.Version)
 	if existingByName != nil {
 		// Update existing: delete old Qdrant vector, then update
-		apiID, err := h.updateExistingSpec(c, existingByName, config, contentHash, "file", req.FilePath)
+		apiID, err := h.updateExistingSpec(c.Request.Context(), c.Request, existingByName, config, contentHash, "file", req.FilePath)
 		if err != nil {
 			h.logIngestion(c, "file", req.FilePath, "failed", 0, err.Error())
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update: %s", err)})
@@ -98,7 +128,7 @@ func (h *IngestionHandler) IngestFile(c *gin.Context) {
 	}
 
 	// Generate embeddings and store (new file)
-	apiID, err := h.processAndStore(c, config, contentHash, "file", req.FilePath)
+	apiID, err := h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "file", req.FilePath)
 	if err != nil {
 		h.logIngestion(c, "file", req.FilePath, "failed", 0, err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process: %s", err)})
@@ -153,7 +183,7 @@ func (h *IngestionHandler) IngestFolder(c *gin.Context) {
 		}
 
 		// Process and store
-		_, err = h.processAndStore(c, config, contentHash, "file", filePath)
+		_, err = h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "file", filePath)
 		if err != nil {
 			failed++
 			errors = append(errors, fmt.Sprintf("%s: %s", filePath, err))
@@ -220,7 +250,7 @@ func (h *IngestionHandler) IngestPostman(c *gin.Context) {
 	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(c.Request.Context(), config.Name, config.Version)
 	if existingByName != nil {
 		// Update existing: delete old Qdrant vector, then update
-		apiID, err := h.updateExistingSpec(c, existingByName, config, contentHash, "postman", header.Filename)
+		apiID, err := h.updateExistingSpec(c.Request.Context(), c.Request, existingByName, config, contentHash, "postman", header.Filename)
 		if err != nil {
 			h.logIngestion(c, "postman", header.Filename, "failed", 0, err.Error())
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update: %s", err)})
@@ -238,7 +268,7 @@ func (h *IngestionHandler) IngestPostman(c *gin.Context) {
 	}
 
 	// Process and store (new collection)
-	apiID, err := h.processAndStore(c, config, contentHash, "postman", header.Filename)
+	apiID, err := h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "postman", header.Filename)
 	if err != nil {
 		h.logIngestion(c, "postman", header.Filename, "failed", 0, err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process: %s", err)})
@@ -254,6 +284,639 @@ func (h *IngestionHandler) IngestPostman(c *gin.Context) {
 	})
 }
 
+// IngestGraphQL handles GraphQL schema upload: SDL or an introspection
+// result, with the API's name, base URL and endpoint path as form fields
+func (h *IngestionHandler) IngestGraphQL(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	source := adapters.GraphQLSource{
+		Name:        c.PostForm("name"),
+		Version:     c.PostForm("version"),
+		Description: c.PostForm("description"),
+		BaseURL:     c.PostForm("base_url"),
+		Path:        c.PostForm("path"),
+	}
+	if source.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	// Parse the schema into one endpoint per query and mutation
+	config, contentHash, err := h.graphqlParser.ParseSchemaData(content, source)
+	if err != nil {
+		h.logIngestion(c, "graphql", header.Filename, "failed", 0, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse schema: %s", err)})
+		return
+	}
+
+	// Check if already ingested (same hash = no changes)
+	existing, _ := h.postgresRepo.GetAPISpecificationByHash(c.Request.Context(), contentHash)
+	if existing != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Schema already ingested (no changes detected)",
+			"api_id":     existing.ID,
+			"name":       config.Name,
+			"operations": len(config.Endpoints),
+		})
+		return
+	}
+
+	// Check if same name+version exists (update scenario)
+	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(c.Request.Context(), config.Name, config.Version)
+	if existingByName != nil {
+		apiID, err := h.updateExistingSpec(c.Request.Context(), c.Request, existingByName, config, contentHash, "graphql", header.Filename)
+		if err != nil {
+			h.logIngestion(c, "graphql", header.Filename, "failed", 0, err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to update: %s", err)})
+			return
+		}
+
+		h.logIngestion(c, "graphql", header.Filename, "updated", 1, "")
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "GraphQL schema updated successfully",
+			"api_id":     apiID,
+			"name":       config.Name,
+			"operations": len(config.Endpoints),
+		})
+		return
+	}
+
+	apiID, err := h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "graphql", header.Filename)
+	if err != nil {
+		h.logIngestion(c, "graphql", header.Filename, "failed", 0, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process: %s", err)})
+		return
+	}
+
+	h.logIngestion(c, "graphql", header.Filename, "success", 1, "")
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "GraphQL schema ingested successfully",
+		"api_id":     apiID,
+		"name":       config.Name,
+		"operations": len(config.Endpoints),
+	})
+}
+
+// IngestGRPC handles gRPC service upload: one or more .proto files, or a
+// compiled descriptor set (.pb, .protoset, .desc), with the API's name and
+// server address as form fields. The descriptors are stored for the
+// execution service, which calls the methods without generated stubs.
+func (h *IngestionHandler) IngestGRPC(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	source := adapters.GRPCSource{
+		Name:        c.PostForm("name"),
+		Version:     c.PostForm("version"),
+		Description: c.PostForm("description"),
+		BaseURL:     c.PostForm("base_url"),
+	}
+	if source.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	var names []string
+	sources := make(map[string][]byte)
+	for _, header := range form.File["file"] {
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		content, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		names = append(names, header.Filename)
+		sources[header.Filename] = content
+	}
+	sourcePath := strings.Join(names, ",")
+
+	// .proto sources are compiled; anything else must be one descriptor set
+	var config *entities.APIConfig
+	var descriptorSet []byte
+	var contentHash string
+	if strings.EqualFold(path.Ext(names[0]), ".proto") {
+		config, descriptorSet, contentHash, err = h.grpcParser.ParseProtoFiles(c.Request.Context(), sources, source)
+	} else if len(names) == 1 {
+		config, descriptorSet, contentHash, err = h.grpcParser.ParseDescriptorSet(sources[names[0]], source)
+	} else {
+		err = fmt.Errorf("upload .proto files or a single descriptor set")
+	}
+	if err != nil {
+		h.logIngestion(c, "grpc", sourcePath, "failed", 0, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse proto files: %s", err)})
+		return
+	}
+
+	// Check if already ingested (same hash = no changes)
+	existing, _ := h.postgresRepo.GetAPISpecificationByHash(c.Request.Context(), contentHash)
+	if existing != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Proto files already ingested (no changes detected)",
+			"api_id":  existing.ID,
+			"name":    config.Name,
+			"methods": len(config.Endpoints),
+		})
+		return
+	}
+
+	// Check if same name+version exists (update scenario)
+	message := "gRPC services ingested successfully"
+	status := "success"
+	var apiID uuid.UUID
+	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(c.Request.Context(), config.Name, config.Version)
+	if existingByName != nil {
+		message, status = "gRPC services updated successfully", "updated"
+		apiID, err = h.updateExistingSpec(c.Request.Context(), c.Request, existingByName, config, contentHash, "grpc", sourcePath)
+	} else {
+		apiID, err = h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "grpc", sourcePath)
+	}
+	if err == nil {
+		err = h.postgresRepo.SaveGRPCDescriptorSet(c.Request.Context(), apiID, descriptorSet)
+	}
+	if err != nil {
+		h.logIngestion(c, "grpc", sourcePath, "failed", 0, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to process: %s", err)})
+		return
+	}
+
+	h.logIngestion(c, "grpc", sourcePath, status, 1, "")
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"api_id":  apiID,
+		"name":    config.Name,
+		"methods": len(config.Endpoints),
+	})
+}
+
+// gitFileResult is the outcome of one spec file of a git ingestion
+type gitFileResult struct {
+	Path   string     `json:"path"`
+	Status string     `json:"status"` // ingested, updated, unchanged, not_a_spec or failed
+	APIID  *uuid.UUID `json:"api_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// IngestGit handles ingestion from a git repository: the YAML and JSON spec
+// files at a branch, tag or commit, optionally filtered by a path glob.
+// Each spec records the commit it was ingested from. Once a repository,
+// ref and glob have been ingested without failures, later runs read only
+// the files changed since that commit, unless full is set.
+func (h *IngestionHandler) IngestGit(c *gin.Context) {
+	var req struct {
+		Repository string `json:"repository" binding:"required"`
+		Ref        string `json:"ref"`
+		Glob       string `json:"glob"`
+		Full       bool   `json:"full"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "repository is required"})
+		return
+	}
+	if err := adapters.ValidateGitRepository(req.Repository); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := adapters.ValidateGlob(req.Glob); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	checkout, err := h.gitClient.Clone(ctx, req.Repository)
+	if err != nil {
+		h.logIngestion(c, "git", req.Repository, "failed", 0, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to clone repository: %s", err)})
+		return
+	}
+	defer checkout.Close()
+
+	commit, err := checkout.ResolveRef(ctx, req.Ref)
+	if err != nil {
+		h.logIngestion(c, "git", req.Repository, "failed", 0, err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	source, _ := h.postgresRepo.GetGitSource(ctx, req.Repository, req.Ref, req.Glob)
+	if source == nil {
+		source = &entities.GitSource{
+			ID:         uuid.New(),
+			Repository: req.Repository,
+			Ref:        req.Ref,
+			Glob:       req.Glob,
+			CreatedAt:  now,
+		}
+	}
+	previousCommit := source.LastCommit
+	if !req.Full && previousCommit == commit {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Repository already ingested (no new commits)",
+			"commit":  commit,
+		})
+		return
+	}
+
+	files, err := checkout.ListFiles(ctx, commit)
+	if err != nil {
+		h.logIngestion(c, "git", req.Repository, "failed", 0, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	inTree := make(map[string]bool)
+	var candidates []string
+	for _, filePath := range files {
+		if gitSpecFile(req.Glob, filePath) {
+			inTree[filePath] = true
+			candidates = append(candidates, filePath)
+		}
+	}
+
+	// A recorded commit the repository no longer has, e.g. after a force
+	// push, means a full ingestion
+	incremental := !req.Full && checkout.HasCommit(ctx, previousCommit)
+	var removed []string
+	if incremental {
+		changed, err := checkout.ChangedFiles(ctx, previousCommit, commit)
+		if err != nil {
+			h.logIngestion(c, "git", req.Repository, "failed", 0, err.Error())
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		candidates = nil
+		for _, filePath := range changed {
+			switch {
+			case inTree[filePath]:
+				candidates = append(candidates, filePath)
+			case gitSpecFile(req.Glob, filePath):
+				removed = append(removed, filePath)
+			}
+		}
+	}
+
+	// The source is stored first, as the spec files refer to it; the new
+	// commit is recorded only once every file is ingested
+	source.UpdatedAt = now
+	if err := h.postgresRepo.SaveGitSource(ctx, source); err != nil {
+		h.logIngestion(c, "git", req.Repository, "failed", 0, err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save git source: %s", err)})
+		return
+	}
+
+	var ingested, updated, skipped, failed int
+	var errors []string
+	results := make([]gitFileResult, 0, len(candidates))
+	for _, filePath := range candidates {
+		result := h.ingestGitFile(c, checkout, source, commit, filePath)
+		switch result.Status {
+		case "ingested":
+			ingested++
+		case "updated":
+			updated++
+		case "failed":
+			failed++
+			errors = append(errors, fmt.Sprintf("%s: %s", filePath, result.Error))
+		default:
+			skipped++
+		}
+		results = append(results, result)
+	}
+
+	status := "success"
+	if failed > 0 {
+		status = "partial"
+	} else {
+		source.LastCommit = commit
+		source.UpdatedAt = time.Now()
+		if err := h.postgresRepo.SaveGitSource(ctx, source); err != nil {
+			status = "partial"
+			errors = append(errors, fmt.Sprintf("failed to record commit %s: %s", commit, err))
+		}
+	}
+	h.logIngestion(c, "git", req.Repository, status, ingested+updated, strings.Join(errors, "; "))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Repository ingestion complete",
+		"source_id":       source.ID,
+		"commit":          commit,
+		"previous_commit": previousCommit,
+		"incremental":     incremental,
+		"ingested":        ingested,
+		"updated":         updated,
+		"skipped":         skipped,
+		"failed":          failed,
+		"removed":         removed,
+		"files":           results,
+		"errors":          errors,
+	})
+}
+
+// ingestGitFile ingests one spec file of a commit and records the commit
+// on its API specification
+func (h *IngestionHandler) ingestGitFile(c *gin.Context, checkout *adapters.GitCheckout, source *entities.GitSource, commit, filePath string) gitFileResult {
+	result := gitFileResult{Path: filePath}
+	fail := func(err error) gitFileResult {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return result
+	}
+
+	data, err := checkout.ReadFile(c.Request.Context(), commit, filePath)
+	if err != nil {
+		return fail(err)
+	}
+	config, contentHash, err := h.parseSpecData(filePath, data)
+	if err != nil {
+		return fail(err)
+	}
+	// Repositories hold other YAML and JSON files too
+	if config.Name == "" || len(config.Endpoints) == 0 {
+		result.Status = "not_a_spec"
+		return result
+	}
+
+	existing, _ := h.postgresRepo.GetAPISpecificationByHash(c.Request.Context(), contentHash)
+	if existing != nil {
+		result.Status = "unchanged"
+		result.APIID = &existing.ID
+		return result
+	}
+
+	sourcePath := strings.TrimSuffix(source.Repository, "/") + "/" + filePath
+	var apiID uuid.UUID
+	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(c.Request.Context(), config.Name, config.Version)
+	if existingByName != nil {
+		result.Status = "updated"
+		apiID, err = h.updateExistingSpec(c.Request.Context(), c.Request, existingByName, config, contentHash, "git", sourcePath)
+	} else {
+		result.Status = "ingested"
+		apiID, err = h.processAndStore(c.Request.Context(), c.Request, config, contentHash, "git", sourcePath)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	result.APIID = &apiID
+
+	file := &entities.GitSpecFile{SourceID: source.ID, Path: filePath, CommitSHA: commit}
+	if err := h.postgresRepo.SaveGitSpecFile(c.Request.Context(), apiID, file); err != nil {
+		return fail(err)
+	}
+	return result
+}
+
+// gitSpecFile reports whether a repository file is a spec file to ingest:
+// YAML or JSON, and matching the glob if one is given
+func gitSpecFile(glob, filePath string) bool {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return false
+	}
+	return glob == "" || adapters.MatchGlob(glob, filePath)
+}
+
+// parseSpecData parses a spec file that is not uploaded to an endpoint of
+// its own: an OpenAPI document, a Postman collection or an API configuration
+func (h *IngestionHandler) parseSpecData(filePath string, data []byte) (*entities.APIConfig, string, error) {
+	if adapters.IsOpenAPIDocument(data) {
+		return h.openapiParser.ParseSpecData(data)
+	}
+	if strings.EqualFold(path.Ext(filePath), ".json") && adapters.IsPostmanCollection(data) {
+		return h.postmanParser.ParseCollectionData(data)
+	}
+	return h.fileParser.ParseFileData(filePath, data)
+}
+
+// Bounds of URL source refreshes
+const (
+	minURLRefreshIntervalSeconds = 60
+	urlRefreshPollInterval       = 30 * time.Second
+	urlRefreshBatchSize          = 10
+)
+
+// IngestURL handles ingestion of an OpenAPI document, Postman collection or
+// API configuration served at a URL, fetched with optional headers such as
+// an Authorization header. With a refresh interval the document is
+// re-fetched periodically and re-ingested only when its content changes.
+func (h *IngestionHandler) IngestURL(c *gin.Context) {
+	var req struct {
+		URL                    string            `json:"url" binding:"required"`
+		Headers                map[string]string `json:"headers"`
+		RefreshIntervalSeconds int               `json:"refresh_interval_seconds"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url is required"})
+		return
+	}
+	if err := adapters.ValidateSpecURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.RefreshIntervalSeconds != 0 && req.RefreshIntervalSeconds < minURLRefreshIntervalSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("refresh_interval_seconds must be 0 or at least %d", minURLRefreshIntervalSeconds),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	source, _ := h.postgresRepo.GetURLSource(ctx, req.URL)
+	created := source == nil
+	if created {
+		source = &entities.URLSource{ID: uuid.New(), URL: req.URL, CreatedAt: time.Now()}
+	}
+	source.Headers = req.Headers
+	source.RefreshIntervalSeconds = req.RefreshIntervalSeconds
+
+	status, err := h.ingestURLSource(ctx, c.Request, source)
+	// A URL that never ingested is not kept, so it is not refreshed either
+	if err != nil && created {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to ingest URL: %s", err)})
+		return
+	}
+	if saveErr := h.postgresRepo.SaveURLSource(ctx, source); saveErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to save URL source: %s", saveErr)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to ingest URL: %s", err)})
+		return
+	}
+
+	source.HeaderNames = headerNames(source.Headers)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "URL ingestion complete",
+		"status":  status,
+		"api_id":  source.APISpecID,
+		"source":  source,
+	})
+}
+
+// ingestURLSource fetches a URL source and ingests its document unless its
+// content hash is unchanged, so unchanged documents are not re-embedded.
+// The source records the outcome but is not saved; failures are logged.
+// r is the request that triggered the ingestion, nil for refreshes.
+func (h *IngestionHandler) ingestURLSource(ctx context.Context, r *http.Request, source *entities.URLSource) (string, error) {
+	now := time.Now()
+	source.LastFetchedAt = &now
+	source.UpdatedAt = now
+	source.NextRefreshAt = nil
+	if source.RefreshIntervalSeconds > 0 {
+		next := now.Add(time.Duration(source.RefreshIntervalSeconds) * time.Second)
+		source.NextRefreshAt = &next
+	}
+
+	status, err := h.ingestURLDocument(ctx, r, source)
+	if err != nil {
+		source.LastError = err.Error()
+		h.saveIngestionLog(ctx, "url", source.URL, "failed", 0, err.Error())
+		return "failed", err
+	}
+	source.LastError = ""
+	if status != "unchanged" {
+		h.saveIngestionLog(ctx, "url", source.URL, "success", 1, "")
+	}
+	return status, nil
+}
+
+// ingestURLDocument fetches, parses and stores the document of a URL
+// source, returning whether it was ingested, updated or unchanged
+func (h *IngestionHandler) ingestURLDocument(ctx context.Context, r *http.Request, source *entities.URLSource) (string, error) {
+	data, contentType, err := h.urlFetcher.Fetch(ctx, source.URL, source.Headers)
+	if err != nil {
+		return "", err
+	}
+	config, contentHash, err := h.parseSpecData(urlSpecFileName(source.URL, contentType, data), data)
+	if err != nil {
+		return "", err
+	}
+	if config.Name == "" || len(config.Endpoints) == 0 {
+		return "", fmt.Errorf("document at %s is not an API specification", source.URL)
+	}
+
+	if contentHash == source.ContentHash && source.APISpecID != nil {
+		return "unchanged", nil
+	}
+	source.ContentHash = contentHash
+
+	existing, _ := h.postgresRepo.GetAPISpecificationByHash(ctx, contentHash)
+	if existing != nil {
+		source.APISpecID = &existing.ID
+		return "unchanged", nil
+	}
+
+	status := "ingested"
+	var apiID uuid.UUID
+	existingByName, _ := h.postgresRepo.GetAPISpecificationByNameVersion(ctx, config.Name, config.Version)
+	if existingByName != nil {
+		status = "updated"
+		apiID, err = h.updateExistingSpec(ctx, r, existingByName, config, contentHash, "url", source.URL)
+	} else {
+		apiID, err = h.processAndStore(ctx, r, config, contentHash, "url", source.URL)
+	}
+	if err != nil {
+		source.ContentHash = ""
+		return "", err
+	}
+	source.APISpecID = &apiID
+	return status, nil
+}
+
+// RunURLRefresh re-ingests the URL sources whose refresh interval has
+// passed, until ctx is done
+func (h *IngestionHandler) RunURLRefresh(ctx context.Context) {
+	ticker := time.NewTicker(urlRefreshPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.refreshURLSources(ctx)
+		}
+	}
+}
+
+// refreshURLSources re-ingests the URL sources that are due, a batch at a time
+func (h *IngestionHandler) refreshURLSources(ctx context.Context) {
+	for {
+		sources, err := h.postgresRepo.ClaimDueURLSources(ctx, time.Now(), urlRefreshBatchSize)
+		if err != nil {
+			logger.Err(err).Msg("Failed to claim URL sources for refresh")
+			if len(sources) == 0 {
+				return
+			}
+		}
+
+		for i := range sources {
+			source := &sources[i]
+			status, err := h.ingestURLSource(ctx, nil, source)
+			if err != nil {
+				logger.Err(err).Str("url", source.URL).Msg("URL source refresh failed")
+			} else if status != "unchanged" {
+				logger.Infof("Refreshed %s (%s)", source.URL, status)
+			}
+			if err := h.postgresRepo.SaveURLSource(ctx, source); err != nil {
+				logger.Err(err).Str("url", source.URL).Msg("Failed to save URL source")
+			}
+		}
+
+		if len(sources) < urlRefreshBatchSize {
+			return
+		}
+	}
+}
+
+// urlSpecFileName returns a file name for the document at a URL whose
+// extension tells its format: that of the URL path, else JSON when the
+// content type or the content says so, else YAML
+func urlSpecFileName(rawURL, contentType string, data []byte) string {
+	name := path.Base(rawURL)
+	if parsed, err := url.Parse(rawURL); err == nil {
+		name = path.Base(parsed.Path)
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return name
+	}
+	if strings.Contains(contentType, "json") || strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return name + ".json"
+	}
+	return name + ".yaml"
+}
+
+// headerNames returns the sorted names of headers, whose values stay hidden
+func headerNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetStatus returns ingestion status and logs
 func (h *IngestionHandler) GetStatus(c *gin.Context) {
 	logs, err := h.postgresRepo.GetIngestionLogs(c.Request.Context(), 10)
@@ -291,6 +954,9 @@ func (h *IngestionHandler) DeleteAPI(c *gin.Context) {
 		return
 	}
 
+	// Capture the current record for the audit log
+	before, _ := h.postgresRepo.GetAPISpecificationByID(c.Request.Context(), id)
+
 	// Delete from Qdrant first
 	if err := h.qdrantAdapter.Delete(id); err != nil {
 		// Log but continue - Qdrant vector may not exist
@@ -304,14 +970,150 @@ func (h *IngestionHandler) DeleteAPI(c *gin.Context) {
 	}
 
 	h.logIngestion(c, "delete", idStr, "success", 1, "")
+	event := audit.FromRequest(c.Request, "api_spec.delete", "api_spec", idStr)
+	if before != nil {
+		event.Before = before
+	}
+	h.recordAudit(c.Request.Context(), event)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "API deleted successfully",
 		"id":      idStr,
 	})
 }
 
-// processAndStore generates embeddings and stores the API config
-func (h *IngestionHandler) processAndStore(c *gin.Context, config *entities.APIConfig, contentHash, sourceType, sourcePath string) (uuid.UUID, error) {
+// ListRevisions returns the revisions of an API specification, latest first
+func (h *IngestionHandler) ListRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	if _, err := h.postgresRepo.GetAPISpecificationByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+			return
+		}
+		logger.WithRequestID(c.GetHeader("X-Request-ID")).Err(err).Msg("Failed to get API specification")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API specification"})
+		return
+	}
+
+	revisions, err := h.postgresRepo.ListAPISpecRevisions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_id":    id,
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// GetRevision returns a revision of an API specification with its configuration
+func (h *IngestionHandler) GetRevision(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	rev, err := h.postgresRepo.GetAPISpecRevision(c.Request.Context(), id, revision)
+	if err != nil {
+		logger.WithRequestID(c.GetHeader("X-Request-ID")).Err(err).Msg("Failed to get revision")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision"})
+		return
+	}
+	if rev == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// DiffRevisions compares two revisions of an API specification and
+// classifies each change as breaking or not. The from and to query
+// parameters default to the revision before the latest and the latest.
+func (h *IngestionHandler) DiffRevisions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	revisions, err := h.postgresRepo.ListAPISpecRevisions(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list revisions"})
+		return
+	}
+	if len(revisions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API has no revisions"})
+		return
+	}
+
+	to := revisions[0].Revision
+	if s := c.Query("to"); s != "" {
+		if to, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a revision number"})
+			return
+		}
+	}
+	from := to - 1
+	if s := c.Query("from"); s != "" {
+		if from, err = strconv.Atoi(s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a revision number"})
+			return
+		}
+	}
+	if from < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No earlier revision to compare with"})
+		return
+	}
+
+	fromRev, err := h.postgresRepo.GetAPISpecRevision(ctx, id, from)
+	if err != nil {
+		logger.WithRequestID(c.GetHeader("X-Request-ID")).Err(err).Msg("Failed to get revision")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision"})
+		return
+	}
+	if fromRev == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %d not found", from)})
+		return
+	}
+	toRev, err := h.postgresRepo.GetAPISpecRevision(ctx, id, to)
+	if err != nil {
+		logger.WithRequestID(c.GetHeader("X-Request-ID")).Err(err).Msg("Failed to get revision")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision"})
+		return
+	}
+	if toRev == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Revision %d not found", to)})
+		return
+	}
+
+	report := specdiff.Compare(fromRev.Config, toRev.Config)
+	c.JSON(http.StatusOK, gin.H{
+		"api_id":             id,
+		"from":               from,
+		"to":                 to,
+		"breaking":           report.Breaking,
+		"breaking_count":     report.BreakingCount,
+		"non_breaking_count": report.NonBreakingCount,
+		"changes":            report.Changes,
+	})
+}
+
+// processAndStore generates embeddings and stores the API config. r is the
+// request that triggered it, nil for scheduled refreshes.
+func (h *IngestionHandler) processAndStore(ctx context.Context, r *http.Request, config *entities.APIConfig, contentHash, sourceType, sourcePath string) (uuid.UUID, error) {
 	apiID := uuid.New()
 	now := time.Now()
 
@@ -359,9 +1161,18 @@ func (h *IngestionHandler) processAndStore(c *gin.Context, config *entities.APIC
 		UpdatedAt: now,
 	}
 
-	if err := h.postgresRepo.SaveAPISpecification(c.Request.Context(), spec); err != nil {
+	if err := h.postgresRepo.SaveAPISpecification(ctx, spec); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to save to database: %w", err)
 	}
+	revision, err := h.saveRevision(ctx, apiID, config, contentHash, sourceType, sourcePath)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	event := auditEvent(r, "api_spec.create", "api_spec", apiID.String())
+	event.After = spec
+	event.Metadata = map[string]interface{}{"revision": revision}
+	h.recordAudit(ctx, event)
 
 	return apiID, nil
 }
@@ -372,6 +1183,22 @@ func (h *IngestionHandler) generateEmbeddingText(config *entities.APIConfig) str
 		config.Name, config.Version, config.Description)
 
 	for _, ep := range config.Endpoints {
+		// GraphQL operations share one path; what tells them apart is the
+		// operation, its arguments and what it returns
+		if op := ep.GraphQL; op != nil {
+			text += fmt.Sprintf("- GraphQL %s %s: %s (returns %s)\n", op.Type, op.Field, ep.Description, op.ReturnType)
+			for _, arg := range op.Arguments {
+				text += fmt.Sprintf("  Argument: %s (%s) - %s\n", arg.Name, arg.Type, arg.Description)
+			}
+			continue
+		}
+		if method := ep.GRPC; method != nil {
+			text += fmt.Sprintf("- gRPC %s: %s (%s -> %s)\n", method.FullMethod, ep.Description, method.InputType, method.OutputType)
+			for _, p := range ep.Parameters {
+				text += fmt.Sprintf("  Field: %s (%s) - %s\n", p.Name, p.Type, p.Description)
+			}
+			continue
+		}
 		text += fmt.Sprintf("- %s %s: %s\n", ep.Method, ep.Path, ep.Description)
 		for _, p := range ep.Parameters {
 			text += fmt.Sprintf("  Parameter: %s (%s) - %s\n", p.Name, p.Type, p.Description)
@@ -381,9 +1208,20 @@ func (h *IngestionHandler) generateEmbeddingText(config *entities.APIConfig) str
 	return text
 }
 
-// updateExistingSpec updates an existing API specification with new content
-func (h *IngestionHandler) updateExistingSpec(c *gin.Context, existing *entities.APISpecification, config *entities.APIConfig, contentHash, sourceType, sourcePath string) (uuid.UUID, error) {
+// updateExistingSpec updates an existing API specification with new content,
+// which is kept as a new revision. r is the request that triggered it, nil
+// for scheduled refreshes.
+func (h *IngestionHandler) updateExistingSpec(ctx context.Context, r *http.Request, existing *entities.APISpecification, config *entities.APIConfig, contentHash, sourceType, sourcePath string) (uuid.UUID, error) {
 	now := time.Now()
+	before := *existing
+
+	// Specs ingested before revisions existed keep their current content as
+	// revision 1. Qdrant holds the only copy of it, so read it before the
+	// vector is replaced.
+	previous, err := h.previousRevision(ctx, existing)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
 	// Delete old Qdrant vector
 	if err := h.qdrantAdapter.Delete(existing.ID); err != nil {
@@ -428,16 +1266,90 @@ func (h *IngestionHandler) updateExistingSpec(c *gin.Context, existing *entities
 		"endpoints":   len(config.Endpoints),
 	}
 
-	if err := h.postgresRepo.UpdateAPISpecification(c.Request.Context(), existing); err != nil {
+	rev := newRevision(existing.ID, config, contentHash, sourceType, sourcePath, now)
+	if err := h.postgresRepo.UpdateAPISpecificationWithRevision(ctx, existing, rev, previous); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to update database: %w", err)
 	}
 
+	event := auditEvent(r, "api_spec.update", "api_spec", existing.ID.String())
+	event.Before = before
+	event.After = existing
+	event.Metadata = map[string]interface{}{"revision": rev.Revision}
+	h.recordAudit(ctx, event)
+
 	return existing.ID, nil
 }
 
+// saveRevision records the content an API specification was ingested with
+// as its next revision, and returns the revision's number
+func (h *IngestionHandler) saveRevision(ctx context.Context, apiID uuid.UUID, config *entities.APIConfig, contentHash, sourceType, sourcePath string) (int, error) {
+	rev := newRevision(apiID, config, contentHash, sourceType, sourcePath, time.Now())
+	if err := h.postgresRepo.SaveAPISpecRevision(ctx, rev); err != nil {
+		return 0, err
+	}
+	return rev.Revision, nil
+}
+
+// previousRevision returns the content an API specification has now as a
+// revision, when it has no revisions yet, or nil otherwise
+func (h *IngestionHandler) previousRevision(ctx context.Context, spec *entities.APISpecification) (*entities.APISpecRevision, error) {
+	hasRevisions, err := h.postgresRepo.HasAPISpecRevisions(ctx, spec.ID)
+	if err != nil || hasRevisions {
+		return nil, err
+	}
+	config, err := h.qdrantAdapter.GetConfig(spec.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current config: %w", err)
+	}
+	if config == nil {
+		return nil, nil
+	}
+	return newRevision(spec.ID, config, spec.ContentHash, spec.SourceType, spec.SourcePath, spec.UpdatedAt), nil
+}
+
+func newRevision(apiID uuid.UUID, config *entities.APIConfig, contentHash, sourceType, sourcePath string, createdAt time.Time) *entities.APISpecRevision {
+	return &entities.APISpecRevision{
+		ID:          uuid.New(),
+		APISpecID:   apiID,
+		ContentHash: contentHash,
+		SourceType:  sourceType,
+		SourcePath:  sourcePath,
+		Config:      config,
+		CreatedAt:   createdAt,
+	}
+}
+
 // logIngestion logs an ingestion operation
 func (h *IngestionHandler) logIngestion(c *gin.Context, sourceType, sourcePath, status string, apisIngested int, errorMessage string) {
+	h.saveIngestionLog(c.Request.Context(), sourceType, sourcePath, status, apisIngested, errorMessage)
+}
+
+// saveIngestionLog logs an ingestion operation, including those no request
+// triggered, like scheduled refreshes
+func (h *IngestionHandler) saveIngestionLog(ctx context.Context, sourceType, sourcePath, status string, apisIngested int, errorMessage string) {
 	result := adapters.NewIngestionResult(sourceType, sourcePath, status, apisIngested, errorMessage)
-	_ = h.postgresRepo.SaveIngestionLog(c.Request.Context(), result)
+	_ = h.postgresRepo.SaveIngestionLog(ctx, result)
 }
 
+// auditEvent builds an audit event for the request that triggered an
+// action, or for the service itself when r is nil
+func auditEvent(r *http.Request, action, targetType, targetID string) audit.Event {
+	if r == nil {
+		return audit.Event{
+			Action:     action,
+			TargetType: targetType,
+			TargetID:   targetID,
+			ActorRole:  "system",
+		}
+	}
+	return audit.FromRequest(r, action, targetType, targetID)
+}
+
+// recordAudit writes an audit entry, logging rather than failing the request on error
+func (h *IngestionHandler) recordAudit(ctx context.Context, event audit.Event) {
+	if err := h.auditRecorder.Record(ctx, event); err != nil {
+		logger.WithRequestID(event.RequestID).Err(err).
+			Str("action", event.Action).
+			Msg("Failed to write audit entry")
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/ingestion/adapters"
+	"github.com/testpilot-ai/ingestion/domain/entities"
+	"github.com/testpilot-ai/ingestion/mock"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// mockConfigTTL controls how long a mocked API's config is cached, so
+// re-ingested specs are picked up without a restart
+const mockConfigTTL = 30 * time.Second
+
+// MockHandler serves ingested APIs as mock servers
+type MockHandler struct {
+	qdrantAdapter *adapters.QdrantAdapter
+	server        *mock.Server
+	baseURL       string
+
+	mu      sync.Mutex
+	configs map[uuid.UUID]*cachedConfig
+}
+
+type cachedConfig struct {
+	config *entities.APIConfig
+	loaded time.Time
+}
+
+// NewMockHandler creates a mock handler. baseURL is where other services
+// reach the mocks, e.g. http://ingestion:8001/mock.
+func NewMockHandler(qdrantAdapter *adapters.QdrantAdapter, server *mock.Server, baseURL string) *MockHandler {
+	return &MockHandler{
+		qdrantAdapter: qdrantAdapter,
+		server:        server,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		configs:       make(map[uuid.UUID]*cachedConfig),
+	}
+}
+
+// Serve answers a request to a mocked API: ANY /mock/:api_id/*path
+func (h *MockHandler) Serve(c *gin.Context) {
+	apiID, err := uuid.Parse(c.Param("api_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	config, ok := h.config(c, apiID)
+	if !ok {
+		return
+	}
+
+	req, err := mock.NewRequest(c.Request, c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp := h.server.Handle(apiID.String(), config, req)
+
+	logger.Logger().Debug().
+		Str("api_id", apiID.String()).
+		Str("method", req.Method).
+		Str("path", req.Path).
+		Str("endpoint", resp.Endpoint).
+		Str("source", resp.Source).
+		Int("status_code", resp.StatusCode).
+		Msg("Mock request served")
+
+	if resp.Endpoint != "" {
+		c.Header("X-Mock-Endpoint", resp.Endpoint)
+	}
+	c.Header("X-Mock-Source", resp.Source)
+	if len(resp.Allow) > 0 {
+		c.Header("Allow", strings.Join(resp.Allow, ", "))
+	}
+	if resp.Body == nil || c.Request.Method == http.MethodHead {
+		c.Status(resp.StatusCode)
+		return
+	}
+	c.JSON(resp.StatusCode, resp.Body)
+}
+
+// Describe returns the base URL and endpoints of an API's mock
+func (h *MockHandler) Describe(c *gin.Context) {
+	apiID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	config, ok := h.config(c, apiID)
+	if !ok {
+		return
+	}
+
+	endpoints := make([]gin.H, 0, len(config.Endpoints))
+	for _, endpoint := range config.Endpoints {
+		endpoints = append(endpoints, gin.H{
+			"name":     endpoint.Name,
+			"method":   strings.ToUpper(endpoint.Method),
+			"path":     endpoint.Path,
+			"examples": len(endpoint.Examples),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"api_id":    apiID,
+		"name":      config.Name,
+		"base_url":  h.baseURL + "/" + apiID.String(),
+		"endpoints": endpoints,
+	})
+}
+
+// GetState returns the resources created through an API's mock, for one
+// scenario (?scenario=) or all of them
+func (h *MockHandler) GetState(c *gin.Context) {
+	apiID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"api_id":    apiID,
+		"scenarios": h.server.State(apiID.String(), c.Query("scenario")),
+	})
+}
+
+// ResetState forgets the resources created through an API's mock, for one
+// scenario (?scenario=) or all of them
+func (h *MockHandler) ResetState(c *gin.Context) {
+	apiID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API ID format"})
+		return
+	}
+	dropped := h.server.Reset(apiID.String(), c.Query("scenario"))
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Mock state reset",
+		"scenarios_dropped": dropped,
+	})
+}
+
+// config returns an API's config, writing an error response if it cannot
+func (h *MockHandler) config(c *gin.Context, apiID uuid.UUID) (*entities.APIConfig, bool) {
+	h.mu.Lock()
+	cached, ok := h.configs[apiID]
+	h.mu.Unlock()
+	if ok && time.Since(cached.loaded) < mockConfigTTL {
+		return cached.config, true
+	}
+
+	config, err := h.qdrantAdapter.GetConfig(apiID)
+	if err != nil {
+		logger.Err(err).Str("api_id", apiID.String()).Msg("Failed to load API config for mock")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API config"})
+		return nil, false
+	}
+	if config == nil {
+		h.mu.Lock()
+		delete(h.configs, apiID)
+		h.mu.Unlock()
+		c.JSON(http.StatusNotFound, gin.H{"error": "API not found"})
+		return nil, false
+	}
+
+	h.mu.Lock()
+	h.configs[apiID] = &cachedConfig{config: config, loaded: time.Now()}
+	h.mu.Unlock()
+	return config, true
+}
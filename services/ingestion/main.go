@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -10,6 +11,10 @@ import (
 	"github.com/testpilot-ai/ingestion/adapters"
 	"github.com/testpilot-ai/ingestion/config"
 	"github.com/testpilot-ai/ingestion/handlers"
+	"github.com/testpilot-ai/ingestion/mock"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/crypto"
+	"github.com/testpilot-ai/shared/egress"
 	"github.com/testpilot-ai/shared/logger"
 )
 
@@ -43,12 +48,28 @@ func main() {
 	}
 	logger.Info("Connected to PostgreSQL")
 
+	keyring, err := loadKeyring(cfg)
+	if err != nil {
+		logger.Err(err).Msg("Invalid secrets master key configuration")
+		os.Exit(1)
+	}
+	allowedCIDRs, err := egress.ParseCIDRs(splitList(cfg.EgressAllowedCIDRs))
+	if err != nil {
+		logger.Err(err).Msg("Invalid EGRESS_ALLOWED_CIDRS")
+		os.Exit(1)
+	}
+
 	// Initialize adapters
 	fileParser := adapters.NewFileParser()
 	postmanParser := adapters.NewPostmanParser()
+	graphqlParser := adapters.NewGraphQLParser()
+	grpcParser := adapters.NewGRPCParser()
+	gitClient := adapters.NewGitClient(cfg.GitWorkDir, splitList(cfg.GitLocalRoots), allowedCIDRs)
+	openapiParser := adapters.NewOpenAPIParser()
+	urlFetcher := adapters.NewURLFetcher(adapters.DefaultURLFetchTimeout, adapters.DefaultURLFetchMaxBytes, allowedCIDRs)
 	embeddingService := adapters.NewEmbeddingService(cfg.GeminiAPIKey)
 	qdrantAdapter := adapters.NewQdrantAdapter(cfg.QdrantURL(), "api-knowledge")
-	postgresRepo := adapters.NewPostgresRepository(pool)
+	postgresRepo := adapters.NewPostgresRepository(pool, keyring)
 
 	// Ensure Qdrant collection exists (768 dimensions for Gemini embeddings)
 	if err := qdrantAdapter.EnsureCollection(768); err != nil {
@@ -65,11 +86,22 @@ func main() {
 	ingestionHandler := handlers.NewIngestionHandler(
 		fileParser,
 		postmanParser,
+		graphqlParser,
+		grpcParser,
+		gitClient,
+		openapiParser,
+		urlFetcher,
 		embeddingService,
 		qdrantAdapter,
 		postgresRepo,
+		audit.NewRecorder(pool, "ingestion"),
 	)
 
+	// Re-ingest URL sources whose refresh interval has passed
+	go ingestionHandler.RunURLRefresh(context.Background())
+
+	mockHandler := handlers.NewMockHandler(qdrantAdapter, mock.NewServer(), cfg.MockBaseURL)
+
 	// Setup router (use gin.New() to avoid default logger noise)
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -86,14 +118,31 @@ func main() {
 			ingest.POST("/file", ingestionHandler.IngestFile)
 			ingest.POST("/folder", ingestionHandler.IngestFolder)
 			ingest.POST("/postman", ingestionHandler.IngestPostman)
+			ingest.POST("/graphql", ingestionHandler.IngestGraphQL)
+			ingest.POST("/grpc", ingestionHandler.IngestGRPC)
+			ingest.POST("/git", ingestionHandler.IngestGit)
+			ingest.POST("/url", ingestionHandler.IngestURL)
 		}
 
 		// Status and listing
 		api.GET("/ingest/status", ingestionHandler.GetStatus)
 		api.GET("/apis", ingestionHandler.ListAPIs)
 		api.DELETE("/apis/:id", ingestionHandler.DeleteAPI)
+
+		// Spec revisions and the changes between them
+		api.GET("/apis/:id/revisions", ingestionHandler.ListRevisions)
+		api.GET("/apis/:id/revisions/:revision", ingestionHandler.GetRevision)
+		api.GET("/apis/:id/diff", ingestionHandler.DiffRevisions)
+
+		// Mock servers of ingested APIs
+		api.GET("/apis/:id/mock", mockHandler.Describe)
+		api.GET("/apis/:id/mock/state", mockHandler.GetState)
+		api.DELETE("/apis/:id/mock/state", mockHandler.ResetState)
 	}
 
+	// Mocked APIs, served outside /api/v1 so their paths stay intact
+	router.Any("/mock/:api_id/*path", mockHandler.Serve)
+
 	// Start server
 	port := cfg.ServerPort
 	if port == "" {
@@ -106,3 +155,22 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func loadKeyring(cfg *config.Config) (*crypto.Keyring, error) {
+	if cfg.SecretsMasterKeys == "" {
+		// Default for development only - should be set in production
+		logger.Warn("SECRETS_MASTER_KEYS not set - using development key for URL source headers")
+		return crypto.DevKeyring(), nil
+	}
+	return crypto.ParseKeyring(cfg.SecretsMasterKeys, cfg.SecretsActiveKeyID)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
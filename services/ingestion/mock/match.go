@@ -0,0 +1,131 @@
+package mock
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+// route is a matched endpoint with the values of its path parameters
+type route struct {
+	endpoint *entities.APIEndpoint
+	params   map[string]string
+	// resourceParam is the parameter naming the resource when the path ends
+	// with one, e.g. payment_id in /payments/{payment_id}
+	resourceParam string
+}
+
+// matchRoute finds the endpoint serving method and path. When the path
+// matches endpoints with other methods only, their methods are returned so
+// the caller can answer 405. Paths are tried as given and, when the spec's
+// base URL has a path of its own (e.g. /mastercard), without it.
+func matchRoute(config *entities.APIConfig, method, path string) (*route, []string) {
+	candidates := []string{path}
+	if base, err := url.Parse(config.BaseURL); err == nil {
+		prefix := strings.TrimSuffix(base.Path, "/")
+		if prefix != "" && strings.HasPrefix(path, prefix+"/") {
+			candidates = append(candidates, strings.TrimPrefix(path, prefix))
+		}
+	}
+
+	var best *route
+	bestLiterals := -1
+	allowed := map[string]bool{}
+	for _, candidate := range candidates {
+		segments := splitPath(candidate)
+		for i := range config.Endpoints {
+			endpoint := &config.Endpoints[i]
+			params, literals, ok := matchTemplate(endpoint.Path, segments)
+			if !ok {
+				continue
+			}
+			if !strings.EqualFold(endpoint.Method, method) {
+				allowed[strings.ToUpper(endpoint.Method)] = true
+				continue
+			}
+			// Prefer the most specific template: /payments/search over /payments/{id}
+			if literals > bestLiterals {
+				best = &route{endpoint: endpoint, params: params, resourceParam: resourceParam(endpoint.Path)}
+				bestLiterals = literals
+			}
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return nil, methods
+}
+
+// matchTemplate matches path segments against a template such as
+// /authorizations/{payment_id}/paytocard. Parameters may be written as
+// {name}, {{name}} or :name (Postman).
+func matchTemplate(template string, segments []string) (map[string]string, int, bool) {
+	if i := strings.IndexByte(template, '?'); i >= 0 {
+		template = template[:i]
+	}
+	parts := splitPath(template)
+	if len(parts) != len(segments) {
+		return nil, 0, false
+	}
+
+	params := make(map[string]string)
+	literals := 0
+	for i, part := range parts {
+		if name, ok := paramName(part); ok {
+			if segments[i] == "" {
+				return nil, 0, false
+			}
+			params[name] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+// resourceParam returns the parameter ending a template, if any
+func resourceParam(template string) string {
+	parts := splitPath(template)
+	if len(parts) == 0 {
+		return ""
+	}
+	name, _ := paramName(parts[len(parts)-1])
+	return name
+}
+
+func paramName(segment string) (string, bool) {
+	switch {
+	case strings.HasPrefix(segment, "{{") && strings.HasSuffix(segment, "}}"):
+		return segment[2 : len(segment)-2], true
+	case strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}"):
+		return segment[1 : len(segment)-1], true
+	case strings.HasPrefix(segment, ":") && len(segment) > 1:
+		return segment[1:], true
+	}
+	return "", false
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	return segments
+}
@@ -0,0 +1,133 @@
+package mock
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+func paymentsAPI() *entities.APIConfig {
+	return &entities.APIConfig{
+		Name:    "payments",
+		BaseURL: "https://api.example.com/v1",
+		Endpoints: []entities.APIEndpoint{
+			{Name: "list", Method: "GET", Path: "/payments"},
+			{Name: "create", Method: "POST", Path: "/payments"},
+			{Name: "get", Method: "GET", Path: "/payments/{payment_id}"},
+			{Name: "search", Method: "GET", Path: "/payments/search"},
+			{Name: "delete", Method: "DELETE", Path: "/payments/{payment_id}"},
+			{Name: "refund", Method: "POST", Path: "/payments/{payment_id}/refunds/{refund_id}"},
+			{Name: "capture", Method: "POST", Path: "/authorizations/{{authorization_id}}/capture"},
+			{Name: "user", Method: "get", Path: "/users/:user_id?expand=true"},
+		},
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		want        string
+		wantParams  map[string]string
+		wantAllowed []string
+	}{
+		{name: "literal path", method: "GET", path: "/payments", want: "list", wantParams: map[string]string{}},
+		{name: "method picks the endpoint", method: "POST", path: "/payments", want: "create", wantParams: map[string]string{}},
+		{name: "lower case method", method: "post", path: "/payments", want: "create", wantParams: map[string]string{}},
+		{
+			name: "path parameter", method: "GET", path: "/payments/pay_123",
+			want: "get", wantParams: map[string]string{"payment_id": "pay_123"},
+		},
+		{
+			name: "literal segment wins over a parameter", method: "GET", path: "/payments/search",
+			want: "search", wantParams: map[string]string{},
+		},
+		{
+			name: "escaped parameter value", method: "GET", path: "/payments/pay%2F1",
+			want: "get", wantParams: map[string]string{"payment_id": "pay/1"},
+		},
+		{
+			name: "several parameters", method: "POST", path: "/payments/pay_1/refunds/ref_2",
+			want: "refund", wantParams: map[string]string{"payment_id": "pay_1", "refund_id": "ref_2"},
+		},
+		{
+			name: "double brace parameter", method: "POST", path: "/authorizations/auth_9/capture",
+			want: "capture", wantParams: map[string]string{"authorization_id": "auth_9"},
+		},
+		{
+			name: "colon parameter and query in the template", method: "GET", path: "/users/42",
+			want: "user", wantParams: map[string]string{"user_id": "42"},
+		},
+		{name: "trailing slash", method: "GET", path: "/payments/", want: "list", wantParams: map[string]string{}},
+		{
+			name: "base url path is stripped", method: "GET", path: "/v1/payments/pay_1",
+			want: "get", wantParams: map[string]string{"payment_id": "pay_1"},
+		},
+		{name: "method mismatch", method: "PUT", path: "/payments/pay_1", wantAllowed: []string{"DELETE", "GET"}},
+		{name: "method mismatch on a literal path", method: "DELETE", path: "/payments", wantAllowed: []string{"GET", "POST"}},
+		{name: "unknown path", method: "GET", path: "/invoices"},
+		{name: "too many segments", method: "GET", path: "/payments/pay_1/extra"},
+		{name: "empty parameter", method: "POST", path: "/authorizations//capture"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, allowed := matchRoute(paymentsAPI(), tt.method, tt.path)
+			if tt.want == "" {
+				if matched != nil {
+					t.Fatalf("matched %s, want no match", matched.endpoint.Name)
+				}
+				if !reflect.DeepEqual(allowed, tt.wantAllowed) && len(allowed)+len(tt.wantAllowed) > 0 {
+					t.Errorf("allowed = %v, want %v", allowed, tt.wantAllowed)
+				}
+				return
+			}
+			if matched == nil {
+				t.Fatalf("no match, allowed = %v", allowed)
+			}
+			if matched.endpoint.Name != tt.want {
+				t.Errorf("matched %s, want %s", matched.endpoint.Name, tt.want)
+			}
+			if !reflect.DeepEqual(matched.params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", matched.params, tt.wantParams)
+			}
+		})
+	}
+}
+
+func TestMatchTemplate(t *testing.T) {
+	tests := []struct {
+		template     string
+		path         string
+		wantParams   map[string]string
+		wantLiterals int
+		wantOK       bool
+	}{
+		{"/payments", "/payments", map[string]string{}, 1, true},
+		{"/payments/{id}", "/payments/1", map[string]string{"id": "1"}, 1, true},
+		{"/payments/{{id}}", "/payments/1", map[string]string{"id": "1"}, 1, true},
+		{"/payments/:id", "/payments/1", map[string]string{"id": "1"}, 1, true},
+		{"/{a}/{b}", "/x/y", map[string]string{"a": "x", "b": "y"}, 0, true},
+		{"/payments/{id}?expand=true", "/payments/1", map[string]string{"id": "1"}, 1, true},
+		{"/", "/", map[string]string{}, 0, true},
+		{"/payments/{id}", "/payments", nil, 0, false},
+		{"/payments/{id}", "/invoices/1", nil, 0, false},
+		{"/payments", "/Payments", nil, 0, false},
+		{"/payments/:", "/payments/1", nil, 0, false},
+	}
+	for _, tt := range tests {
+		params, literals, ok := matchTemplate(tt.template, splitPath(tt.path))
+		if ok != tt.wantOK {
+			t.Errorf("matchTemplate(%q, %q) ok = %v, want %v", tt.template, tt.path, ok, tt.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(params, tt.wantParams) || literals != tt.wantLiterals {
+			t.Errorf("matchTemplate(%q, %q) = %v, %d literals; want %v, %d",
+				tt.template, tt.path, params, literals, tt.wantParams, tt.wantLiterals)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// maxGenerateDepth stops generation on deeply nested or self-similar schemas
+const maxGenerateDepth = 10
+
+// isJSONSchema tells a JSON schema apart from a sample body: Postman
+// collections store the example request body as the request schema
+func isJSONSchema(schema map[string]interface{}) bool {
+	if len(schema) == 0 {
+		return false
+	}
+	if t, ok := schema["type"].(string); ok {
+		switch t {
+		case "object", "array", "string", "integer", "number", "boolean", "null":
+			return true
+		}
+	}
+	for _, keyword := range []string{"properties", "items", "allOf", "anyOf", "oneOf", "$ref"} {
+		if _, ok := schema[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBody checks a decoded body against a JSON schema and returns the
+// violations. A schema that does not compile is skipped, as the mock should
+// not fail requests over a spec problem.
+func validateBody(schema map[string]interface{}, body interface{}) ([]string, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request_schema: %w", err)
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate body: %w", err)
+	}
+
+	var violations []string
+	for _, desc := range result.Errors() {
+		violations = append(violations, desc.String())
+	}
+	return violations, nil
+}
+
+// generate builds a value satisfying a schema, preferring the schema's own
+// example, default or first enum value
+func generate(schema map[string]interface{}, name string, depth int) interface{} {
+	if schema == nil || depth > maxGenerateDepth {
+		return nil
+	}
+	for _, keyword := range []string{"example", "default", "const"} {
+		if value, ok := schema[keyword]; ok {
+			return value
+		}
+	}
+	if values, ok := schema["enum"].([]interface{}); ok && len(values) > 0 {
+		return values[0]
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{}
+		for _, sub := range all {
+			if object, ok := generate(asSchema(sub), name, depth+1).(map[string]interface{}); ok {
+				for k, v := range object {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if options, ok := schema[keyword].([]interface{}); ok && len(options) > 0 {
+			return generate(asSchema(options[0]), name, depth+1)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		object := map[string]interface{}{}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for property, sub := range properties {
+			object[property] = generate(asSchema(sub), property, depth+1)
+		}
+		return object
+	case "array":
+		item := generate(asSchema(schema["items"]), name, depth+1)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	case "integer":
+		if minimum, ok := number(schema["minimum"]); ok {
+			return int64(minimum)
+		}
+		return 1
+	case "number":
+		if minimum, ok := number(schema["minimum"]); ok {
+			return minimum
+		}
+		return 1.0
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	}
+	return generateString(schema, name)
+}
+
+func generateString(schema map[string]interface{}, name string) string {
+	now := time.Now().UTC()
+	switch format, _ := schema["format"].(string); format {
+	case "uuid":
+		return uuid.NewString()
+	case "date-time":
+		return now.Format(time.RFC3339)
+	case "date":
+		return now.Format("2006-01-02")
+	case "email":
+		return "user@example.com"
+	case "uri", "url":
+		return "https://example.com"
+	}
+
+	value := "string"
+	if isIdentifier(name) {
+		value = randomID("")
+	}
+	if minLength, ok := number(schema["minLength"]); ok && len(value) < int(minLength) {
+		value += strings.Repeat("x", int(minLength)-len(value))
+	}
+	return value
+}
+
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, candidate := range t {
+			if s, ok := candidate.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return "string"
+}
+
+func asSchema(value interface{}) map[string]interface{} {
+	schema, _ := value.(map[string]interface{})
+	return schema
+}
+
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// isIdentifier reports whether a field name looks like an ID
+func isIdentifier(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "id" || strings.HasSuffix(lower, "_id") || strings.HasSuffix(name, "Id")
+}
+
+const idAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// randomID returns a fresh ID shaped like like: a UUID for a UUID, the same
+// prefix and length for prefixed IDs such as pay_abc123
+func randomID(like string) string {
+	if _, err := uuid.Parse(like); err == nil {
+		return uuid.NewString()
+	}
+	prefix, length := "", 16
+	if i := strings.LastIndexByte(like, '_'); i >= 0 {
+		prefix = like[:i+1]
+		if n := len(like) - len(prefix); n >= 6 {
+			length = n
+		}
+	}
+	id := make([]byte, length)
+	for i := range id {
+		id[i] = idAlphabet[rand.Intn(len(idAlphabet))]
+	}
+	return prefix + string(id)
+}
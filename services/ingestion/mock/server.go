@@ -0,0 +1,457 @@
+// Package mock serves ingested API specs as mock servers: requests are
+// matched to endpoints by method and path template, checked against the
+// request schema and answered from the spec's examples or from data
+// generated from the response schema. Resources created through a mock are
+// remembered per scenario, so a created payment can be fetched afterwards.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// Request headers that steer the mock
+const (
+	// HeaderScenario selects the state a request reads and writes
+	HeaderScenario = "X-Mock-Scenario"
+	// HeaderStatus asks for one of the endpoint's declared status codes
+	HeaderStatus = "X-Mock-Status"
+	// HeaderExample picks an example by name
+	HeaderExample = "X-Mock-Example"
+)
+
+// DefaultScenario is used by requests without a scenario header
+const DefaultScenario = "default"
+
+// maxBodyBytes bounds the request bodies the mock reads
+const maxBodyBytes = 10 << 20
+
+// Where a response body came from, reported in the X-Mock-Source header
+const (
+	SourceExample = "example"
+	SourceSchema  = "schema"
+	SourceState   = "state"
+	SourceMock    = "mock"
+)
+
+// Request is an incoming request to a mock
+type Request struct {
+	Method   string
+	Path     string
+	Query    url.Values
+	Header   http.Header
+	Body     interface{} // decoded JSON or form body, nil when empty
+	Scenario string
+}
+
+// Response is what the mock answers
+type Response struct {
+	StatusCode int
+	Body       interface{} // nil sends no body
+	Endpoint   string
+	Source     string
+	Allow      []string
+}
+
+// NewRequest reads an HTTP request addressed to path within a mock
+func NewRequest(r *http.Request, path string) (*Request, error) {
+	req := &Request{
+		Method:   strings.ToUpper(r.Method),
+		Path:     path,
+		Query:    r.URL.Query(),
+		Header:   r.Header,
+		Scenario: r.Header.Get(HeaderScenario),
+	}
+	if req.Scenario == "" {
+		req.Scenario = DefaultScenario
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		return req, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		form := make(map[string]interface{}, len(values))
+		for key := range values {
+			form[key] = values.Get(key)
+		}
+		req.Body = form
+		return req, nil
+	}
+	if err := json.Unmarshal(raw, &req.Body); err != nil {
+		// Not JSON; keep it as text so schema checks can report it
+		req.Body = string(raw)
+	}
+	return req, nil
+}
+
+// Server answers requests to mocked APIs
+type Server struct {
+	state *stateStore
+}
+
+// NewServer creates a mock server with empty state
+func NewServer() *Server {
+	return &Server{state: newStateStore()}
+}
+
+// Handle answers a request to the API apiID described by config
+func (s *Server) Handle(apiID string, config *entities.APIConfig, req *Request) *Response {
+	matched, allowed := matchRoute(config, req.Method, req.Path)
+	if matched == nil {
+		if len(allowed) > 0 {
+			return &Response{
+				StatusCode: http.StatusMethodNotAllowed,
+				Body:       map[string]interface{}{"error": fmt.Sprintf("%s is not allowed on %s", req.Method, req.Path)},
+				Source:     SourceMock,
+				Allow:      allowed,
+			}
+		}
+		return &Response{
+			StatusCode: http.StatusNotFound,
+			Body:       map[string]interface{}{"error": fmt.Sprintf("no endpoint of %s matches %s %s", config.Name, req.Method, req.Path)},
+			Source:     SourceMock,
+		}
+	}
+	endpoint := matched.endpoint
+	codes := declaredStatusCodes(endpoint)
+
+	if violations := checkRequest(endpoint, req); len(violations) > 0 {
+		status := http.StatusBadRequest
+		if containsCode(codes, http.StatusUnprocessableEntity) {
+			status = http.StatusUnprocessableEntity
+		}
+		return &Response{
+			StatusCode: status,
+			Body: map[string]interface{}{
+				"error":   "request does not match the " + endpoint.Name + " spec",
+				"details": violations,
+			},
+			Endpoint: endpoint.Name,
+			Source:   SourceMock,
+		}
+	}
+
+	// A requested status short-circuits state: it is how tests exercise
+	// the error paths a spec declares
+	if forced := req.Header.Get(HeaderStatus); forced != "" {
+		code, err := strconv.Atoi(forced)
+		if err != nil || !containsCode(codes, code) {
+			return &Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       map[string]interface{}{"error": fmt.Sprintf("%s %q is not a status code declared by %s", HeaderStatus, forced, endpoint.Name)},
+				Endpoint:   endpoint.Name,
+				Source:     SourceMock,
+			}
+		}
+		if code < 200 || code >= 300 {
+			return &Response{
+				StatusCode: code,
+				Body:       map[string]interface{}{"error": statusDescription(endpoint, code)},
+				Endpoint:   endpoint.Name,
+				Source:     SourceMock,
+			}
+		}
+		return s.respond(apiID, matched, req, code)
+	}
+
+	return s.respond(apiID, matched, req, successCode(codes))
+}
+
+// respond builds a success response and applies it to the scenario state
+func (s *Server) respond(apiID string, matched *route, req *Request, status int) *Response {
+	endpoint := matched.endpoint
+	key := scenarioKey{apiID: apiID, scenario: req.Scenario}
+	resp := &Response{StatusCode: status, Endpoint: endpoint.Name}
+
+	// Requests addressing a resource, e.g. GET /payments/{payment_id}
+	var resourceID string
+	var stored map[string]interface{}
+	if matched.resourceParam != "" {
+		resourceID = matched.params[matched.resourceParam]
+		var deleted bool
+		stored, deleted = s.state.lookup(key, resourceID)
+		if deleted && req.Method != http.MethodPut {
+			resp.StatusCode = http.StatusNotFound
+			resp.Body = map[string]interface{}{"error": fmt.Sprintf("%s %s was deleted", matched.resourceParam, resourceID)}
+			resp.Source = SourceState
+			return resp
+		}
+	}
+
+	if stored != nil {
+		resp.Source = SourceState
+		switch req.Method {
+		case http.MethodDelete:
+			s.state.remove(key, resourceID)
+			resp.Body = body(status, stored)
+			return resp
+		case http.MethodPut, http.MethodPatch, http.MethodPost:
+			if update, ok := req.Body.(map[string]interface{}); ok {
+				for field, value := range update {
+					stored[field] = value
+				}
+				s.store(key, resourceID, stored)
+			}
+		}
+		resp.Body = body(status, stored)
+		return resp
+	}
+
+	generated, source := responseBody(endpoint, matched.params, req)
+	resp.Source = source
+	object, isObject := generated.(map[string]interface{})
+	if isObject {
+		echo(object, matched, req)
+	}
+
+	switch {
+	case req.Method == http.MethodDelete && resourceID != "":
+		s.state.remove(key, resourceID)
+	case (req.Method == http.MethodPut || req.Method == http.MethodPost) && resourceID != "" && isObject:
+		// Writes to a resource path create the resource at that path
+		s.store(key, resourceID, copyValue(object).(map[string]interface{}))
+	case req.Method == http.MethodPost && isObject:
+		// POST to a collection creates a resource when the response has an ID
+		if id, ok := object["id"].(string); ok {
+			object["id"] = randomID(id)
+			s.store(key, object["id"].(string), copyValue(object).(map[string]interface{}))
+		}
+	}
+	resp.Body = body(status, generated)
+	return resp
+}
+
+func (s *Server) store(key scenarioKey, id string, resource map[string]interface{}) {
+	if !s.state.store(key, id, resource) {
+		logger.Logger().Warn().
+			Str("api_id", key.apiID).
+			Str("scenario", key.scenario).
+			Msg("Mock scenario is full, not storing resource")
+	}
+}
+
+// State returns the resources created through the mock of an API
+func (s *Server) State(apiID, scenario string) map[string]map[string]map[string]interface{} {
+	return s.state.snapshot(apiID, scenario)
+}
+
+// Reset forgets the resources of one scenario, or of all when scenario is
+// empty, and returns how many scenarios were dropped
+func (s *Server) Reset(apiID, scenario string) int {
+	return s.state.reset(apiID, scenario)
+}
+
+// checkRequest validates required parameters and the body
+func checkRequest(endpoint *entities.APIEndpoint, req *Request) []string {
+	var violations []string
+	for _, param := range endpoint.Parameters {
+		if !param.Required {
+			continue
+		}
+		switch strings.ToLower(param.In) {
+		case "query":
+			if !req.Query.Has(param.Name) {
+				violations = append(violations, fmt.Sprintf("query parameter %s is required", param.Name))
+			}
+		case "header":
+			if req.Header.Get(param.Name) == "" {
+				violations = append(violations, fmt.Sprintf("header %s is required", param.Name))
+			}
+		}
+	}
+
+	if !isJSONSchema(endpoint.RequestSchema) {
+		return violations
+	}
+	if req.Body == nil {
+		if required, _ := endpoint.RequestSchema["required"].([]interface{}); len(required) > 0 {
+			violations = append(violations, "request body is required")
+		}
+		return violations
+	}
+	if _, isText := req.Body.(string); isText && schemaType(endpoint.RequestSchema) != "string" {
+		return append(violations, "request body must be JSON")
+	}
+
+	bodyViolations, err := validateBody(endpoint.RequestSchema, req.Body)
+	if err != nil {
+		logger.Logger().Warn().Err(err).Str("endpoint", endpoint.Name).Msg("Skipping mock request validation")
+	}
+	return append(violations, bodyViolations...)
+}
+
+// responseBody picks the example matching the request best, falling back
+// to data generated from the response schema
+func responseBody(endpoint *entities.APIEndpoint, params map[string]string, req *Request) (interface{}, string) {
+	if example := pickExample(endpoint.Examples, params, req); example != nil && example.Response != nil {
+		return copyValue(example.Response), SourceExample
+	}
+	if isJSONSchema(endpoint.ResponseSchema) {
+		return generate(endpoint.ResponseSchema, "", 0), SourceSchema
+	}
+	return map[string]interface{}{}, SourceMock
+}
+
+// pickExample returns the example named in the request, or the one whose
+// request fields agree most with the request's body, path and query values
+func pickExample(examples []entities.Example, params map[string]string, req *Request) *entities.Example {
+	if name := req.Header.Get(HeaderExample); name != "" {
+		for i := range examples {
+			if strings.EqualFold(examples[i].Name, name) {
+				return &examples[i]
+			}
+		}
+	}
+
+	values := map[string]interface{}{}
+	for key := range req.Query {
+		values[key] = req.Query.Get(key)
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+	if object, ok := req.Body.(map[string]interface{}); ok {
+		for key, value := range object {
+			values[key] = value
+		}
+	}
+
+	var best *entities.Example
+	bestScore := -1
+	for i := range examples {
+		score := 0
+		for key, expected := range examples[i].Request {
+			if actual, ok := values[key]; ok && sameValue(actual, expected) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = &examples[i], score
+		}
+	}
+	return best
+}
+
+// echo copies request values into the response: path parameters and body
+// fields the response also has, and the resource ID into id
+func echo(object map[string]interface{}, matched *route, req *Request) {
+	for name, value := range matched.params {
+		if _, ok := object[name]; ok {
+			object[name] = value
+		}
+	}
+	if matched.resourceParam != "" {
+		if _, ok := object["id"]; ok {
+			object["id"] = matched.params[matched.resourceParam]
+		}
+	}
+	if fields, ok := req.Body.(map[string]interface{}); ok {
+		for field, value := range fields {
+			if _, ok := object[field]; ok && field != "id" {
+				object[field] = value
+			}
+		}
+	}
+}
+
+// declaredStatusCodes reads expected_status_codes entries like {code: 201}
+func declaredStatusCodes(endpoint *entities.APIEndpoint) []int {
+	var codes []int
+	for _, entry := range endpoint.ExpectedStatusCodes {
+		switch code := entry["code"].(type) {
+		case int:
+			codes = append(codes, code)
+		case float64:
+			codes = append(codes, int(code))
+		case string:
+			if n, err := strconv.Atoi(code); err == nil {
+				codes = append(codes, n)
+			}
+		}
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+// successCode is the lowest declared 2xx code, or 200
+func successCode(codes []int) int {
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			return code
+		}
+	}
+	return http.StatusOK
+}
+
+func statusDescription(endpoint *entities.APIEndpoint, code int) string {
+	for _, entry := range endpoint.ExpectedStatusCodes {
+		if description, ok := entry["description"].(string); ok && fmt.Sprint(entry["code"]) == strconv.Itoa(code) {
+			return description
+		}
+	}
+	return http.StatusText(code)
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// body drops the body of responses that cannot have one
+func body(status int, value interface{}) interface{} {
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		return nil
+	}
+	return value
+}
+
+// sameValue compares decoded values, treating "5" and 5 as equal since
+// path and query values are always strings
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(normalize(a), normalize(b)) {
+		return true
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// copyValue deep-copies a decoded value, normalizing numbers to float64
+func copyValue(value interface{}) interface{} {
+	return normalize(value)
+}
+
+func normalize(value interface{}) interface{} {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
@@ -0,0 +1,132 @@
+package mock
+
+import (
+	"sync"
+	"time"
+)
+
+// Bounds of the in-memory state, so a long-running mock cannot grow without
+// limit. The least recently used scenario is dropped first.
+const (
+	maxScenarios            = 100
+	maxResourcesPerScenario = 1000
+)
+
+// stateStore holds the resources created through mocks, per API and scenario
+type stateStore struct {
+	mu        sync.Mutex
+	scenarios map[scenarioKey]*scenarioState
+}
+
+type scenarioKey struct {
+	apiID    string
+	scenario string
+}
+
+type scenarioState struct {
+	resources map[string]map[string]interface{}
+	deleted   map[string]bool
+	touched   time.Time
+}
+
+func newStateStore() *stateStore {
+	return &stateStore{scenarios: make(map[scenarioKey]*scenarioState)}
+}
+
+// lookup returns a copy of a stored resource; deleted reports one that was
+// removed
+func (s *stateStore) lookup(key scenarioKey, id string) (resource map[string]interface{}, deleted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.scenarios[key]
+	if !ok {
+		return nil, false
+	}
+	state.touched = time.Now()
+	if stored, ok := state.resources[id]; ok {
+		resource, _ = copyValue(stored).(map[string]interface{})
+	}
+	return resource, state.deleted[id]
+}
+
+// store saves a resource, returning false when the scenario is full
+func (s *stateStore) store(key scenarioKey, id string, resource map[string]interface{}) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.scenario(key)
+	if _, exists := state.resources[id]; !exists && len(state.resources) >= maxResourcesPerScenario {
+		return false
+	}
+	state.resources[id] = resource
+	delete(state.deleted, id)
+	return true
+}
+
+// remove deletes a resource, remembering it so later reads answer 404
+func (s *stateStore) remove(key scenarioKey, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.scenario(key)
+	delete(state.resources, id)
+	if len(state.deleted) < maxResourcesPerScenario {
+		state.deleted[id] = true
+	}
+}
+
+// snapshot returns the resources of one scenario, or of all scenarios of
+// the API when scenario is empty
+func (s *stateStore) snapshot(apiID, scenario string) map[string]map[string]map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[string]map[string]map[string]interface{})
+	for key, state := range s.scenarios {
+		if key.apiID != apiID || (scenario != "" && key.scenario != scenario) {
+			continue
+		}
+		resources := make(map[string]map[string]interface{}, len(state.resources))
+		for id, resource := range state.resources {
+			resources[id] = resource
+		}
+		result[key.scenario] = resources
+	}
+	return result
+}
+
+// reset drops one scenario, or all scenarios of the API when scenario is empty
+func (s *stateStore) reset(apiID, scenario string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := 0
+	for key := range s.scenarios {
+		if key.apiID == apiID && (scenario == "" || key.scenario == scenario) {
+			delete(s.scenarios, key)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// scenario returns the state of key, creating it; s.mu must be held
+func (s *stateStore) scenario(key scenarioKey) *scenarioState {
+	if state, ok := s.scenarios[key]; ok {
+		state.touched = time.Now()
+		return state
+	}
+	if len(s.scenarios) >= maxScenarios {
+		var oldest scenarioKey
+		var oldestTime time.Time
+		for k, state := range s.scenarios {
+			if oldestTime.IsZero() || state.touched.Before(oldestTime) {
+				oldest, oldestTime = k, state.touched
+			}
+		}
+		delete(s.scenarios, oldest)
+	}
+	state := &scenarioState{
+		resources: make(map[string]map[string]interface{}),
+		deleted:   make(map[string]bool),
+		touched:   time.Now(),
+	}
+	s.scenarios[key] = state
+	return state
+}
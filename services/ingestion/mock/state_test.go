@@ -0,0 +1,217 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+// statefulAPI creates payments with a generated ID and reads, updates and
+// deletes them by ID
+func statefulAPI() *entities.APIConfig {
+	payment := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":     map[string]interface{}{"type": "string", "example": "pay_0123456789ab"},
+			"amount": map[string]interface{}{"type": "integer", "example": 100},
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"pending", "captured"}},
+		},
+	}
+	return &entities.APIConfig{
+		Name: "payments",
+		Endpoints: []entities.APIEndpoint{
+			{
+				Name: "create", Method: "POST", Path: "/payments", ResponseSchema: payment,
+				ExpectedStatusCodes: []map[string]interface{}{{"code": 201}, {"code": 422}},
+			},
+			{Name: "get", Method: "GET", Path: "/payments/{payment_id}", ResponseSchema: payment},
+			{Name: "update", Method: "PATCH", Path: "/payments/{payment_id}", ResponseSchema: payment},
+			{Name: "replace", Method: "PUT", Path: "/payments/{payment_id}", ResponseSchema: payment},
+			{
+				Name: "delete", Method: "DELETE", Path: "/payments/{payment_id}",
+				ExpectedStatusCodes: []map[string]interface{}{{"code": 204}},
+			},
+		},
+	}
+}
+
+// mockClient sends requests to one API's mock in one scenario
+type mockClient struct {
+	t        *testing.T
+	server   *Server
+	config   *entities.APIConfig
+	scenario string
+}
+
+func (c *mockClient) do(method, path string, body map[string]interface{}, header ...string) *Response {
+	c.t.Helper()
+	req := &Request{Method: method, Path: path, Query: url.Values{}, Header: http.Header{}, Scenario: c.scenario}
+	if body != nil {
+		req.Body = body
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return c.server.Handle("api-1", c.config, req)
+}
+
+func field(t *testing.T, resp *Response, name string) interface{} {
+	t.Helper()
+	object, ok := resp.Body.(map[string]interface{})
+	if !ok {
+		t.Fatalf("body = %#v, want an object", resp.Body)
+	}
+	return object[name]
+}
+
+func TestScenarioStateTransitions(t *testing.T) {
+	server := NewServer()
+	client := &mockClient{t: t, server: server, config: statefulAPI(), scenario: DefaultScenario}
+
+	// Unknown resources are generated from the schema and echo their ID
+	resp := client.do("GET", "/payments/pay_unknown", nil)
+	if resp.StatusCode != http.StatusOK || resp.Source != SourceSchema || field(t, resp, "id") != "pay_unknown" {
+		t.Fatalf("get before create = %d %s %v", resp.StatusCode, resp.Source, resp.Body)
+	}
+
+	// Create: a fresh ID shaped like the example, and the request's fields
+	resp = client.do("POST", "/payments", map[string]interface{}{"amount": 250})
+	if resp.StatusCode != http.StatusCreated || resp.Source != SourceSchema {
+		t.Fatalf("create = %d %s", resp.StatusCode, resp.Source)
+	}
+	id, _ := field(t, resp, "id").(string)
+	if id == "pay_0123456789ab" || len(id) != len("pay_0123456789ab") || id[:4] != "pay_" {
+		t.Fatalf("created id = %q, want a fresh pay_ ID", id)
+	}
+	if field(t, resp, "amount") != 250 {
+		t.Errorf("created amount = %v", field(t, resp, "amount"))
+	}
+
+	// Read back from state
+	resp = client.do("GET", "/payments/"+id, nil)
+	if resp.Source != SourceState || field(t, resp, "amount") != 250.0 || field(t, resp, "status") != "pending" {
+		t.Fatalf("get after create = %s %v", resp.Source, resp.Body)
+	}
+
+	// Update merges fields into the stored resource
+	resp = client.do("PATCH", "/payments/"+id, map[string]interface{}{"status": "captured"})
+	if resp.Source != SourceState || field(t, resp, "status") != "captured" || field(t, resp, "amount") != 250.0 {
+		t.Fatalf("patch = %s %v", resp.Source, resp.Body)
+	}
+	resp = client.do("GET", "/payments/"+id, nil)
+	if field(t, resp, "status") != "captured" {
+		t.Errorf("get after patch = %v", resp.Body)
+	}
+
+	// Delete, then the resource is gone for reads and updates
+	resp = client.do("DELETE", "/payments/"+id, nil)
+	if resp.StatusCode != http.StatusNoContent || resp.Body != nil || resp.Source != SourceState {
+		t.Fatalf("delete = %d %s %v", resp.StatusCode, resp.Source, resp.Body)
+	}
+	for _, method := range []string{"GET", "PATCH", "DELETE"} {
+		if resp = client.do(method, "/payments/"+id, nil); resp.StatusCode != http.StatusNotFound || resp.Source != SourceState {
+			t.Errorf("%s after delete = %d %s", method, resp.StatusCode, resp.Source)
+		}
+	}
+
+	// PUT creates the resource again at its path
+	resp = client.do("PUT", "/payments/"+id, map[string]interface{}{"amount": 75})
+	if resp.StatusCode != http.StatusOK || field(t, resp, "id") != id {
+		t.Fatalf("put after delete = %d %v", resp.StatusCode, resp.Body)
+	}
+	resp = client.do("GET", "/payments/"+id, nil)
+	if resp.Source != SourceState || field(t, resp, "amount") != 75.0 {
+		t.Fatalf("get after put = %s %v", resp.Source, resp.Body)
+	}
+
+	// A forced error status leaves the state alone
+	resp = client.do("POST", "/payments", map[string]interface{}{"amount": 1}, HeaderStatus, "422")
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("forced 422 = %d", resp.StatusCode)
+	}
+	if got := len(server.State("api-1", DefaultScenario)[DefaultScenario]); got != 1 {
+		t.Errorf("default scenario holds %d resources, want 1", got)
+	}
+}
+
+func TestScenarioIsolation(t *testing.T) {
+	server := NewServer()
+	config := statefulAPI()
+	alice := &mockClient{t: t, server: server, config: config, scenario: "alice"}
+	bob := &mockClient{t: t, server: server, config: config, scenario: "bob"}
+
+	id := field(t, alice.do("POST", "/payments", map[string]interface{}{"amount": 10}), "id").(string)
+	if resp := bob.do("GET", "/payments/"+id, nil); resp.Source != SourceSchema {
+		t.Errorf("other scenario read the resource from %s", resp.Source)
+	}
+	bob.do("DELETE", "/payments/"+id, nil)
+	if resp := alice.do("GET", "/payments/"+id, nil); resp.StatusCode != http.StatusOK || resp.Source != SourceState {
+		t.Errorf("delete in another scenario removed the resource: %d %s", resp.StatusCode, resp.Source)
+	}
+
+	state := server.State("api-1", "")
+	if len(state) != 2 || len(state["alice"]) != 1 || len(state["bob"]) != 0 {
+		t.Errorf("state = %v", state)
+	}
+	if other := server.State("api-2", ""); len(other) != 0 {
+		t.Errorf("state of another api = %v", other)
+	}
+
+	if dropped := server.Reset("api-1", "alice"); dropped != 1 {
+		t.Errorf("reset alice dropped %d scenarios", dropped)
+	}
+	if resp := alice.do("GET", "/payments/"+id, nil); resp.Source != SourceSchema {
+		t.Errorf("get after reset read from %s", resp.Source)
+	}
+	// Reads do not create a scenario, so only bob's is left
+	if dropped := server.Reset("api-1", ""); dropped != 1 {
+		t.Errorf("reset of all scenarios dropped %d, want 1", dropped)
+	}
+}
+
+func TestStateStoreBounds(t *testing.T) {
+	store := newStateStore()
+	key := scenarioKey{apiID: "api-1", scenario: "full"}
+	for i := 0; i < maxResourcesPerScenario; i++ {
+		if !store.store(key, fmt.Sprint(i), map[string]interface{}{}) {
+			t.Fatalf("store %d refused", i)
+		}
+	}
+	if store.store(key, "one-more", map[string]interface{}{}) {
+		t.Error("store accepted a resource beyond the bound")
+	}
+	if !store.store(key, "0", map[string]interface{}{"updated": true}) {
+		t.Error("store refused to update an existing resource")
+	}
+
+	// Lookups hand out copies
+	resource, _ := store.lookup(key, "0")
+	resource["updated"] = false
+	if again, _ := store.lookup(key, "0"); again["updated"] != true {
+		t.Error("changing a looked up resource changed the stored one")
+	}
+
+	// The least recently used scenario is dropped when there are too many
+	store = newStateStore()
+	start := time.Now()
+	for i := 0; i < maxScenarios; i++ {
+		k := scenarioKey{apiID: "api-1", scenario: fmt.Sprint(i)}
+		store.store(k, "id", map[string]interface{}{})
+		store.scenarios[k].touched = start.Add(time.Duration(i) * time.Second)
+	}
+	store.scenarios[scenarioKey{apiID: "api-1", scenario: "0"}].touched = start.Add(time.Hour)
+	store.store(scenarioKey{apiID: "api-1", scenario: "new"}, "id", map[string]interface{}{})
+	if len(store.scenarios) != maxScenarios {
+		t.Fatalf("%d scenarios, want %d", len(store.scenarios), maxScenarios)
+	}
+	if _, ok := store.scenarios[scenarioKey{apiID: "api-1", scenario: "1"}]; ok {
+		t.Error("least recently used scenario was kept")
+	}
+	if _, ok := store.scenarios[scenarioKey{apiID: "api-1", scenario: "0"}]; !ok {
+		t.Error("recently used scenario was dropped")
+	}
+}
@@ -0,0 +1,393 @@
+// Package specdiff compares two revisions of an API configuration at the
+// endpoint, parameter and schema level, and classifies each change as
+// breaking or non-breaking for existing clients.
+package specdiff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+// Severities of a change
+const (
+	Breaking    = "breaking"
+	NonBreaking = "non_breaking"
+)
+
+// Kinds of change
+const (
+	KindEndpointAdded          = "endpoint_added"
+	KindEndpointRemoved        = "endpoint_removed"
+	KindAuthenticationChanged  = "authentication_changed"
+	KindParameterAdded         = "parameter_added"
+	KindRequiredParameterAdded = "required_parameter_added"
+	KindParameterRemoved       = "parameter_removed"
+	KindParameterRequired      = "parameter_required"
+	KindParameterOptional      = "parameter_optional"
+	KindFieldAdded             = "field_added"
+	KindRequiredFieldAdded     = "required_field_added"
+	KindFieldRemoved           = "field_removed"
+	KindFieldRequired          = "field_required"
+	KindFieldOptional          = "field_optional"
+	KindTypeChanged            = "type_changed"
+	KindEnumNarrowed           = "enum_narrowed"
+	KindEnumWidened            = "enum_widened"
+	KindSchemaAdded            = "schema_added"
+	KindSchemaRemoved          = "schema_removed"
+	KindExampleChanged         = "example_changed"
+)
+
+// maxSchemaDepth stops the comparison of deeply nested schemas
+const maxSchemaDepth = 20
+
+// Change is one difference between two revisions
+type Change struct {
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Endpoint string `json:"endpoint"`
+	// Location is the parameter or schema field changed, e.g.
+	// "query.limit" or "request.items[].name"; empty for the endpoint
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Report lists the changes from one revision to another
+type Report struct {
+	Breaking         bool     `json:"breaking"`
+	BreakingCount    int      `json:"breaking_count"`
+	NonBreakingCount int      `json:"non_breaking_count"`
+	Changes          []Change `json:"changes"`
+}
+
+// Compare returns the changes from the old configuration to the new one.
+// Whether a schema change breaks clients depends on its direction: a
+// request may not demand more than before (new required fields, fewer enum
+// values), and a response may not promise less (removed fields, more enum
+// values). Any type change is breaking.
+func Compare(old, new *entities.APIConfig) *Report {
+	d := &differ{report: &Report{Changes: []Change{}}}
+
+	newEndpoints := make(map[string]entities.APIEndpoint, len(new.Endpoints))
+	for _, endpoint := range new.Endpoints {
+		newEndpoints[EndpointKey(endpoint)] = endpoint
+	}
+	oldKeys := make(map[string]bool, len(old.Endpoints))
+	for _, before := range old.Endpoints {
+		key := EndpointKey(before)
+		oldKeys[key] = true
+		after, ok := newEndpoints[key]
+		if !ok {
+			d.add(KindEndpointRemoved, Breaking, key, "", "endpoint removed")
+			continue
+		}
+		d.endpoint(key, before, after)
+	}
+	for _, endpoint := range new.Endpoints {
+		if key := EndpointKey(endpoint); !oldKeys[key] {
+			d.add(KindEndpointAdded, NonBreaking, key, "", "endpoint added")
+		}
+	}
+
+	return d.report
+}
+
+// EndpointKey identifies an endpoint across revisions: its method and path,
+// the operation of a GraphQL endpoint or the method of a gRPC one
+func EndpointKey(endpoint entities.APIEndpoint) string {
+	if op := endpoint.GraphQL; op != nil {
+		return op.Type + " " + op.Field
+	}
+	if method := endpoint.GRPC; method != nil {
+		return method.FullMethod
+	}
+	return strings.ToUpper(endpoint.Method) + " " + endpoint.Path
+}
+
+type differ struct {
+	report *Report
+}
+
+func (d *differ) add(kind, severity, endpoint, location, message string) {
+	d.report.Changes = append(d.report.Changes, Change{
+		Kind:     kind,
+		Severity: severity,
+		Endpoint: endpoint,
+		Location: location,
+		Message:  message,
+	})
+	if severity == Breaking {
+		d.report.Breaking = true
+		d.report.BreakingCount++
+	} else {
+		d.report.NonBreakingCount++
+	}
+}
+
+func (d *differ) endpoint(key string, before, after entities.APIEndpoint) {
+	oldAuth, newAuth := authType(before.Authentication), authType(after.Authentication)
+	switch {
+	case oldAuth == newAuth:
+	case newAuth == "":
+		d.add(KindAuthenticationChanged, NonBreaking, key, "", "authentication no longer required")
+	default:
+		d.add(KindAuthenticationChanged, Breaking, key, "",
+			fmt.Sprintf("authentication changed from %q to %q", oldAuth, newAuth))
+	}
+
+	if before.GraphQL != nil && after.GraphQL != nil && before.GraphQL.ReturnType != after.GraphQL.ReturnType {
+		d.add(KindTypeChanged, Breaking, key, "return",
+			fmt.Sprintf("return type changed from %s to %s", before.GraphQL.ReturnType, after.GraphQL.ReturnType))
+	}
+
+	d.parameters(key, before.Parameters, after.Parameters)
+	d.schema(key, "request", before.RequestSchema, after.RequestSchema, true, 0)
+	d.schema(key, "response", before.ResponseSchema, after.ResponseSchema, false, 0)
+}
+
+func (d *differ) parameters(key string, before, after []entities.Parameter) {
+	newParams := make(map[string]entities.Parameter, len(after))
+	for _, param := range after {
+		newParams[parameterKey(param)] = param
+	}
+	oldParams := make(map[string]bool, len(before))
+	for _, old := range before {
+		location := parameterKey(old)
+		oldParams[location] = true
+		param, ok := newParams[location]
+		if !ok {
+			d.add(KindParameterRemoved, NonBreaking, key, location, "parameter removed")
+			continue
+		}
+		if old.Type != "" && param.Type != "" && old.Type != param.Type {
+			d.add(KindTypeChanged, Breaking, key, location,
+				fmt.Sprintf("parameter type changed from %s to %s", old.Type, param.Type))
+		}
+		switch {
+		case !old.Required && param.Required:
+			d.add(KindParameterRequired, Breaking, key, location, "parameter became required")
+		case old.Required && !param.Required:
+			d.add(KindParameterOptional, NonBreaking, key, location, "parameter became optional")
+		}
+	}
+	for _, param := range after {
+		location := parameterKey(param)
+		switch {
+		case oldParams[location]:
+		case param.Required:
+			d.add(KindRequiredParameterAdded, Breaking, key, location, "required parameter added")
+		default:
+			d.add(KindParameterAdded, NonBreaking, key, location, "optional parameter added")
+		}
+	}
+}
+
+// schema compares a request or response schema. Sample bodies, which
+// Postman collections store as schemas, are only compared as a whole.
+func (d *differ) schema(key, location string, before, after map[string]interface{}, request bool, depth int) {
+	if depth > maxSchemaDepth {
+		return
+	}
+	switch {
+	case len(before) == 0 && len(after) == 0:
+		return
+	case len(before) == 0:
+		d.add(KindSchemaAdded, NonBreaking, key, location, "schema added")
+		return
+	case len(after) == 0:
+		d.add(KindSchemaRemoved, severity(!request), key, location, "schema removed")
+		return
+	}
+	if !isJSONSchema(before) || !isJSONSchema(after) {
+		if !reflect.DeepEqual(before, after) {
+			d.add(KindExampleChanged, NonBreaking, key, location, "example body changed")
+		}
+		return
+	}
+
+	oldType, newType := schemaType(before), schemaType(after)
+	if oldType != "" && newType != "" && oldType != newType {
+		d.add(KindTypeChanged, Breaking, key, location, fmt.Sprintf("type changed from %s to %s", oldType, newType))
+		return
+	}
+
+	d.enum(key, location, before, after, request)
+	d.properties(key, location, before, after, request, depth)
+
+	oldItems, _ := before["items"].(map[string]interface{})
+	newItems, _ := after["items"].(map[string]interface{})
+	if oldItems != nil && newItems != nil {
+		d.schema(key, location+"[]", oldItems, newItems, request, depth+1)
+	}
+}
+
+// enum reports removed and added enum values. A request accepting fewer
+// values, or a response returning more, breaks clients.
+func (d *differ) enum(key, location string, before, after map[string]interface{}, request bool) {
+	oldValues, oldOK := enumValues(before)
+	newValues, newOK := enumValues(after)
+	if !oldOK && !newOK {
+		return
+	}
+
+	var removed, added []string
+	switch {
+	case !newOK:
+		d.add(KindEnumWidened, severity(!request), key, location, "enum restriction removed")
+		return
+	case !oldOK:
+		d.add(KindEnumNarrowed, severity(request), key, location,
+			fmt.Sprintf("values restricted to %s", strings.Join(sortedKeys(newValues), ", ")))
+		return
+	}
+	for value := range oldValues {
+		if !newValues[value] {
+			removed = append(removed, value)
+		}
+	}
+	for value := range newValues {
+		if !oldValues[value] {
+			added = append(added, value)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	if len(removed) > 0 {
+		d.add(KindEnumNarrowed, severity(request), key, location,
+			fmt.Sprintf("enum values removed: %s", strings.Join(removed, ", ")))
+	}
+	if len(added) > 0 {
+		d.add(KindEnumWidened, severity(!request), key, location,
+			fmt.Sprintf("enum values added: %s", strings.Join(added, ", ")))
+	}
+}
+
+// properties compares the fields of object schemas. Clients break when a
+// request requires a field they do not send, or a response may omit a
+// field they read.
+func (d *differ) properties(key, location string, before, after map[string]interface{}, request bool, depth int) {
+	oldProps, _ := before["properties"].(map[string]interface{})
+	newProps, _ := after["properties"].(map[string]interface{})
+	oldRequired, newRequired := requiredFields(before), requiredFields(after)
+
+	for _, name := range sortedKeys(oldProps) {
+		field := location + "." + name
+		newProp, ok := newProps[name]
+		if !ok {
+			d.add(KindFieldRemoved, severity(!request), key, field, "field removed")
+			continue
+		}
+		switch {
+		case !oldRequired[name] && newRequired[name]:
+			d.add(KindFieldRequired, severity(request), key, field, "field became required")
+		case oldRequired[name] && !newRequired[name]:
+			d.add(KindFieldOptional, severity(!request), key, field, "field became optional")
+		}
+		oldProp, _ := oldProps[name].(map[string]interface{})
+		newSchema, _ := newProp.(map[string]interface{})
+		if oldProp != nil && newSchema != nil {
+			d.schema(key, field, oldProp, newSchema, request, depth+1)
+		}
+	}
+	for _, name := range sortedKeys(newProps) {
+		if _, ok := oldProps[name]; ok {
+			continue
+		}
+		field := location + "." + name
+		if request && newRequired[name] {
+			d.add(KindRequiredFieldAdded, Breaking, key, field, "required field added")
+		} else {
+			d.add(KindFieldAdded, NonBreaking, key, field, "field added")
+		}
+	}
+}
+
+func severity(breaking bool) string {
+	if breaking {
+		return Breaking
+	}
+	return NonBreaking
+}
+
+func authType(auth *entities.AuthConfig) string {
+	if auth == nil || auth.Type == "none" {
+		return ""
+	}
+	return auth.Type
+}
+
+// parameterKey identifies a parameter by where it is sent and its name
+func parameterKey(param entities.Parameter) string {
+	if param.In == "" {
+		return param.Name
+	}
+	return param.In + "." + param.Name
+}
+
+// isJSONSchema tells a JSON schema apart from a sample body
+func isJSONSchema(schema map[string]interface{}) bool {
+	if _, ok := schema["type"]; ok {
+		return true
+	}
+	for _, keyword := range []string{"properties", "items", "enum", "allOf", "anyOf", "oneOf", "$ref"} {
+		if _, ok := schema[keyword]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType returns the type of a schema, joining the types of an
+// OpenAPI 3.1 type list, or object when only properties are given
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			types = append(types, fmt.Sprint(item))
+		}
+		sort.Strings(types)
+		return strings.Join(types, "|")
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+func enumValues(schema map[string]interface{}) (map[string]bool, bool) {
+	list, ok := schema["enum"].([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values := make(map[string]bool, len(list))
+	for _, value := range list {
+		values[fmt.Sprint(value)] = true
+	}
+	return values, true
+}
+
+func requiredFields(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	list, _ := schema["required"].([]interface{})
+	for _, name := range list {
+		if s, ok := name.(string); ok {
+			required[s] = true
+		}
+	}
+	return required
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,269 @@
+package specdiff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/testpilot-ai/ingestion/domain/entities"
+)
+
+func schema(t *testing.T, doc string) map[string]interface{} {
+	t.Helper()
+	var s map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// ordersAPI is the revision the test cases change
+func ordersAPI(t *testing.T) *entities.APIConfig {
+	return &entities.APIConfig{
+		Name:    "orders",
+		Version: "1.0",
+		Endpoints: []entities.APIEndpoint{
+			{
+				Method: "POST",
+				Path:   "/orders",
+				RequestSchema: schema(t, `{
+					"type": "object",
+					"required": ["item"],
+					"properties": {
+						"item": {"type": "string"},
+						"quantity": {"type": "integer"},
+						"priority": {"type": "string", "enum": ["low", "high"]}
+					}
+				}`),
+				ResponseSchema: schema(t, `{
+					"type": "object",
+					"required": ["id", "status"],
+					"properties": {
+						"id": {"type": "string"},
+						"status": {"type": "string", "enum": ["pending", "shipped"]},
+						"total": {"type": "number"}
+					}
+				}`),
+			},
+			{
+				Method: "GET",
+				Path:   "/orders/{id}",
+				Parameters: []entities.Parameter{
+					{Name: "id", In: "path", Type: "string", Required: true},
+					{Name: "expand", In: "query", Type: "boolean"},
+				},
+			},
+		},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	type change struct {
+		kind, severity, endpoint, location string
+	}
+	request := func(c *entities.APIConfig) map[string]interface{} {
+		return c.Endpoints[0].RequestSchema["properties"].(map[string]interface{})
+	}
+	response := func(c *entities.APIConfig) map[string]interface{} {
+		return c.Endpoints[0].ResponseSchema["properties"].(map[string]interface{})
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *entities.APIConfig)
+		want   []change
+	}{
+		{
+			name:   "unchanged",
+			change: func(c *entities.APIConfig) {},
+		},
+		{
+			name:   "endpoint removed",
+			change: func(c *entities.APIConfig) { c.Endpoints = c.Endpoints[:1] },
+			want:   []change{{KindEndpointRemoved, Breaking, "GET /orders/{id}", ""}},
+		},
+		{
+			name: "endpoint added",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints = append(c.Endpoints, entities.APIEndpoint{Method: "delete", Path: "/orders/{id}"})
+			},
+			want: []change{{KindEndpointAdded, NonBreaking, "DELETE /orders/{id}", ""}},
+		},
+		{
+			name: "required request field added",
+			change: func(c *entities.APIConfig) {
+				request(c)["customer"] = map[string]interface{}{"type": "string"}
+				c.Endpoints[0].RequestSchema["required"] = []interface{}{"item", "customer"}
+			},
+			want: []change{{KindRequiredFieldAdded, Breaking, "POST /orders", "request.customer"}},
+		},
+		{
+			name: "optional request field added",
+			change: func(c *entities.APIConfig) {
+				request(c)["note"] = map[string]interface{}{"type": "string"}
+			},
+			want: []change{{KindFieldAdded, NonBreaking, "POST /orders", "request.note"}},
+		},
+		{
+			name: "required response field added",
+			change: func(c *entities.APIConfig) {
+				response(c)["created_at"] = map[string]interface{}{"type": "string"}
+				c.Endpoints[0].ResponseSchema["required"] = []interface{}{"id", "status", "created_at"}
+			},
+			want: []change{{KindFieldAdded, NonBreaking, "POST /orders", "response.created_at"}},
+		},
+		{
+			name: "request enum narrowed",
+			change: func(c *entities.APIConfig) {
+				request(c)["priority"].(map[string]interface{})["enum"] = []interface{}{"low"}
+			},
+			want: []change{{KindEnumNarrowed, Breaking, "POST /orders", "request.priority"}},
+		},
+		{
+			name: "response enum narrowed",
+			change: func(c *entities.APIConfig) {
+				response(c)["status"].(map[string]interface{})["enum"] = []interface{}{"pending"}
+			},
+			want: []change{{KindEnumNarrowed, NonBreaking, "POST /orders", "response.status"}},
+		},
+		{
+			name: "request enum widened",
+			change: func(c *entities.APIConfig) {
+				request(c)["priority"].(map[string]interface{})["enum"] = []interface{}{"low", "high", "urgent"}
+			},
+			want: []change{{KindEnumWidened, NonBreaking, "POST /orders", "request.priority"}},
+		},
+		{
+			name: "response enum widened",
+			change: func(c *entities.APIConfig) {
+				response(c)["status"].(map[string]interface{})["enum"] = []interface{}{"pending", "shipped", "cancelled"}
+			},
+			want: []change{{KindEnumWidened, Breaking, "POST /orders", "response.status"}},
+		},
+		{
+			name: "request field type changed",
+			change: func(c *entities.APIConfig) {
+				request(c)["quantity"] = map[string]interface{}{"type": "string"}
+			},
+			want: []change{{KindTypeChanged, Breaking, "POST /orders", "request.quantity"}},
+		},
+		{
+			name: "response field type changed",
+			change: func(c *entities.APIConfig) {
+				response(c)["total"] = map[string]interface{}{"type": "string"}
+			},
+			want: []change{{KindTypeChanged, Breaking, "POST /orders", "response.total"}},
+		},
+		{
+			name: "parameter type changed",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints[1].Parameters[1].Type = "string"
+			},
+			want: []change{{KindTypeChanged, Breaking, "GET /orders/{id}", "query.expand"}},
+		},
+		{
+			name:   "response field removed",
+			change: func(c *entities.APIConfig) { delete(response(c), "total") },
+			want:   []change{{KindFieldRemoved, Breaking, "POST /orders", "response.total"}},
+		},
+		{
+			name:   "request field removed",
+			change: func(c *entities.APIConfig) { delete(request(c), "quantity") },
+			want:   []change{{KindFieldRemoved, NonBreaking, "POST /orders", "request.quantity"}},
+		},
+		{
+			name: "response field became optional",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints[0].ResponseSchema["required"] = []interface{}{"id"}
+			},
+			want: []change{{KindFieldOptional, Breaking, "POST /orders", "response.status"}},
+		},
+		{
+			name: "request field became optional",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints[0].RequestSchema["required"] = []interface{}{}
+			},
+			want: []change{{KindFieldOptional, NonBreaking, "POST /orders", "request.item"}},
+		},
+		{
+			name: "required parameter added",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints[1].Parameters = append(c.Endpoints[1].Parameters,
+					entities.Parameter{Name: "X-Tenant", In: "header", Type: "string", Required: true})
+			},
+			want: []change{{KindRequiredParameterAdded, Breaking, "GET /orders/{id}", "header.X-Tenant"}},
+		},
+		{
+			name:   "parameter removed",
+			change: func(c *entities.APIConfig) { c.Endpoints[1].Parameters = c.Endpoints[1].Parameters[:1] },
+			want:   []change{{KindParameterRemoved, NonBreaking, "GET /orders/{id}", "query.expand"}},
+		},
+		{
+			name: "authentication added",
+			change: func(c *entities.APIConfig) {
+				c.Endpoints[0].Authentication = &entities.AuthConfig{Type: "bearer"}
+			},
+			want: []change{{KindAuthenticationChanged, Breaking, "POST /orders", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := ordersAPI(t), ordersAPI(t)
+			tt.change(new)
+			report := Compare(old, new)
+
+			got := make([]change, 0, len(report.Changes))
+			for _, c := range report.Changes {
+				got = append(got, change{c.Kind, c.Severity, c.Endpoint, c.Location})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("changes = %+v, want %+v", got, tt.want)
+			}
+			breaking := 0
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+				if tt.want[i].severity == Breaking {
+					breaking++
+				}
+			}
+			if report.Breaking != (breaking > 0) || report.BreakingCount != breaking || report.NonBreakingCount != len(got)-breaking {
+				t.Errorf("report breaking = %v (%d breaking, %d non-breaking), want %d breaking of %d",
+					report.Breaking, report.BreakingCount, report.NonBreakingCount, breaking, len(got))
+			}
+		})
+	}
+}
+
+func TestCompareNestedSchemas(t *testing.T) {
+	old := &entities.APIConfig{Endpoints: []entities.APIEndpoint{{
+		Method: "GET",
+		Path:   "/orders",
+		ResponseSchema: schema(t, `{
+			"type": "array",
+			"items": {"type": "object", "properties": {"lines": {"type": "array", "items": {
+				"type": "object", "properties": {"sku": {"type": "string"}, "qty": {"type": "integer"}}
+			}}}}
+		}`),
+	}}}
+	new := &entities.APIConfig{Endpoints: []entities.APIEndpoint{{
+		Method: "GET",
+		Path:   "/orders",
+		ResponseSchema: schema(t, `{
+			"type": "array",
+			"items": {"type": "object", "properties": {"lines": {"type": "array", "items": {
+				"type": "object", "properties": {"sku": {"type": "string"}}
+			}}}}
+		}`),
+	}}}
+
+	report := Compare(old, new)
+	if len(report.Changes) != 1 {
+		t.Fatalf("changes = %+v", report.Changes)
+	}
+	c := report.Changes[0]
+	if c.Kind != KindFieldRemoved || c.Severity != Breaking || c.Location != "response[].lines[].qty" {
+		t.Errorf("change = %+v", c)
+	}
+}
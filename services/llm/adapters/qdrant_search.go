@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/testpilot-ai/llm/domain/entities"
 )
 
@@ -88,6 +89,10 @@ func (a *QdrantSearchAdapter) Search(vector []float32, limit int) ([]entities.Re
 		ctx := entities.RetrievalContext{
 			Score: r.Score,
 		}
+		// Points are stored under their API spec's ID
+		if id, err := uuid.Parse(r.ID); err == nil {
+			ctx.APISpecID = id
+		}
 
 		// Extract fields from payload
 		if name, ok := r.Payload["api_name"].(string); ok {
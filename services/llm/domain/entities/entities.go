@@ -16,21 +16,32 @@ type TestRequest struct {
 
 // APICall represents a constructed API call
 type APICall struct {
-	ID              uuid.UUID              `json:"id"`
-	Method          string                 `json:"method"`
-	URL             string                 `json:"url"`
-	Path            string                 `json:"path"`
-	Headers         map[string]string      `json:"headers,omitempty"`
-	QueryParams     map[string]string      `json:"query_params,omitempty"`
-	Body            map[string]interface{} `json:"body,omitempty"`
-	APISpecID       uuid.UUID              `json:"api_spec_id,omitempty"`
-	APIName         string                 `json:"api_name,omitempty"`
-	EndpointName    string                 `json:"endpoint_name,omitempty"`
-	Confidence      float64                `json:"confidence"`
+	ID               uuid.UUID              `json:"id"`
+	Method           string                 `json:"method"`
+	URL              string                 `json:"url"`
+	Path             string                 `json:"path"`
+	Headers          map[string]string      `json:"headers,omitempty"`
+	QueryParams      map[string]interface{} `json:"query_params,omitempty"`
+	QueryParamStyles map[string]string      `json:"query_param_styles,omitempty"` // spec array style per query parameter
+	Body             map[string]interface{} `json:"body,omitempty"`
+	BodyType         string                 `json:"body_type,omitempty"` // graphql for GraphQL operations
+	GRPC             *GRPCCall              `json:"grpc,omitempty"`
+	APISpecID        uuid.UUID              `json:"api_spec_id,omitempty"`
+	APIName          string                 `json:"api_name,omitempty"`
+	EndpointName     string                 `json:"endpoint_name,omitempty"`
+	Confidence       float64                `json:"confidence"`
+}
+
+// GRPCCall makes an API call a unary gRPC call of Method
+// (package.Service/Method); the body is the request message in protobuf
+// JSON and the URL the server address
+type GRPCCall struct {
+	Method string `json:"method"`
 }
 
 // RetrievalContext represents context retrieved from vector search
 type RetrievalContext struct {
+	APISpecID   uuid.UUID              `json:"api_spec_id,omitempty"`
 	APIName     string                 `json:"api_name"`
 	Version     string                 `json:"version"`
 	Description string                 `json:"description"`
@@ -84,6 +95,7 @@ type ParseResult struct {
 	Intent       string                 `json:"intent"`
 	APIName      string                 `json:"api_name,omitempty"`
 	Endpoint     string                 `json:"endpoint,omitempty"`
+	Operation    string                 `json:"operation,omitempty"` // GraphQL query or mutation field, or gRPC full method
 	Parameters   map[string]interface{} `json:"parameters,omitempty"`
 	Confidence   float64                `json:"confidence"`
 	NeedsClarify bool                   `json:"needs_clarification"`
@@ -87,7 +87,7 @@ func (h *LLMHandler) ParseRequest(c *gin.Context) {
 		Msg("Parsing natural language request")
 
 	// Get API context from vector search (RAG) - use 3 results for parsing
-	apiContext, err := h.retrieveAPIContext(c.Request.Context(), req.NaturalLanguage, 3)
+	apiContext, _, err := h.retrieveAPIContext(c.Request.Context(), req.NaturalLanguage, 3)
 	if err != nil {
 		logger.WithRequestID(requestIDStr).Err(err).
 			Msg("Failed to retrieve API context")
@@ -234,18 +234,33 @@ func (h *LLMHandler) ConstructRequest(c *gin.Context) {
 	// Retrieve API context using RAG for the api_name from parse result
 	// Use limit 1 to get ONLY the matched API (avoids confusion with other APIs)
 	var apiContext string
+	var apiMatches []entities.RetrievalContext
 	if apiName, ok := req.ParseResult["api_name"].(string); ok && apiName != "" {
-		apiContext, _ = h.retrieveAPIContext(c.Request.Context(), apiName, 1)
+		apiContext, apiMatches, _ = h.retrieveAPIContext(c.Request.Context(), apiName, 1)
 		logger.WithRequestID(requestIDStr).Debug().
 			Str("api_name", apiName).
 			Str("api_context_length", fmt.Sprintf("%d", len(apiContext))).
 			Msg("Retrieved API context for construct")
 	}
 
-	// Build prompt with API context included
+	// Build prompt with API context included. GraphQL operations and gRPC
+	// methods get their own prompts, built from the matched operation
+	// rather than the whole schema.
 	parseResultJSON, _ := json.Marshal(req.ParseResult)
-	apiConfigJSON, _ := json.Marshal(req.APIConfig)
-	prompt := prompts.ConstructRequestPromptWithContext(string(parseResultJSON), string(apiConfigJSON), apiContext, generatedData)
+	baseURL, graphQLEndpoint := matchGraphQLEndpoint(apiMatches, req.ParseResult)
+	grpcMatch, grpcEndpoint := matchGRPCEndpoint(apiMatches, req.ParseResult)
+	var prompt string
+	if graphQLEndpoint != nil {
+		operationContext := prompts.BuildGraphQLOperationContext(baseURL, graphQLEndpoint)
+		prompt = prompts.ConstructGraphQLRequestPrompt(string(parseResultJSON), operationContext, generatedData)
+	} else if grpcEndpoint != nil {
+		grpcBaseURL, _ := grpcMatch.Config["base_url"].(string)
+		methodContext := prompts.BuildGRPCMethodContext(grpcBaseURL, grpcEndpoint)
+		prompt = prompts.ConstructGRPCRequestPrompt(string(parseResultJSON), methodContext, generatedData)
+	} else {
+		apiConfigJSON, _ := json.Marshal(req.APIConfig)
+		prompt = prompts.ConstructRequestPromptWithContext(string(parseResultJSON), string(apiConfigJSON), apiContext, generatedData)
+	}
 
 	// Call LLM
 	response, err := provider.Complete(c.Request.Context(), prompt)
@@ -288,6 +303,13 @@ func (h *LLMHandler) ConstructRequest(c *gin.Context) {
 	}
 
 	apiCall.ID = uuid.New()
+	if graphQLEndpoint != nil {
+		completeGraphQLCall(&apiCall, baseURL, graphQLEndpoint)
+	} else if grpcEndpoint != nil {
+		completeGRPCCall(&apiCall, grpcMatch, grpcEndpoint)
+	} else {
+		apiCall.QueryParamStyles = queryParamStyles(apiMatches, apiCall.Method, apiCall.Path)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"api_call":       apiCall,
@@ -374,21 +396,22 @@ func (h *LLMHandler) ListProviders(c *gin.Context) {
 
 // retrieveAPIContext retrieves relevant API context using RAG
 // limit: number of results to return (use 1 for construct, 3 for parse)
-func (h *LLMHandler) retrieveAPIContext(ctx context.Context, query string, limit int) (string, error) {
+// The matched API configs are returned along with the prompt context.
+func (h *LLMHandler) retrieveAPIContext(ctx context.Context, query string, limit int) (string, []entities.RetrievalContext, error) {
 	// Generate embedding for query using Gemini
 	if h.geminiEmbedding == nil || !h.geminiEmbedding.IsAvailable() {
-		return "No API context available (embeddings not configured)", nil
+		return "No API context available (embeddings not configured)", nil, nil
 	}
 
 	embedding, err := h.geminiEmbedding.GenerateEmbedding(ctx, query)
 	if err != nil {
-		return "No API context available (embedding failed: " + err.Error() + ")", nil
+		return "No API context available (embedding failed: " + err.Error() + ")", nil, nil
 	}
 
 	// Search Qdrant with specified limit
 	results, err := h.qdrantSearch.Search(embedding, limit)
 	if err != nil {
-		return "No API context available (search failed: " + err.Error() + ")", nil
+		return "No API context available (search failed: " + err.Error() + ")", nil, nil
 	}
 
 	// Build context from results
@@ -402,7 +425,167 @@ func (h *LLMHandler) retrieveAPIContext(ctx context.Context, query string, limit
 		})
 	}
 
-	return prompts.BuildAPIContext(contexts), nil
+	return prompts.BuildAPIContext(contexts), results, nil
+}
+
+// queryParamStyles returns the array style of each query parameter of the
+// endpoint matching method and path, for parameters that set one
+func queryParamStyles(matches []entities.RetrievalContext, method, path string) map[string]string {
+	for _, match := range matches {
+		endpoints, _ := match.Config["endpoints"].([]interface{})
+		for _, ep := range endpoints {
+			epMap, ok := ep.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			epMethod, _ := epMap["method"].(string)
+			epPath, _ := epMap["path"].(string)
+			if !strings.EqualFold(epMethod, method) || epPath != path {
+				continue
+			}
+
+			styles := make(map[string]string)
+			params, _ := epMap["parameters"].([]interface{})
+			for _, p := range params {
+				pMap, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := pMap["name"].(string)
+				in, _ := pMap["in"].(string)
+				style, _ := pMap["style"].(string)
+				explode, hasExplode := pMap["explode"].(bool)
+				if name == "" || (in != "" && in != "query") {
+					continue
+				}
+				if (style == "" || style == "form") && hasExplode && !explode {
+					style = "comma"
+				}
+				if style != "" {
+					styles[name] = style
+				}
+			}
+			if len(styles) == 0 {
+				return nil
+			}
+			return styles
+		}
+	}
+	return nil
+}
+
+// matchGraphQLEndpoint returns the GraphQL endpoint a parse result chose,
+// by its operation or else its endpoint, with its API's base URL. It
+// returns nil for REST endpoints.
+func matchGraphQLEndpoint(matches []entities.RetrievalContext, parseResult map[string]interface{}) (string, map[string]interface{}) {
+	operation, _ := parseResult["operation"].(string)
+	if operation == "" {
+		operation, _ = parseResult["endpoint"].(string)
+	}
+	if operation == "" {
+		return "", nil
+	}
+
+	for _, match := range matches {
+		endpoints, _ := match.Config["endpoints"].([]interface{})
+		for _, ep := range endpoints {
+			epMap, ok := ep.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op, ok := epMap["graphql"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if field, _ := op["field"].(string); field == operation {
+				baseURL, _ := match.Config["base_url"].(string)
+				return baseURL, epMap
+			}
+		}
+	}
+	return "", nil
+}
+
+// completeGraphQLCall makes a constructed call a GraphQL POST to its
+// endpoint, falling back to the base URL and the ingested operation
+// document where the model left them out
+func completeGraphQLCall(apiCall *entities.APICall, baseURL string, endpoint map[string]interface{}) {
+	apiCall.Method = http.MethodPost
+	apiCall.Path, _ = endpoint["path"].(string)
+	if apiCall.URL == "" && baseURL != "" {
+		apiCall.URL = strings.TrimSuffix(baseURL, "/") + apiCall.Path
+	}
+	apiCall.BodyType = "graphql"
+	apiCall.QueryParams = nil
+	apiCall.EndpointName, _ = endpoint["name"].(string)
+	if apiCall.Headers == nil {
+		apiCall.Headers = make(map[string]string)
+	}
+	apiCall.Headers["Content-Type"] = "application/json"
+
+	if apiCall.Body == nil {
+		apiCall.Body = make(map[string]interface{})
+	}
+	if query, _ := apiCall.Body["query"].(string); strings.TrimSpace(query) == "" {
+		op, _ := endpoint["graphql"].(map[string]interface{})
+		apiCall.Body["query"], _ = op["document"].(string)
+	}
+	if _, ok := apiCall.Body["variables"].(map[string]interface{}); !ok {
+		apiCall.Body["variables"] = map[string]interface{}{}
+	}
+}
+
+// matchGRPCEndpoint returns the gRPC method a parse result chose, by its
+// operation or else its endpoint path, with the API it belongs to. It
+// returns nil for other endpoints.
+func matchGRPCEndpoint(matches []entities.RetrievalContext, parseResult map[string]interface{}) (entities.RetrievalContext, map[string]interface{}) {
+	operation, _ := parseResult["operation"].(string)
+	if operation == "" {
+		operation, _ = parseResult["endpoint"].(string)
+	}
+	operation = strings.TrimPrefix(operation, "/")
+	if operation == "" {
+		return entities.RetrievalContext{}, nil
+	}
+
+	for _, match := range matches {
+		endpoints, _ := match.Config["endpoints"].([]interface{})
+		for _, ep := range endpoints {
+			epMap, ok := ep.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			method, ok := epMap["grpc"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if fullMethod, _ := method["full_method"].(string); fullMethod == operation {
+				return match, epMap
+			}
+		}
+	}
+	return entities.RetrievalContext{}, nil
+}
+
+// completeGRPCCall makes a constructed call a unary gRPC call of its
+// method: a POST of the request message to the API's server, with the
+// descriptors ingested for the API
+func completeGRPCCall(apiCall *entities.APICall, match entities.RetrievalContext, endpoint map[string]interface{}) {
+	method, _ := endpoint["grpc"].(map[string]interface{})
+	fullMethod, _ := method["full_method"].(string)
+
+	apiCall.Method = http.MethodPost
+	apiCall.Path, _ = endpoint["path"].(string)
+	apiCall.URL, _ = match.Config["base_url"].(string)
+	apiCall.GRPC = &entities.GRPCCall{Method: fullMethod}
+	apiCall.APISpecID = match.APISpecID
+	apiCall.APIName = match.APIName
+	apiCall.EndpointName, _ = endpoint["name"].(string)
+	apiCall.QueryParams = nil
+	delete(apiCall.Headers, "Content-Type")
+	if apiCall.Body == nil {
+		apiCall.Body = make(map[string]interface{})
+	}
 }
 
 // extractJSON attempts to extract JSON from a response that may be wrapped in markdown
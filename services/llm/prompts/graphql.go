@@ -0,0 +1,110 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// graphQLOperationSummary describes a GraphQL operation in an API context:
+// its type, field, arguments and return type. The operation document is
+// left for the construct prompt, to keep large schemas within bounds.
+func graphQLOperationSummary(path, description string, op map[string]interface{}) string {
+	opType, _ := op["type"].(string)
+	field, _ := op["field"].(string)
+	returnType, _ := op["return_type"].(string)
+
+	part := fmt.Sprintf("\n- GraphQL %s %s (POST %s): %s", opType, field, path, description)
+	if args, ok := op["arguments"].([]interface{}); ok && len(args) > 0 {
+		part += "\n  Arguments:"
+		for _, a := range args {
+			aMap, ok := a.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := aMap["name"].(string)
+			typ, _ := aMap["type"].(string)
+			desc, _ := aMap["description"].(string)
+			part += fmt.Sprintf("\n    - %s: %s", name, typ)
+			if desc != "" {
+				part += " - " + desc
+			}
+		}
+	}
+	if returnType != "" {
+		part += "\n  Returns: " + returnType
+	}
+	return part
+}
+
+// BuildGraphQLOperationContext describes the one GraphQL operation a
+// request was matched to, with its ready-made document and example
+// variables
+func BuildGraphQLOperationContext(baseURL string, endpoint map[string]interface{}) string {
+	path, _ := endpoint["path"].(string)
+	description, _ := endpoint["description"].(string)
+	op, _ := endpoint["graphql"].(map[string]interface{})
+
+	part := ""
+	if baseURL != "" {
+		part += fmt.Sprintf("**Base URL**: %s\n", baseURL)
+	}
+	part += "**Operation:**" + graphQLOperationSummary(path, description, op)
+
+	if document, ok := op["document"].(string); ok && document != "" {
+		part += "\n\n**Operation document:**\n" + document
+	}
+	if examples, ok := endpoint["examples"].([]interface{}); ok && len(examples) > 0 {
+		if ex, ok := examples[0].(map[string]interface{}); ok {
+			if exReq, ok := ex["request"].(map[string]interface{}); ok {
+				if variables, ok := exReq["variables"]; ok {
+					varsJSON, _ := json.MarshalIndent(variables, "", "  ")
+					part += "\n\n**Example variables:**\n" + string(varsJSON)
+				}
+			}
+		}
+	}
+	return part
+}
+
+// ConstructGraphQLRequestPrompt generates a prompt for constructing a
+// GraphQL operation with its variables
+func ConstructGraphQLRequestPrompt(parseResult string, operationContext string, generatedData map[string]interface{}) string {
+	dataStr := ""
+	if len(generatedData) > 0 {
+		var pairs []string
+		for k, v := range generatedData {
+			pairs = append(pairs, fmt.Sprintf("  %s: %v", k, v))
+		}
+		dataStr = "\n## Generated Test Data\n" + strings.Join(pairs, "\n")
+	}
+
+	return fmt.Sprintf(`%s
+
+## Parse Result
+%s
+
+## GraphQL Operation
+%s
+%s
+
+## Task
+Construct the GraphQL request for this operation:
+
+1. Start from the operation document above. Keep its variable definitions; you may remove fields from the selection set the user does not care about, or add fields of the returned type they ask for.
+2. Pass every argument value as a variable - never inline values in the query. Variable values must match the argument types (numbers as numbers, enums as their bare names, input objects as JSON objects).
+3. Use values from the parse result; generate realistic test data for required arguments the user did not give.
+4. The URL is the base URL followed by the operation's path.
+
+Respond in this JSON format:
+{
+    "url": "FULL URL starting with http:// or https://",
+    "headers": {"Content-Type": "application/json"},
+    "body": {
+        "query": "the operation document",
+        "variables": {"name": "value"},
+        "operationName": "the operation's name from the document"
+    },
+    "confidence": 0.0 to 1.0
+}`, SystemPrompt, parseResult, operationContext, dataStr)
+}
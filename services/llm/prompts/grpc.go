@@ -0,0 +1,97 @@
+package prompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// grpcMethodSummary describes a gRPC method in an API context: its full
+// name, message types and the fields of its request message
+func grpcMethodSummary(description string, endpoint map[string]interface{}) string {
+	method, _ := endpoint["grpc"].(map[string]interface{})
+	fullMethod, _ := method["full_method"].(string)
+	inputType, _ := method["input_type"].(string)
+	outputType, _ := method["output_type"].(string)
+
+	part := fmt.Sprintf("\n- gRPC %s (%s -> %s): %s", fullMethod, inputType, outputType, description)
+	if params, ok := endpoint["parameters"].([]interface{}); ok && len(params) > 0 {
+		part += "\n  Request fields:"
+		for _, p := range params {
+			pMap, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := pMap["name"].(string)
+			typ, _ := pMap["type"].(string)
+			desc, _ := pMap["description"].(string)
+			part += fmt.Sprintf("\n    - %s: %s", name, typ)
+			if desc != "" {
+				part += " - " + desc
+			}
+		}
+	}
+	return part
+}
+
+// BuildGRPCMethodContext describes the one gRPC method a request was
+// matched to, with the JSON schema and an example of its request message
+func BuildGRPCMethodContext(baseURL string, endpoint map[string]interface{}) string {
+	description, _ := endpoint["description"].(string)
+
+	part := ""
+	if baseURL != "" {
+		part += fmt.Sprintf("**Server**: %s\n", baseURL)
+	}
+	part += "**Method:**" + grpcMethodSummary(description, endpoint)
+
+	if schema, ok := endpoint["request_schema"].(map[string]interface{}); ok && len(schema) > 0 {
+		schemaJSON, _ := json.MarshalIndent(schema, "", "  ")
+		part += "\n\n**Request message schema:**\n" + string(schemaJSON)
+	}
+	if examples, ok := endpoint["examples"].([]interface{}); ok && len(examples) > 0 {
+		if ex, ok := examples[0].(map[string]interface{}); ok {
+			if exReq, ok := ex["request"].(map[string]interface{}); ok {
+				exJSON, _ := json.MarshalIndent(exReq, "", "  ")
+				part += "\n\n**Example request message:**\n" + string(exJSON)
+			}
+		}
+	}
+	return part
+}
+
+// ConstructGRPCRequestPrompt generates a prompt for constructing the
+// request message of a gRPC call
+func ConstructGRPCRequestPrompt(parseResult string, methodContext string, generatedData map[string]interface{}) string {
+	dataStr := ""
+	if len(generatedData) > 0 {
+		var pairs []string
+		for k, v := range generatedData {
+			pairs = append(pairs, fmt.Sprintf("  %s: %v", k, v))
+		}
+		dataStr = "\n## Generated Test Data\n" + strings.Join(pairs, "\n")
+	}
+
+	return fmt.Sprintf(`%s
+
+## Parse Result
+%s
+
+## gRPC Method
+%s
+%s
+
+## Task
+Construct the request message for this gRPC method:
+
+1. The body is the request message in protobuf JSON: field names in lowerCamelCase as in the schema, enums by name, 64-bit integers as strings, bytes as base64, timestamps as RFC 3339 strings.
+2. Use values from the parse result; generate realistic test data for fields the request needs but the user did not give. Leave out fields that do not apply. Set at most one field of each oneof.
+3. Metadata the user asks for goes in headers, with lowercase names.
+
+Respond in this JSON format:
+{
+    "headers": {},
+    "body": {"fieldName": "value"},
+    "confidence": 0.0 to 1.0
+}`, SystemPrompt, parseResult, methodContext, dataStr)
+}
@@ -37,12 +37,17 @@ IMPORTANT: For payment/test fields like card_number, expiry_month, expiry_year,
 
 Only use null for truly business-critical fields that the user MUST specify (like payment_id, transaction type, or specific amounts the user wants).
 
+For GraphQL APIs (operations listed as "GraphQL query" or "GraphQL mutation"): set "endpoint" to the operation's path, "method" to "POST", "operation" to the query or mutation field name, and put its arguments in "parameters".
+
+For gRPC APIs (methods listed as "gRPC"): set "endpoint" to the method's path (/package.Service/Method), "method" to "POST", "operation" to the full method name (package.Service/Method), and put the request message fields in "parameters".
+
 Respond in this JSON format:
 {
     "intent": "description of what the user wants",
     "api_name": "name of the matching API",
     "endpoint": "the specific endpoint path",
     "method": "HTTP method",
+    "operation": "GraphQL query or mutation field name, or gRPC full method name (omit for REST APIs)",
     "parameters": {
         "param_name": "value, '[AUTO]' for auto-generate, or null if user must specify"
     },
@@ -117,7 +122,7 @@ Respond in this JSON format (body must be a complete, valid payload):
     "url": "FULL URL starting with http:// or https:// with all path params replaced",
     "path": "the endpoint path",
     "headers": {"Content-Type": "application/json"},
-    "query_params": {},
+    "query_params": {"name": "value", "array_param": ["value1", "value2"]},
     "body": {COMPLETE request body with ALL required nested objects},
     "confidence": 0.0 to 1.0
 }`, SystemPrompt, parseResult, apiConfig, contextStr, dataStr)
@@ -183,6 +188,17 @@ func BuildAPIContext(contexts []map[string]interface{}) string {
 						method, _ := epMap["method"].(string)
 						path, _ := epMap["path"].(string)
 						epDesc, _ := epMap["description"].(string)
+
+						// GraphQL operations all share one path
+						if op, ok := epMap["graphql"].(map[string]interface{}); ok {
+							part += graphQLOperationSummary(path, epDesc, op)
+							continue
+						}
+						if _, ok := epMap["grpc"].(map[string]interface{}); ok {
+							part += grpcMethodSummary(epDesc, epMap)
+							continue
+						}
+
 						part += fmt.Sprintf("\n- %s %s: %s", method, path, epDesc)
 
 						// Include parameters info
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/query/application/usecases"
+	"github.com/testpilot-ai/query/domain/repositories"
+	"github.com/testpilot-ai/shared/logger"
+)
+
+// AuditHandler handles audit log HTTP requests (admin only)
+type AuditHandler struct {
+	auditUseCase *usecases.GetAuditLogUseCase
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditUseCase *usecases.GetAuditLogUseCase) *AuditHandler {
+	return &AuditHandler{auditUseCase: auditUseCase}
+}
+
+// ListAuditLog retrieves a filtered, paginated page of audit entries
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	filters := parseAuditFilters(c)
+	entries, total, err := h.auditUseCase.Execute(c.Request.Context(), filters)
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error().Err(err).Msg("Failed to list audit log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// ExportAuditLog streams the filtered audit log as CSV
+func (h *AuditHandler) ExportAuditLog(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	entries, err := h.auditUseCase.Export(c.Request.Context(), parseAuditFilters(c))
+	if err != nil {
+		logger.WithContext(c.Request.Context()).Error().Err(err).Msg("Failed to export audit log")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit log"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"id", "occurred_at", "source", "actor_id", "actor_role", "action",
+		"target_type", "target_id", "before_state", "after_state",
+		"request_id", "ip_address", "metadata",
+	})
+	for _, e := range entries {
+		actorID := ""
+		if e.ActorID != nil {
+			actorID = e.ActorID.String()
+		}
+		w.Write([]string{
+			e.ID.String(),
+			e.OccurredAt.UTC().Format(time.RFC3339),
+			e.Source,
+			actorID,
+			e.ActorRole,
+			e.Action,
+			e.TargetType,
+			e.TargetID,
+			jsonCell(e.BeforeState),
+			jsonCell(e.AfterState),
+			e.RequestID,
+			e.IPAddress,
+			jsonCell(e.Metadata),
+		})
+	}
+	w.Flush()
+}
+
+// isAdmin checks the role forwarded by the gateway after JWT validation
+func isAdmin(c *gin.Context) bool {
+	return c.GetHeader("X-User-Role") == "admin"
+}
+
+// parseAuditFilters reads audit filters from the query string
+func parseAuditFilters(c *gin.Context) repositories.AuditFilters {
+	filters := repositories.AuditFilters{
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Source:     c.Query("source"),
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filters.Limit = limit
+		}
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			filters.Offset = offset
+		}
+	}
+
+	if actorID := c.Query("actor_id"); actorID != "" {
+		if id, err := uuid.Parse(actorID); err == nil {
+			filters.ActorID = &id
+		}
+	}
+
+	// Accept both YYYY-MM-DD and RFC3339 formats, matching the history endpoint
+	if fromDate := c.Query("from_date"); fromDate != "" {
+		if t, err := time.Parse("2006-01-02", fromDate); err == nil {
+			filters.StartDate = &t
+		} else if t, err := time.Parse(time.RFC3339, fromDate); err == nil {
+			filters.StartDate = &t
+		}
+	}
+
+	if toDate := c.Query("to_date"); toDate != "" {
+		if t, err := time.Parse("2006-01-02", toDate); err == nil {
+			endOfDay := t.Add(24*time.Hour - time.Second)
+			filters.EndDate = &endOfDay
+		} else if t, err := time.Parse(time.RFC3339, toDate); err == nil {
+			filters.EndDate = &t
+		}
+	}
+
+	return filters
+}
+
+// jsonCell renders a JSON column for a CSV cell
+func jsonCell(value map[string]interface{}) string {
+	if value == nil {
+		return ""
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
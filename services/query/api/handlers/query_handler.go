@@ -155,6 +155,50 @@ func (h *QueryHandler) GetAPIAnalytics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetTimingAnalytics retrieves HTTP phase percentiles, optionally filtered
+// by API spec, environment and date range
+func (h *QueryHandler) GetTimingAnalytics(c *gin.Context) {
+	var filters repositories.TimingFilters
+
+	if idStr := c.Query("api_spec_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API spec ID"})
+			return
+		}
+		filters.APISpecID = &id
+	}
+
+	if idStr := c.Query("environment_id"); idStr != "" {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid environment ID"})
+			return
+		}
+		filters.EnvironmentID = &id
+	}
+
+	if start := c.Query("start_date"); start != "" {
+		if t, err := time.Parse(time.RFC3339, start); err == nil {
+			filters.StartDate = &t
+		}
+	}
+
+	if end := c.Query("end_date"); end != "" {
+		if t, err := time.Parse(time.RFC3339, end); err == nil {
+			filters.EndDate = &t
+		}
+	}
+
+	timing, err := h.analyticsUseCase.GetTiming(c.Request.Context(), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, timing)
+}
+
 // HealthCheck handles health check requests
 func (h *QueryHandler) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
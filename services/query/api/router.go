@@ -6,7 +6,7 @@ import (
 )
 
 // SetupRouter configures the Gin router
-func SetupRouter(handler *handlers.QueryHandler) *gin.Engine {
+func SetupRouter(handler *handlers.QueryHandler, auditHandler *handlers.AuditHandler) *gin.Engine {
 	// Use gin.New() to avoid default logger noise
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -28,7 +28,12 @@ func SetupRouter(handler *handlers.QueryHandler) *gin.Engine {
 		{
 			analytics.GET("/overview", handler.GetAnalyticsOverview)
 			analytics.GET("/by-api/:id", handler.GetAPIAnalytics)
+			analytics.GET("/timing", handler.GetTimingAnalytics)
 		}
+
+		// Audit log endpoints (admin only)
+		v1.GET("/audit", auditHandler.ListAuditLog)
+		v1.GET("/audit/export", auditHandler.ExportAuditLog)
 	}
 
 	return router
@@ -29,6 +29,11 @@ func (uc *GetAnalyticsUseCase) GetByAPI(ctx context.Context, apiSpecID uuid.UUID
 	return uc.repo.GetAPIAnalytics(ctx, apiSpecID)
 }
 
+// GetTiming retrieves HTTP phase percentiles
+func (uc *GetAnalyticsUseCase) GetTiming(ctx context.Context, filters repositories.TimingFilters) (*entities.TimingAnalytics, error) {
+	return uc.repo.GetTimingAnalytics(ctx, filters)
+}
+
 
 
 
@@ -0,0 +1,40 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/testpilot-ai/query/domain/entities"
+	"github.com/testpilot-ai/query/domain/repositories"
+)
+
+// MaxAuditExportRows caps the number of rows in a single CSV export
+const MaxAuditExportRows = 10000
+
+// GetAuditLogUseCase handles audit log retrieval
+type GetAuditLogUseCase struct {
+	repo repositories.AuditRepository
+}
+
+// NewGetAuditLogUseCase creates a new use case
+func NewGetAuditLogUseCase(repo repositories.AuditRepository) *GetAuditLogUseCase {
+	return &GetAuditLogUseCase{repo: repo}
+}
+
+// Execute retrieves a page of audit entries
+func (uc *GetAuditLogUseCase) Execute(ctx context.Context, filters repositories.AuditFilters) ([]entities.AuditEntry, int64, error) {
+	if filters.Limit <= 0 {
+		filters.Limit = 50
+	}
+	if filters.Limit > 500 {
+		filters.Limit = 500
+	}
+	return uc.repo.ListAuditEntries(ctx, filters)
+}
+
+// Export retrieves all matching audit entries for export, up to MaxAuditExportRows
+func (uc *GetAuditLogUseCase) Export(ctx context.Context, filters repositories.AuditFilters) ([]entities.AuditEntry, error) {
+	filters.Limit = MaxAuditExportRows
+	filters.Offset = 0
+	entries, _, err := uc.repo.ListAuditEntries(ctx, filters)
+	return entries, err
+}
@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry represents a single append-only audit log record
+type AuditEntry struct {
+	ID          uuid.UUID              `json:"id"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+	Source      string                 `json:"source"`
+	ActorID     *uuid.UUID             `json:"actor_id"`
+	ActorRole   string                 `json:"actor_role,omitempty"`
+	Action      string                 `json:"action"`
+	TargetType  string                 `json:"target_type,omitempty"`
+	TargetID    string                 `json:"target_id,omitempty"`
+	BeforeState map[string]interface{} `json:"before_state,omitempty"`
+	AfterState  map[string]interface{} `json:"after_state,omitempty"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	IPAddress   string                 `json:"ip_address,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
@@ -8,16 +8,19 @@ import (
 
 // TestExecution represents a test execution record
 type TestExecution struct {
-	ID                      uuid.UUID              `json:"id"`
-	UserID                  *uuid.UUID             `json:"user_id"`
-	APISpecID               *uuid.UUID             `json:"api_spec_id"`
-	NaturalLanguageRequest  string                 `json:"natural_language_request"`
-	ConstructedRequest      map[string]interface{} `json:"constructed_request"`
-	Response                map[string]interface{} `json:"response"`
-	ValidationResult        map[string]interface{} `json:"validation_result"`
-	Status                  string                 `json:"status"` // success, failed, error
-	ExecutionTimeMs         int64                  `json:"execution_time_ms"`
-	CreatedAt               time.Time              `json:"created_at"`
+	ID                     uuid.UUID                `json:"id"`
+	UserID                 *uuid.UUID               `json:"user_id"`
+	APISpecID              *uuid.UUID               `json:"api_spec_id"`
+	NaturalLanguageRequest string                   `json:"natural_language_request"`
+	ConstructedRequest     map[string]interface{}   `json:"constructed_request"`
+	Response               map[string]interface{}   `json:"response"`
+	ValidationResult       map[string]interface{}   `json:"validation_result"`
+	Status                 string                   `json:"status"` // success, failed, error
+	ExecutionTimeMs        int64                    `json:"execution_time_ms"`
+	Timing                 map[string]interface{}   `json:"timing,omitempty"`
+	Attempts               []map[string]interface{} `json:"attempts,omitempty"`
+	Callbacks              []map[string]interface{} `json:"callbacks,omitempty"` // received by receivers the execution opened
+	CreatedAt              time.Time                `json:"created_at"`
 }
 
 // Analytics represents aggregated statistics
@@ -39,6 +42,24 @@ type APIStats struct {
 	SuccessRate  float64 `json:"success_rate"`
 }
 
+// TimingAnalytics summarises the HTTP phase timings of executions
+type TimingAnalytics struct {
+	SampleCount         int64            `json:"sample_count"`
+	ConnectionReuseRate float64          `json:"connection_reuse_rate"`
+	DNS                 PhasePercentiles `json:"dns_ms"`
+	Connect             PhasePercentiles `json:"connect_ms"`
+	TLS                 PhasePercentiles `json:"tls_ms"`
+	TTFB                PhasePercentiles `json:"ttfb_ms"`
+	Transfer            PhasePercentiles `json:"transfer_ms"`
+	Total               PhasePercentiles `json:"total_ms"`
+}
 
-
-
+// PhasePercentiles are the distribution of one phase, in milliseconds
+type PhasePercentiles struct {
+	Samples int64   `json:"samples"`
+	Avg     float64 `json:"avg"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+}
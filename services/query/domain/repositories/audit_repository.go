@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/testpilot-ai/query/domain/entities"
+)
+
+// AuditRepository defines read access to the audit log
+type AuditRepository interface {
+	// ListAuditEntries retrieves audit entries with filters, newest first
+	ListAuditEntries(ctx context.Context, filters AuditFilters) ([]entities.AuditEntry, int64, error)
+}
+
+// AuditFilters for querying the audit log
+type AuditFilters struct {
+	ActorID    *uuid.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Source     string
+	StartDate  *time.Time
+	EndDate    *time.Time
+	Limit      int
+	Offset     int
+}
@@ -27,6 +27,17 @@ type QueryRepository interface {
 	
 	// GetAPIAnalytics retrieves per-API statistics
 	GetAPIAnalytics(ctx context.Context, apiSpecID uuid.UUID) (*entities.APIStats, error)
+
+	// GetTimingAnalytics retrieves HTTP phase percentiles
+	GetTimingAnalytics(ctx context.Context, filters TimingFilters) (*entities.TimingAnalytics, error)
+}
+
+// TimingFilters narrow the executions included in timing analytics
+type TimingFilters struct {
+	APISpecID     *uuid.UUID
+	EnvironmentID *uuid.UUID
+	StartDate     *time.Time
+	EndDate       *time.Time
 }
 
 // Filters for querying executions
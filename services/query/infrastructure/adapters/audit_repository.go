@@ -0,0 +1,122 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testpilot-ai/query/domain/entities"
+	"github.com/testpilot-ai/query/domain/repositories"
+)
+
+// PostgresAuditRepository implements read access to the audit log
+type PostgresAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAuditRepository creates a new audit repository
+func NewPostgresAuditRepository(pool *pgxpool.Pool) *PostgresAuditRepository {
+	return &PostgresAuditRepository{pool: pool}
+}
+
+// ListAuditEntries retrieves audit entries with filters, newest first
+func (r *PostgresAuditRepository) ListAuditEntries(ctx context.Context, filters repositories.AuditFilters) ([]entities.AuditEntry, int64, error) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	argCount := 1
+
+	addFilter := func(clause string, value interface{}) {
+		where = append(where, fmt.Sprintf(clause, argCount))
+		args = append(args, value)
+		argCount++
+	}
+
+	if filters.ActorID != nil {
+		addFilter("actor_id = $%d", *filters.ActorID)
+	}
+	if filters.Action != "" {
+		addFilter("action = $%d", filters.Action)
+	}
+	if filters.TargetType != "" {
+		addFilter("target_type = $%d", filters.TargetType)
+	}
+	if filters.TargetID != "" {
+		addFilter("target_id = $%d", filters.TargetID)
+	}
+	if filters.Source != "" {
+		addFilter("source = $%d", filters.Source)
+	}
+	if filters.StartDate != nil {
+		addFilter("occurred_at >= $%d", *filters.StartDate)
+	}
+	if filters.EndDate != nil {
+		addFilter("occurred_at <= $%d", *filters.EndDate)
+	}
+
+	whereClause := strings.Join(where, " AND ")
+
+	var total int64
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_log WHERE %s", whereClause)
+	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, occurred_at, source, actor_id, COALESCE(actor_role, ''), action,
+		       COALESCE(target_type, ''), COALESCE(target_id, ''), before_state, after_state,
+		       COALESCE(request_id, ''), COALESCE(ip_address, ''), metadata
+		FROM audit_log
+		WHERE %s
+		ORDER BY occurred_at DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argCount, argCount+1)
+
+	args = append(args, filters.Limit, filters.Offset)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []entities.AuditEntry{}
+	for rows.Next() {
+		var entry entities.AuditEntry
+		var before, after, metadata []byte
+
+		err := rows.Scan(
+			&entry.ID,
+			&entry.OccurredAt,
+			&entry.Source,
+			&entry.ActorID,
+			&entry.ActorRole,
+			&entry.Action,
+			&entry.TargetType,
+			&entry.TargetID,
+			&before,
+			&after,
+			&entry.RequestID,
+			&entry.IPAddress,
+			&metadata,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+
+		if len(before) > 0 {
+			json.Unmarshal(before, &entry.BeforeState)
+		}
+		if len(after) > 0 {
+			json.Unmarshal(after, &entry.AfterState)
+		}
+		if len(metadata) > 0 {
+			json.Unmarshal(metadata, &entry.Metadata)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
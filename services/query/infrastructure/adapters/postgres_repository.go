@@ -28,13 +28,13 @@ func (r *PostgresQueryRepository) FindExecutionByID(ctx context.Context, id uuid
 	query := `
 		SELECT id, user_id, api_spec_id, natural_language_request,
 		       constructed_request, response, validation_result,
-		       status, execution_time_ms, created_at
+		       status, execution_time_ms, timing, attempts, created_at
 		FROM test_executions
 		WHERE id = $1
 	`
 
 	var exec entities.TestExecution
-	var constructedReq, response, validationResult []byte
+	var constructedReq, response, validationResult, timing, attempts []byte
 
 	err := r.pool.QueryRow(ctx, query, id).Scan(
 		&exec.ID,
@@ -46,6 +46,8 @@ func (r *PostgresQueryRepository) FindExecutionByID(ctx context.Context, id uuid
 		&validationResult,
 		&exec.Status,
 		&exec.ExecutionTimeMs,
+		&timing,
+		&attempts,
 		&exec.CreatedAt,
 	)
 	if err != nil {
@@ -55,10 +57,60 @@ func (r *PostgresQueryRepository) FindExecutionByID(ctx context.Context, id uuid
 	json.Unmarshal(constructedReq, &exec.ConstructedRequest)
 	json.Unmarshal(response, &exec.Response)
 	json.Unmarshal(validationResult, &exec.ValidationResult)
+	json.Unmarshal(timing, &exec.Timing)
+	json.Unmarshal(attempts, &exec.Attempts)
+
+	callbacks, err := r.findExecutionCallbacks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	exec.Callbacks = callbacks
 
 	return &exec, nil
 }
 
+// findExecutionCallbacks retrieves the callbacks received by the receivers an
+// execution opened, oldest first
+func (r *PostgresQueryRepository) findExecutionCallbacks(ctx context.Context, executionID uuid.UUID) ([]map[string]interface{}, error) {
+	query := `
+		SELECT jsonb_build_object(
+			'id', rc.id,
+			'receiver_id', rc.receiver_id,
+			'method', rc.method,
+			'query', rc.query,
+			'headers', rc.headers,
+			'body', rc.body,
+			'truncated', rc.truncated,
+			'signature_valid', rc.signature_valid,
+			'signature_error', rc.signature_error,
+			'remote_addr', rc.remote_addr,
+			'received_at', rc.received_at
+		)
+		FROM received_callbacks rc
+		JOIN callback_receivers cr ON cr.id = rc.receiver_id
+		WHERE cr.execution_id = $1
+		ORDER BY rc.received_at, rc.id
+	`
+
+	rows, err := r.pool.Query(ctx, query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var callbacks []map[string]interface{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var callback map[string]interface{}
+		json.Unmarshal(raw, &callback)
+		callbacks = append(callbacks, callback)
+	}
+	return callbacks, rows.Err()
+}
+
 // ListExecutions retrieves executions with filters
 func (r *PostgresQueryRepository) ListExecutions(ctx context.Context, filters repositories.Filters) ([]entities.TestExecution, int64, error) {
 	// Build query dynamically
@@ -117,7 +169,7 @@ func (r *PostgresQueryRepository) ListExecutions(ctx context.Context, filters re
 	query := fmt.Sprintf(`
 		SELECT id, user_id, api_spec_id, natural_language_request,
 		       constructed_request, response, validation_result,
-		       status, execution_time_ms, created_at
+		       status, execution_time_ms, timing, attempts, created_at
 		FROM test_executions
 		WHERE %s
 		ORDER BY created_at DESC
@@ -135,7 +187,7 @@ func (r *PostgresQueryRepository) ListExecutions(ctx context.Context, filters re
 	var executions []entities.TestExecution
 	for rows.Next() {
 		var exec entities.TestExecution
-		var constructedReq, response, validationResult []byte
+		var constructedReq, response, validationResult, timing, attempts []byte
 
 		err := rows.Scan(
 			&exec.ID,
@@ -147,6 +199,8 @@ func (r *PostgresQueryRepository) ListExecutions(ctx context.Context, filters re
 			&validationResult,
 			&exec.Status,
 			&exec.ExecutionTimeMs,
+			&timing,
+			&attempts,
 			&exec.CreatedAt,
 		)
 		if err != nil {
@@ -156,6 +210,8 @@ func (r *PostgresQueryRepository) ListExecutions(ctx context.Context, filters re
 		json.Unmarshal(constructedReq, &exec.ConstructedRequest)
 		json.Unmarshal(response, &exec.Response)
 		json.Unmarshal(validationResult, &exec.ValidationResult)
+		json.Unmarshal(timing, &exec.Timing)
+		json.Unmarshal(attempts, &exec.Attempts)
 
 		executions = append(executions, exec)
 	}
@@ -222,6 +278,87 @@ func (r *PostgresQueryRepository) GetAPIAnalytics(ctx context.Context, apiSpecID
 	return stats, nil
 }
 
+// timingPhase maps a timing JSON field to its aggregate. Connection setup
+// phases only count executions that opened a new connection.
+type timingPhase struct {
+	field       string
+	newConnOnly bool
+	target      *entities.PhasePercentiles
+}
+
+// GetTimingAnalytics retrieves HTTP phase percentiles
+func (r *PostgresQueryRepository) GetTimingAnalytics(ctx context.Context, filters repositories.TimingFilters) (*entities.TimingAnalytics, error) {
+	analytics := &entities.TimingAnalytics{}
+	phases := []timingPhase{
+		{"dns_ms", true, &analytics.DNS},
+		{"connect_ms", true, &analytics.Connect},
+		{"tls_ms", true, &analytics.TLS},
+		{"ttfb_ms", false, &analytics.TTFB},
+		{"transfer_ms", false, &analytics.Transfer},
+		{"total_ms", false, &analytics.Total},
+	}
+
+	columns := []string{
+		"COUNT(*)",
+		"COALESCE(AVG(CASE WHEN (timing->>'connection_reused')::boolean THEN 1.0 ELSE 0.0 END), 0)",
+	}
+	for _, phase := range phases {
+		value := fmt.Sprintf("(timing->>'%s')::float8", phase.field)
+		filter := ""
+		if phase.newConnOnly {
+			filter = " FILTER (WHERE NOT (timing->>'connection_reused')::boolean)"
+		}
+		columns = append(columns,
+			fmt.Sprintf("COUNT(%s)%s", value, filter),
+			fmt.Sprintf("COALESCE(AVG(%s)%s, 0)", value, filter),
+			fmt.Sprintf("percentile_cont(ARRAY[0.5, 0.9, 0.95, 0.99]) WITHIN GROUP (ORDER BY %s)%s", value, filter),
+		)
+	}
+
+	where := []string{"timing IS NOT NULL"}
+	args := []interface{}{}
+	if filters.APISpecID != nil {
+		args = append(args, *filters.APISpecID)
+		where = append(where, fmt.Sprintf("api_spec_id = $%d", len(args)))
+	}
+	if filters.EnvironmentID != nil {
+		args = append(args, *filters.EnvironmentID)
+		where = append(where, fmt.Sprintf("environment_id = $%d", len(args)))
+	}
+	if filters.StartDate != nil {
+		args = append(args, *filters.StartDate)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filters.EndDate != nil {
+		args = append(args, *filters.EndDate)
+		where = append(where, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM test_executions WHERE %s",
+		strings.Join(columns, ",\n\t\t"), strings.Join(where, " AND "))
+
+	percentiles := make([][]float64, len(phases))
+	dest := []interface{}{&analytics.SampleCount, &analytics.ConnectionReuseRate}
+	for i, phase := range phases {
+		dest = append(dest, &phase.target.Samples, &phase.target.Avg, &percentiles[i])
+	}
+
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	for i, phase := range phases {
+		if len(percentiles[i]) == 4 {
+			phase.target.P50 = percentiles[i][0]
+			phase.target.P90 = percentiles[i][1]
+			phase.target.P95 = percentiles[i][2]
+			phase.target.P99 = percentiles[i][3]
+		}
+	}
+
+	return analytics, nil
+}
+
 // DeleteExecution removes an execution by ID
 func (r *PostgresQueryRepository) DeleteExecution(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM test_executions WHERE id = $1`
@@ -37,16 +37,19 @@ func main() {
 
 	// Initialize repository
 	repo := adapters.NewPostgresQueryRepository(pool)
+	auditRepo := adapters.NewPostgresAuditRepository(pool)
 
 	// Initialize use cases
 	historyUseCase := usecases.NewGetTestHistoryUseCase(repo)
 	analyticsUseCase := usecases.NewGetAnalyticsUseCase(repo)
+	auditUseCase := usecases.NewGetAuditLogUseCase(auditRepo)
 
 	// Initialize handler
 	handler := handlers.NewQueryHandler(historyUseCase, analyticsUseCase)
+	auditHandler := handlers.NewAuditHandler(auditUseCase)
 
 	// Setup router
-	router := api.SetupRouter(handler)
+	router := api.SetupRouter(handler, auditHandler)
 
 	// Start server
 	port := os.Getenv("SERVER_PORT")
@@ -55,6 +55,48 @@ func (v *JSONSchemaValidator) ValidateStatus(actual, expected int) *entities.Sta
 	}
 }
 
+// ValidateGraphQL checks a GraphQL response: it must have data or errors,
+// and any errors fail it. GraphQL servers usually report errors with
+// status 200, so the status check alone does not catch them.
+func (v *JSONSchemaValidator) ValidateGraphQL(response map[string]interface{}) *entities.GraphQLCheckResult {
+	result := &entities.GraphQLCheckResult{IsValid: true}
+	data, hasData := response["data"]
+	result.HasData = hasData && data != nil
+
+	rawErrors, hasErrors := response["errors"]
+	if !hasData && !hasErrors {
+		result.IsValid = false
+		result.Errors = []string{"GraphQL response has neither data nor errors"}
+		return result
+	}
+
+	list, ok := rawErrors.([]interface{})
+	if hasErrors && rawErrors != nil && !ok {
+		result.IsValid = false
+		result.Errors = []string{"GraphQL response errors is not a list"}
+		return result
+	}
+	for _, item := range list {
+		message := fmt.Sprintf("%v", item)
+		if entry, ok := item.(map[string]interface{}); ok {
+			message, _ = entry["message"].(string)
+			if message == "" {
+				message = "unknown error"
+			}
+			if path, ok := entry["path"].([]interface{}); ok && len(path) > 0 {
+				segments := make([]string, len(path))
+				for i, segment := range path {
+					segments[i] = fmt.Sprintf("%v", segment)
+				}
+				message = strings.Join(segments, ".") + ": " + message
+			}
+		}
+		result.IsValid = false
+		result.Errors = append(result.Errors, "GraphQL error: "+message)
+	}
+	return result
+}
+
 // CompareResponses compares two responses and returns differences
 func (v *JSONSchemaValidator) CompareResponses(current, previous map[string]interface{}) *entities.DiffResult {
 	result := &entities.DiffResult{HasDifferences: false}
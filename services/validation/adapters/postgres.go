@@ -89,6 +89,30 @@ func (r *PostgresRepository) GetAllRules(ctx context.Context) ([]entities.Valida
 	return rules, nil
 }
 
+// GetRuleByID retrieves a single validation rule
+func (r *PostgresRepository) GetRuleByID(ctx context.Context, id uuid.UUID) (*entities.ValidationRule, error) {
+	query := `
+		SELECT id, api_spec_id, rule_type, rule_definition, created_at, updated_at
+		FROM validation_rules
+		WHERE id = $1
+	`
+
+	var rule entities.ValidationRule
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&rule.ID,
+		&rule.APISpecID,
+		&rule.RuleType,
+		&rule.RuleDefinition,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rule: %w", err)
+	}
+
+	return &rule, nil
+}
+
 // CreateRule creates a new validation rule
 func (r *PostgresRepository) CreateRule(ctx context.Context, rule *entities.ValidationRule) error {
 	query := `
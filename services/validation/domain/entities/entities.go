@@ -21,6 +21,7 @@ type ValidationResult struct {
 	IsValid       bool              `json:"is_valid"`
 	StatusCheck   *StatusCheckResult `json:"status_check,omitempty"`
 	SchemaCheck   *SchemaCheckResult `json:"schema_check,omitempty"`
+	GraphQLCheck  *GraphQLCheckResult `json:"graphql_check,omitempty"`
 	CustomChecks  []CustomCheckResult `json:"custom_checks,omitempty"`
 	Errors        []string          `json:"errors,omitempty"`
 	Warnings      []string          `json:"warnings,omitempty"`
@@ -40,6 +41,14 @@ type SchemaCheckResult struct {
 	Errors  []string `json:"errors,omitempty"`
 }
 
+// GraphQLCheckResult represents the check of a GraphQL response's errors.
+// A response with errors fails, even alongside partial data.
+type GraphQLCheckResult struct {
+	IsValid bool     `json:"is_valid"`
+	HasData bool     `json:"has_data"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
 // CustomCheckResult represents a custom rule check result
 type CustomCheckResult struct {
 	RuleName string `json:"rule_name"`
@@ -52,6 +61,7 @@ type ValidationRequest struct {
 	APISpecID       *uuid.UUID             `json:"api_spec_id,omitempty"`
 	Response        map[string]interface{} `json:"response"`
 	StatusCode      int                    `json:"status_code"`
+	Protocol        string                 `json:"protocol,omitempty"` // rest (the default) or graphql
 	ExpectedStatus  int                    `json:"expected_status,omitempty"`
 	ExpectedSchema  map[string]interface{} `json:"expected_schema,omitempty"`
 	PreviousSuccess map[string]interface{} `json:"previous_success,omitempty"` // For comparison
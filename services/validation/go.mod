@@ -7,12 +7,15 @@ require (
 	github.com/google/uuid v1.5.0
 	github.com/jackc/pgx/v5 v5.5.0
 	github.com/joho/godotenv v1.5.1
+	github.com/testpilot-ai/shared/audit v0.0.0-00010101000000-000000000000
 	github.com/testpilot-ai/shared/logger v0.0.0-00010101000000-000000000000
 	github.com/xeipuuv/gojsonschema v1.2.0
 )
 
 replace github.com/testpilot-ai/shared/logger => ../../shared/logger
 
+replace github.com/testpilot-ai/shared/audit => ../../shared/audit
+
 require (
 	github.com/bytedance/sonic v1.9.1 // indirect
 	github.com/chenzhuoyu/base64x v0.0.0-20221115062448-fe3a3abad311 // indirect
@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/testpilot-ai/shared/audit"
+	"github.com/testpilot-ai/shared/logger"
 	"github.com/testpilot-ai/validation/adapters"
 	"github.com/testpilot-ai/validation/domain/entities"
 )
@@ -14,16 +16,19 @@ import (
 type ValidationHandler struct {
 	schemaValidator *adapters.JSONSchemaValidator
 	postgresRepo    *adapters.PostgresRepository
+	auditRecorder   *audit.Recorder
 }
 
 // NewValidationHandler creates a new validation handler
 func NewValidationHandler(
 	schemaValidator *adapters.JSONSchemaValidator,
 	postgresRepo *adapters.PostgresRepository,
+	auditRecorder *audit.Recorder,
 ) *ValidationHandler {
 	return &ValidationHandler{
 		schemaValidator: schemaValidator,
 		postgresRepo:    postgresRepo,
+		auditRecorder:   auditRecorder,
 	}
 }
 
@@ -59,6 +64,15 @@ func (h *ValidationHandler) Validate(c *gin.Context) {
 		}
 	}
 
+	// GraphQL responses carry their errors in the body, usually with 200
+	if req.Protocol == "graphql" {
+		result.GraphQLCheck = h.schemaValidator.ValidateGraphQL(req.Response)
+		if !result.GraphQLCheck.IsValid {
+			result.IsValid = false
+			result.Errors = append(result.Errors, result.GraphQLCheck.Errors...)
+		}
+	}
+
 	// Schema validation
 	if req.ExpectedSchema != nil {
 		result.SchemaCheck = h.schemaValidator.ValidateSchema(req.Response, req.ExpectedSchema)
@@ -137,6 +151,10 @@ func (h *ValidationHandler) CreateRule(c *gin.Context) {
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "rule.create", "validation_rule", rule.ID.String())
+	event.After = rule
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusCreated, rule)
 }
 
@@ -155,12 +173,25 @@ func (h *ValidationHandler) UpdateRule(c *gin.Context) {
 		return
 	}
 
+	before, err := h.postgresRepo.GetRuleByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
 	rule.ID = id
+	rule.APISpecID = before.APISpecID
+	rule.CreatedAt = before.CreatedAt
 	if err := h.postgresRepo.UpdateRule(c.Request.Context(), &rule); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "rule.update", "validation_rule", idParam)
+	event.Before = before
+	event.After = rule
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusOK, rule)
 }
 
@@ -173,11 +204,29 @@ func (h *ValidationHandler) DeleteRule(c *gin.Context) {
 		return
 	}
 
+	before, err := h.postgresRepo.GetRuleByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		return
+	}
+
 	if err := h.postgresRepo.DeleteRule(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
 		return
 	}
 
+	event := audit.FromRequest(c.Request, "rule.delete", "validation_rule", idParam)
+	event.Before = before
+	h.recordAudit(c, event)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
 }
 
+// recordAudit writes an audit entry, logging rather than failing the request on error
+func (h *ValidationHandler) recordAudit(c *gin.Context, event audit.Event) {
+	if err := h.auditRecorder.Record(c.Request.Context(), event); err != nil {
+		logger.WithRequestID(event.RequestID).Err(err).
+			Str("action", event.Action).
+			Msg("Failed to write audit entry")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/testpilot-ai/validation/adapters"
 	"github.com/testpilot-ai/validation/config"
 	"github.com/testpilot-ai/validation/handlers"
+	"github.com/testpilot-ai/shared/audit"
 	"github.com/testpilot-ai/shared/logger"
 )
 
@@ -48,7 +49,7 @@ func main() {
 	postgresRepo := adapters.NewPostgresRepository(pool)
 
 	// Initialize handlers
-	validationHandler := handlers.NewValidationHandler(schemaValidator, postgresRepo)
+	validationHandler := handlers.NewValidationHandler(schemaValidator, postgresRepo, audit.NewRecorder(pool, "validation"))
 
 	// Setup router (use gin.New() to avoid default logger noise)
 	router := gin.New()
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// recordTimeout bounds how long writing a single audit entry may take
+const recordTimeout = 5 * time.Second
+
+// Event describes a single security-relevant or destructive action
+type Event struct {
+	ActorID    *uuid.UUID
+	ActorRole  string
+	Action     string // e.g. "api_spec.delete", "user.create", "http.post"
+	TargetType string // e.g. "api_spec", "validation_rule", "environment"
+	TargetID   string
+	Before     interface{}
+	After      interface{}
+	RequestID  string
+	IPAddress  string
+	Metadata   map[string]interface{}
+}
+
+// Recorder appends audit events to the audit_log table
+type Recorder struct {
+	pool   *pgxpool.Pool
+	source string
+}
+
+// NewRecorder creates a new recorder; source identifies the writing service
+func NewRecorder(pool *pgxpool.Pool, source string) *Recorder {
+	return &Recorder{pool: pool, source: source}
+}
+
+// Record appends an event to the audit log. The write is detached from the
+// caller's cancellation so that a client disconnect does not drop the entry.
+func (r *Recorder) Record(ctx context.Context, event Event) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	before, err := marshalNullable(event.Before)
+	if err != nil {
+		return fmt.Errorf("failed to marshal before state: %w", err)
+	}
+	after, err := marshalNullable(event.After)
+	if err != nil {
+		return fmt.Errorf("failed to marshal after state: %w", err)
+	}
+	var metadata []byte
+	if len(event.Metadata) > 0 {
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (
+			id, occurred_at, source, actor_id, actor_role, action,
+			target_type, target_id, before_state, after_state,
+			request_id, ip_address, metadata
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err = r.pool.Exec(ctx, query,
+		uuid.New(),
+		time.Now(),
+		r.source,
+		event.ActorID,
+		nullableString(event.ActorRole),
+		event.Action,
+		nullableString(event.TargetType),
+		nullableString(event.TargetID),
+		before,
+		after,
+		nullableString(event.RequestID),
+		nullableString(event.IPAddress),
+		metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// FromRequest builds an event pre-filled with the actor, request ID and
+// client IP that the gateway forwards to backend services.
+func FromRequest(r *http.Request, action, targetType, targetID string) Event {
+	event := Event{
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ActorRole:  r.Header.Get("X-User-Role"),
+		RequestID:  r.Header.Get("X-Request-ID"),
+		IPAddress:  ClientIP(r),
+	}
+
+	if userID, err := uuid.Parse(r.Header.Get("X-User-ID")); err == nil {
+		event.ActorID = &userID
+	}
+
+	return event
+}
+
+// ClientIP returns the originating client IP, preferring X-Forwarded-For
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// marshalNullable marshals v to JSON, returning nil for nil values
+func marshalNullable(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// nullableString converts empty strings to NULL
+func nullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,196 @@
+// Package crypto envelope-encrypts secrets the services store. Each value
+// has its own AES-256-GCM data key, wrapped by a master key; the master keys
+// are configured as SECRETS_MASTER_KEYS and SECRETS_ACTIVE_KEY_ID.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// keySize is the AES-256 key length used for master and data keys
+const keySize = 32
+
+// ErrUnknownKey is returned for values wrapped by a master key that is no
+// longer configured
+var ErrUnknownKey = errors.New("value is wrapped by an unknown master key")
+
+// Envelope is an encrypted value with its wrapped data key
+type Envelope struct {
+	Ciphertext []byte
+	Nonce      []byte
+	WrappedKey []byte
+	KeyNonce   []byte
+	KeyID      string
+}
+
+// Keyring holds the master keys used to wrap per-value data keys. New
+// values are always wrapped with the active key; older keys are kept so
+// existing values stay readable until they are rotated.
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+// NewKeyring creates a keyring; activeID must be one of the provided keys
+func NewKeyring(keys map[string][]byte, activeID string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no master keys configured")
+	}
+	for id, key := range keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not configured", activeID)
+	}
+	return &Keyring{keys: keys, activeID: activeID}, nil
+}
+
+// ParseKeyring builds a keyring from "id:base64key,id:base64key" and the
+// active key ID. When activeID is empty the first listed key is active.
+func ParseKeyring(spec, activeID string) (*Keyring, error) {
+	keys := make(map[string][]byte)
+	var firstID string
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid master key entry, expected id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+		if firstID == "" {
+			firstID = id
+		}
+	}
+	if activeID == "" {
+		activeID = firstID
+	}
+	return NewKeyring(keys, activeID)
+}
+
+// DevKeyring returns a keyring derived from a fixed passphrase.
+// Development only - production must configure real master keys.
+func DevKeyring() *Keyring {
+	key := sha256.Sum256([]byte("testpilot-dev-secrets-key-change-in-production"))
+	return &Keyring{keys: map[string][]byte{"dev": key[:]}, activeID: "dev"}
+}
+
+// ActiveKeyID returns the ID of the key used for new values
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Seal encrypts plaintext with a fresh data key and wraps that key with the
+// active master key. aad binds the value to where it is stored, so values
+// cannot be swapped between rows.
+func (k *Keyring) Seal(aad string, plaintext []byte) (*Envelope, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, nonce, err := seal(dataKey, plaintext, []byte(aad))
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, keyNonce, err := seal(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Ciphertext: ciphertext,
+		Nonce:      nonce,
+		WrappedKey: wrappedKey,
+		KeyNonce:   keyNonce,
+		KeyID:      k.activeID,
+	}, nil
+}
+
+// Open decrypts a value sealed with the same aad
+func (k *Keyring) Open(envelope *Envelope, aad string) ([]byte, error) {
+	dataKey, err := k.unwrap(envelope)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dataKey, envelope.Ciphertext, envelope.Nonce, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap re-encrypts a value's data key with the active master key.
+// The value itself is untouched. It reports whether anything changed.
+func (k *Keyring) Rewrap(envelope *Envelope) (bool, error) {
+	if envelope.KeyID == k.activeID {
+		return false, nil
+	}
+	dataKey, err := k.unwrap(envelope)
+	if err != nil {
+		return false, err
+	}
+	wrappedKey, keyNonce, err := seal(k.keys[k.activeID], dataKey, []byte(k.activeID))
+	if err != nil {
+		return false, err
+	}
+	envelope.WrappedKey = wrappedKey
+	envelope.KeyNonce = keyNonce
+	envelope.KeyID = k.activeID
+	return true, nil
+}
+
+func (k *Keyring) unwrap(envelope *Envelope) ([]byte, error) {
+	masterKey, ok := k.keys[envelope.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, envelope.KeyID)
+	}
+	dataKey, err := open(masterKey, envelope.WrappedKey, envelope.KeyNonce, []byte(envelope.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func seal(key, plaintext, aad []byte) ([]byte, []byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nil, nonce, plaintext, aad), nonce, nil
+}
+
+func open(key, ciphertext, nonce, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	keyring, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{"empty", []byte{}},
+		{"ascii", []byte("sk-live-123")},
+		{"binary", []byte{0, 1, 2, 255, 254}},
+		{"large", bytes.Repeat([]byte("x"), 64<<10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := keyring.Seal("row:1", tt.plaintext)
+			if err != nil {
+				t.Fatalf("Seal: %v", err)
+			}
+			if sealed.KeyID != "k1" {
+				t.Errorf("KeyID = %q, want k1", sealed.KeyID)
+			}
+			if len(tt.plaintext) > 0 && bytes.Contains(sealed.Ciphertext, tt.plaintext) {
+				t.Error("ciphertext contains the plaintext")
+			}
+			got, err := keyring.Open(sealed, "row:1")
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !bytes.Equal(got, tt.plaintext) {
+				t.Errorf("Open = %q, want %q", got, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshKeys(t *testing.T) {
+	keyring, _ := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1")
+	a, _ := keyring.Seal("row:1", []byte("same"))
+	b, _ := keyring.Seal("row:1", []byte("same"))
+	if bytes.Equal(a.Ciphertext, b.Ciphertext) || bytes.Equal(a.WrappedKey, b.WrappedKey) {
+		t.Error("sealing the same value twice produced the same ciphertext")
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	keyring, _ := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1")
+
+	tests := []struct {
+		name   string
+		aad    string
+		tamper func(e *Envelope)
+	}{
+		{"other aad", "row:2", func(e *Envelope) {}},
+		{"flipped ciphertext", "row:1", func(e *Envelope) { e.Ciphertext[0] ^= 1 }},
+		{"flipped wrapped key", "row:1", func(e *Envelope) { e.WrappedKey[0] ^= 1 }},
+		{"other nonce", "row:1", func(e *Envelope) { e.Nonce[0] ^= 1 }},
+		{"other key nonce", "row:1", func(e *Envelope) { e.KeyNonce[0] ^= 1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := keyring.Seal("row:1", []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(sealed)
+			if _, err := keyring.Open(sealed, tt.aad); err == nil {
+				t.Error("Open succeeded on a tampered value")
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := NewKeyring(map[string][]byte{"old": testKey(1)}, "old")
+	sealed, err := old.Seal("row:1", []byte("rotate me"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalCiphertext := append([]byte(nil), sealed.Ciphertext...)
+
+	rotated, _ := NewKeyring(map[string][]byte{"old": testKey(1), "new": testKey(2)}, "new")
+	if got, err := rotated.Open(sealed, "row:1"); err != nil || string(got) != "rotate me" {
+		t.Fatalf("Open with the old key still configured = %q, %v", got, err)
+	}
+
+	changed, err := rotated.Rewrap(sealed)
+	if err != nil || !changed {
+		t.Fatalf("Rewrap = %v, %v; want true, nil", changed, err)
+	}
+	if sealed.KeyID != "new" {
+		t.Errorf("KeyID after Rewrap = %q, want new", sealed.KeyID)
+	}
+	if !bytes.Equal(sealed.Ciphertext, originalCiphertext) {
+		t.Error("Rewrap changed the value ciphertext")
+	}
+	if changed, err := rotated.Rewrap(sealed); err != nil || changed {
+		t.Errorf("second Rewrap = %v, %v; want false, nil", changed, err)
+	}
+
+	// Once rewrapped, the old key can be retired
+	retired, _ := NewKeyring(map[string][]byte{"new": testKey(2)}, "new")
+	if got, err := retired.Open(sealed, "row:1"); err != nil || string(got) != "rotate me" {
+		t.Fatalf("Open after retiring the old key = %q, %v", got, err)
+	}
+
+	// A value still on a retired key cannot be read
+	stale, _ := old.Seal("row:1", []byte("stale"))
+	if _, err := retired.Open(stale, "row:1"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with an unknown key = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	tests := []struct {
+		name       string
+		spec       string
+		activeID   string
+		wantActive string
+		wantErr    bool
+	}{
+		{"single key", "a:" + k1, "", "a", false},
+		{"first key is active", "a:" + k1 + ", b:" + k2, "", "a", false},
+		{"explicit active key", "a:" + k1 + ",b:" + k2, "b", "b", false},
+		{"unknown active key", "a:" + k1, "c", "", true},
+		{"empty", "", "", "", true},
+		{"missing id", ":" + k1, "", "", true},
+		{"not base64", "a:not-base64!", "", "", true},
+		{"short key", "a:" + base64.StdEncoding.EncodeToString([]byte("short")), "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.spec, tt.activeID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeyring: %v", err)
+			}
+			if keyring.ActiveKeyID() != tt.wantActive {
+				t.Errorf("ActiveKeyID = %q, want %q", keyring.ActiveKeyID(), tt.wantActive)
+			}
+		})
+	}
+}
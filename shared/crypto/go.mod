@@ -0,0 +1,3 @@
+module github.com/testpilot-ai/shared/crypto
+
+go 1.23
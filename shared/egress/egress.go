@@ -0,0 +1,266 @@
+// Package egress guards outbound connections the services make on behalf of
+// users. Connections may only reach public addresses, allowed hosts and
+// ranges, or internal hosts the operator configured.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrBlocked is returned for connections the rules refuse
+var ErrBlocked = errors.New("egress blocked")
+
+// blockedRanges are non-public ranges the net/netip predicates do not cover.
+// Loopback, RFC 1918 / ULA private, link-local (including the cloud metadata
+// address 169.254.169.254), multicast and unspecified addresses are checked
+// with those predicates.
+var blockedRanges = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),  // reserved and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64, may embed any IPv4 address
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// BlockedError reports a refused connection: a host that is not allowed,
+// or a host resolving to a non-public address
+type BlockedError struct {
+	Host   string
+	IP     string
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	if e.IP == "" || e.IP == e.Host {
+		return fmt.Sprintf("%v: %s is %s", ErrBlocked, e.Host, e.Reason)
+	}
+	return fmt.Sprintf("%v: %s resolves to %s, %s", ErrBlocked, e.Host, e.IP, e.Reason)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrBlocked
+}
+
+// Rules are the hosts and addresses connections may reach
+type Rules struct {
+	// Hosts is an allowlist of host names, "*.example.com" matching any
+	// subdomain. Empty allows any host.
+	Hosts []string
+	// Ranges are non-public ranges connections may reach anyway
+	Ranges []netip.Prefix
+	// Internal are services run alongside the platform. They may be reached
+	// on whatever address they resolve to: they are resolved by the
+	// platform's own DNS, so their addresses are trusted.
+	Internal []string
+	// Hint tells users how to allow a refused address
+	Hint string
+}
+
+// CheckHost refuses hosts missing from a non-empty allowlist
+func (r *Rules) CheckHost(host string) error {
+	if len(r.Hosts) == 0 || r.isInternal(host) {
+		return nil
+	}
+	host = NormalizeHost(host)
+	for _, pattern := range r.Hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		} else if host == pattern {
+			return nil
+		}
+	}
+	return &BlockedError{Host: host, Reason: "not in the egress allowlist"}
+}
+
+// CheckAddr refuses non-public addresses outside the allowed ranges
+func (r *Rules) CheckAddr(host string, addr netip.Addr) error {
+	if r.isInternal(host) {
+		return nil
+	}
+	addr = addr.Unmap()
+	for _, prefix := range r.Ranges {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	kind := ""
+	switch {
+	case addr.IsLoopback():
+		kind = "a loopback address"
+	case addr.IsPrivate():
+		kind = "a private address"
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		kind = "a link-local address"
+	case addr.IsUnspecified(), addr.IsMulticast():
+		kind = "not a unicast address"
+	default:
+		for _, prefix := range blockedRanges {
+			if prefix.Contains(addr) {
+				kind = "a reserved address"
+				break
+			}
+		}
+	}
+	if kind == "" {
+		return nil
+	}
+	if r.Hint != "" {
+		kind += "; " + r.Hint
+	}
+	return &BlockedError{Host: host, IP: addr.String(), Reason: kind}
+}
+
+// isInternal reports whether host is one of the operator's internal hosts
+func (r *Rules) isInternal(host string) bool {
+	host = NormalizeHost(host)
+	for _, internal := range r.Internal {
+		if host == NormalizeHost(internal) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeHost lower-cases a host name and drops its trailing dot
+func NormalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
+
+// ParseCIDRs parses a list of CIDRs such as "10.20.0.0/16"
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Dialer resolves the host itself and connects only to addresses the rules
+// allow. Checking the address actually dialed, rather than an earlier
+// lookup, is what defeats DNS rebinding.
+type Dialer struct {
+	dialer *net.Dialer
+	rules  func(ctx context.Context) *Rules
+
+	// proxies are the proxy addresses in use; they are set by the operator,
+	// so dialing them is not checked. Connections through a proxy are
+	// checked in Proxy instead.
+	proxies sync.Map
+}
+
+// NewDialer creates a dialer applying the rules returned for each
+// connection's context. With nil rules connections are not checked.
+func NewDialer(dialer *net.Dialer, rules func(ctx context.Context) *Rules) *Dialer {
+	return &Dialer{dialer: dialer, rules: rules}
+}
+
+// DialContext connects to address if the rules allow it
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if _, ok := d.proxies.Load(address); ok || d.rules == nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	rules := d.rules(ctx)
+	if err := rules.CheckHost(host); err != nil {
+		return nil, err
+	}
+	addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Dial the first allowed address that accepts; the refusal is reported
+	// only if no address was allowed
+	var firstErr error
+	for _, addr := range addrs {
+		if err := rules.CheckAddr(host, addr); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil || errors.Is(firstErr, ErrBlocked) {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// Check applies the rules to a host that something else connects to, like
+// a proxy or an external command. That resolves the host again, so this
+// cannot rule out DNS rebinding.
+func (d *Dialer) Check(ctx context.Context, host string) error {
+	if d.rules == nil {
+		return nil
+	}
+	rules := d.rules(ctx)
+	if err := rules.CheckHost(host); err != nil {
+		return err
+	}
+	addrs, err := d.resolve(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := rules.CheckAddr(host, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Proxy picks the proxy from the environment like http.ProxyFromEnvironment,
+// for use as an http.Transport's Proxy. The proxy connects to the target
+// itself, so the target is checked here.
+func (d *Dialer) Proxy(req *http.Request) (*url.URL, error) {
+	proxyURL, err := http.ProxyFromEnvironment(req)
+	if err != nil || proxyURL == nil || d.rules == nil {
+		return proxyURL, err
+	}
+	if err := d.Check(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	port := proxyURL.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxyURL.Scheme]
+	}
+	d.proxies.Store(net.JoinHostPort(proxyURL.Hostname(), port), true)
+	return proxyURL, nil
+}
+
+func (d *Dialer) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	resolver := d.dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return resolver.LookupNetIP(ctx, "ip", host)
+}
@@ -0,0 +1,184 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		ip      string
+		ranges  []string
+		blocked bool
+	}{
+		{"public ipv4", "93.184.216.34", nil, false},
+		{"public ipv6", "2606:2800:220:1::1", nil, false},
+		{"loopback", "127.0.0.1", nil, true},
+		{"ipv6 loopback", "::1", nil, true},
+		{"ipv4-mapped loopback", "::ffff:127.0.0.1", nil, true},
+		{"ipv4-mapped private", "::ffff:192.168.0.1", nil, true},
+		{"rfc 1918", "10.1.2.3", nil, true},
+		{"rfc 1918 172.16", "172.17.0.2", nil, true},
+		{"rfc 1918 192.168", "192.168.1.10", nil, true},
+		{"unique local ipv6", "fd00::1", nil, true},
+		{"cloud metadata", "169.254.169.254", nil, true},
+		{"ipv6 link-local", "fe80::1", nil, true},
+		{"unspecified", "0.0.0.0", nil, true},
+		{"this network", "0.1.2.3", nil, true},
+		{"carrier-grade nat", "100.64.0.1", nil, true},
+		{"broadcast", "255.255.255.255", nil, true},
+		{"multicast", "224.0.0.1", nil, true},
+		{"nat64 embedding a private address", "64:ff9b::a00:1", nil, true},
+		{"nat64 embedding the metadata address", "64:ff9b::a9fe:a9fe", nil, true},
+		{"allowed private range", "10.20.1.5", []string{"10.20.0.0/16"}, false},
+		{"outside the allowed range", "10.21.1.5", []string{"10.20.0.0/16"}, true},
+		{"allowed loopback via mapped address", "::ffff:127.0.0.1", []string{"127.0.0.0/8"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := ParseCIDRs(tt.ranges)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rules := &Rules{Ranges: ranges}
+			err = rules.CheckAddr("api.example.com", netip.MustParseAddr(tt.ip))
+			if tt.blocked != (err != nil) {
+				t.Fatalf("CheckAddr(%s) = %v, blocked want %v", tt.ip, err, tt.blocked)
+			}
+			if err != nil && !errors.Is(err, ErrBlocked) {
+				t.Errorf("error %v does not wrap ErrBlocked", err)
+			}
+		})
+	}
+}
+
+func TestCheckAddrHint(t *testing.T) {
+	rules := &Rules{Hint: "allow its range in EGRESS_ALLOWED_CIDRS"}
+
+	err := rules.CheckAddr("127.0.0.1", netip.MustParseAddr("127.0.0.1"))
+	want := "egress blocked: 127.0.0.1 is a loopback address; allow its range in EGRESS_ALLOWED_CIDRS"
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+
+	err = rules.CheckAddr("db.internal", netip.MustParseAddr("10.0.0.5"))
+	want = "egress blocked: db.internal resolves to 10.0.0.5, a private address; allow its range in EGRESS_ALLOWED_CIDRS"
+	if err == nil || err.Error() != want {
+		t.Errorf("error = %v, want %q", err, want)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    []string
+		internal []string
+		host     string
+		blocked  bool
+	}{
+		{"empty allowlist", nil, nil, "anything.example.org", false},
+		{"exact match", []string{"api.example.com"}, nil, "api.example.com", false},
+		{"trailing dot and case", []string{"api.example.com"}, nil, "API.example.com.", false},
+		{"not listed", []string{"api.example.com"}, nil, "evil.example.net", true},
+		{"wildcard subdomain", []string{"*.example.com"}, nil, "v2.api.example.com", false},
+		{"wildcard does not match the suffix alone", []string{"*.example.com"}, nil, "notexample.com", true},
+		{"internal host outside the allowlist", []string{"api.example.com"}, []string{"Ingestion."}, "ingestion", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Rules{Hosts: tt.hosts, Internal: tt.internal}).CheckHost(tt.host)
+			if tt.blocked != (err != nil) {
+				t.Errorf("CheckHost(%s) = %v, blocked want %v", tt.host, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := ParseCIDRs([]string{" 10.20.1.0/16", "::1/128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefixes[0].String() != "10.20.0.0/16" || prefixes[1].String() != "::1/128" {
+		t.Errorf("ParseCIDRs = %v", prefixes)
+	}
+	if _, err := ParseCIDRs([]string{"10.0.0.1"}); err == nil {
+		t.Error("expected an error for an address without a prefix length")
+	}
+}
+
+// TestDialedAddress sends real requests through a guarded transport to a
+// server on the loopback interface. Whether the request goes through
+// depends on the address dialed, whatever the host name says.
+func TestDialedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	loopback, _ := ParseCIDRs([]string{"127.0.0.0/8", "::1/128"})
+	tests := []struct {
+		name    string
+		rules   *Rules
+		host    string
+		blocked bool
+	}{
+		{"no rules", nil, "127.0.0.1", false},
+		{"loopback ip is refused", &Rules{}, "127.0.0.1", true},
+		{"allowed host name resolving to loopback is refused", &Rules{Hosts: []string{"localhost"}}, "localhost", true},
+		{"host name resolving to an allowed range", &Rules{Ranges: loopback}, "localhost", false},
+		{"host outside the allowlist is refused before dialing", &Rules{Hosts: []string{"api.example.com"}, Ranges: loopback}, "127.0.0.1", true},
+		{"internal host on a non-public address", &Rules{Hosts: []string{"api.example.com"}, Internal: []string{"LocalHost."}}, "localhost", false},
+		{"internal host does not open its address to other names", &Rules{Internal: []string{"localhost"}}, "127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules func(context.Context) *Rules
+			if tt.rules != nil {
+				rules = func(context.Context) *Rules { return tt.rules }
+			}
+			dialer := NewDialer(&net.Dialer{Timeout: 2 * time.Second}, rules)
+			client := &http.Client{Transport: &http.Transport{Proxy: dialer.Proxy, DialContext: dialer.DialContext}}
+
+			resp, err := client.Get("http://" + net.JoinHostPort(tt.host, port) + "/")
+			if tt.blocked {
+				if !errors.Is(err, ErrBlocked) {
+					t.Fatalf("request error = %v, want ErrBlocked", err)
+				}
+				if checkErr := dialer.Check(context.Background(), tt.host); !errors.Is(checkErr, ErrBlocked) {
+					t.Errorf("Check(%s) = %v, want ErrBlocked", tt.host, checkErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("status = %d", resp.StatusCode)
+			}
+			if err := dialer.Check(context.Background(), tt.host); err != nil {
+				t.Errorf("Check(%s) = %v", tt.host, err)
+			}
+		})
+	}
+}
+
+func TestCheckResolvesHostNames(t *testing.T) {
+	dialer := NewDialer(&net.Dialer{}, func(context.Context) *Rules { return &Rules{} })
+	err := dialer.Check(context.Background(), "localhost")
+	if !errors.Is(err, ErrBlocked) || !strings.Contains(err.Error(), "localhost resolves to") {
+		t.Errorf("Check(localhost) = %v", err)
+	}
+}